@@ -0,0 +1,71 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package client carries information about the client that sent the received
+// data, e.g. its address, in the context.Context passed to the consumers.
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc/peer"
+)
+
+// Client is the sender of the received data.
+type Client struct {
+	// IP is the address of the connection the data was received on.
+	IP string
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the given client.
+func NewContext(ctx context.Context, c *Client) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the client carried by the context, if any.
+func FromContext(ctx context.Context) (*Client, bool) {
+	c, ok := ctx.Value(contextKey{}).(*Client)
+	return c, ok && c != nil
+}
+
+// FromGRPC returns the client of the gRPC peer of ctx, if any.
+func FromGRPC(ctx context.Context) (*Client, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil, false
+	}
+	return fromAddress(p.Addr.String())
+}
+
+// FromHTTP returns the client of the remote address of the request.
+func FromHTTP(r *http.Request) (*Client, bool) {
+	return fromAddress(r.RemoteAddr)
+}
+
+func fromAddress(addr string) (*Client, bool) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		// The address may have no port, e.g. for Unix sockets.
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, false
+	}
+	return &Client{IP: ip.String()}, true
+}
@@ -0,0 +1,70 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/peer"
+)
+
+func TestFromContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := NewContext(context.Background(), &Client{IP: "10.1.2.3"})
+	c, ok := FromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "10.1.2.3", c.IP)
+}
+
+func TestFromGRPC(t *testing.T) {
+	_, ok := FromGRPC(context.Background())
+	assert.False(t, ok)
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 55678},
+	})
+	c, ok := FromGRPC(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "10.1.2.3", c.IP)
+}
+
+func TestFromHTTP(t *testing.T) {
+	tests := []struct {
+		remoteAddr string
+		want       string
+	}{
+		{remoteAddr: "10.1.2.3:9411", want: "10.1.2.3"},
+		{remoteAddr: "[fd00::1]:9411", want: "fd00::1"},
+		{remoteAddr: "10.1.2.3", want: "10.1.2.3"},
+		{remoteAddr: "@"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.remoteAddr, func(t *testing.T) {
+			c, ok := FromHTTP(&http.Request{RemoteAddr: tt.remoteAddr})
+			if tt.want == "" {
+				assert.False(t, ok)
+				return
+			}
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, c.IP)
+		})
+	}
+}
@@ -158,8 +158,7 @@ func runOCAgent() {
 	}
 
 	if agentConfig.JaegerReceiverEnabled() {
-		collectorHTTPPort, collectorThriftPort := agentConfig.JaegerReceiverPorts()
-		jaegerDoneFn, err := runJaegerReceiver(collectorThriftPort, collectorHTTPPort, commonSpanSink, asyncErrorChan)
+		jaegerDoneFn, err := runJaegerReceiver(&agentConfig, commonSpanSink, asyncErrorChan)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -263,15 +262,25 @@ func runOCReceiver(logger *zap.Logger, acfg *config.Config, tc consumer.TraceCon
 	return doneFn, nil
 }
 
-func runJaegerReceiver(collectorThriftPort, collectorHTTPPort int, next consumer.TraceConsumer, asyncErrorChan chan<- error) (doneFn func() error, err error) {
+func runJaegerReceiver(acfg *config.Config, next consumer.TraceConsumer, asyncErrorChan chan<- error) (doneFn func() error, err error) {
+	collectorHTTPPort, collectorThriftPort := acfg.JaegerReceiverPorts()
+	collectorGRPCPort := acfg.JaegerReceiverGRPCPort()
 	config := &jaegerreceiver.Configuration{
 		CollectorThriftPort: collectorThriftPort,
 		CollectorHTTPPort:   collectorHTTPPort,
+		CollectorGRPCPort:   collectorGRPCPort,
 
 		// TODO: (@odeke-em, @pjanotti) send a change
 		// to dynamically retrieve the Jaeger Agent's ports
 		// and not use their defaults of 5778, 6831, 6832
 	}
+	tlsCredsOption, hasTLSCreds, err := acfg.JaegerReceiverTLSCredentialsServerOption()
+	if err != nil {
+		return nil, fmt.Errorf("Jaeger receiver TLS Credentials: %v", err)
+	}
+	if hasTLSCreds {
+		config.CollectorGRPCOptions = append(config.CollectorGRPCOptions, tlsCredsOption)
+	}
 	jtr, err := jaegerreceiver.New(context.Background(), config, next)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create new Jaeger receiver: %v", err)
@@ -282,7 +291,7 @@ func runJaegerReceiver(collectorThriftPort, collectorHTTPPort int, next consumer
 	doneFn = func() error {
 		return jtr.StopTraceReception(context.Background())
 	}
-	log.Printf("Running Jaeger receiver with CollectorThriftPort %d CollectHTTPPort %d", collectorThriftPort, collectorHTTPPort)
+	log.Printf("Running Jaeger receiver with CollectorThriftPort %d CollectHTTPPort %d CollectorGRPCPort %d", collectorThriftPort, collectorHTTPPort, collectorGRPCPort)
 	return doneFn, nil
 }
 
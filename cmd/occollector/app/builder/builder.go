@@ -21,6 +21,8 @@ import (
 	"time"
 
 	"github.com/census-instrumentation/opencensus-service/internal/config"
+	"github.com/census-instrumentation/opencensus-service/propagation"
+	"github.com/census-instrumentation/opencensus-service/tenancy"
 	"github.com/spf13/viper"
 )
 
@@ -30,6 +32,7 @@ const (
 	opencensusEntry   = "opencensus"
 	zipkinEntry       = "zipkin"
 	zipkinScribeEntry = "zipkin-scribe"
+	kafkaEntry        = "kafka"
 
 	// flags
 	configCfg                   = "config"
@@ -37,6 +40,7 @@ const (
 	ocReceiverFlg               = "receive-oc-trace"
 	zipkinReceiverFlg           = "receive-zipkin"
 	zipkinScribeReceiverFlg     = "receive-zipkin-scribe"
+	kafkaReceiverFlg            = "receive-kafka"
 	loggingExporterFlg          = "logging-exporter"
 	useTailSamplingAlwaysSample = "tail-sampling-always-sample"
 )
@@ -52,6 +56,8 @@ func Flags(flags *flag.FlagSet) {
 		fmt.Sprintf("Flag to run the Zipkin receiver, default settings: %+v", *NewDefaultZipkinReceiverCfg()))
 	flags.Bool(zipkinScribeReceiverFlg, false,
 		fmt.Sprintf("Flag to run the Zipkin Scribe receiver, default settings: %+v", *NewDefaultZipkinScribeReceiverCfg()))
+	flags.Bool(kafkaReceiverFlg, false,
+		fmt.Sprintf("Flag to run the Kafka receiver, default settings: %+v", *NewDefaultKafkaReceiverCfg()))
 	flags.Bool(loggingExporterFlg, false, "Flag to add a logging exporter (combine with log level DEBUG to log incoming spans)")
 	flags.Bool(useTailSamplingAlwaysSample, false, "Flag to use a tail-based sampling processor with an always sample policy, "+
 		"unless tail sampling setting is present on configuration file.")
@@ -78,6 +84,13 @@ type JaegerReceiverCfg struct {
 	ThriftTChannelPort int `mapstructure:"jaeger-thrift-tchannel-port"`
 	// ThriftHTTPPort is the port that the relay receives on for jaeger thrift http requests
 	ThriftHTTPPort int `mapstructure:"jaeger-thrift-http-port"`
+	// ProtoGRPCPort is the port that the relay receives on for jaeger proto grpc requests,
+	// no grpc requests are received if it is not set
+	ProtoGRPCPort int `mapstructure:"jaeger-proto-grpc-port"`
+	// TLSCredentials is a (cert_file, key_file) configuration for the grpc endpoint.
+	TLSCredentials *config.TLSCredentials `mapstructure:"tls_credentials"`
+	// Metadata selects the headers of the thrift http requests passed along with the data
+	Metadata *propagation.Extractor `mapstructure:"metadata"`
 }
 
 // JaegerReceiverEnabled checks if the Jaeger receiver is enabled, via a command-line flag, environment
@@ -116,6 +129,12 @@ type OpenCensusReceiverCfg struct {
 
 	// MaxConcurrentStreams sets the limit on the number of concurrent streams to each ServerTransport.
 	MaxConcurrentStreams uint32 `mapstructure:"max-concurrent-streams"`
+
+	// Tenant configures how the tenant of each stream is identified.
+	Tenant *tenancy.Extractor `mapstructure:"tenant"`
+
+	// Metadata selects the gRPC metadata of each stream passed along with the data.
+	Metadata *propagation.Extractor `mapstructure:"metadata"`
 }
 
 type serverParametersAndEnforcementPolicy struct {
@@ -163,6 +182,12 @@ func (cfg *OpenCensusReceiverCfg) InitFromViper(v *viper.Viper) (*OpenCensusRece
 type ZipkinReceiverCfg struct {
 	// Port is the port that the receiver will use
 	Port int `mapstructure:"port"`
+
+	// Tenant configures how the tenant of each request is identified.
+	Tenant *tenancy.Extractor `mapstructure:"tenant"`
+
+	// Metadata selects the headers of each request passed along with the data.
+	Metadata *propagation.Extractor `mapstructure:"metadata"`
 }
 
 // ZipkinReceiverEnabled checks if the Zipkin receiver is enabled, via a command-line flag, environment
@@ -219,6 +244,48 @@ func (cfg *ScribeReceiverCfg) InitFromViper(v *viper.Viper) (*ScribeReceiverCfg,
 	return cfg, initFromViper(cfg, v, receiversRoot, zipkinEntry)
 }
 
+// KafkaReceiverCfg carries the settings for the Kafka receiver.
+type KafkaReceiverCfg struct {
+	// Brokers is the addresses of the Kafka brokers used to bootstrap.
+	Brokers []string `mapstructure:"brokers"`
+	// Topics is the topics to consume spans from.
+	Topics []string `mapstructure:"topics"`
+	// GroupID is the consumer group the collector joins.
+	GroupID string `mapstructure:"group-id"`
+	// ClientID is the client ID sent to the brokers.
+	ClientID string `mapstructure:"client-id"`
+	// Encoding is the encoding of the messages: jaeger_proto, jaeger_json,
+	// zipkin_json, zipkin_proto or oc_proto.
+	Encoding string `mapstructure:"encoding"`
+	// InitialOffset is where to start partitions without a committed offset:
+	// latest or earliest.
+	InitialOffset string `mapstructure:"initial-offset"`
+}
+
+// KafkaReceiverEnabled checks if the Kafka receiver is enabled, via a command-line flag, environment
+// variable, or configuration file.
+func KafkaReceiverEnabled(v *viper.Viper) bool {
+	return featureEnabled(v, kafkaReceiverFlg, receiversRoot, kafkaEntry)
+}
+
+// NewDefaultKafkaReceiverCfg returns an instance of KafkaReceiverCfg with default values.
+func NewDefaultKafkaReceiverCfg() *KafkaReceiverCfg {
+	opts := &KafkaReceiverCfg{
+		Brokers:       []string{"localhost:9092"},
+		Topics:        []string{"jaeger-spans"},
+		GroupID:       "opencensus-service",
+		ClientID:      "opencensus-service",
+		Encoding:      "jaeger_proto",
+		InitialOffset: "latest",
+	}
+	return opts
+}
+
+// InitFromViper returns a KafkaReceiverCfg according to the configuration.
+func (cfg *KafkaReceiverCfg) InitFromViper(v *viper.Viper) (*KafkaReceiverCfg, error) {
+	return cfg, initFromViper(cfg, v, receiversRoot, kafkaEntry)
+}
+
 // Helper functions
 
 func initFromViper(cfg interface{}, v *viper.Viper, labels ...string) error {
@@ -170,6 +170,14 @@ func TestTailSamplingPoliciesConfiguration(t *testing.T) {
 				Values: []string{"key 1", "key 2"},
 			},
 		},
+		{
+			Name:      "span-match5",
+			Type:      SpanMatch,
+			Exporters: []string{"jaeger6"},
+			Configuration: &SpanMatchCfg{
+				Expression: `service == "frontend" and duration > 2s`,
+			},
+		},
 		{
 			Name:      "numeric-attribute-filter4",
 			Type:      NumericAttributeFilter,
@@ -242,6 +250,32 @@ func TestOpencensusReceiverKeepaliveSettings(t *testing.T) {
 	}
 }
 
+func TestKafkaReceiverConfig(t *testing.T) {
+	v, err := loadViperFromFile("./testdata/kafka_receiver_config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load viper from test file: %v", err)
+	}
+
+	if !KafkaReceiverEnabled(v) {
+		t.Fatalf("Kafka receiver was not enabled")
+	}
+
+	wCfg := NewDefaultKafkaReceiverCfg()
+	wCfg.Brokers = []string{"kafka-0:9092", "kafka-1:9092"}
+	wCfg.Topics = []string{"oc-spans"}
+	wCfg.GroupID = "collector-tier-2"
+	wCfg.Encoding = "oc_proto"
+	wCfg.InitialOffset = "earliest"
+
+	gCfg, err := NewDefaultKafkaReceiverCfg().InitFromViper(v)
+	if err != nil {
+		t.Fatalf("got '%v', want nil", err)
+	}
+	if !reflect.DeepEqual(gCfg, wCfg) {
+		t.Fatalf("Wanted %+v but got %+v", *wCfg, *gCfg)
+	}
+}
+
 func loadViperFromFile(file string) (*viper.Viper, error) {
 	v := viper.New()
 	v.SetConfigFile(file)
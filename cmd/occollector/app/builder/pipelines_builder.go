@@ -26,16 +26,27 @@ import (
 )
 
 // builtProcessor is a processor that is built based on a config.
-// It can have a trace and/or a metrics consumer.
+// It can have a trace and/or a metrics consumer, and the stop functions of
+// the processors of its pipeline that need to be stopped.
 type builtProcessor struct {
-	tc consumer.TraceConsumer
-	mc consumer.MetricsConsumer
+	tc    consumer.TraceConsumer
+	mc    consumer.MetricsConsumer
+	stops []func() error
 }
 
 // PipelineProcessors is a map of entry-point processors created from pipeline configs.
 // Each element of the map points to the first processor of the pipeline.
 type PipelineProcessors map[*configmodels.Pipeline]*builtProcessor
 
+// StopAll stops the processors of all pipelines.
+func (pps PipelineProcessors) StopAll() {
+	for _, pp := range pps {
+		for _, stop := range pp.stops {
+			stop()
+		}
+	}
+}
+
 // PipelinesBuilder builds pipelines from config.
 type PipelinesBuilder struct {
 	logger    *zap.Logger
@@ -70,22 +81,27 @@ func (eb *PipelinesBuilder) Build() (PipelineProcessors, error) {
 
 // Builds a pipeline of processors. Returns the first processor in the pipeline.
 // The last processor in the pipeline will be plugged to fan out the data into exporters
-// that are configured for this pipeline.
+// that are configured for this pipeline, unless it is a routing processor, which is given
+// the exporters themselves.
 func (eb *PipelinesBuilder) buildPipeline(
 	pipelineCfg *configmodels.Pipeline,
 ) (*builtProcessor, error) {
 
 	// Build the pipeline backwards.
 
-	// First create a consumer junction point that fans out the data to all exporters.
+	// First create a consumer junction point that fans out the data to all exporters,
+	// unless the last processor routes the data to the exporters itself.
 	var tc consumer.TraceConsumer
 	var mc consumer.MetricsConsumer
+	var stops []func() error
 
-	switch pipelineCfg.InputType {
-	case configmodels.TracesDataType:
-		tc = eb.buildFanoutExportersTraceConsumer(pipelineCfg.Exporters)
-	case configmodels.MetricsDataType:
-		mc = eb.buildFanoutExportersMetricsConsumer(pipelineCfg.Exporters)
+	if !eb.isRoutingPipeline(pipelineCfg) {
+		switch pipelineCfg.InputType {
+		case configmodels.TracesDataType:
+			tc = eb.buildFanoutExportersTraceConsumer(pipelineCfg.Exporters)
+		case configmodels.MetricsDataType:
+			mc = eb.buildFanoutExportersMetricsConsumer(pipelineCfg.Exporters)
+		}
 	}
 
 	// Now build the processors backwards, starting from the last one.
@@ -97,6 +113,36 @@ func (eb *PipelinesBuilder) buildPipeline(
 		procCfg := eb.config.Processors[procName]
 
 		factory := factories.GetProcessorFactory(procCfg.Type())
+		if stoppable, ok := factory.(factories.StoppableProcessorFactory); ok {
+			stops = append(stops, func() error {
+				return stoppable.StopProcessor(procCfg)
+			})
+		}
+
+		// A routing processor is given the exporters of the pipeline instead
+		// of a next consumer, so it can only be the last one.
+		if routingFactory, ok := factory.(factories.RoutingProcessorFactory); ok {
+			if i != len(pipelineCfg.Processors)-1 {
+				return nil, fmt.Errorf("routing processor %q must be the last processor in pipeline %q",
+					procName, pipelineCfg.Name)
+			}
+
+			var err error
+			switch pipelineCfg.InputType {
+			case configmodels.TracesDataType:
+				tc, err = routingFactory.CreateRoutingTraceProcessor(
+					eb.getExportersTraceConsumersByNames(pipelineCfg.Exporters), procCfg)
+			case configmodels.MetricsDataType:
+				mc, err = routingFactory.CreateRoutingMetricsProcessor(
+					eb.getExportersMetricsConsumersByNames(pipelineCfg.Exporters), procCfg)
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("error creating processor %q in pipeline %q: %v",
+					procName, pipelineCfg.Name, err)
+			}
+			continue
+		}
 
 		// This processor must point to the next consumer and then
 		// it becomes the next for the previous one (previous in the pipeline,
@@ -115,7 +161,7 @@ func (eb *PipelinesBuilder) buildPipeline(
 		}
 	}
 
-	return &builtProcessor{tc, mc}, nil
+	return &builtProcessor{tc, mc, stops}, nil
 }
 
 // Converts the list of exporter names to a list of corresponding builtExporters.
@@ -129,6 +175,35 @@ func (eb *PipelinesBuilder) getBuiltExportersByNames(exporterNames []string) []*
 	return result
 }
 
+// isRoutingPipeline returns true if the last processor of the pipeline routes
+// the data to the exporters itself.
+func (eb *PipelinesBuilder) isRoutingPipeline(pipelineCfg *configmodels.Pipeline) bool {
+	if len(pipelineCfg.Processors) == 0 {
+		return false
+	}
+	procCfg := eb.config.Processors[pipelineCfg.Processors[len(pipelineCfg.Processors)-1]]
+	_, ok := factories.GetProcessorFactory(procCfg.Type()).(factories.RoutingProcessorFactory)
+	return ok
+}
+
+// Converts the list of exporter names to a map of the corresponding trace consumers.
+func (eb *PipelinesBuilder) getExportersTraceConsumersByNames(exporterNames []string) map[string]consumer.TraceConsumer {
+	result := make(map[string]consumer.TraceConsumer, len(exporterNames))
+	for _, name := range exporterNames {
+		result[name] = eb.exporters[eb.config.Exporters[name]].tc
+	}
+	return result
+}
+
+// Converts the list of exporter names to a map of the corresponding metrics consumers.
+func (eb *PipelinesBuilder) getExportersMetricsConsumersByNames(exporterNames []string) map[string]consumer.MetricsConsumer {
+	result := make(map[string]consumer.MetricsConsumer, len(exporterNames))
+	for _, name := range exporterNames {
+		result[name] = eb.exporters[eb.config.Exporters[name]].mc
+	}
+	return result
+}
+
 func (eb *PipelinesBuilder) buildFanoutExportersTraceConsumer(exporterNames []string) consumer.TraceConsumer {
 	builtExporters := eb.getBuiltExportersByNames(exporterNames)
 
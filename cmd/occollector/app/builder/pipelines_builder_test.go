@@ -29,10 +29,12 @@ import (
 	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
 	"github.com/census-instrumentation/opencensus-service/internal/configv2"
 	"github.com/census-instrumentation/opencensus-service/processor/addattributesprocessor"
+	"github.com/census-instrumentation/opencensus-service/processor/routingprocessor"
 )
 
-// Ensure attributes processor is registered.
+// Ensure attributes and routing processors are registered.
 var _ = addattributesprocessor.ConfigV2{}
+var _ = routingprocessor.ConfigV2{}
 
 // Register test factories used in the pipelines_builder.yaml test config.
 var _ = configv2.RegisterTestFactories()
@@ -53,6 +55,11 @@ func TestPipelinesBuilder_Build(t *testing.T) {
 			pipelineName:  "traces/2",
 			exporterNames: []string{"exampleexporter", "exampleexporter/2"},
 		},
+		{
+			name:          "routing",
+			pipelineName:  "traces/routing",
+			exporterNames: []string{"exampleexporter/2"},
+		},
 	}
 
 	for _, test := range tests {
@@ -141,3 +148,18 @@ func TestPipelinesBuilder_Error(t *testing.T) {
 
 	assert.NotNil(t, err)
 }
+
+func TestPipelinesBuilder_RoutingNotLast(t *testing.T) {
+	config, err := configv2.LoadConfigFile(t, "testdata/pipelines_builder.yaml")
+	require.Nil(t, err)
+
+	// Move the routing processor before the "attributes" processor.
+	pipeline := config.Pipelines["traces/routing"]
+	pipeline.Processors = []string{"routing", "attributes"}
+
+	exporters, err := NewExportersBuilder(zap.NewNop(), config).Build()
+	require.NoError(t, err)
+
+	_, err = NewPipelinesBuilder(zap.NewNop(), config, exporters).Build()
+	assert.Error(t, err)
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/census-instrumentation/opencensus-service/processor/attributekeyprocessor"
+	"github.com/census-instrumentation/opencensus-service/propagation"
 )
 
 // SenderType indicates the type of sender
@@ -58,9 +59,10 @@ func NewJaegerThriftTChannelSenderCfg() *JaegerThriftTChannelSenderCfg {
 
 // JaegerThriftHTTPSenderCfg holds configuration for Jaeger Thrift HTTP sender
 type JaegerThriftHTTPSenderCfg struct {
-	CollectorEndpoint string            `mapstructure:"collector-endpoint"`
-	Timeout           time.Duration     `mapstructure:"timeout"`
-	Headers           map[string]string `mapstructure:"headers"`
+	CollectorEndpoint string                `mapstructure:"collector-endpoint"`
+	Timeout           time.Duration         `mapstructure:"timeout"`
+	Headers           map[string]string     `mapstructure:"headers"`
+	ForwardMetadata   *propagation.Injector `mapstructure:"forward-metadata"`
 }
 
 // NewJaegerThriftHTTPSenderCfg returns an instance of JaegerThriftHTTPSenderCfg with default values
@@ -131,6 +133,10 @@ type AttributesCfg struct {
 	Overwrite       bool                                   `mapstructure:"overwrite"`
 	Values          map[string]interface{}                 `mapstructure:"values"`
 	KeyReplacements []attributekeyprocessor.KeyReplacement `mapstructure:"key-mapping,omitempty"`
+	// Match is a spanmatch expression selecting the spans the attributes are
+	// added to and the keys replaced on, all of them if empty. Key replacements
+	// with their own match expression use it instead.
+	Match string `mapstructure:"match"`
 }
 
 // GlobalProcessorCfg holds global configuration values that apply to all processors
@@ -55,6 +55,9 @@ const (
 	StringAttributeFilter PolicyType = "string-attribute-filter"
 	// RateLimiting allows all traces until the specified limits are satisfied.
 	RateLimiting PolicyType = "rate-limiting"
+	// SpanMatch samples traces that have a span matching a spanmatch expression,
+	// e.g.: service == "frontend" and duration > 2s.
+	SpanMatch PolicyType = "span-match"
 )
 
 // PolicyCfg holds the common configuration to all policies.
@@ -97,6 +100,13 @@ type RateLimitingCfg struct {
 	SpansPerSecond int64 `mapstructure:"spans-per-second"`
 }
 
+// SpanMatchCfg holds the configurable settings to create a span match sampling
+// policy evaluator.
+type SpanMatchCfg struct {
+	// Expression is the spanmatch expression a span of the trace must match.
+	Expression string `mapstructure:"expression"`
+}
+
 // SamplingCfg holds the sampling configuration.
 type SamplingCfg struct {
 	// Mode specifies the sampling mode to be used.
@@ -147,6 +157,9 @@ func (sCfg *SamplingCfg) InitFromViper(v *viper.Viper) *SamplingCfg {
 			case RateLimiting:
 				rateLimitingCfg := &RateLimitingCfg{}
 				cfg = rateLimitingCfg
+			case SpanMatch:
+				spanMatchCfg := &SpanMatchCfg{}
+				cfg = spanMatchCfg
 			}
 			cfgSub.Unmarshal(cfg)
 			polCfg.Configuration = cfg
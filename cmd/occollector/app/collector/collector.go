@@ -51,6 +51,14 @@ type Application struct {
 	receivers   []receiver.TraceReceiver
 	exporters   builder.Exporters
 
+	// metricsProcessor receives the metrics of the OpenCensus receiver, it is
+	// nil when no metrics exporter is configured.
+	metricsProcessor consumer.MetricsConsumer
+
+	// pipelineProcessors are the processors of the pipelines of the unified
+	// service.
+	pipelineProcessors builder.PipelineProcessors
+
 	// stopTestChan is used to terminate the application in end to end tests.
 	stopTestChan chan struct{}
 	// readyChan is used in tests to indicate that the application is ready.
@@ -172,9 +180,9 @@ func (app *Application) execute() {
 
 	app.setupPProf()
 	app.setupHealthCheck()
-	app.processor, app.closeFns = startProcessor(app.v, app.logger)
+	app.processor, app.metricsProcessor, app.closeFns = startProcessor(app.v, app.logger)
 	app.setupZPages()
-	app.receivers = createReceivers(app.v, app.logger, app.processor, app.asyncErrorChannel)
+	app.receivers = createReceivers(app.v, app.logger, app.processor, app.metricsProcessor, app.asyncErrorChannel)
 	app.setupTelemetry()
 
 	// Everything is ready, now run until an event requiring shutdown happens.
@@ -239,7 +247,7 @@ func (app *Application) setupPipelines() {
 
 	// Create pipelines and their processors and plug exporters to the
 	// end of the pipelines.
-	_, err = builder.NewPipelinesBuilder(app.logger, config, app.exporters).Build()
+	app.pipelineProcessors, err = builder.NewPipelinesBuilder(app.logger, config, app.exporters).Build()
 	if err != nil {
 		log.Fatalf("Cannot load configuration: %v", err)
 	}
@@ -254,8 +262,7 @@ func (app *Application) shutdownPipelines() {
 
 	// TODO: shutdown receivers.
 
-	// TODO: shutdown processors
-
+	app.pipelineProcessors.StopAll()
 	app.exporters.StopAll()
 }
 
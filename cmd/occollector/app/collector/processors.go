@@ -32,6 +32,7 @@ import (
 	"github.com/census-instrumentation/opencensus-service/internal/collector/processor/tailsampling"
 	"github.com/census-instrumentation/opencensus-service/internal/collector/sampling"
 	"github.com/census-instrumentation/opencensus-service/internal/config"
+	"github.com/census-instrumentation/opencensus-service/internal/spanmatch"
 	"github.com/census-instrumentation/opencensus-service/processor/addattributesprocessor"
 	"github.com/census-instrumentation/opencensus-service/processor/attributekeyprocessor"
 	"github.com/census-instrumentation/opencensus-service/processor/multiconsumer"
@@ -59,9 +60,13 @@ func createExporters(v *viper.Viper, logger *zap.Logger) ([]func(), []consumer.T
 	return wrappedDoneFns, traceExporters, metricsExporters
 }
 
+// buildQueuedSpanProcessor builds the queued processors for the senders and exporters of a
+// queued-exporters entry: spans go to its sender and trace exporters, metrics to its metrics
+// exporters, all through queues sharing the entry configuration. queuedMetricsProcessor is nil
+// when the entry has no metrics exporters.
 func buildQueuedSpanProcessor(
 	logger *zap.Logger, opts *builder.QueuedSpanProcessorCfg,
-) (closeFns []func(), queuedSpanProcessor consumer.TraceConsumer, err error) {
+) (closeFns []func(), queuedSpanProcessor consumer.TraceConsumer, queuedMetricsProcessor consumer.MetricsConsumer, err error) {
 	logger.Info("Constructing queue processor with name", zap.String("name", opts.Name))
 
 	// build span batch sender from configured options
@@ -78,7 +83,7 @@ func buildQueuedSpanProcessor(
 		tchreporter, err := tchrepbuilder.CreateReporter(logger)
 		if err != nil {
 			logger.Fatal("Cannot create tchannel reporter.", zap.Error(err))
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		spanSender = sender.NewJaegerThriftTChannelSender(tchreporter, logger)
 	case builder.ThriftHTTPSenderType:
@@ -90,6 +95,7 @@ func buildQueuedSpanProcessor(
 			thriftHTTPSenderOpts.Headers,
 			logger,
 			sender.HTTPTimeout(thriftHTTPSenderOpts.Timeout),
+			sender.HTTPMetadataInjector(thriftHTTPSenderOpts.ForwardMetadata),
 		)
 	case builder.ProtoGRPCSenderType:
 		protoGRPCSenderOpts := opts.SenderConfig.(*builder.JaegerProtoGRPCSenderCfg)
@@ -100,9 +106,9 @@ func buildQueuedSpanProcessor(
 			logger,
 		)
 	}
-	doneFns, traceExporters, _ := createExporters(opts.RawConfig, logger)
+	doneFns, traceExporters, metricsExporters := createExporters(opts.RawConfig, logger)
 
-	if spanSender == nil && len(traceExporters) == 0 {
+	if spanSender == nil && len(traceExporters) == 0 && len(metricsExporters) == 0 {
 		if opts.SenderType != "" {
 			logger.Fatal("Unrecognized sender type", zap.String("SenderType", string(opts.SenderType)))
 		}
@@ -145,25 +151,37 @@ func buildQueuedSpanProcessor(
 		}
 	}
 
+	queuedOptions := []queued.Option{
+		queued.Options.WithLogger(logger),
+		queued.Options.WithName(opts.Name),
+		queued.Options.WithNumWorkers(opts.NumWorkers),
+		queued.Options.WithQueueSize(opts.QueueSize),
+		queued.Options.WithRetryOnProcessingFailures(opts.RetryOnFailure),
+		queued.Options.WithBackoffDelay(opts.BackoffDelay),
+		queued.Options.WithBatching(opts.BatchingConfig.Enable),
+		queued.Options.WithBatchingOptions(batchingOptions...),
+	}
+
 	queuedConsumers := make([]consumer.TraceConsumer, 0, len(allSendersAndExporters))
 	for _, senderOrExporter := range allSendersAndExporters {
 		// build queued span processor with underlying sender
 		queuedConsumers = append(
 			queuedConsumers,
-			queued.NewQueuedSpanProcessor(
-				senderOrExporter,
-				queued.Options.WithLogger(logger),
-				queued.Options.WithName(opts.Name),
-				queued.Options.WithNumWorkers(opts.NumWorkers),
-				queued.Options.WithQueueSize(opts.QueueSize),
-				queued.Options.WithRetryOnProcessingFailures(opts.RetryOnFailure),
-				queued.Options.WithBackoffDelay(opts.BackoffDelay),
-				queued.Options.WithBatching(opts.BatchingConfig.Enable),
-				queued.Options.WithBatchingOptions(batchingOptions...),
-			),
+			queued.NewQueuedSpanProcessor(senderOrExporter, queuedOptions...),
 		)
 	}
-	return doneFns, multiconsumer.NewTraceProcessor(queuedConsumers), nil
+
+	if len(metricsExporters) > 0 {
+		queuedMetricsConsumers := make([]consumer.MetricsConsumer, 0, len(metricsExporters))
+		for _, metricsExporter := range metricsExporters {
+			queuedMetricsConsumers = append(
+				queuedMetricsConsumers,
+				queued.NewQueuedMetricsProcessor(metricsExporter, queuedOptions...),
+			)
+		}
+		queuedMetricsProcessor = multiconsumer.NewMetricsProcessor(queuedMetricsConsumers)
+	}
+	return doneFns, multiconsumer.NewTraceProcessor(queuedConsumers), queuedMetricsProcessor, nil
 }
 
 func buildSamplingProcessor(cfg *builder.SamplingCfg, nameToTraceConsumer map[string]consumer.TraceConsumer, v *viper.Viper, logger *zap.Logger) (consumer.TraceConsumer, error) {
@@ -187,6 +205,13 @@ func buildSamplingProcessor(cfg *builder.SamplingCfg, nameToTraceConsumer map[st
 		case builder.RateLimiting:
 			rateLimitingCfg := polCfg.Configuration.(*builder.RateLimitingCfg)
 			policy.Evaluator = sampling.NewRateLimiting(rateLimitingCfg.SpansPerSecond)
+		case builder.SpanMatch:
+			spanMatchCfg := polCfg.Configuration.(*builder.SpanMatchCfg)
+			expr, err := spanmatch.Parse(spanMatchCfg.Expression)
+			if err != nil {
+				return nil, fmt.Errorf("sampling policy %q: %v", polCfg.Name, err)
+			}
+			policy.Evaluator = sampling.NewSpanMatchFilter(expr)
 		default:
 			return nil, fmt.Errorf("unknown sampling policy %s", polCfg.Name)
 		}
@@ -233,11 +258,12 @@ func buildSamplingProcessor(cfg *builder.SamplingCfg, nameToTraceConsumer map[st
 	return tailSamplingProcessor, err
 }
 
-func startProcessor(v *viper.Viper, logger *zap.Logger) (consumer.TraceConsumer, []func()) {
+func startProcessor(v *viper.Viper, logger *zap.Logger) (consumer.TraceConsumer, consumer.MetricsConsumer, []func()) {
 	// Build pipeline from its end: 1st exporters, the OC-proto queue processor, and
 	// finally the receivers.
 	var closeFns []func()
 	var traceConsumers []consumer.TraceConsumer
+	var metricsConsumers []consumer.MetricsConsumer
 	nameToTraceConsumer := make(map[string]consumer.TraceConsumer)
 	exportersCloseFns, traceExporters, metricsExporters := createExporters(v, logger)
 	closeFns = append(closeFns, exportersCloseFns...)
@@ -252,8 +278,9 @@ func startProcessor(v *viper.Viper, logger *zap.Logger) (consumer.TraceConsumer,
 		traceConsumers = append(traceConsumers, traceExpProc)
 	}
 
-	// TODO: (@pjanotti) make use of metrics exporters
-	_ = metricsExporters
+	if len(metricsExporters) > 0 {
+		metricsConsumers = append(metricsConsumers, multiconsumer.NewMetricsProcessor(metricsExporters))
+	}
 
 	if builder.LoggingExporterEnabled(v) {
 		dbgProc, _ := loggingexporter.NewTraceExporter(logger)
@@ -265,16 +292,25 @@ func startProcessor(v *viper.Viper, logger *zap.Logger) (consumer.TraceConsumer,
 	multiProcessorCfg := builder.NewDefaultMultiSpanProcessorCfg().InitFromViper(v)
 	for _, queuedJaegerProcessorCfg := range multiProcessorCfg.Processors {
 		logger.Info("Queued Jaeger Sender Enabled")
-		doneFns, queuedJaegerProcessor, err := buildQueuedSpanProcessor(logger, queuedJaegerProcessorCfg)
+		doneFns, queuedJaegerProcessor, queuedMetricsProcessor, err := buildQueuedSpanProcessor(logger, queuedJaegerProcessorCfg)
 		if err != nil {
 			logger.Error("Failed to build the queued span processor", zap.Error(err))
 			os.Exit(1)
 		}
 		nameToTraceConsumer[queuedJaegerProcessorCfg.Name] = queuedJaegerProcessor
 		traceConsumers = append(traceConsumers, queuedJaegerProcessor)
+		if queuedMetricsProcessor != nil {
+			metricsConsumers = append(metricsConsumers, queuedMetricsProcessor)
+		}
 		closeFns = append(closeFns, doneFns...)
 	}
 
+	// Only the OpenCensus receiver accepts metrics, and only when there is a consumer for them.
+	var mp consumer.MetricsConsumer
+	if len(metricsConsumers) > 0 {
+		mp = multiconsumer.NewMetricsProcessor(metricsConsumers)
+	}
+
 	if len(traceConsumers) == 0 {
 		logger.Warn("Nothing to do: no processor was enabled. Shutting down.")
 		os.Exit(1)
@@ -324,17 +360,36 @@ func startProcessor(v *viper.Viper, logger *zap.Logger) (consumer.TraceConsumer,
 			zap.Bool("overwrite", multiProcessorCfg.Global.Attributes.Overwrite),
 			zap.Any("values", multiProcessorCfg.Global.Attributes.Values),
 			zap.Any("key-mapping", multiProcessorCfg.Global.Attributes.KeyReplacements),
+			zap.String("match", multiProcessorCfg.Global.Attributes.Match),
 		)
 
 		if len(multiProcessorCfg.Global.Attributes.Values) > 0 {
-			tp, _ = addattributesprocessor.NewTraceProcessor(
+			var err error
+			tp, err = addattributesprocessor.NewTraceProcessor(
 				tp,
 				addattributesprocessor.WithAttributes(multiProcessorCfg.Global.Attributes.Values),
 				addattributesprocessor.WithOverwrite(multiProcessorCfg.Global.Attributes.Overwrite),
+				addattributesprocessor.WithMatch(multiProcessorCfg.Global.Attributes.Match),
 			)
+			if err != nil {
+				logger.Error("Global attributes configuration error", zap.Error(err))
+				os.Exit(1)
+			}
 		}
 		if len(multiProcessorCfg.Global.Attributes.KeyReplacements) > 0 {
-			tp, _ = attributekeyprocessor.NewTraceProcessor(tp, multiProcessorCfg.Global.Attributes.KeyReplacements...)
+			replacements := make([]attributekeyprocessor.KeyReplacement, 0, len(multiProcessorCfg.Global.Attributes.KeyReplacements))
+			for _, replacement := range multiProcessorCfg.Global.Attributes.KeyReplacements {
+				if replacement.Match == "" {
+					replacement.Match = multiProcessorCfg.Global.Attributes.Match
+				}
+				replacements = append(replacements, replacement)
+			}
+			var err error
+			tp, err = attributekeyprocessor.NewTraceProcessor(tp, replacements...)
+			if err != nil {
+				logger.Error("Global attributes key-mapping configuration error", zap.Error(err))
+				os.Exit(1)
+			}
 		}
 	}
 
@@ -355,8 +410,12 @@ func startProcessor(v *viper.Viper, logger *zap.Logger) (consumer.TraceConsumer,
 			"Trace head-sampling enabled",
 			zap.Float32("sampling-percentage", samplerCfg.SamplingPercentage),
 		)
-		tp, _ = tracesamplerprocessor.NewTraceProcessor(tp, *samplerCfg)
+		tp, err = tracesamplerprocessor.NewTraceProcessor(tp, *samplerCfg)
+		if err != nil {
+			logger.Error("Trace head-based sampling configuration error", zap.Error(err))
+			os.Exit(1)
+		}
 	}
 
-	return tp, closeFns
+	return tp, mp, closeFns
 }
@@ -15,12 +15,15 @@
 package collector
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
+	"github.com/census-instrumentation/opencensus-service/cmd/occollector/app/builder"
+	"github.com/census-instrumentation/opencensus-service/data"
 	"github.com/census-instrumentation/opencensus-service/processor/addattributesprocessor"
 	"github.com/census-instrumentation/opencensus-service/processor/attributekeyprocessor"
 	"github.com/census-instrumentation/opencensus-service/processor/multiconsumer"
@@ -107,7 +110,7 @@ func Test_startProcessor(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			consumer, closeFns := startProcessor(tt.setupViperCfg(), zap.NewNop())
+			consumer, _, closeFns := startProcessor(tt.setupViperCfg(), zap.NewNop())
 			if consumer == nil {
 				t.Errorf("startProcessor() got nil consumer")
 			}
@@ -123,3 +126,29 @@ func Test_startProcessor(t *testing.T) {
 		})
 	}
 }
+
+func Test_buildQueuedSpanProcessor_metricsExporters(t *testing.T) {
+	v := viper.New()
+	v.Set("exporters.prometheus.address", "127.0.0.1:0")
+	cfg := builder.NewDefaultQueuedSpanProcessorCfg()
+	cfg.RawConfig = v
+
+	closeFns, tc, mc, err := buildQueuedSpanProcessor(zap.NewNop(), cfg)
+	if err != nil {
+		t.Fatalf("buildQueuedSpanProcessor() error = %v", err)
+	}
+	defer func() {
+		for _, closeFn := range closeFns {
+			closeFn()
+		}
+	}()
+	if tc == nil {
+		t.Errorf("buildQueuedSpanProcessor() got nil trace consumer")
+	}
+	if mc == nil {
+		t.Fatalf("buildQueuedSpanProcessor() got nil metrics consumer")
+	}
+	if err := mc.ConsumeMetricsData(context.Background(), data.MetricsData{}); err != nil {
+		t.Errorf("ConsumeMetricsData() error = %v", err)
+	}
+}
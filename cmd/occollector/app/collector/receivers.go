@@ -24,13 +24,18 @@ import (
 	"github.com/census-instrumentation/opencensus-service/cmd/occollector/app/builder"
 	"github.com/census-instrumentation/opencensus-service/consumer"
 	jaegerreceiver "github.com/census-instrumentation/opencensus-service/internal/collector/jaeger"
+	kafkareceiver "github.com/census-instrumentation/opencensus-service/internal/collector/kafka"
 	ocreceiver "github.com/census-instrumentation/opencensus-service/internal/collector/opencensus"
 	zipkinreceiver "github.com/census-instrumentation/opencensus-service/internal/collector/zipkin"
 	zipkinscribereceiver "github.com/census-instrumentation/opencensus-service/internal/collector/zipkin/scribe"
 	"github.com/census-instrumentation/opencensus-service/receiver"
 )
 
-func createReceivers(v *viper.Viper, logger *zap.Logger, traceConsumers consumer.TraceConsumer, asyncErrorChan chan<- error) []receiver.TraceReceiver {
+func createReceivers(v *viper.Viper, logger *zap.Logger, traceConsumers consumer.TraceConsumer, metricsConsumer consumer.MetricsConsumer, asyncErrorChan chan<- error) []receiver.TraceReceiver {
+	startOCReceiver := func(logger *zap.Logger, v *viper.Viper, traceConsumer consumer.TraceConsumer, asyncErrorChan chan<- error) (receiver.TraceReceiver, error) {
+		return ocreceiver.Start(logger, v, traceConsumer, metricsConsumer, asyncErrorChan)
+	}
+
 	var someReceiverEnabled bool
 	receivers := []struct {
 		runFn   func(*zap.Logger, *viper.Viper, consumer.TraceConsumer, chan<- error) (receiver.TraceReceiver, error)
@@ -38,9 +43,10 @@ func createReceivers(v *viper.Viper, logger *zap.Logger, traceConsumers consumer
 		name    string
 	}{
 		{jaegerreceiver.Start, builder.JaegerReceiverEnabled(v), "Jaeger"},
-		{ocreceiver.Start, builder.OpenCensusReceiverEnabled(v), "OpenCensus"},
+		{startOCReceiver, builder.OpenCensusReceiverEnabled(v), "OpenCensus"},
 		{zipkinreceiver.Start, builder.ZipkinReceiverEnabled(v), "Zipkin"},
 		{zipkinscribereceiver.Start, builder.ZipkinScribeReceiverEnabled(v), "Zipkin-Scribe"},
+		{kafkareceiver.Start, builder.KafkaReceiverEnabled(v), "Kafka"},
 	}
 
 	var startedTraceReceivers []receiver.TraceReceiver
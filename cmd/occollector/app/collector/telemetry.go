@@ -31,6 +31,17 @@ import (
 	"github.com/census-instrumentation/opencensus-service/internal/collector/processor/tailsampling"
 	"github.com/census-instrumentation/opencensus-service/internal/collector/telemetry"
 	"github.com/census-instrumentation/opencensus-service/observability"
+	"github.com/census-instrumentation/opencensus-service/processor/attributeschemaprocessor"
+	"github.com/census-instrumentation/opencensus-service/processor/criticalpathprocessor"
+	"github.com/census-instrumentation/opencensus-service/processor/dedupprocessor"
+	"github.com/census-instrumentation/opencensus-service/processor/geoipprocessor"
+	"github.com/census-instrumentation/opencensus-service/processor/groupbytraceprocessor"
+	"github.com/census-instrumentation/opencensus-service/processor/k8sprocessor"
+	"github.com/census-instrumentation/opencensus-service/processor/ratelimitprocessor"
+	"github.com/census-instrumentation/opencensus-service/processor/spanlimitsprocessor"
+	"github.com/census-instrumentation/opencensus-service/processor/tenantlimitsprocessor"
+	"github.com/census-instrumentation/opencensus-service/processor/tracecompletenessprocessor"
+	"github.com/census-instrumentation/opencensus-service/processor/traceidprocessor"
 )
 
 const (
@@ -70,6 +81,17 @@ func (tel *appTelemetry) init(asyncErrorChannel chan<- error, v *viper.Viper, lo
 	views = append(views, nodebatcher.MetricViews(level)...)
 	views = append(views, observability.AllViews...)
 	views = append(views, tailsampling.SamplingProcessorMetricViews(level)...)
+	views = append(views, groupbytraceprocessor.MetricViews(level)...)
+	views = append(views, spanlimitsprocessor.MetricViews(level)...)
+	views = append(views, dedupprocessor.MetricViews(level)...)
+	views = append(views, tenantlimitsprocessor.MetricViews(level)...)
+	views = append(views, traceidprocessor.MetricViews(level)...)
+	views = append(views, attributeschemaprocessor.MetricViews(level)...)
+	views = append(views, k8sprocessor.MetricViews(level)...)
+	views = append(views, geoipprocessor.MetricViews(level)...)
+	views = append(views, tracecompletenessprocessor.MetricViews(level)...)
+	views = append(views, criticalpathprocessor.MetricViews(level)...)
+	views = append(views, ratelimitprocessor.MetricViews(level)...)
 	processMetricsViews := telemetry.NewProcessMetricsViews()
 	views = append(views, processMetricsViews.Views()...)
 	tel.views = views
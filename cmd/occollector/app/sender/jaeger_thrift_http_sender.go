@@ -28,6 +28,7 @@ import (
 
 	"github.com/census-instrumentation/opencensus-service/consumer"
 	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/propagation"
 	jaegertranslator "github.com/census-instrumentation/opencensus-service/translator/trace/jaeger"
 )
 
@@ -37,10 +38,11 @@ const defaultHTTPTimeout = time.Second * 5
 // JaegerThriftHTTPSender forwards spans encoded in the jaeger thrift
 // format to a http server
 type JaegerThriftHTTPSender struct {
-	url     string
-	headers map[string]string
-	client  *http.Client
-	logger  *zap.Logger
+	url      string
+	headers  map[string]string
+	injector *propagation.Injector
+	client   *http.Client
+	logger   *zap.Logger
 }
 
 var _ consumer.TraceConsumer = (*JaegerThriftHTTPSender)(nil)
@@ -61,6 +63,12 @@ func HTTPRoundTripper(transport http.RoundTripper) HTTPOption {
 	}
 }
 
+// HTTPMetadataInjector sets the request metadata, passed along with the data,
+// that is forwarded as headers.
+func HTTPMetadataInjector(injector *propagation.Injector) HTTPOption {
+	return func(s *JaegerThriftHTTPSender) { s.injector = injector }
+}
+
 // NewJaegerThriftHTTPSender returns a new HTTP-backend span sender. url should be an http
 // url of the collector to handle POST request, typically something like:
 //     http://hostname:14268/api/traces?format=jaeger.thrift
@@ -103,6 +111,7 @@ func (s *JaegerThriftHTTPSender) ConsumeTraceData(ctx context.Context, td data.T
 	for k, v := range s.headers {
 		req.Header.Set(k, v)
 	}
+	s.injector.InjectHTTP(ctx, req.Header)
 	resp, err := s.client.Do(req)
 	if err != nil {
 		return err
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+	"github.com/census-instrumentation/opencensus-service/propagation"
 )
 
 // ConfigV2 defines configuration for OpenCensus exporter.
@@ -31,4 +32,5 @@ type ConfigV2 struct {
 	UseSecure                     bool                     `mapstructure:"secure,omitempty"`
 	ReconnectionDelay             time.Duration            `mapstructure:"reconnection-delay,omitempty"`
 	KeepaliveParameters           *keepaliveConfig         `mapstructure:"keepalive,omitempty"`
+	ForwardMetadata               *propagation.Injector    `mapstructure:"forward-metadata,omitempty"`
 }
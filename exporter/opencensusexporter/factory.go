@@ -71,9 +71,12 @@ func (f *exporterFactory) CreateTraceExporter(config configmodels.Exporter) (con
 	}
 
 	opts := []ocagent.ExporterOption{ocagent.WithAddress(ocac.Endpoint)}
+	// dialOpts mirror opts for the connection of the forwarding exporter.
+	var dialOpts []grpc.DialOption
 	if ocac.Compression != "" {
 		if compressionKey := compressiongrpc.GetGRPCCompressionKey(ocac.Compression); compressionKey != compression.Unsupported {
 			opts = append(opts, ocagent.UseCompressor(compressionKey))
+			dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.UseCompressor(compressionKey)))
 		} else {
 			return nil, nil, &ocTraceExporterError{
 				code: errUnsupportedCompressionType,
@@ -90,6 +93,7 @@ func (f *exporterFactory) CreateTraceExporter(config configmodels.Exporter) (con
 			}
 		}
 		opts = append(opts, ocagent.WithTLSCredentials(creds))
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(creds))
 	} else if ocac.UseSecure {
 		certPool, err := x509.SystemCertPool()
 		if err != nil {
@@ -101,8 +105,10 @@ func (f *exporterFactory) CreateTraceExporter(config configmodels.Exporter) (con
 		}
 		creds := credentials.NewClientTLSFromCert(certPool, "")
 		opts = append(opts, ocagent.WithTLSCredentials(creds))
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(creds))
 	} else {
 		opts = append(opts, ocagent.WithInsecure())
+		dialOpts = append(dialOpts, grpc.WithInsecure())
 	}
 	if len(ocac.Headers) > 0 {
 		opts = append(opts, ocagent.WithHeaders(ocac.Headers))
@@ -111,11 +117,13 @@ func (f *exporterFactory) CreateTraceExporter(config configmodels.Exporter) (con
 		opts = append(opts, ocagent.WithReconnectionPeriod(ocac.ReconnectionDelay))
 	}
 	if ocac.KeepaliveParameters != nil {
-		opts = append(opts, ocagent.WithGRPCDialOption(grpc.WithKeepaliveParams(keepalive.ClientParameters{
+		keepaliveOpt := grpc.WithKeepaliveParams(keepalive.ClientParameters{
 			Time:                ocac.KeepaliveParameters.Time,
 			Timeout:             ocac.KeepaliveParameters.Timeout,
 			PermitWithoutStream: ocac.KeepaliveParameters.PermitWithoutStream,
-		})))
+		})
+		opts = append(opts, ocagent.WithGRPCDialOption(keepaliveOpt))
+		dialOpts = append(dialOpts, keepaliveOpt)
 	}
 
 	numWorkers := defaultNumWorkers
@@ -132,7 +140,10 @@ func (f *exporterFactory) CreateTraceExporter(config configmodels.Exporter) (con
 		exportersChan <- exporter
 	}
 
-	oce := &ocagentExporter{exporters: exportersChan}
+	oce, err := newOCAgentExporter(exportersChan, ocac.ForwardMetadata, ocac.Endpoint, dialOpts, ocac.Headers)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot configure OpenCensus Trace exporter: %v", err)
+	}
 	oexp, err := exporterhelper.NewTraceExporter(
 		"oc_trace",
 		oce.PushTraceData,
@@ -0,0 +1,89 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opencensusexporter
+
+import (
+	"context"
+	"io"
+	"time"
+
+	agenttracepb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// forwardingExportTimeout bounds each forwarded export, it is not tied to the
+// request that carried the data since that is usually done by the time a
+// queued processor gets to export it.
+const forwardingExportTimeout = 30 * time.Second
+
+// forwardingExporter sends the data carrying forwarded request metadata. The
+// headers of an ocagent.Exporter are fixed for its stream, so instead all
+// the data is sent over a single shared connection, each export in its own
+// stream carrying the headers as metadata. That way no state is kept for
+// each distinct set of forwarded headers.
+type forwardingExporter struct {
+	headers map[string]string
+	conn    *grpc.ClientConn
+	client  agenttracepb.TraceServiceClient
+}
+
+func newForwardingExporter(endpoint string, dialOpts []grpc.DialOption, headers map[string]string) (*forwardingExporter, error) {
+	conn, err := grpc.Dial(endpoint, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &forwardingExporter{
+		headers: headers,
+		conn:    conn,
+		client:  agenttracepb.NewTraceServiceClient(conn),
+	}, nil
+}
+
+// export sends the request with the given headers along with the statically
+// configured ones, the forwarded headers taking precedence.
+func (fe *forwardingExporter) export(headers map[string]string, req *agenttracepb.ExportTraceServiceRequest) error {
+	md := metadata.New(fe.headers)
+	for name, value := range headers {
+		md.Set(name, value)
+	}
+
+	ctx, cancel := context.WithTimeout(metadata.NewOutgoingContext(context.Background(), md), forwardingExportTimeout)
+	defer cancel()
+	stream, err := fe.client.Export(ctx)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(req); err != nil && err != io.EOF {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	// Wait for the receiver to end the stream, a failed Send also surfaces
+	// its error here.
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func (fe *forwardingExporter) stop() error {
+	return fe.conn.Close()
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/census-instrumentation/opencensus-service/internal"
 	"github.com/census-instrumentation/opencensus-service/internal/compression"
 	compressiongrpc "github.com/census-instrumentation/opencensus-service/internal/compression/grpc"
+	"github.com/census-instrumentation/opencensus-service/propagation"
 )
 
 // keepaliveConfig exposes the keepalive.ClientParameters to be used by the exporter.
@@ -53,12 +54,20 @@ type opencensusConfig struct {
 	UseSecure           bool              `mapstructure:"secure,omitempty"`
 	ReconnectionDelay   time.Duration     `mapstructure:"reconnection-delay,omitempty"`
 	KeepaliveParameters *keepaliveConfig  `mapstructure:"keepalive,omitempty"`
+	// ForwardMetadata selects the request metadata passed along with the data
+	// that is sent as headers.
+	ForwardMetadata *propagation.Injector `mapstructure:"forward-metadata,omitempty"`
 	// TODO: service name options.
 }
 
 type ocagentExporter struct {
 	counter   uint32
 	exporters chan *ocagent.Exporter
+
+	// injector selects the forwarded request metadata, the data carrying any
+	// is sent by the forwarding exporter instead of the pool.
+	injector   *propagation.Injector
+	forwarding *forwardingExporter
 }
 
 type ocTraceExporterErrorCode int
@@ -109,9 +118,12 @@ func OpenCensusTraceExportersFromViper(v *viper.Viper) (tps []consumer.TraceCons
 	}
 
 	opts := []ocagent.ExporterOption{ocagent.WithAddress(ocac.Endpoint)}
+	// dialOpts mirror opts for the connection of the forwarding exporter.
+	var dialOpts []grpc.DialOption
 	if ocac.Compression != "" {
 		if compressionKey := compressiongrpc.GetGRPCCompressionKey(ocac.Compression); compressionKey != compression.Unsupported {
 			opts = append(opts, ocagent.UseCompressor(compressionKey))
+			dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.UseCompressor(compressionKey)))
 		} else {
 			return nil, nil, nil, &ocTraceExporterError{
 				code: errUnsupportedCompressionType,
@@ -128,6 +140,7 @@ func OpenCensusTraceExportersFromViper(v *viper.Viper) (tps []consumer.TraceCons
 			}
 		}
 		opts = append(opts, ocagent.WithTLSCredentials(creds))
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(creds))
 	} else if ocac.UseSecure {
 		certPool, err := x509.SystemCertPool()
 		if err != nil {
@@ -139,8 +152,10 @@ func OpenCensusTraceExportersFromViper(v *viper.Viper) (tps []consumer.TraceCons
 		}
 		creds := credentials.NewClientTLSFromCert(certPool, "")
 		opts = append(opts, ocagent.WithTLSCredentials(creds))
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(creds))
 	} else {
 		opts = append(opts, ocagent.WithInsecure())
+		dialOpts = append(dialOpts, grpc.WithInsecure())
 	}
 	if len(ocac.Headers) > 0 {
 		opts = append(opts, ocagent.WithHeaders(ocac.Headers))
@@ -149,11 +164,13 @@ func OpenCensusTraceExportersFromViper(v *viper.Viper) (tps []consumer.TraceCons
 		opts = append(opts, ocagent.WithReconnectionPeriod(ocac.ReconnectionDelay))
 	}
 	if ocac.KeepaliveParameters != nil {
-		opts = append(opts, ocagent.WithGRPCDialOption(grpc.WithKeepaliveParams(keepalive.ClientParameters{
+		keepaliveOpt := grpc.WithKeepaliveParams(keepalive.ClientParameters{
 			Time:                ocac.KeepaliveParameters.Time,
 			Timeout:             ocac.KeepaliveParameters.Timeout,
 			PermitWithoutStream: ocac.KeepaliveParameters.PermitWithoutStream,
-		})))
+		})
+		opts = append(opts, ocagent.WithGRPCDialOption(keepaliveOpt))
+		dialOpts = append(dialOpts, keepaliveOpt)
 	}
 
 	numWorkers := defaultNumWorkers
@@ -170,7 +187,10 @@ func OpenCensusTraceExportersFromViper(v *viper.Viper) (tps []consumer.TraceCons
 		exportersChan <- exporter
 	}
 
-	oce := &ocagentExporter{exporters: exportersChan}
+	oce, err := newOCAgentExporter(exportersChan, ocac.ForwardMetadata, ocac.Endpoint, dialOpts, ocac.Headers)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot configure OpenCensus Trace exporter: %v", err)
+	}
 	oexp, err := exporterhelper.NewTraceExporter(
 		"oc_trace",
 		oce.PushTraceData,
@@ -189,9 +209,32 @@ func OpenCensusTraceExportersFromViper(v *viper.Viper) (tps []consumer.TraceCons
 	return
 }
 
+func newOCAgentExporter(
+	exporters chan *ocagent.Exporter,
+	injector *propagation.Injector,
+	endpoint string,
+	dialOpts []grpc.DialOption,
+	headers map[string]string,
+) (*ocagentExporter, error) {
+	oce := &ocagentExporter{exporters: exporters, injector: injector}
+	if injector != nil {
+		forwarding, err := newForwardingExporter(endpoint, dialOpts, headers)
+		if err != nil {
+			return nil, err
+		}
+		oce.forwarding = forwarding
+	}
+	return oce, nil
+}
+
 func (oce *ocagentExporter) stop() error {
 	wg := &sync.WaitGroup{}
 	var errors []error
+	if oce.forwarding != nil {
+		if err := oce.forwarding.stop(); err != nil {
+			errors = append(errors, err)
+		}
+	}
 	var errorsMu sync.Mutex
 	visitedCnt := 0
 	for currExporter := range oce.exporters {
@@ -219,6 +262,10 @@ func (oce *ocagentExporter) stop() error {
 }
 
 func (oce *ocagentExporter) PushTraceData(ctx context.Context, td data.TraceData) (int, error) {
+	if headers := oce.injector.Headers(ctx); len(headers) > 0 {
+		return oce.pushForwardedTraceData(headers, td)
+	}
+
 	// Get first available exporter.
 	exporter, ok := <-oce.exporters
 	if !ok {
@@ -242,3 +289,18 @@ func (oce *ocagentExporter) PushTraceData(ctx context.Context, td data.TraceData
 	}
 	return 0, nil
 }
+
+func (oce *ocagentExporter) pushForwardedTraceData(headers map[string]string, td data.TraceData) (int, error) {
+	err := oce.forwarding.export(
+		headers,
+		&agenttracepb.ExportTraceServiceRequest{
+			Spans:    td.Spans,
+			Resource: td.Resource,
+			Node:     td.Node,
+		},
+	)
+	if err != nil {
+		return len(td.Spans), err
+	}
+	return 0, nil
+}
@@ -16,12 +16,25 @@ package opencensusexporter
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
 	"testing"
 	"time"
 
-	"github.com/census-instrumentation/opencensus-service/data"
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	agenttracepb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/trace/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
 	"github.com/google/go-cmp/cmp"
 	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/internal/collector/processor/queued"
+	"github.com/census-instrumentation/opencensus-service/propagation"
 )
 
 func TestOpenCensusTraceExportersFromViper(t *testing.T) {
@@ -214,3 +227,141 @@ func errorCode(err error) ocTraceExporterErrorCode {
 	}
 	return ocErr.code
 }
+
+// metadataRecordingTraceService records the metadata of the export streams,
+// the headers are counted for each received span since the streams of the
+// ocagent exporters also send requests without any.
+type metadataRecordingTraceService struct {
+	mu       sync.Mutex
+	tenants  map[string]int
+	apiKeys  map[string]int
+	numSpans int
+}
+
+var _ agenttracepb.TraceServiceServer = (*metadataRecordingTraceService)(nil)
+
+func (ts *metadataRecordingTraceService) Config(agenttracepb.TraceService_ConfigServer) error {
+	return nil
+}
+
+func (ts *metadataRecordingTraceService) Export(tes agenttracepb.TraceService_ExportServer) error {
+	md, _ := metadata.FromIncomingContext(tes.Context())
+	for {
+		req, err := tes.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		ts.mu.Lock()
+		for _, tenant := range md.Get("x-scope-orgid") {
+			ts.tenants[tenant] += len(req.Spans)
+		}
+		for _, apiKey := range md.Get("api-key") {
+			ts.apiKeys[apiKey] += len(req.Spans)
+		}
+		ts.numSpans += len(req.Spans)
+		ts.mu.Unlock()
+	}
+}
+
+// startForwardingExporter starts a server recording the metadata it receives
+// and an exporter to it forwarding the "x-tenant" metadata.
+func startForwardingExporter(t *testing.T) (*metadataRecordingTraceService, consumer.TraceConsumer, func()) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ts := &metadataRecordingTraceService{tenants: map[string]int{}, apiKeys: map[string]int{}}
+	srv := grpc.NewServer()
+	agenttracepb.RegisterTraceServiceServer(srv, ts)
+	go srv.Serve(ln)
+
+	v := viper.New()
+	v.Set("opencensus.endpoint", ln.Addr().String())
+	v.Set("opencensus.headers", map[string]string{"api-key": "secret"})
+	v.Set("opencensus.forward-metadata.rename", map[string]string{"x-tenant": "X-Scope-OrgID"})
+	tps, _, doneFns, err := OpenCensusTraceExportersFromViper(v)
+	if err != nil {
+		srv.Stop()
+		t.Fatalf("Unexpected error building OpenCensus Exporter: %v", err)
+	}
+	return ts, tps[0], func() {
+		for _, doneFn := range doneFns {
+			doneFn()
+		}
+		srv.Stop()
+	}
+}
+
+var forwardedTraceData = data.TraceData{
+	Node: &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "svc"}},
+	Spans: []*tracepb.Span{{
+		TraceId: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanId:  []byte{1, 2, 3, 4, 5, 6, 7, 8},
+	}},
+}
+
+func TestOpenCensusTraceExporter_ForwardMetadata(t *testing.T) {
+	ts, tp, stop := startForwardingExporter(t)
+	defer stop()
+
+	td := forwardedTraceData
+	// Go past the number of distinct forwarded headers the exporter used to
+	// hold a connection for.
+	const numTenants = 250
+	for i := 0; i < numTenants; i++ {
+		md := propagation.Metadata{"x-tenant": fmt.Sprintf("tenant-%d", i)}
+		ctx := propagation.NewContext(context.Background(), md)
+		if err := tp.ConsumeTraceData(ctx, td); err != nil {
+			t.Fatalf("Failed to export the spans of tenant %d: %v", i, err)
+		}
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.numSpans != numTenants {
+		t.Errorf("Received %d spans, want %d", ts.numSpans, numTenants)
+	}
+	if len(ts.tenants) != numTenants {
+		t.Errorf("Received %d distinct tenants, want %d", len(ts.tenants), numTenants)
+	}
+	if ts.apiKeys["secret"] != numTenants {
+		t.Errorf("Received the configured header %d times, want %d", ts.apiKeys["secret"], numTenants)
+	}
+}
+
+func TestOpenCensusTraceExporter_ForwardMetadataQueued(t *testing.T) {
+	ts, tp, stop := startForwardingExporter(t)
+	defer stop()
+
+	qp := queued.NewQueuedSpanProcessor(tp, queued.Options.WithNumWorkers(1))
+	defer qp.(interface{ Stop() }).Stop()
+
+	// The request carrying the data is over by the time the queue exports it.
+	md := propagation.Metadata{"x-tenant": "tenant-1"}
+	ctx, cancel := context.WithCancel(propagation.NewContext(context.Background(), md))
+	cancel()
+	if err := qp.ConsumeTraceData(ctx, forwardedTraceData); err != nil {
+		t.Fatalf("Failed to queue the spans: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ts.mu.Lock()
+		numSpans := ts.numSpans
+		tenantSpans := ts.tenants["tenant-1"]
+		ts.mu.Unlock()
+		if numSpans == 1 {
+			if tenantSpans != 1 {
+				t.Errorf("Received %d spans of the forwarded tenant, want 1", tenantSpans)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Received %d spans, want 1", numSpans)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zipkinexporter
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+
+	zipkinreporter "github.com/openzipkin/zipkin-go/reporter"
+	zipkinhttp "github.com/openzipkin/zipkin-go/reporter/http"
+
+	"github.com/census-instrumentation/opencensus-service/propagation"
+)
+
+const (
+	// maxForwardingReporters bounds the number of distinct sets of forwarded
+	// headers that have a reporter at once, the least recently used reporter
+	// is closed to make room for a new one.
+	maxForwardingReporters = 100
+
+	// forwardingReporterIdleTimeout is how long a reporter is kept without
+	// being used before it is closed.
+	forwardingReporterIdleTimeout = 5 * time.Minute
+
+	// Same as the default timeout of the Zipkin HTTP reporter.
+	forwardingReporterTimeout = 5 * time.Second
+)
+
+// forwardingReporters keeps a reporter for each distinct set of forwarded
+// headers, since spans with different forwarded headers can't be batched
+// together. It is not safe for concurrent use.
+type forwardingReporters struct {
+	newReporter func(headers map[string]string) zipkinreporter.Reporter
+	now         func() time.Time
+
+	// ll holds the reporters, the most recently used first.
+	ll    *list.List
+	items map[string]*list.Element
+
+	// closing tracks the evicted reporters still flushing their spans.
+	closing sync.WaitGroup
+}
+
+type forwardingReporter struct {
+	key      string
+	reporter zipkinreporter.Reporter
+	lastUsed time.Time
+}
+
+func newForwardingReporters(newReporter func(headers map[string]string) zipkinreporter.Reporter) *forwardingReporters {
+	return &forwardingReporters{
+		newReporter: newReporter,
+		now:         time.Now,
+		ll:          list.New(),
+		items:       make(map[string]*list.Element),
+	}
+}
+
+// get returns the reporter that sends the given headers, creating it if
+// needed. Reporters idle for too long, or the least recently used one when
+// the limit is reached, are closed.
+func (frs *forwardingReporters) get(headers map[string]string) zipkinreporter.Reporter {
+	now := frs.now()
+	frs.expire(now)
+
+	key := propagation.HeadersKey(headers)
+	if elem, ok := frs.items[key]; ok {
+		fr := elem.Value.(*forwardingReporter)
+		fr.lastUsed = now
+		frs.ll.MoveToFront(elem)
+		return fr.reporter
+	}
+
+	for frs.ll.Len() >= maxForwardingReporters {
+		frs.evict(frs.ll.Back())
+	}
+	fr := &forwardingReporter{key: key, reporter: frs.newReporter(headers), lastUsed: now}
+	frs.items[key] = frs.ll.PushFront(fr)
+	return fr.reporter
+}
+
+// expire closes the reporters that were not used since the idle timeout.
+func (frs *forwardingReporters) expire(now time.Time) {
+	for elem := frs.ll.Back(); elem != nil; elem = frs.ll.Back() {
+		if now.Sub(elem.Value.(*forwardingReporter).lastUsed) < forwardingReporterIdleTimeout {
+			return
+		}
+		frs.evict(elem)
+	}
+}
+
+func (frs *forwardingReporters) evict(elem *list.Element) {
+	fr := elem.Value.(*forwardingReporter)
+	frs.ll.Remove(elem)
+	delete(frs.items, fr.key)
+
+	// Closing flushes the pending spans, don't block the exports meanwhile.
+	frs.closing.Add(1)
+	go func() {
+		defer frs.closing.Done()
+		_ = fr.reporter.Close()
+	}()
+}
+
+// len returns the number of open reporters.
+func (frs *forwardingReporters) len() int {
+	return frs.ll.Len()
+}
+
+// closeAll closes all the reporters, waiting for them to flush their spans.
+func (frs *forwardingReporters) closeAll() {
+	for elem := frs.ll.Back(); elem != nil; elem = frs.ll.Back() {
+		frs.evict(elem)
+	}
+	frs.closing.Wait()
+}
+
+// newForwardingReporter returns a Zipkin HTTP reporter sending the given
+// headers along with the spans.
+func (ze *zipkinExporter) newForwardingReporter(headers map[string]string) zipkinreporter.Reporter {
+	client := &http.Client{
+		Timeout:   forwardingReporterTimeout,
+		Transport: &headersRoundTripper{headers: headers, next: http.DefaultTransport},
+	}
+	opts := append(ze.reporterOpts[:len(ze.reporterOpts):len(ze.reporterOpts)], zipkinhttp.Client(client))
+	return zipkinhttp.NewReporter(ze.endpointURI, opts...)
+}
+
+// reporterForHeaders returns the reporter that sends the given headers.
+// The caller must hold ze.mu.
+func (ze *zipkinExporter) reporterForHeaders(headers map[string]string) zipkinreporter.Reporter {
+	if len(headers) == 0 {
+		return ze.reporter
+	}
+	if ze.forwardingReporters == nil {
+		ze.forwardingReporters = newForwardingReporters(ze.newForwardingReporter)
+	}
+	return ze.forwardingReporters.get(headers)
+}
+
+// headersRoundTripper sets fixed headers on the requests it sends.
+type headersRoundTripper struct {
+	headers map[string]string
+	next    http.RoundTripper
+}
+
+var _ http.RoundTripper = (*headersRoundTripper)(nil)
+
+func (rt *headersRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request, so send a copy.
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+len(rt.headers))
+	for name, values := range req.Header {
+		r.Header[name] = values
+	}
+	for name, value := range rt.headers {
+		r.Header.Set(name, value)
+	}
+	return rt.next.RoundTrip(r)
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/census-instrumentation/opencensus-service/consumer"
 	"github.com/census-instrumentation/opencensus-service/data"
 	"github.com/census-instrumentation/opencensus-service/observability"
+	"github.com/census-instrumentation/opencensus-service/propagation"
 	spandatatranslator "github.com/census-instrumentation/opencensus-service/translator/trace/spandata"
 )
 
@@ -43,6 +44,9 @@ type ZipkinConfig struct {
 	Endpoint         string         `mapstructure:"endpoint,omitempty"`
 	LocalEndpointURI string         `mapstructure:"local_endpoint,omitempty"`
 	UploadPeriod     *time.Duration `mapstructure:"upload_period,omitempty"`
+	// ForwardMetadata selects the request metadata passed along with the
+	// data that is sent as headers.
+	ForwardMetadata *propagation.Injector `mapstructure:"forward_metadata,omitempty"`
 }
 
 // zipkinExporter is a multiplexing exporter that spawns a new OpenCensus-Go Zipkin
@@ -57,8 +61,12 @@ type zipkinExporter struct {
 	defaultServiceName      string
 	defaultLocalEndpointURI string
 
-	endpointURI string
-	reporter    zipkinreporter.Reporter
+	endpointURI  string
+	reporter     zipkinreporter.Reporter
+	reporterOpts []zipkinhttp.ReporterOption
+
+	injector            *propagation.Injector
+	forwardingReporters *forwardingReporters
 }
 
 // Default values for Zipkin endpoint.
@@ -109,6 +117,7 @@ func ZipkinExportersFromViper(v *viper.Viper) (tps []consumer.TraceConsumer, mps
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("Cannot configure Zipkin exporter: %v", err)
 	}
+	zle.injector = zc.ForwardMetadata
 	tps = append(tps, zle)
 	doneFns = append(doneFns, zle.stop)
 	return
@@ -125,6 +134,7 @@ func newZipkinExporter(finalEndpointURI, defaultServiceName, defaultLocalEndpoin
 		defaultServiceName:      defaultServiceName,
 		defaultLocalEndpointURI: defaultLocalEndpointURI,
 		reporter:                reporter,
+		reporterOpts:            opts,
 	}
 	return zle, nil
 }
@@ -189,6 +199,9 @@ func (ze *zipkinExporter) stop() error {
 	ze.mu.Lock()
 	defer ze.mu.Unlock()
 
+	if ze.forwardingReporters != nil {
+		ze.forwardingReporters.closeAll()
+	}
 	return ze.reporter.Close()
 }
 
@@ -204,7 +217,7 @@ func (ze *zipkinExporter) ConsumeTraceData(ctx context.Context, td data.TraceDat
 		span.End()
 	}()
 
-	goodSpans := 0
+	zspans := make([]zipkinmodel.SpanModel, 0, len(td.Spans))
 	for _, span := range td.Spans {
 		sd, err := spandatatranslator.ProtoSpanToOCSpanData(span)
 		if err != nil {
@@ -212,17 +225,22 @@ func (ze *zipkinExporter) ConsumeTraceData(ctx context.Context, td data.TraceDat
 		}
 		zs, err := ze.zipkinSpan(td.Node, sd)
 		if err == nil {
-			// ze.reporter can get closed in the midst of a Send
-			// so avoid a read/write during that mutation.
-			ze.mu.Lock()
-			ze.reporter.Send(zs)
-			ze.mu.Unlock()
-			goodSpans++
+			zspans = append(zspans, zs)
 		}
 	}
 
+	// The reporters can get closed in the midst of a Send, either by stop
+	// or by evicting a forwarding reporter, so avoid a read/write during
+	// that mutation.
+	ze.mu.Lock()
+	reporter := ze.reporterForHeaders(ze.injector.Headers(ctx))
+	for _, zs := range zspans {
+		reporter.Send(zs)
+	}
+	ze.mu.Unlock()
+
 	// And finally record metrics on the number of exported spans.
-	observability.RecordTraceExporterMetrics(observability.ContextWithExporterName(ctx, "zipkin"), len(td.Spans), len(td.Spans)-goodSpans)
+	observability.RecordTraceExporterMetrics(observability.ContextWithExporterName(ctx, "zipkin"), len(td.Spans), len(td.Spans)-len(zspans))
 
 	return nil
 }
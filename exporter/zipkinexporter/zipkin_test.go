@@ -27,14 +27,18 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
 	zipkinmodel "github.com/openzipkin/zipkin-go/model"
 	zipkinreporter "github.com/openzipkin/zipkin-go/reporter"
 
+	"github.com/census-instrumentation/opencensus-service/data"
 	"github.com/census-instrumentation/opencensus-service/internal/config/viperutils"
 	"github.com/census-instrumentation/opencensus-service/internal/testutils"
 	"github.com/census-instrumentation/opencensus-service/processor/multiconsumer"
+	"github.com/census-instrumentation/opencensus-service/propagation"
 	"github.com/census-instrumentation/opencensus-service/receiver/zipkinreceiver"
 )
 
@@ -362,3 +366,114 @@ const zipkinSpansJSONJavaLibrary = `
   "duration": 207000
 }]
 `
+
+func TestZipkinExporterForwardMetadata(t *testing.T) {
+	headersC := make(chan http.Header, 2)
+	cst := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+		r.Body.Close()
+		headersC <- r.Header
+	}))
+	defer cst.Close()
+
+	config := `
+zipkin:
+  upload_period: 1ms
+  forward_metadata:
+    rename:
+      x-tenant: X-Scope-OrgID
+  endpoint: ` + cst.URL
+	v, _ := viperutils.ViperFromYAMLBytes([]byte(config))
+	tes, _, doneFns, err := ZipkinExportersFromViper(v)
+	if len(tes) != 1 || err != nil {
+		t.Fatalf("Failed to parse out exporters: %v", err)
+	}
+
+	td := data.TraceData{
+		Spans: []*tracepb.Span{{
+			TraceId: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			SpanId:  []byte{1, 2, 3, 4, 5, 6, 7, 8},
+			Name:    &tracepb.TruncatableString{Value: "forwarded"},
+		}},
+	}
+	ctx := propagation.NewContext(context.Background(), propagation.Metadata{"x-tenant": "acme"})
+	if err := tes[0].ConsumeTraceData(ctx, td); err != nil {
+		t.Fatalf("Failed to export the spans: %v", err)
+	}
+
+	// Stopping the exporter flushes the spans.
+	for _, fn := range doneFns {
+		fn()
+	}
+
+	select {
+	case header := <-headersC:
+		if got := header.Get("X-Scope-OrgID"); got != "acme" {
+			t.Errorf("X-Scope-OrgID = %q, want %q", got, "acme")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("The spans were not sent")
+	}
+}
+
+type closeRecordingReporter struct {
+	mockZipkinReporter
+	closed chan struct{}
+}
+
+func (r *closeRecordingReporter) Close() error {
+	close(r.closed)
+	return nil
+}
+
+func TestForwardingReporters_PastTheLimit(t *testing.T) {
+	var created []*closeRecordingReporter
+	frs := newForwardingReporters(func(headers map[string]string) zipkinreporter.Reporter {
+		r := &closeRecordingReporter{closed: make(chan struct{})}
+		created = append(created, r)
+		return r
+	})
+	now := time.Unix(0, 0)
+	frs.now = func() time.Time { return now }
+
+	for i := 0; i < 2*maxForwardingReporters; i++ {
+		if frs.get(map[string]string{"x-tenant": fmt.Sprint(i)}) == nil {
+			t.Fatalf("No reporter for the header set %d", i)
+		}
+		if frs.len() > maxForwardingReporters {
+			t.Fatalf("%d reporters open, want at most %d", frs.len(), maxForwardingReporters)
+		}
+	}
+
+	// The least recently used reporters were closed to make room.
+	for i, r := range created[:maxForwardingReporters] {
+		select {
+		case <-r.closed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("The evicted reporter %d was not closed", i)
+		}
+	}
+
+	// A reporter in use is kept while the idle ones are closed.
+	now = now.Add(forwardingReporterIdleTimeout / 2)
+	kept := frs.get(map[string]string{"x-tenant": fmt.Sprint(2*maxForwardingReporters - 1)})
+	now = now.Add(forwardingReporterIdleTimeout / 2)
+	if got := frs.get(map[string]string{"x-tenant": fmt.Sprint(2*maxForwardingReporters - 1)}); got != kept {
+		t.Errorf("The reporter in use was replaced")
+	}
+	if frs.len() != 1 {
+		t.Errorf("%d reporters open after the idle timeout, want 1", frs.len())
+	}
+
+	frs.closeAll()
+	if frs.len() != 0 {
+		t.Errorf("%d reporters open after closeAll, want 0", frs.len())
+	}
+	for i, r := range created {
+		select {
+		case <-r.closed:
+		default:
+			t.Errorf("The reporter %d was not closed", i)
+		}
+	}
+}
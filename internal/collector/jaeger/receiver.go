@@ -14,16 +14,16 @@
 
 // Package jaegerreceiver wraps the functionality to start the end-point that
 // receives Jaeger data sent by the jaeger-agent in jaeger.thrift format over
-// TChannel and directly from clients in jaeger.thrift format over binary thrift
-// protocol (HTTP transport).
+// TChannel, directly from clients in jaeger.thrift format over binary thrift
+// protocol (HTTP transport), and in jaeger proto format over gRPC.
 // Note that the UDP transport is not supported since these protocol/transport
 // are for task->jaeger-agent communication only and the receiver does not try to
 // support jaeger-agent endpoints.
-// TODO: add support for the jaeger proto endpoint released in jaeger 1.8package jaegerreceiver
 package jaegerreceiver
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -45,7 +45,25 @@ func Start(logger *zap.Logger, v *viper.Viper, traceConsumer consumer.TraceConsu
 	config := &jaegerreceiver.Configuration{
 		CollectorThriftPort: rOpts.ThriftTChannelPort,
 		CollectorHTTPPort:   rOpts.ThriftHTTPPort,
+		CollectorGRPCPort:   rOpts.ProtoGRPCPort,
+		Metadata:            rOpts.Metadata,
 	}
+	zapFields := []zap.Field{
+		zap.Int("thrift-tchannel-port", rOpts.ThriftTChannelPort),
+		zap.Int("thrift-http-port", rOpts.ThriftHTTPPort),
+		zap.Int("proto-grpc-port", rOpts.ProtoGRPCPort),
+	}
+
+	tlsCredsOption, hasTLSCreds, err := rOpts.TLSCredentials.ToGRPCServerOption()
+	if err != nil {
+		return nil, fmt.Errorf("Jaeger receiver TLS Credentials: %v", err)
+	}
+	if hasTLSCreds {
+		config.CollectorGRPCOptions = append(config.CollectorGRPCOptions, tlsCredsOption)
+		tlsCreds := rOpts.TLSCredentials
+		zapFields = append(zapFields, zap.String("cert_file", tlsCreds.CertFile), zap.String("key_file", tlsCreds.KeyFile))
+	}
+
 	jtr, err := jaegerreceiver.New(ctx, config, traceConsumer)
 	if err != nil {
 		return nil, err
@@ -55,9 +73,7 @@ func Start(logger *zap.Logger, v *viper.Viper, traceConsumer consumer.TraceConsu
 		return nil, err
 	}
 
-	logger.Info("Jaeger receiver is running.",
-		zap.Int("thrift-tchannel-port", rOpts.ThriftTChannelPort),
-		zap.Int("thrift-http-port", rOpts.ThriftHTTPPort))
+	logger.Info("Jaeger receiver is running.", zapFields...)
 
 	return jtr, nil
 }
@@ -0,0 +1,63 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kafkareceiver wraps the functionality to start the receiver that
+// consumes spans from Kafka topics as a member of a consumer group.
+package kafkareceiver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/census-instrumentation/opencensus-service/cmd/occollector/app/builder"
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/receiver"
+	"github.com/census-instrumentation/opencensus-service/receiver/kafkareceiver"
+)
+
+// Start starts the Kafka receiver.
+func Start(logger *zap.Logger, v *viper.Viper, traceConsumer consumer.TraceConsumer, asyncErrorChan chan<- error) (receiver.TraceReceiver, error) {
+	rOpts, err := builder.NewDefaultKafkaReceiverCfg().InitFromViper(v)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &kafkareceiver.Configuration{
+		Brokers:       rOpts.Brokers,
+		Topics:        rOpts.Topics,
+		GroupID:       rOpts.GroupID,
+		ClientID:      rOpts.ClientID,
+		Encoding:      rOpts.Encoding,
+		InitialOffset: rOpts.InitialOffset,
+	}
+	kr, err := kafkareceiver.New(config, traceConsumer, kafkareceiver.WithLogger(logger))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create the Kafka receiver: %v", err)
+	}
+
+	if err := kr.StartTraceReception(context.Background(), asyncErrorChan); err != nil {
+		return nil, fmt.Errorf("Cannot start Kafka receiver %+v: %v", rOpts, err)
+	}
+
+	logger.Info("Kafka receiver is running.",
+		zap.Strings("brokers", rOpts.Brokers),
+		zap.Strings("topics", rOpts.Topics),
+		zap.String("group-id", rOpts.GroupID),
+		zap.String("encoding", rOpts.Encoding))
+
+	return kr, nil
+}
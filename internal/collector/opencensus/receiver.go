@@ -30,21 +30,31 @@ import (
 	"github.com/census-instrumentation/opencensus-service/consumer"
 	"github.com/census-instrumentation/opencensus-service/receiver"
 	"github.com/census-instrumentation/opencensus-service/receiver/opencensusreceiver"
+	"github.com/census-instrumentation/opencensus-service/receiver/opencensusreceiver/ocmetrics"
+	"github.com/census-instrumentation/opencensus-service/receiver/opencensusreceiver/octrace"
 )
 
-// Start starts the OpenCensus receiver endpoint.
-func Start(logger *zap.Logger, v *viper.Viper, traceConsumer consumer.TraceConsumer, asyncErrorChan chan<- error) (receiver.TraceReceiver, error) {
+// Start starts the OpenCensus receiver endpoint. It also receives metrics if metricsConsumer
+// is not nil.
+func Start(logger *zap.Logger, v *viper.Viper, traceConsumer consumer.TraceConsumer, metricsConsumer consumer.MetricsConsumer, asyncErrorChan chan<- error) (receiver.TraceReceiver, error) {
 	addr, opts, zapFields, err := receiverOptions(v)
 	if err != nil {
 		return nil, err
 	}
 
-	ocr, err := opencensusreceiver.New(addr, traceConsumer, nil, opts...)
+	ocr, err := opencensusreceiver.New(addr, traceConsumer, metricsConsumer, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create the OpenCensus trace receiver: %v", err)
 	}
 
-	if err := ocr.StartTraceReception(context.Background(), asyncErrorChan); err != nil {
+	// Both services have to be registered before the gRPC server starts
+	// serving, so use the combined Start when metrics are also received.
+	if metricsConsumer != nil {
+		err = ocr.Start(context.Background())
+	} else {
+		err = ocr.StartTraceReception(context.Background(), asyncErrorChan)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("Cannot bind Opencensus receiver to address %q: %v", addr, err)
 	}
 
@@ -74,6 +84,24 @@ func receiverOptions(v *viper.Viper) (addr string, opts []opencensusreceiver.Opt
 		opts = append(opts, opencensusreceiver.WithGRPCServerOptions(grpcServerOptions...))
 	}
 
+	// The trace and metrics receiver options must be passed at once, later
+	// calls replace the previous ones.
+	var traceOpts []octrace.Option
+	var metricsOpts []ocmetrics.Option
+	if rOpts.Tenant != nil {
+		traceOpts = append(traceOpts, octrace.WithTenantExtractor(rOpts.Tenant))
+		metricsOpts = append(metricsOpts, ocmetrics.WithTenantExtractor(rOpts.Tenant))
+	}
+	if rOpts.Metadata != nil {
+		traceOpts = append(traceOpts, octrace.WithMetadataExtractor(rOpts.Metadata))
+		metricsOpts = append(metricsOpts, ocmetrics.WithMetadataExtractor(rOpts.Metadata))
+	}
+	if len(traceOpts) > 0 {
+		opts = append(opts,
+			opencensusreceiver.WithTraceReceiverOptions(traceOpts...),
+			opencensusreceiver.WithMetricsReceiverOptions(metricsOpts...))
+	}
+
 	addr = ":" + strconv.FormatInt(int64(rOpts.Port), 10)
 	zapFields = append(zapFields, zap.Int("port", rOpts.Port))
 
@@ -17,13 +17,21 @@
 package ocreceiver
 
 import (
+	"context"
+	"net"
 	"testing"
 	"time"
 
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	agentmetricspb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/metrics/v1"
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 
 	"github.com/census-instrumentation/opencensus-service/cmd/occollector/app/builder"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+	"github.com/census-instrumentation/opencensus-service/internal/testutils"
 	"github.com/census-instrumentation/opencensus-service/processor/processortest"
 	"github.com/census-instrumentation/opencensus-service/receiver/opencensusreceiver"
 )
@@ -86,7 +94,7 @@ func TestStart(t *testing.T) {
 			}
 			nopProcessor := processortest.NewNopTraceProcessor(nil)
 			asyncErrChan := make(chan error, 1)
-			got, err := Start(zap.NewNop(), v, nopProcessor, asyncErrChan)
+			got, err := Start(zap.NewNop(), v, nopProcessor, nil, asyncErrChan)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Start() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -99,3 +107,54 @@ func TestStart(t *testing.T) {
 		})
 	}
 }
+
+func TestStartWithMetricsConsumer(t *testing.T) {
+	_, port, err := net.SplitHostPort(testutils.GetAvailableLocalAddress(t))
+	if err != nil {
+		t.Fatalf("SplitHostPort() error = %v", err)
+	}
+	v := viper.New()
+	v.Set("receivers.opencensus.port", port)
+
+	sink := &exportertest.SinkMetricsExporter{}
+	got, err := Start(zap.NewNop(), v, processortest.NewNopTraceProcessor(nil), sink, make(chan error, 1))
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer got.(*opencensusreceiver.Receiver).Stop()
+
+	cc, err := grpc.Dial("localhost:"+port, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("grpc.Dial() error = %v", err)
+	}
+	defer cc.Close()
+	stream, err := agentmetricspb.NewMetricsServiceClient(cc).Export(context.Background())
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	req := &agentmetricspb.ExportMetricsServiceRequest{
+		Node: &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "frontend"}},
+		Metrics: []*metricspb.Metric{
+			{MetricDescriptor: &metricspb.MetricDescriptor{Name: "requests"}},
+		},
+	}
+	if err := stream.Send(req); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("CloseSend() error = %v", err)
+	}
+
+	// The metrics are passed on asynchronously.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(sink.AllMetrics()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	metrics := sink.AllMetrics()
+	if len(metrics) != 1 {
+		t.Fatalf("got %d metrics batches, want 1", len(metrics))
+	}
+	if name := metrics[0].Metrics[0].GetMetricDescriptor().GetName(); name != "requests" {
+		t.Errorf("got metric %q, want %q", name, "requests")
+	}
+}
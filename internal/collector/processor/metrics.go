@@ -31,6 +31,9 @@ var (
 
 	StatReceivedSpanCount = stats.Int64("spans_received", "counts the number of spans received", stats.UnitDimensionless)
 	StatDroppedSpanCount  = stats.Int64("spans_dropped", "counts the number of spans dropped", stats.UnitDimensionless)
+
+	StatReceivedMetricPointCount = stats.Int64("metric_points_received", "counts the number of metric points received", stats.UnitDimensionless)
+	StatDroppedMetricPointCount  = stats.Int64("metric_points_dropped", "counts the number of metric points dropped", stats.UnitDimensionless)
 )
 
 // MetricTagKeys returns the metric tag keys according to the given telemetry level.
@@ -90,7 +93,29 @@ func MetricViews(level telemetry.Level) []*view.View {
 		Aggregation: view.Sum(),
 	}
 
-	return []*view.View{receivedBatchesView, droppedBatchesView, receivedSpansView, droppedSpansView}
+	receivedMetricPointsView := &view.View{
+		Name:        StatReceivedMetricPointCount.Name(),
+		Measure:     StatReceivedMetricPointCount,
+		Description: "The number of metric points received.",
+		TagKeys:     tagKeys,
+		Aggregation: view.Sum(),
+	}
+	droppedMetricPointsView := &view.View{
+		Name:        StatDroppedMetricPointCount.Name(),
+		Measure:     StatDroppedMetricPointCount,
+		Description: "The number of metric points dropped.",
+		TagKeys:     tagKeys,
+		Aggregation: view.Sum(),
+	}
+
+	return []*view.View{
+		receivedBatchesView,
+		droppedBatchesView,
+		receivedSpansView,
+		droppedSpansView,
+		receivedMetricPointsView,
+		droppedMetricPointsView,
+	}
 }
 
 // ServiceNameForNode gets the service name for a specified node. Used for metrics.
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
 	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
 	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
 	"github.com/golang/protobuf/proto"
@@ -49,7 +50,9 @@ const (
 
 // batcher is a component that accepts spans, and places them into batches grouped by node and resource.
 //
-// batcher implements consumer.TraceConsumer
+// batcher implements consumer.TraceConsumer. A batcher created with NewMetricsBatcher accepts metrics
+// instead of spans and implements consumer.MetricsConsumer, its batch sizes are counted in timeseries
+// points.
 //
 // batcher is a composition of four main pieces. First is its buckets map which maps nodes to buckets.
 // Second is the nodebatcher which keeps a batch associated with a single node, and sends it downstream.
@@ -60,11 +63,12 @@ const (
 //   2) bucketTicker should be simplified significantly and replaced with a single ticker, since
 //      tracking by node is no longer needed.
 type batcher struct {
-	buckets sync.Map
-	sender  consumer.TraceConsumer
-	tickers []*bucketTicker
-	name    string
-	logger  *zap.Logger
+	buckets       sync.Map
+	sender        consumer.TraceConsumer
+	metricsSender consumer.MetricsConsumer
+	tickers       []*bucketTicker
+	name          string
+	logger        *zap.Logger
 
 	removeAfterCycles uint32
 	sendBatchSize     uint32
@@ -76,13 +80,33 @@ type batcher struct {
 }
 
 var _ consumer.TraceConsumer = (*batcher)(nil)
+var _ consumer.MetricsConsumer = (*batcher)(nil)
 
 // NewBatcher creates a new batcher that batches spans by node and resource
 func NewBatcher(name string, logger *zap.Logger, sender consumer.TraceConsumer, opts ...Option) consumer.TraceConsumer {
+	b := newBatcher(name, logger, opts...)
+	b.sender = sender
+
+	// start tickers after options loaded in
+	b.tickers = newStartedBucketTickersForBatch(b)
+	return b
+}
+
+// NewMetricsBatcher creates a new batcher that batches metrics by node and resource. The send batch
+// size is counted in timeseries points.
+func NewMetricsBatcher(name string, logger *zap.Logger, sender consumer.MetricsConsumer, opts ...Option) consumer.MetricsConsumer {
+	b := newBatcher(name, logger, opts...)
+	b.metricsSender = sender
+
+	// start tickers after options loaded in
+	b.tickers = newStartedBucketTickersForBatch(b)
+	return b
+}
+
+func newBatcher(name string, logger *zap.Logger, opts ...Option) *batcher {
 	// Init with defaults
 	b := &batcher{
 		name:   name,
-		sender: sender,
 		logger: logger,
 
 		removeAfterCycles: defaultRemoveAfterCycles,
@@ -96,9 +120,6 @@ func NewBatcher(name string, logger *zap.Logger, sender consumer.TraceConsumer,
 	for _, opt := range opts {
 		opt(b)
 	}
-
-	// start tickers after options loaded in
-	b.tickers = newStartedBucketTickersForBatch(b)
 	return b
 }
 
@@ -111,6 +132,15 @@ func (b *batcher) ConsumeTraceData(ctx context.Context, td data.TraceData) error
 	return nil
 }
 
+// ConsumeMetricsData implements batcher as a MetricsProcessor and takes the provided metrics and adds
+// them to batches
+func (b *batcher) ConsumeMetricsData(ctx context.Context, md data.MetricsData) error {
+	bucketID := b.genBucketID(md.Node, md.Resource, "")
+	bucket := b.getOrAddBucket(bucketID, md.Node, md.Resource, "")
+	bucket.addMetrics(md.Metrics)
+	return nil
+}
+
 func (b *batcher) genBucketID(node *commonpb.Node, resource *resourcepb.Resource, spanFormat string) string {
 	h := md5.New()
 	if node != nil {
@@ -167,6 +197,7 @@ func (b *batcher) removeBucket(bucketID string) {
 type nodeBatch struct {
 	mu              sync.RWMutex
 	items           [][]*tracepb.Span
+	metrics         [][]*metricspb.Metric
 	totalItemCount  uint32
 	cyclesUntouched uint32
 	dead            uint32
@@ -184,38 +215,76 @@ func newNodeBatch(
 	node *commonpb.Node,
 	resource *resourcepb.Resource,
 ) *nodeBatch {
-	return &nodeBatch{
+	nb := &nodeBatch{
 		parent:   parent,
 		format:   format,
 		node:     node,
 		resource: resource,
-		items:    make([][]*tracepb.Span, 0, initialBatchCapacity),
 	}
+	if parent.metricsSender != nil {
+		nb.metrics = make([][]*metricspb.Metric, 0, initialBatchCapacity)
+	} else {
+		nb.items = make([][]*tracepb.Span, 0, initialBatchCapacity)
+	}
+	return nb
 }
 
 func (nb *nodeBatch) add(spans []*tracepb.Span) {
 	nb.mu.Lock()
 	nb.items = append(nb.items, spans)
-	nb.totalItemCount = nb.totalItemCount + uint32(len(spans))
+	nb.addItemCount(uint32(len(spans)))
+}
+
+func (nb *nodeBatch) addMetrics(metrics []*metricspb.Metric) {
+	nb.mu.Lock()
+	nb.metrics = append(nb.metrics, metrics)
+	nb.addItemCount(countPoints(metrics))
+}
+
+// addItemCount must be called with nb.mu locked, it unlocks it before sending the batch if it reached
+// the send batch size.
+func (nb *nodeBatch) addItemCount(count uint32) {
+	nb.totalItemCount = nb.totalItemCount + count
 	nb.cyclesUntouched = 0
 
 	itemCount := nb.totalItemCount
 	var itemsToProcess [][]*tracepb.Span
+	var metricsToProcess [][]*metricspb.Metric
 	if nb.totalItemCount > nb.parent.sendBatchSize || nb.dead == nodeStatusDead {
-		itemsToProcess, itemCount = nb.getAndReset()
+		itemsToProcess, metricsToProcess, itemCount = nb.getAndReset()
 	}
 	nb.mu.Unlock()
 
-	if len(itemsToProcess) > 0 {
-		nb.sendItems(itemsToProcess, itemCount, statBatchSizeTriggerSend)
+	if len(itemsToProcess) > 0 || len(metricsToProcess) > 0 {
+		nb.sendItems(itemsToProcess, metricsToProcess, itemCount, statBatchSizeTriggerSend)
 	}
 }
 
 func (nb *nodeBatch) sendItems(
 	itemsToProcess [][]*tracepb.Span,
+	metricsToProcess [][]*metricspb.Metric,
 	itemCount uint32,
 	measure *stats.Int64Measure,
 ) {
+	statsTags := processor.StatsTagsForBatch(
+		nb.parent.name, processor.ServiceNameForNode(nb.node), nb.format,
+	)
+	_ = stats.RecordWithTags(context.Background(), statsTags, measure.M(1))
+
+	if nb.parent.metricsSender != nil {
+		var mdItems []*metricspb.Metric
+		for _, metrics := range metricsToProcess {
+			mdItems = append(mdItems, metrics...)
+		}
+		md := data.MetricsData{
+			Node:     nb.node,
+			Resource: nb.resource,
+			Metrics:  mdItems,
+		}
+		_ = nb.parent.metricsSender.ConsumeMetricsData(context.Background(), md)
+		return
+	}
+
 	tdItems := make([]*tracepb.Span, 0, itemCount)
 	for _, items := range itemsToProcess {
 		tdItems = append(tdItems, items...)
@@ -226,23 +295,35 @@ func (nb *nodeBatch) sendItems(
 		Spans:        tdItems,
 		SourceFormat: nb.format,
 	}
-	statsTags := processor.StatsTagsForBatch(
-		nb.parent.name, processor.ServiceNameForNode(nb.node), nb.format,
-	)
-	_ = stats.RecordWithTags(context.Background(), statsTags, measure.M(1))
 
 	// TODO: This process should be done in an async way, perhaps with a channel + goroutine worker(s)
 	ctx := observability.ContextWithReceiverName(context.Background(), nb.format)
 	_ = nb.parent.sender.ConsumeTraceData(ctx, td)
 }
 
-func (nb *nodeBatch) getAndReset() ([][]*tracepb.Span, uint32) {
+func (nb *nodeBatch) getAndReset() ([][]*tracepb.Span, [][]*metricspb.Metric, uint32) {
 	itemsToProcess := nb.items
+	metricsToProcess := nb.metrics
 	itemsCount := nb.totalItemCount
-	nb.items = make([][]*tracepb.Span, 0, len(itemsToProcess))
+	if nb.parent.metricsSender != nil {
+		nb.metrics = make([][]*metricspb.Metric, 0, len(metricsToProcess))
+	} else {
+		nb.items = make([][]*tracepb.Span, 0, len(itemsToProcess))
+	}
 	nb.lastSent = time.Now().UnixNano()
 	nb.totalItemCount = 0
-	return itemsToProcess, itemsCount
+	return itemsToProcess, metricsToProcess, itemsCount
+}
+
+// countPoints returns the number of timeseries points in metrics.
+func countPoints(metrics []*metricspb.Metric) uint32 {
+	var count uint32
+	for _, metric := range metrics {
+		for _, ts := range metric.GetTimeseries() {
+			count += uint32(len(ts.GetPoints()))
+		}
+	}
+	return count
 }
 
 type bucketTicker struct {
@@ -312,13 +393,14 @@ func (bt *bucketTicker) processNodeBatch(nbKey string, nb *nodeBatch) {
 		// If the batch is non-empty, go ahead and send it
 		var itemCount uint32
 		var itemsToProcess [][]*tracepb.Span
+		var metricsToProcess [][]*metricspb.Metric
 		if nb.lastSent+bt.parent.timeout.Nanoseconds() < time.Now().UnixNano() {
-			itemsToProcess, itemCount = nb.getAndReset()
+			itemsToProcess, metricsToProcess, itemCount = nb.getAndReset()
 		}
 		nb.mu.Unlock()
 
-		if len(itemsToProcess) > 0 {
-			nb.sendItems(itemsToProcess, itemCount, statTimeoutTriggerSend)
+		if len(itemsToProcess) > 0 || len(metricsToProcess) > 0 {
+			nb.sendItems(itemsToProcess, metricsToProcess, itemCount, statTimeoutTriggerSend)
 		}
 	} else {
 		nb.cyclesUntouched++
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
 	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
 	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
 	"github.com/census-instrumentation/opencensus-service/data"
@@ -302,6 +303,80 @@ func TestConcurrentBatchAdds(t *testing.T) {
 	}
 }
 
+func TestMetricsBatchSizeTrigger(t *testing.T) {
+	sender := newTestMetricsSender()
+	batcher := NewMetricsBatcher(
+		"test",
+		zap.NewNop(),
+		sender,
+		WithSendBatchSize(5),
+		WithTickTime(time.Hour),
+	).(*batcher)
+
+	node := &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "svc"}}
+	for requestNum := 0; requestNum < 3; requestNum++ {
+		request := data.MetricsData{
+			Node:    node,
+			Metrics: []*metricspb.Metric{getTestMetric(requestNum, 2)},
+		}
+		batcher.ConsumeMetricsData(context.Background(), request)
+	}
+
+	select {
+	case md := <-sender.reqChan:
+		if len(md.Metrics) != 3 {
+			t.Errorf("Wanted a batch with 3 metrics, got %d", len(md.Metrics))
+		}
+		if md.Node != node {
+			t.Errorf("Batch should keep the node of the requests")
+		}
+	case <-time.After(1 * time.Second):
+		t.Errorf("timed out waiting for metrics")
+	}
+}
+
+func TestMetricsBucketRemove(t *testing.T) {
+	sender := newTestMetricsSender()
+	tickTime := 50 * time.Millisecond
+	removeAfterTicks := 2
+	batcher := NewMetricsBatcher(
+		"test",
+		zap.NewNop(),
+		sender,
+		WithTimeout(50*time.Millisecond),
+		WithTickTime(tickTime),
+		WithRemoveAfterTicks(removeAfterTicks),
+	).(*batcher)
+
+	request := data.MetricsData{
+		Node: &commonpb.Node{
+			ServiceInfo: &commonpb.ServiceInfo{Name: "svc"},
+		},
+		Metrics: []*metricspb.Metric{getTestMetric(0, 1)},
+	}
+	batcher.ConsumeMetricsData(context.Background(), request)
+
+	select {
+	case md := <-sender.reqChan:
+		if len(md.Metrics) != 1 {
+			t.Errorf("Wanted a batch with 1 metric, got %d", len(md.Metrics))
+		}
+	case <-time.After(1 * time.Second):
+		t.Errorf("timed out waiting for metrics")
+	}
+
+	if batcher.getBucket(batcher.genBucketID(request.Node, nil, "")) == nil {
+		t.Errorf("Bucket should exist but does not.")
+	}
+
+	// Doesn't seem to be a great way to test this without waiting
+	<-time.After(2 * time.Duration(removeAfterTicks) * tickTime)
+
+	if batcher.getBucket(batcher.genBucketID(request.Node, nil, "")) != nil {
+		t.Errorf("Bucket should be deleted but is not.")
+	}
+}
+
 func BenchmarkConcurrentBatchAdds(b *testing.B) {
 	sender1 := newNopSender()
 	batcher := NewBatcher("test", zap.NewNop(), sender1).(*batcher)
@@ -335,6 +410,20 @@ func getTestSpanName(requestNum, index int) *tracepb.TruncatableString {
 	}
 }
 
+func getTestMetric(requestNum, numPoints int) *metricspb.Metric {
+	points := make([]*metricspb.Point, 0, numPoints)
+	for i := 0; i < numPoints; i++ {
+		points = append(points, &metricspb.Point{Value: &metricspb.Point_Int64Value{Int64Value: int64(i)}})
+	}
+	return &metricspb.Metric{
+		MetricDescriptor: &metricspb.MetricDescriptor{
+			Name: fmt.Sprintf("test-metric-%d", requestNum),
+			Type: metricspb.MetricDescriptor_GAUGE_INT64,
+		},
+		Timeseries: []*metricspb.TimeSeries{{Points: points}},
+	}
+}
+
 type nopSender struct{}
 
 func newNopSender() *nopSender {
@@ -385,3 +474,18 @@ func (ts *testSender) waitFor(spans int, timeout time.Duration) chan error {
 	}()
 	return errorCn
 }
+
+type testMetricsSender struct {
+	reqChan chan data.MetricsData
+}
+
+func newTestMetricsSender() *testMetricsSender {
+	return &testMetricsSender{
+		reqChan: make(chan data.MetricsData, 100),
+	}
+}
+
+func (ts *testMetricsSender) ConsumeMetricsData(ctx context.Context, md data.MetricsData) error {
+	ts.reqChan <- md
+	return nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queued
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jaegertracing/jaeger/pkg/queue"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/internal/collector/processor"
+	"github.com/census-instrumentation/opencensus-service/internal/collector/processor/nodebatcher"
+)
+
+// metricsFormat is the format used to tag the stats of metrics batches, which unlike span batches
+// don't carry their source format.
+const metricsFormat = "metrics"
+
+type queuedMetricsProcessor struct {
+	name                     string
+	queue                    *queue.BoundedQueue
+	logger                   *zap.Logger
+	sender                   consumer.MetricsConsumer
+	numWorkers               int
+	retryOnProcessingFailure bool
+	backoffDelay             time.Duration
+	stopCh                   chan struct{}
+	stopOnce                 sync.Once
+}
+
+var _ consumer.MetricsConsumer = (*queuedMetricsProcessor)(nil)
+
+type metricsQueueItem struct {
+	queuedTime time.Time
+	md         data.MetricsData
+	ctx        context.Context
+}
+
+// NewQueuedMetricsProcessor returns a consumer.MetricsConsumer that queues the metrics it receives
+// and sends them to sender from a pool of workers, retrying on failures if configured. It is the
+// metrics counterpart of NewQueuedSpanProcessor and accepts the same options.
+func NewQueuedMetricsProcessor(sender consumer.MetricsConsumer, opts ...Option) consumer.MetricsConsumer {
+	options := Options.apply(opts...)
+	mp := newQueuedMetricsProcessor(sender, options)
+
+	mp.queue.StartConsumers(mp.numWorkers, func(item interface{}) {
+		value := item.(*metricsQueueItem)
+		mp.processItemFromQueue(value)
+	})
+
+	// Start a timer to report the queue length.
+	go reportQueueLength(mp.name, mp.queue, mp.stopCh)
+
+	if options.batchingEnabled {
+		mp.logger.Info("Using queued metrics processor with batching.")
+		batcher := nodebatcher.NewMetricsBatcher(mp.name, mp.logger, mp, options.batchingOptions...)
+		return batcher
+	}
+
+	return mp
+}
+
+func newQueuedMetricsProcessor(sender consumer.MetricsConsumer, opts options) *queuedMetricsProcessor {
+	boundedQueue := queue.NewBoundedQueue(opts.queueSize, func(item interface{}) {})
+	return &queuedMetricsProcessor{
+		name:                     opts.name,
+		queue:                    boundedQueue,
+		logger:                   opts.logger,
+		numWorkers:               opts.numWorkers,
+		sender:                   sender,
+		retryOnProcessingFailure: opts.retryOnProcessingFailure,
+		backoffDelay:             opts.backoffDelay,
+		stopCh:                   make(chan struct{}),
+	}
+}
+
+func (mp *queuedMetricsProcessor) Stop() {
+	mp.stopOnce.Do(func() {
+		close(mp.stopCh)
+		mp.queue.Stop()
+	})
+}
+
+func (mp *queuedMetricsProcessor) ConsumeMetricsData(ctx context.Context, md data.MetricsData) error {
+	item := &metricsQueueItem{
+		queuedTime: time.Now(),
+		md:         md,
+		ctx:        ctx,
+	}
+
+	statsTags := processor.StatsTagsForBatch(mp.name, processor.ServiceNameForNode(md.Node), metricsFormat)
+	stats.RecordWithTags(context.Background(), statsTags, processor.StatReceivedMetricPointCount.M(countPoints(md)))
+
+	addedToQueue := mp.queue.Produce(item)
+	if !addedToQueue {
+		mp.onItemDropped(item, statsTags)
+	}
+	return nil
+}
+
+func (mp *queuedMetricsProcessor) processItemFromQueue(item *metricsQueueItem) {
+	startTime := time.Now()
+	err := mp.sender.ConsumeMetricsData(item.ctx, item.md)
+	statsTags := processor.StatsTagsForBatch(mp.name, processor.ServiceNameForNode(item.md.Node), metricsFormat)
+	if err == nil {
+		// Record latency metrics and return
+		sendLatencyMs := int64(time.Since(startTime) / time.Millisecond)
+		inQueueLatencyMs := int64(time.Since(item.queuedTime) / time.Millisecond)
+		stats.RecordWithTags(context.Background(),
+			statsTags,
+			statSuccessSendOps.M(1),
+			statSendLatencyMs.M(sendLatencyMs),
+			statInQueueLatencyMs.M(inQueueLatencyMs))
+
+		return
+	}
+
+	// There was an error
+	stats.RecordWithTags(context.Background(), statsTags, statFailedSendOps.M(1))
+	batchSize := len(item.md.Metrics)
+	mp.logger.Warn("Sender failed", zap.String("processor", mp.name), zap.Error(err))
+	if !mp.retryOnProcessingFailure {
+		// throw away the batch
+		mp.logger.Error("Failed to process metrics batch, discarding", zap.String("processor", mp.name), zap.Int("batch-size", batchSize))
+		mp.onItemDropped(item, statsTags)
+	} else {
+		if !mp.queue.Produce(item) {
+			mp.logger.Error("Failed to process metrics batch and failed to re-enqueue", zap.String("processor", mp.name), zap.Int("batch-size", batchSize))
+			mp.onItemDropped(item, statsTags)
+		} else {
+			mp.logger.Warn("Failed to process metrics batch, re-enqueued", zap.String("processor", mp.name), zap.Int("batch-size", batchSize))
+		}
+	}
+
+	// back-off for configured delay, but get interrupted when shutting down
+	if mp.backoffDelay > 0 {
+		mp.logger.Warn("Backing off before next attempt",
+			zap.String("processor", mp.name),
+			zap.Duration("backoff-delay", mp.backoffDelay))
+		select {
+		case <-mp.stopCh:
+			mp.logger.Info("Interrupted due to shutdown", zap.String("processor", mp.name))
+		case <-time.After(mp.backoffDelay):
+			mp.logger.Info("Resume processing", zap.String("processor", mp.name))
+		}
+	}
+}
+
+func (mp *queuedMetricsProcessor) onItemDropped(item *metricsQueueItem, statsTags []tag.Mutator) {
+	numPoints := countPoints(item.md)
+	stats.RecordWithTags(context.Background(), statsTags, processor.StatDroppedMetricPointCount.M(numPoints))
+
+	mp.logger.Warn("Metrics batch dropped",
+		zap.String("processor", mp.name),
+		zap.Int("#metrics", len(item.md.Metrics)),
+		zap.Int64("#points", numPoints))
+}
+
+// countPoints returns the number of timeseries points in md.
+func countPoints(md data.MetricsData) int64 {
+	var count int64
+	for _, metric := range md.Metrics {
+		for _, ts := range metric.GetTimeseries() {
+			count += int64(len(ts.GetPoints()))
+		}
+	}
+	return count
+}
@@ -65,20 +65,7 @@ func NewQueuedSpanProcessor(sender consumer.TraceConsumer, opts ...Option) consu
 	})
 
 	// Start a timer to report the queue length.
-	ctx, _ := tag.New(context.Background(), tag.Upsert(processor.TagExporterNameKey, sp.name))
-	ticker := time.NewTicker(1 * time.Second)
-	go func(ctx context.Context) {
-		defer ticker.Stop()
-		for {
-			select {
-			case <-sp.stopCh:
-				return
-			case <-ticker.C:
-				length := int64(sp.queue.Size())
-				stats.Record(ctx, statQueueLength.M(length))
-			}
-		}
-	}(ctx)
+	go reportQueueLength(sp.name, sp.queue, sp.stopCh)
 
 	if options.batchingEnabled {
 		sp.logger.Info("Using queued processor with batching.")
@@ -89,6 +76,22 @@ func NewQueuedSpanProcessor(sender consumer.TraceConsumer, opts ...Option) consu
 	return sp
 }
 
+// reportQueueLength records the length of the queue every second until stopCh is closed.
+func reportQueueLength(name string, boundedQueue *queue.BoundedQueue, stopCh chan struct{}) {
+	ctx, _ := tag.New(context.Background(), tag.Upsert(processor.TagExporterNameKey, name))
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			length := int64(boundedQueue.Size())
+			stats.Record(ctx, statQueueLength.M(length))
+		}
+	}
+}
+
 func newQueuedSpanProcessor(sender consumer.TraceConsumer, opts options) *queuedSpanProcessor {
 	boundedQueue := queue.NewBoundedQueue(opts.queueSize, func(item interface{}) {})
 	return &queuedSpanProcessor{
@@ -16,12 +16,14 @@ package queued
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
 	"testing"
 
 	"github.com/census-instrumentation/opencensus-service/consumer"
 
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
 	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
 	"github.com/census-instrumentation/opencensus-service/data"
 )
@@ -58,6 +60,75 @@ func TestQueueProcessorHappyPath(t *testing.T) {
 	}
 }
 
+func TestQueueMetricsProcessorHappyPath(t *testing.T) {
+	mockProc := newMockConcurrentMetricsProcessor(0)
+	qp := NewQueuedMetricsProcessor(mockProc)
+
+	metrics := []*metricspb.Metric{{}}
+	wantBatches := 10
+	wantMetrics := 0
+	for i := 0; i < wantBatches; i++ {
+		md := data.MetricsData{Metrics: metrics}
+		wantMetrics += len(metrics)
+		metrics = append(metrics, &metricspb.Metric{})
+		mockProc.waitGroup.Add(1)
+		go qp.ConsumeMetricsData(context.Background(), md)
+	}
+
+	// Wait until all batches received
+	mockProc.waitGroup.Wait()
+
+	if wantBatches != int(mockProc.batchCount) {
+		t.Fatalf("Wanted %d batches, got %d", wantBatches, mockProc.batchCount)
+	}
+	if wantMetrics != int(mockProc.metricCount) {
+		t.Fatalf("Wanted %d metrics, got %d", wantMetrics, mockProc.metricCount)
+	}
+}
+
+func TestQueueMetricsProcessorRetry(t *testing.T) {
+	mockProc := newMockConcurrentMetricsProcessor(2)
+	qp := NewQueuedMetricsProcessor(mockProc, Options.WithRetryOnProcessingFailures(true))
+
+	mockProc.waitGroup.Add(1)
+	qp.ConsumeMetricsData(context.Background(), data.MetricsData{Metrics: []*metricspb.Metric{{}}})
+
+	// Wait until the batch is successfully sent
+	mockProc.waitGroup.Wait()
+
+	if mockProc.batchCount != 1 {
+		t.Fatalf("Wanted 1 batch, got %d", mockProc.batchCount)
+	}
+	if atomic.LoadInt32(&mockProc.failures) != 0 {
+		t.Fatalf("Wanted all the failures to be consumed, %d left", mockProc.failures)
+	}
+}
+
+type mockConcurrentMetricsProcessor struct {
+	waitGroup   *sync.WaitGroup
+	failures    int32
+	batchCount  int32
+	metricCount int32
+}
+
+var _ consumer.MetricsConsumer = (*mockConcurrentMetricsProcessor)(nil)
+
+// newMockConcurrentMetricsProcessor returns a mock that fails the first failures calls.
+func newMockConcurrentMetricsProcessor(failures int32) *mockConcurrentMetricsProcessor {
+	return &mockConcurrentMetricsProcessor{waitGroup: new(sync.WaitGroup), failures: failures}
+}
+
+func (p *mockConcurrentMetricsProcessor) ConsumeMetricsData(ctx context.Context, md data.MetricsData) error {
+	if atomic.AddInt32(&p.failures, -1) >= 0 {
+		return errors.New("transient failure")
+	}
+	atomic.StoreInt32(&p.failures, 0)
+	atomic.AddInt32(&p.batchCount, 1)
+	atomic.AddInt32(&p.metricCount, int32(len(md.Metrics)))
+	p.waitGroup.Done()
+	return nil
+}
+
 type mockConcurrentSpanProcessor struct {
 	waitGroup  *sync.WaitGroup
 	batchCount int32
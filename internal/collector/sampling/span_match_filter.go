@@ -0,0 +1,65 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sampling
+
+import (
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+
+	"github.com/census-instrumentation/opencensus-service/internal/spanmatch"
+)
+
+type spanMatchFilter struct {
+	expr *spanmatch.Expr
+}
+
+var _ PolicyEvaluator = (*spanMatchFilter)(nil)
+
+// NewSpanMatchFilter creates a policy evaluator that samples all traces with
+// at least one span matching the given expression.
+func NewSpanMatchFilter(expr *spanmatch.Expr) PolicyEvaluator {
+	return &spanMatchFilter{
+		expr: expr,
+	}
+}
+
+// OnLateArrivingSpans notifies the evaluator that the given list of spans arrived
+// after the sampling decision was already taken for the trace.
+// This gives the evaluator a chance to log any message/metrics and/or update any
+// related internal state.
+func (smf *spanMatchFilter) OnLateArrivingSpans(earlyDecision Decision, spans []*tracepb.Span) error {
+	return nil
+}
+
+// Evaluate looks at the trace data and returns a corresponding SamplingDecision.
+func (smf *spanMatchFilter) Evaluate(traceID []byte, trace *TraceData) (Decision, error) {
+	trace.Lock()
+	batches := trace.ReceivedBatches
+	trace.Unlock()
+	for _, batch := range batches {
+		for _, span := range batch.Spans {
+			if span != nil && smf.expr.Match(batch.Node, batch.Resource, span) {
+				return Sampled, nil
+			}
+		}
+	}
+
+	return NotSampled, nil
+}
+
+// OnDroppedSpans is called when the trace needs to be dropped, due to memory
+// pressure, before the decision_wait time has been reached.
+func (smf *spanMatchFilter) OnDroppedSpans(traceID []byte, trace *TraceData) (Decision, error) {
+	return NotSampled, nil
+}
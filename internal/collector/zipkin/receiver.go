@@ -38,7 +38,9 @@ func Start(logger *zap.Logger, v *viper.Viper, traceConsumer consumer.TraceConsu
 	}
 
 	addr := ":" + strconv.FormatInt(int64(rOpts.Port), 10)
-	zi, err := zipkinreceiver.New(addr, traceConsumer)
+	zi, err := zipkinreceiver.New(addr, traceConsumer,
+		zipkinreceiver.WithTenantExtractor(rOpts.Tenant),
+		zipkinreceiver.WithMetadataExtractor(rOpts.Metadata))
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create the Zipkin receiver: %v", err)
 	}
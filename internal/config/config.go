@@ -124,6 +124,7 @@ type ReceiverConfig struct {
 	Address             string `mapstructure:"address"`
 	CollectorHTTPPort   int    `mapstructure:"collector_http_port"`
 	CollectorThriftPort int    `mapstructure:"collector_thrift_port"`
+	CollectorGRPCPort   int    `mapstructure:"collector_grpc_port"`
 
 	// The allowed CORS origins for HTTP/JSON requests the grpc-gateway adapter
 	// for the OpenCensus receiver. See github.com/rs/cors
@@ -329,6 +330,26 @@ func (c *Config) JaegerReceiverPorts() (collectorPort, thriftPort int) {
 	return jc.CollectorHTTPPort, jc.CollectorThriftPort
 }
 
+// JaegerReceiverGRPCPort is a helper to safely retrieve the port that the
+// gRPC collector of the Jaeger receiver will run on.
+func (c *Config) JaegerReceiverGRPCPort() int {
+	if c == nil || c.Receivers == nil || c.Receivers.Jaeger == nil {
+		return 0
+	}
+	return c.Receivers.Jaeger.CollectorGRPCPort
+}
+
+// JaegerReceiverTLSCredentialsServerOption checks if the Jaeger receiver's Configuration
+// has TLS credentials in the form of a certificate file and a key file. If it doesn't
+// have any, it will return a nil option and a nil error. Otherwise, it will try to
+// retrieve gRPC transport credentials for its gRPC collector from the file combinations.
+func (c *Config) JaegerReceiverTLSCredentialsServerOption() (opt grpc.ServerOption, ok bool, err error) {
+	if !c.JaegerReceiverEnabled() || !c.Receivers.Jaeger.HasTLSCredentials() {
+		return nil, false, nil
+	}
+	return c.Receivers.Jaeger.TLSCredentials.ToGRPCServerOption()
+}
+
 // HasTLSCredentials returns true if TLSCredentials is non-nil
 func (rCfg *ReceiverConfig) HasTLSCredentials() bool {
 	return rCfg != nil && rCfg.TLSCredentials != nil && rCfg.TLSCredentials.nonEmpty()
@@ -358,14 +379,28 @@ func (tlsCreds *TLSCredentials) ToOpenCensusReceiverServerOption() (opt opencens
 		return opencensusreceiver.WithNoopOption(), false, nil
 	}
 
-	transportCreds, err := credentials.NewServerTLSFromFile(tlsCreds.CertFile, tlsCreds.KeyFile)
+	gRPCCredsOpt, _, err := tlsCreds.ToGRPCServerOption()
 	if err != nil {
 		return nil, false, err
 	}
-	gRPCCredsOpt := grpc.Creds(transportCreds)
 	return opencensusreceiver.WithGRPCServerOptions(gRPCCredsOpt), true, nil
 }
 
+// ToGRPCServerOption retrieves gRPC transport credentials from the certificate file
+// and key file, and creates a gRPC server option with them. If the TLS credentials
+// are nil, it returns a nil option and a nil error.
+func (tlsCreds *TLSCredentials) ToGRPCServerOption() (opt grpc.ServerOption, ok bool, err error) {
+	if tlsCreds == nil {
+		return nil, false, nil
+	}
+
+	transportCreds, err := credentials.NewServerTLSFromFile(tlsCreds.CertFile, tlsCreds.KeyFile)
+	if err != nil {
+		return nil, false, err
+	}
+	return grpc.Creds(transportCreds), true, nil
+}
+
 // OpenCensusReceiverTLSCredentialsServerOption checks if the OpenCensus receiver's Configuration
 // has TLS credentials in the form of a certificate file and a key file. If it doesn't
 // have any, it will return opencensusreceiver.WithNoopOption() and a nil error.
@@ -50,7 +50,6 @@ const (
 	errPipelineReceiverNotExists
 	errPipelineProcessorNotExists
 	errPipelineExporterNotExists
-	errMetricPipelineCannotHaveProcessors
 	errUnmarshalError
 )
 
@@ -493,14 +492,6 @@ func validatePipelineProcessors(cfg *configmodels.ConfigV2, pipeline *configmode
 				msg:  fmt.Sprintf("pipeline %q must have at least one processor", pipeline.Name),
 			}
 		}
-	} else if pipeline.InputType == configmodels.MetricsDataType {
-		// Metrics pipeline cannot have processors.
-		if len(pipeline.Processors) > 0 {
-			return &configError{
-				code: errMetricPipelineCannotHaveProcessors,
-				msg:  fmt.Sprintf("metrics pipeline %q cannot have processors", pipeline.Name),
-			}
-		}
 	}
 
 	// Validate pipeline processor name references
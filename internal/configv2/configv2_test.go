@@ -146,6 +146,24 @@ func TestDecodeConfig_MultiProto(t *testing.T) {
 		}, "Did not load receiver config correctly")
 }
 
+func TestDecodeConfig_MetricsPipelineWithProcessors(t *testing.T) {
+
+	// Load the config
+	config, err := LoadConfigFile(t, path.Join(".", "testdata", "metrics-pipeline-with-processors.yaml"))
+	if err != nil {
+		t.Fatalf("unable to load config, %v", err)
+	}
+
+	assert.Equal(t, config.Pipelines["metrics"],
+		&configmodels.Pipeline{
+			Name:       "metrics",
+			InputType:  configmodels.MetricsDataType,
+			Receivers:  []string{"multireceiver"},
+			Processors: []string{"exampleprocessor"},
+			Exporters:  []string{"exampleexporter"},
+		}, "Did not load pipeline config correctly")
+}
+
 func TestDecodeConfig_Invalid(t *testing.T) {
 
 	var testCases = []struct {
@@ -169,7 +187,6 @@ func TestDecodeConfig_Invalid(t *testing.T) {
 		{name: "pipeline-exporter-not-exists", expected: errPipelineExporterNotExists},
 		{name: "pipeline-processor-not-exists", expected: errPipelineProcessorNotExists},
 		{name: "pipeline-must-have-processors", expected: errPipelineMustHaveProcessors},
-		{name: "metric-pipeline-cannot-have-processors", expected: errMetricPipelineCannotHaveProcessors},
 		{name: "unknown-receiver-type", expected: errUnknownReceiverType},
 		{name: "unknown-exporter-type", expected: errUnknownExporterType},
 		{name: "unknown-processor-type", expected: errUnknownProcessorType},
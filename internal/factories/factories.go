@@ -148,6 +148,35 @@ type ProcessorFactory interface {
 		cfg configmodels.Processor) (processor.MetricsProcessor, error)
 }
 
+// RoutingProcessorFactory is implemented by the factories of processors that,
+// instead of passing data to the next consumer, send it to exporters picked
+// for each batch. Such processors must be the last ones of their pipelines.
+type RoutingProcessorFactory interface {
+	ProcessorFactory
+
+	// CreateRoutingTraceProcessor creates a trace processor that routes data
+	// to the given exporters of its pipeline, keyed by name.
+	CreateRoutingTraceProcessor(exporters map[string]consumer.TraceConsumer,
+		cfg configmodels.Processor) (processor.TraceProcessor, error)
+
+	// CreateRoutingMetricsProcessor creates a metrics processor that routes
+	// data to the given exporters of its pipeline, keyed by name.
+	CreateRoutingMetricsProcessor(exporters map[string]consumer.MetricsConsumer,
+		cfg configmodels.Processor) (processor.MetricsProcessor, error)
+}
+
+// StoppableProcessorFactory is implemented by the factories of processors that
+// hold resources, e.g. a watch cache shared by the processors created from the
+// same config, that must be released when the pipelines shut down.
+type StoppableProcessorFactory interface {
+	ProcessorFactory
+
+	// StopProcessor is called once on shutdown for each processor created
+	// from the given config, the shared resources can be released once the
+	// last one is stopped.
+	StopProcessor(cfg configmodels.Processor) error
+}
+
 // List of registered processor factories.
 var processorFactories = make(map[string]ProcessorFactory)
 
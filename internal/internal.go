@@ -34,10 +34,12 @@ func TimeToTimestamp(t time.Time) *timestamp.Timestamp {
 	}
 }
 
-// CombineErrors converts a list of errors into one error.
+// CombineErrors converts a list of errors into one error. If all the errors
+// are the same, e.g. every consumer of a fan-out rejected the data with the
+// same error, the first one is returned as is so that its type is kept.
 func CombineErrors(errs []error) error {
 	numErrors := len(errs)
-	if numErrors == 1 {
+	if numErrors == 1 || (numErrors > 1 && allSameErrors(errs)) {
 		return errs[0]
 	} else if numErrors > 1 {
 		errMsgs := make([]string, 0, numErrors)
@@ -48,3 +50,12 @@ func CombineErrors(errs []error) error {
 	}
 	return nil
 }
+
+func allSameErrors(errs []error) bool {
+	for _, err := range errs[1:] {
+		if err != errs[0] && err.Error() != errs[0].Error() {
+			return false
+		}
+	}
+	return true
+}
@@ -59,6 +59,12 @@ func TestCombineErrors(t *testing.T) {
 			fmt.Errorf("bar"),
 		},
 		expected: "[foo; bar]",
+	}, {
+		errors: []error{
+			fmt.Errorf("foo"),
+			fmt.Errorf("foo"),
+		},
+		expected: "foo",
 	}}
 
 	for _, tc := range testCases {
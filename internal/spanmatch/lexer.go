@@ -0,0 +1,188 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanmatch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokDuration
+	tokOperator
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+)
+
+// token is a lexical token of an expression.
+type token struct {
+	kind tokenKind
+	// text is the token as written, or the unquoted value of strings.
+	text     string
+	number   float64
+	duration time.Duration
+	// pos is the byte offset of the token in the expression.
+	pos int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// lex splits the expression into tokens, ending with a tokEOF one.
+func lex(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			continue
+
+		case r == '(' || r == ')' || r == '[' || r == ']':
+			kind := map[rune]tokenKind{'(': tokLParen, ')': tokRParen, '[': tokLBracket, ']': tokRBracket}[r]
+			tokens = append(tokens, token{kind: kind, text: string(r), pos: start})
+			i += size
+
+		case r == '"' || r == '`':
+			end, err := stringEnd(s, i)
+			if err != nil {
+				return nil, err
+			}
+			value, err := strconv.Unquote(s[i:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %v", start, err)
+			}
+			tokens = append(tokens, token{kind: tokString, text: value, pos: start})
+			i = end
+
+		case r == '-' || r == '.' || unicode.IsDigit(r):
+			i += size
+			for i < len(s) {
+				r, size = utf8.DecodeRuneInString(s[i:])
+				if !(r == '.' || unicode.IsDigit(r) || unicode.IsLetter(r)) {
+					break
+				}
+				i += size
+			}
+			tok, err := numberToken(s[start:i], start)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+
+		case r == '_' || unicode.IsLetter(r):
+			for i < len(s) {
+				r, size = utf8.DecodeRuneInString(s[i:])
+				if !(r == '_' || r == '.' || unicode.IsDigit(r) || unicode.IsLetter(r)) {
+					break
+				}
+				i += size
+			}
+			text := s[start:i]
+			kind := tokIdent
+			switch text {
+			case "and":
+				kind = tokAnd
+			case "or":
+				kind = tokOr
+			case "not":
+				kind = tokNot
+			}
+			tokens = append(tokens, token{kind: kind, text: text, pos: start})
+
+		default:
+			two := s[i:]
+			if len(two) > 2 {
+				two = two[:2]
+			}
+			switch {
+			case two == "&&":
+				tokens = append(tokens, token{kind: tokAnd, text: two, pos: start})
+				i += 2
+			case two == "||":
+				tokens = append(tokens, token{kind: tokOr, text: two, pos: start})
+				i += 2
+			case two == "==" || two == "!=" || two == "<=" || two == ">=" || two == "=~" || two == "!~":
+				tokens = append(tokens, token{kind: tokOperator, text: two, pos: start})
+				i += 2
+			case r == '<' || r == '>':
+				tokens = append(tokens, token{kind: tokOperator, text: string(r), pos: start})
+				i++
+			case r == '!':
+				tokens = append(tokens, token{kind: tokNot, text: "!", pos: start})
+				i++
+			default:
+				return nil, fmt.Errorf("unexpected %q at offset %d", r, start)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(s)}), nil
+}
+
+// stringEnd returns the offset right after the quoted string starting at i.
+func stringEnd(s string, i int) (int, error) {
+	quote := s[i]
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			if quote == '"' {
+				j++
+			}
+		case quote:
+			return j + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string at offset %d", i)
+}
+
+// numberToken returns the token of a number, or of a duration when it has a
+// unit, e.g. 2s or 1m30s.
+func numberToken(text string, pos int) (token, error) {
+	if strings.IndexFunc(text, unicode.IsLetter) >= 0 {
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return token{}, fmt.Errorf("invalid duration %q at offset %d", text, pos)
+		}
+		return token{kind: tokDuration, text: text, duration: d, pos: pos}, nil
+	}
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return token{}, fmt.Errorf("invalid number %q at offset %d", text, pos)
+	}
+	return token{kind: tokNumber, text: text, number: n, pos: pos}, nil
+}
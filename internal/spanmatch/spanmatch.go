@@ -0,0 +1,482 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package spanmatch implements a small expression language selecting spans,
+// so processors can apply only to some of them, e.g.
+//
+//	service == "frontend" and attributes["http.status_code"] >= 500
+//
+// An expression compares a field of the span to a literal, with ==, !=, <,
+// <=, >, >=, or =~ and !~ to match a regular expression. The fields are:
+//
+//	service            the service.name span attribute, or else the Node service
+//	name               the span name
+//	kind               the span kind, compared to SERVER, CLIENT or UNSPECIFIED
+//	duration           the span duration, compared to durations like 2s or 150ms
+//	status.code        the canonical status code, 0 when the span has no status
+//	attributes["key"]  the span attribute with the given key
+//	resource["key"]    the Resource label with the given key
+//
+// Attributes and labels compare to strings, numbers or true and false, with
+// string values converted to numbers or booleans when needed, as the Zipkin
+// tags are all strings. A comparison never matches when the attribute or
+// label is missing or can't be converted; an attribute or label alone
+// matches when it is present.
+//
+// Comparisons are combined with and, or, not, or &&, || and !, and
+// parentheses. Expressions are parsed once, when the configuration is
+// loaded, and errors such as comparing a duration to a string are reported
+// then.
+package spanmatch
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/golang/protobuf/ptypes"
+)
+
+// ServiceAttribute is the span attribute the service field is read from
+// before the Node, e.g. as set by the attribute promotion processor.
+const ServiceAttribute = "service.name"
+
+// Expr is a parsed expression. A nil *Expr matches all the spans.
+type Expr struct {
+	src   string
+	match matcher
+}
+
+// target is what an expression is evaluated against.
+type target struct {
+	node     *commonpb.Node
+	resource *resourcepb.Resource
+	span     *tracepb.Span
+}
+
+type matcher func(t *target) bool
+
+// Parse parses an expression. An empty expression gives a nil *Expr, which
+// matches all the spans.
+func Parse(s string) (*Expr, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid match expression %q: %v", s, err)
+	}
+	p := &parser{tokens: tokens}
+	m, err := p.parseOr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = p.unexpected()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid match expression %q: %v", s, err)
+	}
+	return &Expr{src: s, match: m}, nil
+}
+
+// MustParse is like Parse but panics if the expression can't be parsed. It
+// simplifies tests and expressions known to be valid.
+func MustParse(s string) *Expr {
+	e, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Match returns whether the span, received with the given Node and Resource,
+// which can be nil, matches the expression.
+func (e *Expr) Match(node *commonpb.Node, resource *resourcepb.Resource, span *tracepb.Span) bool {
+	if e == nil {
+		return true
+	}
+	if span == nil {
+		return false
+	}
+	return e.match(&target{node: node, resource: resource, span: span})
+}
+
+// String returns the expression as written.
+func (e *Expr) String() string {
+	if e == nil {
+		return ""
+	}
+	return e.src
+}
+
+// parser is a recursive descent parser of the grammar:
+//
+//	or         = and { ("or" | "||") and }
+//	and        = unary { ("and" | "&&") unary }
+//	unary      = ("not" | "!") unary | "(" or ")" | comparison
+//	comparison = field [ operator literal ]
+//	field      = ident [ "[" string "]" ]
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	return fmt.Errorf("unexpected %v at offset %d", t, t.pos)
+}
+
+func (p *parser) parseOr() (matcher, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(t *target) bool { return l(t) || right(t) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (matcher, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(t *target) bool { return l(t) && right(t) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (matcher, error) {
+	switch p.peek().kind {
+	case tokNot:
+		p.next()
+		m, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(t *target) bool { return !m(t) }, nil
+	case tokLParen:
+		p.next()
+		m, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, p.unexpected()
+		}
+		p.next()
+		return m, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (matcher, error) {
+	f, err := p.parseField()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokOperator {
+		if f.name != fieldAttributes && f.name != fieldResource {
+			return nil, fmt.Errorf("%s must be compared to a value", f)
+		}
+		return func(t *target) bool {
+			_, ok := f.value(t)
+			return ok
+		}, nil
+	}
+	op := p.next()
+	lit := p.next()
+	switch lit.kind {
+	case tokString, tokNumber, tokDuration, tokIdent:
+	default:
+		return nil, fmt.Errorf("unexpected %v at offset %d, expecting a value", lit, lit.pos)
+	}
+	m, err := f.compare(op.text, lit)
+	if err != nil {
+		return nil, fmt.Errorf("%v at offset %d", err, op.pos)
+	}
+	return m, nil
+}
+
+// Fields of the spans.
+const (
+	fieldService    = "service"
+	fieldName       = "name"
+	fieldKind       = "kind"
+	fieldDuration   = "duration"
+	fieldStatusCode = "status.code"
+	fieldAttributes = "attributes"
+	fieldResource   = "resource"
+)
+
+// spanKinds holds the span kinds the kind field can be compared to, by name.
+var spanKinds = map[string]tracepb.Span_SpanKind{
+	"SERVER":      tracepb.Span_SERVER,
+	"CLIENT":      tracepb.Span_CLIENT,
+	"UNSPECIFIED": tracepb.Span_SPAN_KIND_UNSPECIFIED,
+}
+
+type field struct {
+	name string
+	// key is the attribute or label key.
+	key string
+}
+
+func (f field) String() string {
+	if f.key != "" {
+		return fmt.Sprintf("%s[%q]", f.name, f.key)
+	}
+	return f.name
+}
+
+func (p *parser) parseField() (field, error) {
+	t := p.peek()
+	if t.kind != tokIdent {
+		return field{}, p.unexpected()
+	}
+	p.next()
+	switch t.text {
+	case fieldService, fieldName, fieldKind, fieldDuration, fieldStatusCode:
+		return field{name: t.text}, nil
+	case fieldAttributes, fieldResource:
+		if p.peek().kind != tokLBracket {
+			return field{}, p.unexpected()
+		}
+		p.next()
+		key := p.peek()
+		if key.kind != tokString {
+			return field{}, p.unexpected()
+		}
+		p.next()
+		if p.peek().kind != tokRBracket {
+			return field{}, p.unexpected()
+		}
+		p.next()
+		return field{name: t.text, key: key.text}, nil
+	}
+	return field{}, fmt.Errorf("unknown field %q at offset %d", t.text, t.pos)
+}
+
+// value returns the value of the field for the target, a string, float64,
+// bool, time.Duration or tracepb.Span_SpanKind, and whether it is set.
+func (f field) value(t *target) (interface{}, bool) {
+	span := t.span
+	switch f.name {
+	case fieldService:
+		if service := span.GetAttributes().GetAttributeMap()[ServiceAttribute].GetStringValue().GetValue(); service != "" {
+			return service, true
+		}
+		return t.node.GetServiceInfo().GetName(), true
+	case fieldName:
+		return span.GetName().GetValue(), true
+	case fieldKind:
+		return span.Kind, true
+	case fieldDuration:
+		start, err := ptypes.Timestamp(span.StartTime)
+		if err != nil {
+			return nil, false
+		}
+		end, err := ptypes.Timestamp(span.EndTime)
+		if err != nil {
+			return nil, false
+		}
+		return end.Sub(start), true
+	case fieldStatusCode:
+		return float64(span.GetStatus().GetCode()), true
+	case fieldAttributes:
+		switch v := span.GetAttributes().GetAttributeMap()[f.key].GetValue().(type) {
+		case *tracepb.AttributeValue_StringValue:
+			return v.StringValue.GetValue(), true
+		case *tracepb.AttributeValue_IntValue:
+			return float64(v.IntValue), true
+		case *tracepb.AttributeValue_DoubleValue:
+			return v.DoubleValue, true
+		case *tracepb.AttributeValue_BoolValue:
+			return v.BoolValue, true
+		}
+		return nil, false
+	case fieldResource:
+		label, ok := t.resource.GetLabels()[f.key]
+		return label, ok
+	}
+	return nil, false
+}
+
+// compare returns the matcher comparing the field to the literal with the
+// given operator.
+func (f field) compare(op string, lit token) (matcher, error) {
+	switch f.name {
+	case fieldService, fieldName:
+		if lit.kind != tokString {
+			return nil, fmt.Errorf("%s must be compared to a string", f)
+		}
+	case fieldKind:
+		if op != "==" && op != "!=" {
+			return nil, fmt.Errorf("%s only supports == and !=", f)
+		}
+		want, ok := spanKinds[strings.ToUpper(lit.text)]
+		if (lit.kind != tokIdent && lit.kind != tokString) || !ok {
+			return nil, fmt.Errorf("%s must be compared to SERVER, CLIENT or UNSPECIFIED", f)
+		}
+		return f.matcher(func(v interface{}) bool {
+			return (v.(tracepb.Span_SpanKind) == want) == (op == "==")
+		}), nil
+	case fieldDuration:
+		if lit.kind != tokDuration {
+			return nil, fmt.Errorf("%s must be compared to a duration, e.g. 2s", f)
+		}
+		if op == "=~" || op == "!~" {
+			return nil, fmt.Errorf("%s can't be matched to a regular expression", f)
+		}
+		return f.matcher(func(v interface{}) bool {
+			return compareNumbers(op, float64(v.(time.Duration)), float64(lit.duration))
+		}), nil
+	case fieldStatusCode:
+		if lit.kind != tokNumber {
+			return nil, fmt.Errorf("%s must be compared to a number", f)
+		}
+	}
+
+	switch lit.kind {
+	case tokString:
+		switch op {
+		case "==", "!=":
+			return f.matcher(func(v interface{}) bool {
+				s, ok := toString(v)
+				return ok && (s == lit.text) == (op == "==")
+			}), nil
+		case "=~", "!~":
+			re, err := regexp.Compile(lit.text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q: %v", lit.text, err)
+			}
+			return f.matcher(func(v interface{}) bool {
+				s, ok := toString(v)
+				return ok && re.MatchString(s) == (op == "=~")
+			}), nil
+		}
+		return nil, fmt.Errorf("strings only support ==, !=, =~ and !~")
+	case tokNumber:
+		if op == "=~" || op == "!~" {
+			return nil, fmt.Errorf("numbers can't be matched to a regular expression")
+		}
+		return f.matcher(func(v interface{}) bool {
+			n, ok := toNumber(v)
+			return ok && compareNumbers(op, n, lit.number)
+		}), nil
+	case tokIdent:
+		if lit.text != "true" && lit.text != "false" {
+			return nil, fmt.Errorf("unexpected %v, expecting a value", lit)
+		}
+		if op != "==" && op != "!=" {
+			return nil, fmt.Errorf("booleans only support == and !=")
+		}
+		want := lit.text == "true"
+		return f.matcher(func(v interface{}) bool {
+			b, ok := toBool(v)
+			return ok && (b == want) == (op == "==")
+		}), nil
+	}
+	return nil, fmt.Errorf("%s can't be compared to a duration", f)
+}
+
+// matcher returns a matcher applying fn to the value of the field, which
+// doesn't match when the field isn't set.
+func (f field) matcher(fn func(v interface{}) bool) matcher {
+	return func(t *target) bool {
+		v, ok := f.value(t)
+		return ok && fn(v)
+	}
+}
+
+func compareNumbers(op string, a, b float64) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+func toString(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func toBool(v interface{}) (bool, bool) {
+	switch v := v.(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		return b, err == nil
+	}
+	return false, false
+}
@@ -0,0 +1,163 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanmatch
+
+import (
+	"testing"
+	"time"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stringValue(s string) *tracepb.AttributeValue {
+	return &tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: s}},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	start := time.Date(2019, 7, 1, 12, 0, 0, 0, time.UTC)
+	startTime, _ := ptypes.TimestampProto(start)
+	endTime, _ := ptypes.TimestampProto(start.Add(3 * time.Second))
+	node := &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "frontend"}}
+	resource := &resourcepb.Resource{Labels: map[string]string{"k8s.namespace.name": "shop"}}
+	span := &tracepb.Span{
+		Name:      &tracepb.TruncatableString{Value: "GET /api/cart"},
+		Kind:      tracepb.Span_SERVER,
+		StartTime: startTime,
+		EndTime:   endTime,
+		Status:    &tracepb.Status{Code: 13},
+		Attributes: &tracepb.Span_Attributes{AttributeMap: map[string]*tracepb.AttributeValue{
+			"http.status_code": {Value: &tracepb.AttributeValue_IntValue{IntValue: 503}},
+			"zipkin.status":    stringValue("404"),
+			"error":            {Value: &tracepb.AttributeValue_BoolValue{BoolValue: true}},
+			"retry":            stringValue("false"),
+			"ratio":            {Value: &tracepb.AttributeValue_DoubleValue{DoubleValue: 0.25}},
+		}},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`service == "frontend"`, true},
+		{`service != "frontend"`, false},
+		{`name =~ "^GET /api"`, true},
+		{`name !~ "^GET /api"`, false},
+		{"name =~ `cart$`", true},
+		{`kind == SERVER`, true},
+		{`kind != CLIENT`, true},
+		{`kind == "client"`, false},
+		{`duration > 2s`, true},
+		{`duration >= 3s and duration <= 3000ms`, true},
+		{`duration < 1m30s`, true},
+		{`status.code == 13`, true},
+		{`attributes["http.status_code"] >= 500`, true},
+		{`attributes["http.status_code"] == "503"`, true},
+		{`attributes["http.status_code"] =~ "^5"`, true},
+		{`attributes["zipkin.status"] >= 400 && attributes["zipkin.status"] < 500`, true},
+		{`attributes["error"] == true`, true},
+		{`attributes["retry"] == false`, true},
+		{`attributes["ratio"] < 0.5`, true},
+		{`attributes["error"]`, true},
+		{`attributes["missing"]`, false},
+		{`attributes["missing"] != "x"`, false},
+		{`not attributes["missing"]`, true},
+		{`!(attributes["missing"] == "x")`, true},
+		{`attributes["error"] == "x"`, false},
+		{`resource["k8s.namespace.name"] == "shop"`, true},
+		{`resource["k8s.namespace.name"]`, true},
+		{`kind == CLIENT or service == "frontend" and duration > 1s`, true},
+		{`(kind == CLIENT or service == "frontend") and duration > 5s`, false},
+		{`kind == CLIENT || service == "backend"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Parse(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, e.Match(node, resource, span))
+			assert.Equal(t, tt.expr, e.String())
+		})
+	}
+}
+
+func TestMatchService(t *testing.T) {
+	e := MustParse(`service == "backend"`)
+	span := &tracepb.Span{}
+	assert.False(t, e.Match(nil, nil, span))
+	assert.True(t, e.Match(&commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "backend"}}, nil, span))
+
+	// The service attribute is used before the Node.
+	span.Attributes = &tracepb.Span_Attributes{AttributeMap: map[string]*tracepb.AttributeValue{
+		ServiceAttribute: stringValue("backend"),
+	}}
+	assert.True(t, e.Match(&commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "frontend"}}, nil, span))
+
+	assert.False(t, e.Match(nil, nil, nil))
+	assert.False(t, MustParse(`duration > 1s`).Match(nil, nil, span))
+}
+
+func TestParseEmpty(t *testing.T) {
+	e, err := Parse("  ")
+	require.NoError(t, err)
+	assert.Nil(t, e)
+	assert.True(t, e.Match(nil, nil, &tracepb.Span{}))
+	assert.Equal(t, "", e.String())
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		`service`,
+		`service == 1`,
+		`service > "a"`,
+		`name =~ "("`,
+		`kind == SERVERS`,
+		`kind > SERVER`,
+		`duration > 2`,
+		`duration > 2parsecs`,
+		`duration =~ 2s`,
+		`status.code == "OK"`,
+		`attributes["a"] > "b"`,
+		`attributes["a"] == 2s`,
+		`attributes["a"] =~ 2`,
+		`attributes["a"] < true`,
+		`attributes["a"] == maybe`,
+		`attributes[a] == 1`,
+		`attributes["a" == 1`,
+		`attributes == 1`,
+		`host == "a"`,
+		`service == "a" and`,
+		`(service == "a"`,
+		`service == "a")`,
+		`service == "a`,
+		`service = "a"`,
+		`service == "a" service == "b"`,
+		`== "a"`,
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := Parse(expr)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMustParse(t *testing.T) {
+	assert.Panics(t, func() { MustParse(`service ==`) })
+}
@@ -44,6 +44,9 @@ var TagKeyReceiver, _ = tag.NewKey("oc_receiver")
 // TagKeyExporter defines tag key for Exporter.
 var TagKeyExporter, _ = tag.NewKey("oc_exporter")
 
+// TagKeyTenant defines tag key for the tenant the data belongs to.
+var TagKeyTenant, _ = tag.NewKey("oc_tenant")
+
 // ViewReceiverReceivedSpans defines the view for the receiver received spans metric.
 var ViewReceiverReceivedSpans = &view.View{
 	Name:        mReceiverReceivedSpans.Name(),
@@ -96,6 +99,14 @@ func ContextWithReceiverName(ctx context.Context, receiverName string) context.C
 	return ctx
 }
 
+// ContextWithTenant adds the tag "oc_tenant" and the tenant ID as the value, and returns the
+// newly created context. The views above do not have the tag since the number of tenants is
+// unbounded, views that have it should bound the values they record.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	ctx, _ = tag.New(ctx, tag.Upsert(TagKeyTenant, tenant))
+	return ctx
+}
+
 // RecordTraceReceiverMetrics records the number of the spans received and dropped by the receiver.
 // Use it with a context.Context generated using ContextWithReceiverName().
 func RecordTraceReceiverMetrics(ctxWithTraceReceiverName context.Context, receivedSpans int, droppedSpans int) {
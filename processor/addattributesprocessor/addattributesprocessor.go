@@ -23,6 +23,7 @@ import (
 
 	"github.com/census-instrumentation/opencensus-service/consumer"
 	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/internal/spanmatch"
 	"github.com/census-instrumentation/opencensus-service/processor"
 )
 
@@ -33,6 +34,7 @@ var (
 type addattributesprocessor struct {
 	attributeMap map[string]*tracepb.AttributeValue
 	overwrite    bool
+	match        *spanmatch.Expr
 	nextConsumer consumer.TraceConsumer
 }
 
@@ -47,6 +49,19 @@ func WithOverwrite(overwrite bool) Option {
 	}
 }
 
+// WithMatch returns an Option to only add the attributes to the spans matching
+// the given spanmatch expression. All spans match an empty expression.
+func WithMatch(expr string) Option {
+	return func(aap *addattributesprocessor) error {
+		match, err := spanmatch.Parse(expr)
+		if err != nil {
+			return err
+		}
+		aap.match = match
+		return nil
+	}
+}
+
 // WithAttributes returns an Option to configure the attributes to be added to all spans.
 func WithAttributes(attributes map[string]interface{}) Option {
 	return func(aap *addattributesprocessor) error {
@@ -98,6 +113,9 @@ func (aap *addattributesprocessor) ConsumeTraceData(ctx context.Context, td data
 			// We will not create nil spans with just attributes on them
 			continue
 		}
+		if !aap.match.Match(td.Node, td.Resource, span) {
+			continue
+		}
 		if span.Attributes == nil {
 			span.Attributes = &tracepb.Span_Attributes{}
 		}
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"testing"
 
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
 	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
 	"github.com/census-instrumentation/opencensus-service/data"
 	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
@@ -35,6 +36,50 @@ func TestAddAttributesProcessorInvalidValue(t *testing.T) {
 	}
 }
 
+func TestAddAttributesProcessorInvalidMatch(t *testing.T) {
+	_, err := NewTraceProcessor(exportertest.NewNopTraceExporter(), WithMatch(`service ==`))
+	if err == nil {
+		t.Fatalf("Unexpected error when creating with invalid match expression: want not-nil got nil")
+	}
+}
+
+func TestAddAttributesProcessorMatch(t *testing.T) {
+	tt, err := NewTraceProcessor(
+		exportertest.NewNopTraceExporter(),
+		WithAttributes(map[string]interface{}{"team": "checkout"}),
+		WithMatch(`service == "cart" and kind == SERVER`),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error when creating: want nil got %v", err)
+	}
+
+	serverSpan := &tracepb.Span{Kind: tracepb.Span_SERVER}
+	clientSpan := &tracepb.Span{Kind: tracepb.Span_CLIENT}
+	otherSpan := &tracepb.Span{Kind: tracepb.Span_SERVER}
+	tds := []data.TraceData{
+		{
+			Node:  &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "cart"}},
+			Spans: []*tracepb.Span{serverSpan, clientSpan},
+		},
+		{
+			Node:  &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "payment"}},
+			Spans: []*tracepb.Span{otherSpan},
+		},
+	}
+	for _, td := range tds {
+		if err := tt.ConsumeTraceData(context.Background(), td); err != nil {
+			t.Fatalf("ConsumeTraceData return error: want nil got %v", err)
+		}
+	}
+
+	if val, ok := serverSpan.GetAttributes().GetAttributeMap()["team"]; !ok || val.GetStringValue().GetValue() != "checkout" {
+		t.Errorf("Missing or invalid attribute on matching span")
+	}
+	if clientSpan.Attributes != nil || otherSpan.Attributes != nil {
+		t.Errorf("Attribute added to spans not matching")
+	}
+}
+
 func TestAddAttributesProcessorWithEmptyMap(t *testing.T) {
 	want := error(nil)
 	tt, err := NewTraceProcessor(exportertest.NewNopTraceExporter())
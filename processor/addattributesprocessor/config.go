@@ -23,4 +23,7 @@ type ConfigV2 struct {
 	configmodels.ProcessorSettings `mapstructure:",squash"`
 	Overwrite                      bool                   `mapstructure:"overwrite"`
 	Values                         map[string]interface{} `mapstructure:"values"`
+	// Match is a spanmatch expression selecting the spans the attributes are
+	// added to, all of them if empty.
+	Match string `mapstructure:"match"`
 }
@@ -51,5 +51,6 @@ func TestLoadConfig(t *testing.T) {
 				"string attribute":   "string value",
 				"attribute.with.dot": "another value",
 			},
+			Match: `service == "frontend"`,
 		})
 }
@@ -53,7 +53,12 @@ func (f *processorFactory) CreateTraceProcessor(
 	cfg configmodels.Processor,
 ) (processor.TraceProcessor, error) {
 	oCfg := cfg.(*ConfigV2)
-	return NewTraceProcessor(nextConsumer, WithAttributes(oCfg.Values), WithOverwrite(oCfg.Overwrite))
+	return NewTraceProcessor(
+		nextConsumer,
+		WithAttributes(oCfg.Values),
+		WithOverwrite(oCfg.Overwrite),
+		WithMatch(oCfg.Match),
+	)
 }
 
 // CreateMetricsProcessor creates a metrics processor based on this config.
@@ -21,6 +21,7 @@ import (
 
 	"github.com/census-instrumentation/opencensus-service/consumer"
 	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/internal/spanmatch"
 	"github.com/census-instrumentation/opencensus-service/processor"
 )
 
@@ -37,11 +38,16 @@ type KeyReplacement struct {
 	// KeepOriginal is set to true to indicate that the original key
 	// should not be removed from the attributes.
 	KeepOriginal bool `mapstructure:"keep"`
+	// Match is a spanmatch expression selecting the spans the replacement
+	// applies to, all of them if empty.
+	Match string `mapstructure:"match"`
 }
 
 type attributekeyprocessor struct {
 	nextConsumer consumer.TraceConsumer
 	replacements []KeyReplacement
+	// matches holds the parsed Match of each replacement.
+	matches []*spanmatch.Expr
 }
 
 var _ processor.TraceProcessor = (*attributekeyprocessor)(nil)
@@ -53,9 +59,11 @@ func NewTraceProcessor(nextConsumer consumer.TraceConsumer, replacements ...KeyR
 	}
 
 	lenReplacements := len(replacements)
+	var matches []*spanmatch.Expr
 	if lenReplacements > 0 {
+		matches = make([]*spanmatch.Expr, lenReplacements)
 		seenKeys := make(map[string]bool, lenReplacements)
-		for _, replacement := range replacements {
+		for i, replacement := range replacements {
 			if seenKeys[replacement.Key] {
 				return nil, fmt.Errorf("replacement key %q already specified", replacement.Key)
 			}
@@ -63,12 +71,18 @@ func NewTraceProcessor(nextConsumer consumer.TraceConsumer, replacements ...KeyR
 			if seenKeys[replacement.NewKey] {
 				return nil, fmt.Errorf("replacement new key %q is already a key being mapped", replacement.NewKey)
 			}
+			match, err := spanmatch.Parse(replacement.Match)
+			if err != nil {
+				return nil, fmt.Errorf("replacement key %q: %v", replacement.Key, err)
+			}
+			matches[i] = match
 		}
 	}
 
 	return &attributekeyprocessor{
 		nextConsumer: nextConsumer,
 		replacements: replacements,
+		matches:      matches,
 	}, nil
 }
 
@@ -83,7 +97,10 @@ func (akp *attributekeyprocessor) ConsumeTraceData(ctx context.Context, td data.
 		}
 
 		attribMap := span.Attributes.AttributeMap
-		for _, replacement := range akp.replacements {
+		for i, replacement := range akp.replacements {
+			if !akp.matches[i].Match(td.Node, td.Resource, span) {
+				continue
+			}
 			if keyValue, oldKeyPresent := attribMap[replacement.Key]; oldKeyPresent {
 				newKeyMapped := func() bool {
 					_, newKeyPresent := attribMap[replacement.NewKey]
@@ -19,12 +19,14 @@ import (
 	"reflect"
 	"testing"
 
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
 	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
 	"github.com/google/go-cmp/cmp"
 
 	"github.com/census-instrumentation/opencensus-service/consumer"
 	"github.com/census-instrumentation/opencensus-service/data"
 	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+	"github.com/census-instrumentation/opencensus-service/internal/spanmatch"
 	"github.com/census-instrumentation/opencensus-service/processor"
 	"github.com/census-instrumentation/opencensus-service/processor/processortest"
 )
@@ -92,6 +94,20 @@ func TestNewTraceProcessor(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "invalid_match",
+			args: args{
+				nextConsumer: nopProcessor,
+				replacements: []KeyReplacement{
+					{
+						Key:    "foo",
+						NewKey: "bar",
+						Match:  "kind == SERVERS",
+					},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "happy_path",
 			args: args{
@@ -119,6 +135,7 @@ func TestNewTraceProcessor(t *testing.T) {
 						NewKey: "biz",
 					},
 				},
+				matches: make([]*spanmatch.Expr, 2),
 			},
 		},
 	}
@@ -309,6 +326,79 @@ func Test_attributekeyprocessor_ConsumeTraceData(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "span_replace_key_match",
+			args: []KeyReplacement{
+				{
+					Key:    "foo",
+					NewKey: "bar",
+					Match:  `service == "backend"`,
+				},
+				{
+					Key:    "biz",
+					NewKey: "baz",
+					Match:  `service == "frontend" and kind == SERVER`,
+				},
+			},
+			td: data.TraceData{
+				Node: &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "frontend"}},
+				Spans: []*tracepb.Span{
+					{
+						Kind: tracepb.Span_SERVER,
+						Attributes: &tracepb.Span_Attributes{
+							AttributeMap: map[string]*tracepb.AttributeValue{
+								"foo": {
+									Value: &tracepb.AttributeValue_IntValue{IntValue: 1},
+								},
+								"biz": {
+									Value: &tracepb.AttributeValue_IntValue{IntValue: 2},
+								},
+							},
+						},
+					},
+					{
+						Kind: tracepb.Span_CLIENT,
+						Attributes: &tracepb.Span_Attributes{
+							AttributeMap: map[string]*tracepb.AttributeValue{
+								"biz": {
+									Value: &tracepb.AttributeValue_IntValue{IntValue: 3},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: []data.TraceData{
+				{
+					Node: &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "frontend"}},
+					Spans: []*tracepb.Span{
+						{
+							Kind: tracepb.Span_SERVER,
+							Attributes: &tracepb.Span_Attributes{
+								AttributeMap: map[string]*tracepb.AttributeValue{
+									"foo": {
+										Value: &tracepb.AttributeValue_IntValue{IntValue: 1},
+									},
+									"baz": {
+										Value: &tracepb.AttributeValue_IntValue{IntValue: 2},
+									},
+								},
+							},
+						},
+						{
+							Kind: tracepb.Span_CLIENT,
+							Attributes: &tracepb.Span_Attributes{
+								AttributeMap: map[string]*tracepb.AttributeValue{
+									"biz": {
+										Value: &tracepb.AttributeValue_IntValue{IntValue: 3},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,348 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package attributepromotionprocessor contains a trace processor that copies
+// Node fields and Resource labels into the span attributes, for the backends
+// that only index span attributes, and that moves span attributes shared by a
+// whole batch up into its Resource.
+package attributepromotionprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/processor"
+)
+
+// NodeField is a field of the Node that can be copied into the span
+// attributes.
+type NodeField string
+
+const (
+	// NodeFieldServiceName is the name of the service, copied as AttributeServiceName.
+	NodeFieldServiceName NodeField = "service_name"
+	// NodeFieldHostName is the host name, copied as AttributeHostName.
+	NodeFieldHostName NodeField = "host_name"
+	// NodeFieldPID is the process ID, copied as AttributePID.
+	NodeFieldPID NodeField = "pid"
+	// NodeFieldLibraryLanguage is the language of the instrumentation
+	// library, copied as AttributeLibraryLanguage.
+	NodeFieldLibraryLanguage NodeField = "library_language"
+	// NodeFieldLibraryVersion is the version of the instrumentation library,
+	// copied as AttributeLibraryVersion.
+	NodeFieldLibraryVersion NodeField = "library_version"
+	// NodeFieldExporterVersion is the version of the exporter of the
+	// instrumentation library, copied as AttributeExporterVersion.
+	NodeFieldExporterVersion NodeField = "exporter_version"
+)
+
+// Span attributes the Node fields are copied to.
+const (
+	AttributeServiceName     = "service.name"
+	AttributeHostName        = "host.name"
+	AttributePID             = "process.pid"
+	AttributeLibraryLanguage = "library.language"
+	AttributeLibraryVersion  = "library.version"
+	AttributeExporterVersion = "library.exporter_version"
+)
+
+// OnCollision is what is done when a key is copied or moved to a destination
+// already having a different value for it.
+type OnCollision string
+
+const (
+	// OnCollisionKeep keeps the value of the destination. Span attributes
+	// that can't be moved to the Resource stay on the spans.
+	OnCollisionKeep OnCollision = "keep"
+	// OnCollisionOverwrite replaces the value of the destination.
+	OnCollisionOverwrite OnCollision = "overwrite"
+)
+
+type attributepromotionprocessor struct {
+	nextConsumer   consumer.TraceConsumer
+	nodeFields     []NodeField
+	nodeAttributes []string
+	resourceLabels []string
+	toResource     []string
+	onCollision    OnCollision
+}
+
+// Option represents options that can be applied to the attribute promotion
+// processor.
+type Option func(*attributepromotionprocessor) error
+
+// WithNodeFields returns an Option to copy the given Node fields into the
+// attributes of every span of the batch. Empty fields are not copied.
+func WithNodeFields(fields ...NodeField) Option {
+	return func(app *attributepromotionprocessor) error {
+		for _, field := range fields {
+			switch field {
+			case NodeFieldServiceName, NodeFieldHostName, NodeFieldPID,
+				NodeFieldLibraryLanguage, NodeFieldLibraryVersion, NodeFieldExporterVersion:
+			default:
+				return fmt.Errorf("unknown node field %q", field)
+			}
+		}
+		app.nodeFields = fields
+		return nil
+	}
+}
+
+// WithNodeAttributes returns an Option to copy the Node attributes with the
+// given keys into the attributes of every span of the batch.
+func WithNodeAttributes(keys ...string) Option {
+	return func(app *attributepromotionprocessor) error {
+		app.nodeAttributes = keys
+		return nil
+	}
+}
+
+// WithResourceLabels returns an Option to copy the Resource labels with the
+// given keys into the attributes of every span of the batch.
+func WithResourceLabels(keys ...string) Option {
+	return func(app *attributepromotionprocessor) error {
+		app.resourceLabels = keys
+		return nil
+	}
+}
+
+// WithToResource returns an Option to move the span attributes with the given
+// keys into the Resource labels of the batch. An attribute is only moved if
+// all the spans of the batch have the same value for it.
+func WithToResource(keys ...string) Option {
+	return func(app *attributepromotionprocessor) error {
+		app.toResource = keys
+		return nil
+	}
+}
+
+// WithOnCollision returns an Option to configure what is done when the
+// destination already has a different value for a key.
+func WithOnCollision(onCollision OnCollision) Option {
+	return func(app *attributepromotionprocessor) error {
+		switch onCollision {
+		case OnCollisionKeep, OnCollisionOverwrite:
+		default:
+			return fmt.Errorf("unknown on_collision %q", onCollision)
+		}
+		app.onCollision = onCollision
+		return nil
+	}
+}
+
+var _ processor.TraceProcessor = (*attributepromotionprocessor)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that copies Node
+// fields and attributes, and Resource labels, into the span attributes and
+// moves span attributes into the Resource labels. The span attributes are
+// moved first.
+func NewTraceProcessor(nextConsumer consumer.TraceConsumer, options ...Option) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+
+	app := &attributepromotionprocessor{
+		nextConsumer: nextConsumer,
+		onCollision:  OnCollisionKeep,
+	}
+	for _, opt := range options {
+		if err := opt(app); err != nil {
+			return nil, err
+		}
+	}
+	return app, nil
+}
+
+func (app *attributepromotionprocessor) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	for _, key := range app.toResource {
+		app.moveToResource(&td, key)
+	}
+
+	promoted := app.promotedAttributes(td.Node, td.Resource)
+	if len(promoted) == 0 {
+		return app.nextConsumer.ConsumeTraceData(ctx, td)
+	}
+
+	for _, span := range td.Spans {
+		if span == nil {
+			continue
+		}
+		if span.Attributes == nil {
+			span.Attributes = &tracepb.Span_Attributes{}
+		}
+		if span.Attributes.AttributeMap == nil {
+			span.Attributes.AttributeMap = make(map[string]*tracepb.AttributeValue, len(promoted))
+		}
+		for key, value := range promoted {
+			if _, exists := span.Attributes.AttributeMap[key]; exists && app.onCollision == OnCollisionKeep {
+				continue
+			}
+			// Each span gets its own value, so later processors can change
+			// them independently.
+			span.Attributes.AttributeMap[key] = copyValue(value)
+		}
+	}
+	return app.nextConsumer.ConsumeTraceData(ctx, td)
+}
+
+// promotedAttributes returns the attributes copied into every span of a batch
+// with the given Node and Resource. Resource labels take precedence over Node
+// attributes, which take precedence over Node fields.
+func (app *attributepromotionprocessor) promotedAttributes(node *commonpb.Node, resource *resourcepb.Resource) map[string]*tracepb.AttributeValue {
+	var promoted map[string]*tracepb.AttributeValue
+	add := func(key string, value *tracepb.AttributeValue) {
+		if promoted == nil {
+			promoted = make(map[string]*tracepb.AttributeValue)
+		}
+		promoted[key] = value
+	}
+
+	for _, field := range app.nodeFields {
+		if key, value := nodeFieldAttribute(node, field); value != nil {
+			add(key, value)
+		}
+	}
+	for _, key := range app.nodeAttributes {
+		if value, ok := node.GetAttributes()[key]; ok {
+			add(key, stringValue(value))
+		}
+	}
+	for _, key := range app.resourceLabels {
+		if value, ok := resource.GetLabels()[key]; ok {
+			add(key, stringValue(value))
+		}
+	}
+	return promoted
+}
+
+func nodeFieldAttribute(node *commonpb.Node, field NodeField) (string, *tracepb.AttributeValue) {
+	switch field {
+	case NodeFieldServiceName:
+		if name := node.GetServiceInfo().GetName(); name != "" {
+			return AttributeServiceName, stringValue(name)
+		}
+	case NodeFieldHostName:
+		if hostName := node.GetIdentifier().GetHostName(); hostName != "" {
+			return AttributeHostName, stringValue(hostName)
+		}
+	case NodeFieldPID:
+		if pid := node.GetIdentifier().GetPid(); pid != 0 {
+			return AttributePID, &tracepb.AttributeValue{
+				Value: &tracepb.AttributeValue_IntValue{IntValue: int64(pid)},
+			}
+		}
+	case NodeFieldLibraryLanguage:
+		if language := node.GetLibraryInfo().GetLanguage(); language != commonpb.LibraryInfo_LANGUAGE_UNSPECIFIED {
+			return AttributeLibraryLanguage, stringValue(strings.ToLower(language.String()))
+		}
+	case NodeFieldLibraryVersion:
+		if version := node.GetLibraryInfo().GetCoreLibraryVersion(); version != "" {
+			return AttributeLibraryVersion, stringValue(version)
+		}
+	case NodeFieldExporterVersion:
+		if version := node.GetLibraryInfo().GetExporterVersion(); version != "" {
+			return AttributeExporterVersion, stringValue(version)
+		}
+	}
+	return "", nil
+}
+
+// moveToResource moves the span attribute with the given key into the
+// Resource labels, if all the spans of the batch have the same value for it.
+func (app *attributepromotionprocessor) moveToResource(td *data.TraceData, key string) {
+	value, ok := sharedValue(td.Spans, key)
+	if !ok {
+		return
+	}
+	if current, exists := td.Resource.GetLabels()[key]; exists && current != value && app.onCollision == OnCollisionKeep {
+		return
+	}
+
+	// The Resource may be shared with other batches, e.g. the ones received
+	// on the same stream, so it is copied before being changed.
+	labels := make(map[string]string, len(td.Resource.GetLabels())+1)
+	for k, v := range td.Resource.GetLabels() {
+		labels[k] = v
+	}
+	labels[key] = value
+	td.Resource = &resourcepb.Resource{Type: td.Resource.GetType(), Labels: labels}
+
+	for _, span := range td.Spans {
+		if span != nil {
+			delete(span.Attributes.AttributeMap, key)
+		}
+	}
+}
+
+// sharedValue returns the value, as a string, that all the spans have for the
+// given attribute key. It returns false if a span lacks it or has another
+// value.
+func sharedValue(spans []*tracepb.Span, key string) (string, bool) {
+	shared, found := "", false
+	for _, span := range spans {
+		if span == nil {
+			continue
+		}
+		attr, ok := span.GetAttributes().GetAttributeMap()[key]
+		if !ok {
+			return "", false
+		}
+		value, ok := attributeValueString(attr)
+		if !ok || (found && value != shared) {
+			return "", false
+		}
+		shared, found = value, true
+	}
+	return shared, found
+}
+
+func attributeValueString(value *tracepb.AttributeValue) (string, bool) {
+	switch v := value.GetValue().(type) {
+	case *tracepb.AttributeValue_StringValue:
+		return v.StringValue.GetValue(), true
+	case *tracepb.AttributeValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10), true
+	case *tracepb.AttributeValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64), true
+	case *tracepb.AttributeValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue), true
+	}
+	return "", false
+}
+
+func stringValue(s string) *tracepb.AttributeValue {
+	return &tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: s}},
+	}
+}
+
+// copyValue copies the string and int values, the only ones promoted.
+func copyValue(value *tracepb.AttributeValue) *tracepb.AttributeValue {
+	switch v := value.GetValue().(type) {
+	case *tracepb.AttributeValue_StringValue:
+		return stringValue(v.StringValue.GetValue())
+	case *tracepb.AttributeValue_IntValue:
+		return &tracepb.AttributeValue{Value: &tracepb.AttributeValue_IntValue{IntValue: v.IntValue}}
+	}
+	return value
+}
@@ -0,0 +1,253 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attributepromotionprocessor
+
+import (
+	"context"
+	"testing"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+)
+
+func intValue(i int64) *tracepb.AttributeValue {
+	return &tracepb.AttributeValue{Value: &tracepb.AttributeValue_IntValue{IntValue: i}}
+}
+
+func spanWithAttributes(attrs map[string]*tracepb.AttributeValue) *tracepb.Span {
+	return &tracepb.Span{Attributes: &tracepb.Span_Attributes{AttributeMap: attrs}}
+}
+
+func TestNewTraceProcessor(t *testing.T) {
+	_, err := NewTraceProcessor(nil)
+	assert.Error(t, err)
+
+	sink := &exportertest.SinkTraceExporter{}
+	_, err = NewTraceProcessor(sink, WithNodeFields("service"))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithOnCollision("merge"))
+	assert.Error(t, err)
+}
+
+func TestPromoteToSpans(t *testing.T) {
+	node := &commonpb.Node{
+		Identifier:  &commonpb.ProcessIdentifier{HostName: "host-1", Pid: 42},
+		LibraryInfo: &commonpb.LibraryInfo{Language: commonpb.LibraryInfo_GO_LANG, CoreLibraryVersion: "0.22.0"},
+		ServiceInfo: &commonpb.ServiceInfo{Name: "frontend"},
+		Attributes:  map[string]string{"deployment": "canary"},
+	}
+	resource := &resourcepb.Resource{Labels: map[string]string{"k8s.pod.name": "pod-1"}}
+
+	tests := []struct {
+		name        string
+		onCollision OnCollision
+		attrs       map[string]*tracepb.AttributeValue
+		want        map[string]*tracepb.AttributeValue
+	}{
+		{
+			name:        "no_attributes",
+			onCollision: OnCollisionKeep,
+			want: map[string]*tracepb.AttributeValue{
+				AttributeServiceName:     stringValue("frontend"),
+				AttributeHostName:        stringValue("host-1"),
+				AttributePID:             intValue(42),
+				AttributeLibraryLanguage: stringValue("go_lang"),
+				AttributeLibraryVersion:  stringValue("0.22.0"),
+				"deployment":             stringValue("canary"),
+				"k8s.pod.name":           stringValue("pod-1"),
+			},
+		},
+		{
+			name:        "keep",
+			onCollision: OnCollisionKeep,
+			attrs:       map[string]*tracepb.AttributeValue{AttributeServiceName: stringValue("backend")},
+			want: map[string]*tracepb.AttributeValue{
+				AttributeServiceName:     stringValue("backend"),
+				AttributeHostName:        stringValue("host-1"),
+				AttributePID:             intValue(42),
+				AttributeLibraryLanguage: stringValue("go_lang"),
+				AttributeLibraryVersion:  stringValue("0.22.0"),
+				"deployment":             stringValue("canary"),
+				"k8s.pod.name":           stringValue("pod-1"),
+			},
+		},
+		{
+			name:        "overwrite",
+			onCollision: OnCollisionOverwrite,
+			attrs:       map[string]*tracepb.AttributeValue{AttributeServiceName: stringValue("backend")},
+			want: map[string]*tracepb.AttributeValue{
+				AttributeServiceName:     stringValue("frontend"),
+				AttributeHostName:        stringValue("host-1"),
+				AttributePID:             intValue(42),
+				AttributeLibraryLanguage: stringValue("go_lang"),
+				AttributeLibraryVersion:  stringValue("0.22.0"),
+				"deployment":             stringValue("canary"),
+				"k8s.pod.name":           stringValue("pod-1"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &exportertest.SinkTraceExporter{}
+			app, err := NewTraceProcessor(
+				sink,
+				WithNodeFields(NodeFieldServiceName, NodeFieldHostName, NodeFieldPID,
+					NodeFieldLibraryLanguage, NodeFieldLibraryVersion, NodeFieldExporterVersion),
+				WithNodeAttributes("deployment", "missing"),
+				WithResourceLabels("k8s.pod.name"),
+				WithOnCollision(tt.onCollision),
+			)
+			require.NoError(t, err)
+
+			span := &tracepb.Span{}
+			if tt.attrs != nil {
+				span = spanWithAttributes(tt.attrs)
+			}
+			td := data.TraceData{Node: node, Resource: resource, Spans: []*tracepb.Span{span}}
+			require.NoError(t, app.ConsumeTraceData(context.Background(), td))
+
+			got := sink.AllTraces()
+			require.Len(t, got, 1)
+			assert.Equal(t, tt.want, got[0].Spans[0].Attributes.AttributeMap)
+		})
+	}
+}
+
+func TestPromoteToSpansCopiesValues(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	app, err := NewTraceProcessor(sink, WithNodeFields(NodeFieldServiceName))
+	require.NoError(t, err)
+
+	td := data.TraceData{
+		Node:  &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "frontend"}},
+		Spans: []*tracepb.Span{{}, {}},
+	}
+	require.NoError(t, app.ConsumeTraceData(context.Background(), td))
+
+	first := td.Spans[0].Attributes.AttributeMap[AttributeServiceName]
+	second := td.Spans[1].Attributes.AttributeMap[AttributeServiceName]
+	assert.Equal(t, first, second)
+	assert.False(t, first == second, "spans must not share attribute values")
+}
+
+func TestMoveToResource(t *testing.T) {
+	tests := []struct {
+		name          string
+		onCollision   OnCollision
+		resource      *resourcepb.Resource
+		spans         []*tracepb.Span
+		wantLabels    map[string]string
+		wantSpanAttrs bool
+	}{
+		{
+			name:        "shared_value",
+			onCollision: OnCollisionKeep,
+			spans: []*tracepb.Span{
+				spanWithAttributes(map[string]*tracepb.AttributeValue{"cloud.zone": stringValue("us-east1-b")}),
+				spanWithAttributes(map[string]*tracepb.AttributeValue{"cloud.zone": stringValue("us-east1-b")}),
+			},
+			wantLabels: map[string]string{"cloud.zone": "us-east1-b"},
+		},
+		{
+			name:        "int_value",
+			onCollision: OnCollisionKeep,
+			resource:    &resourcepb.Resource{Type: "host", Labels: map[string]string{"host.name": "host-1"}},
+			spans: []*tracepb.Span{
+				spanWithAttributes(map[string]*tracepb.AttributeValue{"cloud.zone": intValue(3)}),
+			},
+			wantLabels: map[string]string{"host.name": "host-1", "cloud.zone": "3"},
+		},
+		{
+			name:        "conflicting_values",
+			onCollision: OnCollisionOverwrite,
+			spans: []*tracepb.Span{
+				spanWithAttributes(map[string]*tracepb.AttributeValue{"cloud.zone": stringValue("us-east1-b")}),
+				spanWithAttributes(map[string]*tracepb.AttributeValue{"cloud.zone": stringValue("us-east1-c")}),
+			},
+			wantSpanAttrs: true,
+		},
+		{
+			name:        "missing_value",
+			onCollision: OnCollisionOverwrite,
+			spans: []*tracepb.Span{
+				spanWithAttributes(map[string]*tracepb.AttributeValue{"cloud.zone": stringValue("us-east1-b")}),
+				{},
+			},
+			wantSpanAttrs: true,
+		},
+		{
+			name:        "collision_keep",
+			onCollision: OnCollisionKeep,
+			resource:    &resourcepb.Resource{Labels: map[string]string{"cloud.zone": "us-west1-a"}},
+			spans: []*tracepb.Span{
+				spanWithAttributes(map[string]*tracepb.AttributeValue{"cloud.zone": stringValue("us-east1-b")}),
+			},
+			wantLabels:    map[string]string{"cloud.zone": "us-west1-a"},
+			wantSpanAttrs: true,
+		},
+		{
+			name:        "collision_overwrite",
+			onCollision: OnCollisionOverwrite,
+			resource:    &resourcepb.Resource{Labels: map[string]string{"cloud.zone": "us-west1-a"}},
+			spans: []*tracepb.Span{
+				spanWithAttributes(map[string]*tracepb.AttributeValue{"cloud.zone": stringValue("us-east1-b")}),
+			},
+			wantLabels: map[string]string{"cloud.zone": "us-east1-b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var originalLabels map[string]string
+			if tt.resource != nil {
+				originalLabels = make(map[string]string, len(tt.resource.Labels))
+				for k, v := range tt.resource.Labels {
+					originalLabels[k] = v
+				}
+			}
+
+			sink := &exportertest.SinkTraceExporter{}
+			app, err := NewTraceProcessor(sink, WithToResource("cloud.zone"), WithOnCollision(tt.onCollision))
+			require.NoError(t, err)
+
+			td := data.TraceData{Resource: tt.resource, Spans: tt.spans}
+			require.NoError(t, app.ConsumeTraceData(context.Background(), td))
+
+			got := sink.AllTraces()
+			require.Len(t, got, 1)
+			assert.Equal(t, tt.wantLabels, got[0].Resource.GetLabels())
+			if tt.resource != nil {
+				assert.Equal(t, tt.resource.Type, got[0].Resource.Type)
+				// The Resource of the received batch must not be changed.
+				assert.Equal(t, originalLabels, tt.resource.Labels)
+			}
+			for _, span := range got[0].Spans {
+				_, ok := span.GetAttributes().GetAttributeMap()["cloud.zone"]
+				if !tt.wantSpanAttrs {
+					assert.False(t, ok)
+				}
+			}
+			if tt.wantSpanAttrs {
+				_, ok := got[0].Spans[0].Attributes.AttributeMap["cloud.zone"]
+				assert.True(t, ok)
+			}
+		})
+	}
+}
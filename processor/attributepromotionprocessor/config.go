@@ -0,0 +1,40 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attributepromotionprocessor
+
+import (
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+)
+
+// ConfigV2 defines configuration for the attribute promotion processor.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// NodeFields are the Node fields copied into the span attributes, among
+	// service_name, host_name, pid, library_language, library_version and
+	// exporter_version.
+	NodeFields []string `mapstructure:"node_fields"`
+	// NodeAttributes are the keys of the Node attributes copied into the span
+	// attributes.
+	NodeAttributes []string `mapstructure:"node_attributes"`
+	// ResourceLabels are the keys of the Resource labels copied into the span
+	// attributes.
+	ResourceLabels []string `mapstructure:"resource_labels"`
+	// ToResource are the keys of the span attributes moved into the Resource
+	// labels.
+	ToResource []string `mapstructure:"to_resource"`
+	// OnCollision is what is done when the destination already has a
+	// different value for the key, one of "keep" or "overwrite".
+	OnCollision string `mapstructure:"on_collision"`
+}
@@ -0,0 +1,281 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package attributeschemaprocessor contains a trace processor that enforces
+// a declared type for span attributes, converting the mismatched values when
+// possible, e.g. the numeric tags of Zipkin clients, which are always received
+// as strings.
+package attributeschemaprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/processor"
+)
+
+// AttributeType is the type expected for the value of an attribute.
+type AttributeType string
+
+const (
+	// TypeString expects string values. Any value can be converted to it.
+	TypeString AttributeType = "string"
+	// TypeInt expects int64 values. Strings holding an integer and integral
+	// doubles are converted.
+	TypeInt AttributeType = "int"
+	// TypeDouble expects double values. Strings holding a number and ints are
+	// converted.
+	TypeDouble AttributeType = "double"
+	// TypeBool expects bool values. Strings accepted by strconv.ParseBool are
+	// converted.
+	TypeBool AttributeType = "bool"
+)
+
+// OnViolation is what is done with a value that can't be converted to the
+// type expected for its attribute.
+type OnViolation string
+
+const (
+	// OnViolationKeep leaves the value unchanged.
+	OnViolationKeep OnViolation = "keep"
+	// OnViolationDrop removes the attribute from the span.
+	OnViolationDrop OnViolation = "drop"
+	// OnViolationFlag leaves the value unchanged and lists its key in the
+	// ViolationsAttribute of the span.
+	OnViolationFlag OnViolation = "flag"
+)
+
+// ViolationsAttribute is the span attribute listing, comma separated, the
+// keys of the attributes violating the schema when OnViolationFlag is used.
+const ViolationsAttribute = "schema.violations"
+
+type attributeschemaprocessor struct {
+	nextConsumer consumer.TraceConsumer
+	schema       map[string]AttributeType
+	onViolation  OnViolation
+}
+
+// Option represents options that can be applied to the attribute schema
+// processor.
+type Option func(*attributeschemaprocessor) error
+
+// WithSchema returns an Option to configure the expected type of the
+// attributes, by key. The types are the string values of AttributeType.
+func WithSchema(schema map[string]string) Option {
+	return func(asp *attributeschemaprocessor) error {
+		asp.schema = make(map[string]AttributeType, len(schema))
+		for key, typ := range schema {
+			switch attrType := AttributeType(typ); attrType {
+			case TypeString, TypeInt, TypeDouble, TypeBool:
+				asp.schema[key] = attrType
+			default:
+				return fmt.Errorf("unknown type %q for attribute %q", typ, key)
+			}
+		}
+		return nil
+	}
+}
+
+// WithOnViolation returns an Option to configure what is done with the values
+// that can't be converted to the type expected for their attribute.
+func WithOnViolation(onViolation OnViolation) Option {
+	return func(asp *attributeschemaprocessor) error {
+		switch onViolation {
+		case OnViolationKeep, OnViolationDrop, OnViolationFlag:
+		default:
+			return fmt.Errorf("unknown on_violation %q", onViolation)
+		}
+		asp.onViolation = onViolation
+		return nil
+	}
+}
+
+var _ processor.TraceProcessor = (*attributeschemaprocessor)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that converts the span
+// attributes to the type declared for them. Attributes not in the schema are
+// left unchanged.
+func NewTraceProcessor(nextConsumer consumer.TraceConsumer, options ...Option) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+
+	asp := &attributeschemaprocessor{
+		nextConsumer: nextConsumer,
+		onViolation:  OnViolationKeep,
+	}
+	for _, opt := range options {
+		if err := opt(asp); err != nil {
+			return nil, err
+		}
+	}
+	return asp, nil
+}
+
+func (asp *attributeschemaprocessor) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	if len(asp.schema) == 0 {
+		return asp.nextConsumer.ConsumeTraceData(ctx, td)
+	}
+
+	counts := make(keyCounts)
+	for _, span := range td.Spans {
+		if span == nil || span.Attributes == nil {
+			continue
+		}
+		asp.enforce(span.Attributes, counts)
+	}
+
+	counts.record(ctx, td.Node)
+	return asp.nextConsumer.ConsumeTraceData(ctx, td)
+}
+
+func (asp *attributeschemaprocessor) enforce(attributes *tracepb.Span_Attributes, counts keyCounts) {
+	var violations []string
+	for key, attrType := range asp.schema {
+		value, ok := attributes.AttributeMap[key]
+		if !ok || value == nil {
+			continue
+		}
+		if hasType(value, attrType) {
+			continue
+		}
+
+		if converted, ok := convert(value, attrType); ok {
+			attributes.AttributeMap[key] = converted
+			counts.add(key, statConverted)
+			continue
+		}
+
+		counts.add(key, statViolations)
+		switch asp.onViolation {
+		case OnViolationDrop:
+			delete(attributes.AttributeMap, key)
+			attributes.DroppedAttributesCount++
+		case OnViolationFlag:
+			violations = append(violations, key)
+		}
+	}
+
+	if len(violations) > 0 {
+		sort.Strings(violations)
+		attributes.AttributeMap[ViolationsAttribute] = &tracepb.AttributeValue{
+			Value: &tracepb.AttributeValue_StringValue{
+				StringValue: &tracepb.TruncatableString{Value: strings.Join(violations, ",")},
+			},
+		}
+	}
+}
+
+func hasType(value *tracepb.AttributeValue, attrType AttributeType) bool {
+	switch value.Value.(type) {
+	case *tracepb.AttributeValue_StringValue:
+		return attrType == TypeString
+	case *tracepb.AttributeValue_IntValue:
+		return attrType == TypeInt
+	case *tracepb.AttributeValue_DoubleValue:
+		return attrType == TypeDouble
+	case *tracepb.AttributeValue_BoolValue:
+		return attrType == TypeBool
+	}
+	return false
+}
+
+// convert returns the value converted to the given type, if possible.
+func convert(value *tracepb.AttributeValue, attrType AttributeType) (*tracepb.AttributeValue, bool) {
+	switch attrType {
+	case TypeString:
+		if s, ok := toString(value); ok {
+			return &tracepb.AttributeValue{
+				Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: s}},
+			}, true
+		}
+	case TypeInt:
+		if i, ok := toInt(value); ok {
+			return &tracepb.AttributeValue{Value: &tracepb.AttributeValue_IntValue{IntValue: i}}, true
+		}
+	case TypeDouble:
+		if d, ok := toDouble(value); ok {
+			return &tracepb.AttributeValue{Value: &tracepb.AttributeValue_DoubleValue{DoubleValue: d}}, true
+		}
+	case TypeBool:
+		if b, ok := toBool(value); ok {
+			return &tracepb.AttributeValue{Value: &tracepb.AttributeValue_BoolValue{BoolValue: b}}, true
+		}
+	}
+	return nil, false
+}
+
+func toString(value *tracepb.AttributeValue) (string, bool) {
+	switch v := value.Value.(type) {
+	case *tracepb.AttributeValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10), true
+	case *tracepb.AttributeValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64), true
+	case *tracepb.AttributeValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue), true
+	}
+	return "", false
+}
+
+func toInt(value *tracepb.AttributeValue) (int64, bool) {
+	switch v := value.Value.(type) {
+	case *tracepb.AttributeValue_StringValue:
+		s := strings.TrimSpace(v.StringValue.GetValue())
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, true
+		}
+		if d, err := strconv.ParseFloat(s, 64); err == nil {
+			return doubleToInt(d)
+		}
+	case *tracepb.AttributeValue_DoubleValue:
+		return doubleToInt(v.DoubleValue)
+	}
+	return 0, false
+}
+
+// doubleToInt converts integral doubles within the int64 range.
+func doubleToInt(d float64) (int64, bool) {
+	if d != math.Trunc(d) || d < math.MinInt64 || d >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(d), true
+}
+
+func toDouble(value *tracepb.AttributeValue) (float64, bool) {
+	switch v := value.Value.(type) {
+	case *tracepb.AttributeValue_StringValue:
+		d, err := strconv.ParseFloat(strings.TrimSpace(v.StringValue.GetValue()), 64)
+		return d, err == nil
+	case *tracepb.AttributeValue_IntValue:
+		return float64(v.IntValue), true
+	}
+	return 0, false
+}
+
+func toBool(value *tracepb.AttributeValue) (bool, bool) {
+	if v, ok := value.Value.(*tracepb.AttributeValue_StringValue); ok {
+		b, err := strconv.ParseBool(strings.TrimSpace(v.StringValue.GetValue()))
+		return b, err == nil
+	}
+	return false, false
+}
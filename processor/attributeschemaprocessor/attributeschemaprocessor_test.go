@@ -0,0 +1,137 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attributeschemaprocessor
+
+import (
+	"context"
+	"testing"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+)
+
+func stringValue(s string) *tracepb.AttributeValue {
+	return &tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: s}},
+	}
+}
+
+func intValue(i int64) *tracepb.AttributeValue {
+	return &tracepb.AttributeValue{Value: &tracepb.AttributeValue_IntValue{IntValue: i}}
+}
+
+func doubleValue(d float64) *tracepb.AttributeValue {
+	return &tracepb.AttributeValue{Value: &tracepb.AttributeValue_DoubleValue{DoubleValue: d}}
+}
+
+func boolValue(b bool) *tracepb.AttributeValue {
+	return &tracepb.AttributeValue{Value: &tracepb.AttributeValue_BoolValue{BoolValue: b}}
+}
+
+func TestNewTraceProcessor(t *testing.T) {
+	_, err := NewTraceProcessor(nil)
+	assert.Error(t, err)
+
+	sink := &exportertest.SinkTraceExporter{}
+	_, err = NewTraceProcessor(sink, WithSchema(map[string]string{"key": "float"}))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithOnViolation("ignore"))
+	assert.Error(t, err)
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    *tracepb.AttributeValue
+		attrType AttributeType
+		want     *tracepb.AttributeValue
+	}{
+		{name: "string_to_int", value: stringValue("200"), attrType: TypeInt, want: intValue(200)},
+		{name: "padded_string_to_int", value: stringValue(" -3 "), attrType: TypeInt, want: intValue(-3)},
+		{name: "integral_string_to_int", value: stringValue("1e3"), attrType: TypeInt, want: intValue(1000)},
+		{name: "fractional_string_to_int", value: stringValue("1.5"), attrType: TypeInt},
+		{name: "text_to_int", value: stringValue("OK"), attrType: TypeInt},
+		{name: "integral_double_to_int", value: doubleValue(42), attrType: TypeInt, want: intValue(42)},
+		{name: "huge_double_to_int", value: doubleValue(1e300), attrType: TypeInt},
+		{name: "bool_to_int", value: boolValue(true), attrType: TypeInt},
+		{name: "string_to_double", value: stringValue("0.25"), attrType: TypeDouble, want: doubleValue(0.25)},
+		{name: "int_to_double", value: intValue(3), attrType: TypeDouble, want: doubleValue(3)},
+		{name: "string_to_bool", value: stringValue("true"), attrType: TypeBool, want: boolValue(true)},
+		{name: "int_to_bool", value: intValue(1), attrType: TypeBool},
+		{name: "int_to_string", value: intValue(200), attrType: TypeString, want: stringValue("200")},
+		{name: "double_to_string", value: doubleValue(0.5), attrType: TypeString, want: stringValue("0.5")},
+		{name: "bool_to_string", value: boolValue(false), attrType: TypeString, want: stringValue("false")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := convert(tt.value, tt.attrType)
+			assert.Equal(t, tt.want != nil, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEnforceSchema(t *testing.T) {
+	schema := map[string]string{
+		"http.status_code": "int",
+		"error":            "bool",
+		"http.url":         "string",
+	}
+	newSpan := func() *tracepb.Span {
+		return &tracepb.Span{
+			Attributes: &tracepb.Span_Attributes{
+				AttributeMap: map[string]*tracepb.AttributeValue{
+					"http.status_code": stringValue("200"),
+					"error":            stringValue("maybe"),
+					"http.url":         stringValue("/api"),
+					"other":            stringValue("1"),
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		onViolation OnViolation
+		wantError   *tracepb.AttributeValue
+		wantFlag    *tracepb.AttributeValue
+		wantDropped int32
+	}{
+		{onViolation: OnViolationKeep, wantError: stringValue("maybe")},
+		{onViolation: OnViolationDrop, wantDropped: 1},
+		{onViolation: OnViolationFlag, wantError: stringValue("maybe"), wantFlag: stringValue("error")},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.onViolation), func(t *testing.T) {
+			sink := &exportertest.SinkTraceExporter{}
+			tp, err := NewTraceProcessor(sink, WithSchema(schema), WithOnViolation(tt.onViolation))
+			require.NoError(t, err)
+
+			td := data.TraceData{Spans: []*tracepb.Span{newSpan(), {}, nil}}
+			require.NoError(t, tp.ConsumeTraceData(context.Background(), td))
+
+			got := sink.AllTraces()[0].Spans[0].Attributes
+			assert.Equal(t, intValue(200), got.AttributeMap["http.status_code"])
+			assert.Equal(t, stringValue("/api"), got.AttributeMap["http.url"])
+			assert.Equal(t, stringValue("1"), got.AttributeMap["other"])
+			assert.Equal(t, tt.wantError, got.AttributeMap["error"])
+			assert.Equal(t, tt.wantFlag, got.AttributeMap[ViolationsAttribute])
+			assert.Equal(t, tt.wantDropped, got.DroppedAttributesCount)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attributeschemaprocessor
+
+import (
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+)
+
+// ConfigV2 defines configuration for the attribute schema processor.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// Attributes maps span attribute keys to their expected type, one of
+	// "string", "int", "double" or "bool".
+	Attributes map[string]string `mapstructure:"attributes"`
+	// OnViolation is what is done with the values that can't be converted to
+	// their expected type, one of "keep", "drop" or "flag".
+	OnViolation string `mapstructure:"on_violation"`
+}
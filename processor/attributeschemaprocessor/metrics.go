@@ -0,0 +1,95 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attributeschemaprocessor
+
+import (
+	"context"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/census-instrumentation/opencensus-service/internal/collector/processor"
+	"github.com/census-instrumentation/opencensus-service/internal/collector/telemetry"
+)
+
+// Variables related to metrics specific to the attribute schema processor.
+var (
+	tagAttributeKey, _ = tag.NewKey("attribute")
+
+	statConverted  = stats.Int64("attributeschema_values_converted", "Count of attribute values converted to the type declared in the schema", stats.UnitDimensionless)
+	statViolations = stats.Int64("attributeschema_violations", "Count of attribute values that could not be converted to the type declared in the schema", stats.UnitDimensionless)
+)
+
+// keyCounts accumulates, per attribute key, the counts of a batch.
+type keyCounts map[string]map[*stats.Int64Measure]int64
+
+func (kc keyCounts) add(key string, measure *stats.Int64Measure) {
+	counts, ok := kc[key]
+	if !ok {
+		counts = make(map[*stats.Int64Measure]int64, 2)
+		kc[key] = counts
+	}
+	counts[measure]++
+}
+
+func (kc keyCounts) record(ctx context.Context, node *commonpb.Node) {
+	if len(kc) == 0 {
+		return
+	}
+	serviceName := processor.ServiceNameForNode(node)
+	for key, counts := range kc {
+		measurements := make([]stats.Measurement, 0, len(counts))
+		for measure, count := range counts {
+			measurements = append(measurements, measure.M(count))
+		}
+		_ = stats.RecordWithTags(
+			ctx,
+			[]tag.Mutator{
+				tag.Upsert(tagAttributeKey, key),
+				tag.Upsert(processor.TagServiceNameKey, serviceName),
+			},
+			measurements...)
+	}
+}
+
+// MetricViews return the metrics views according to given telemetry level.
+func MetricViews(level telemetry.Level) []*view.View {
+	if level == telemetry.None {
+		return nil
+	}
+
+	tagKeys := []tag.Key{tagAttributeKey}
+	if level == telemetry.Detailed {
+		tagKeys = append(tagKeys, processor.TagServiceNameKey)
+	}
+
+	convertedView := &view.View{
+		Name:        statConverted.Name(),
+		Measure:     statConverted,
+		Description: statConverted.Description(),
+		TagKeys:     tagKeys,
+		Aggregation: view.Sum(),
+	}
+	violationsView := &view.View{
+		Name:        statViolations.Name(),
+		Measure:     statViolations,
+		Description: statViolations.Description(),
+		TagKeys:     tagKeys,
+		Aggregation: view.Sum(),
+	}
+	return []*view.View{convertedView, violationsView}
+}
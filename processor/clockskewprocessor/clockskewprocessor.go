@@ -0,0 +1,194 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package clockskewprocessor contains a trace processor that corrects the
+// timestamps of server spans recorded on hosts whose clocks are skewed in
+// relation to the hosts of their client spans.
+//
+// The processor needs all the spans of a trace to be passed in a single call,
+// so it should be placed after a groupbytrace processor configured with
+// single_batch.
+package clockskewprocessor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/golang/protobuf/ptypes/timestamp"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/internal"
+	"github.com/census-instrumentation/opencensus-service/processor"
+	"github.com/census-instrumentation/opencensus-service/processor/internal/traces"
+)
+
+// AdjustmentAttribute is the key of the attribute that records, in
+// nanoseconds, the adjustment applied to a server span whose clock was found
+// to be skewed. The spans recorded by the same host below it are shifted by
+// the same amount but do not get the attribute.
+const AdjustmentAttribute = "clock_skew_adjustment_ns"
+
+type clockskewprocessor struct {
+	nextConsumer consumer.TraceConsumer
+}
+
+var _ processor.TraceProcessor = (*clockskewprocessor)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that shifts the spans
+// of each trace that, because of clock skew, are not contained by their
+// parents.
+func NewTraceProcessor(nextConsumer consumer.TraceConsumer) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+	return &clockskewprocessor{nextConsumer: nextConsumer}, nil
+}
+
+func (csp *clockskewprocessor) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	for _, spans := range traces.GroupByTraceID(td.Spans) {
+		newSpanTree(spans).adjust()
+	}
+	return csp.nextConsumer.ConsumeTraceData(ctx, td)
+}
+
+// spanTree indexes the spans of a trace by their parents.
+type spanTree struct {
+	roots    []*tracepb.Span
+	children map[string][]*tracepb.Span
+}
+
+func newSpanTree(spans []*tracepb.Span) *spanTree {
+	ids := make(map[string]bool, len(spans))
+	for _, span := range spans {
+		ids[string(span.SpanId)] = true
+	}
+
+	tree := &spanTree{children: make(map[string][]*tracepb.Span)}
+	for _, span := range spans {
+		parentKey := string(span.ParentSpanId)
+		if len(span.ParentSpanId) == 0 || !ids[parentKey] {
+			tree.roots = append(tree.roots, span)
+			continue
+		}
+		tree.children[parentKey] = append(tree.children[parentKey], span)
+	}
+	return tree
+}
+
+// adjust walks the tree from its roots. Spans recorded by the same host as
+// their parents are shifted as much as their parents were, while server spans
+// of client parents, recorded by a different host, get their own adjustment.
+func (tree *spanTree) adjust() {
+	visited := make(map[*tracepb.Span]bool)
+	for _, root := range tree.roots {
+		tree.adjustChildren(root, 0, visited)
+	}
+}
+
+func (tree *spanTree) adjustChildren(parent *tracepb.Span, offset time.Duration, visited map[*tracepb.Span]bool) {
+	if visited[parent] {
+		return
+	}
+	visited[parent] = true
+
+	for _, child := range tree.children[string(parent.SpanId)] {
+		if visited[child] {
+			continue
+		}
+		childOffset := offset
+		if isRemoteCall(parent, child) {
+			childOffset = calculateSkew(parent, child)
+			if childOffset != 0 {
+				recordAdjustment(child, childOffset)
+			}
+		}
+		shiftSpan(child, childOffset)
+		tree.adjustChildren(child, childOffset, visited)
+	}
+}
+
+// isRemoteCall returns true if parent and child describe the same request on
+// both ends of the network. As in Jaeger, only those pairs are adjusted, since
+// for other spans a skew can not be told apart from a regular delay.
+func isRemoteCall(parent, child *tracepb.Span) bool {
+	return parent.Kind == tracepb.Span_CLIENT && child.Kind == tracepb.Span_SERVER
+}
+
+// calculateSkew returns how much child has to be shifted to fit in the already
+// adjusted parent. The child is centred in the parent, assuming the network
+// latency to be the same in both directions.
+func calculateSkew(parent, child *tracepb.Span) time.Duration {
+	if parent.StartTime == nil || parent.EndTime == nil || child.StartTime == nil || child.EndTime == nil {
+		return 0
+	}
+
+	parentStart, parentEnd := toTime(parent.StartTime), toTime(parent.EndTime)
+	childStart, childEnd := toTime(child.StartTime), toTime(child.EndTime)
+	if !childStart.Before(parentStart) && !childEnd.After(parentEnd) {
+		return 0
+	}
+
+	latency := (parentEnd.Sub(parentStart) - childEnd.Sub(childStart)) / 2
+	if latency < 0 {
+		// The child is longer than the parent, the best that can be done is
+		// to make sure it does not start before it.
+		if childStart.Before(parentStart) {
+			return parentStart.Sub(childStart)
+		}
+		return 0
+	}
+	return parentStart.Add(latency).Sub(childStart)
+}
+
+func recordAdjustment(span *tracepb.Span, skew time.Duration) {
+	if span.Attributes == nil {
+		span.Attributes = &tracepb.Span_Attributes{}
+	}
+	if span.Attributes.AttributeMap == nil {
+		span.Attributes.AttributeMap = make(map[string]*tracepb.AttributeValue)
+	}
+	span.Attributes.AttributeMap[AdjustmentAttribute] = &tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_IntValue{IntValue: int64(skew)},
+	}
+}
+
+func shiftSpan(span *tracepb.Span, d time.Duration) {
+	if d == 0 {
+		return
+	}
+	span.StartTime = shift(span.StartTime, d)
+	span.EndTime = shift(span.EndTime, d)
+	for _, timeEvent := range span.GetTimeEvents().GetTimeEvent() {
+		if timeEvent == nil {
+			continue
+		}
+		timeEvent.Time = shift(timeEvent.Time, d)
+	}
+}
+
+// shift returns a new timestamp d after ts, so timestamps shared with other
+// spans are not modified.
+func shift(ts *timestamp.Timestamp, d time.Duration) *timestamp.Timestamp {
+	if ts == nil {
+		return nil
+	}
+	return internal.TimeToTimestamp(toTime(ts).Add(d))
+}
+
+func toTime(ts *timestamp.Timestamp) time.Time {
+	return time.Unix(ts.Seconds, int64(ts.Nanos))
+}
@@ -0,0 +1,206 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clockskewprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+	"github.com/census-instrumentation/opencensus-service/internal"
+)
+
+var baseTime = time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newSpan(id, parentID byte, kind tracepb.Span_SpanKind, start, end time.Duration) *tracepb.Span {
+	span := &tracepb.Span{
+		TraceId:   []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanId:    []byte{0, 0, 0, 0, 0, 0, 0, id},
+		Kind:      kind,
+		StartTime: internal.TimeToTimestamp(baseTime.Add(start)),
+		EndTime:   internal.TimeToTimestamp(baseTime.Add(end)),
+	}
+	if parentID != 0 {
+		span.ParentSpanId = []byte{0, 0, 0, 0, 0, 0, 0, parentID}
+	}
+	return span
+}
+
+func offsets(span *tracepb.Span) (time.Duration, time.Duration) {
+	return toTime(span.StartTime).Sub(baseTime), toTime(span.EndTime).Sub(baseTime)
+}
+
+func adjustment(span *tracepb.Span) (int64, bool) {
+	value, ok := span.GetAttributes().GetAttributeMap()[AdjustmentAttribute]
+	return value.GetIntValue(), ok
+}
+
+func TestNewTraceProcessor(t *testing.T) {
+	_, err := NewTraceProcessor(nil)
+	assert.Error(t, err)
+}
+
+func TestConsumeTraceData(t *testing.T) {
+	tests := []struct {
+		name       string
+		client     *tracepb.Span
+		server     *tracepb.Span
+		wantStart  time.Duration
+		wantEnd    time.Duration
+		wantAdjust time.Duration
+	}{
+		{
+			name:      "contained",
+			client:    newSpan(1, 0, tracepb.Span_CLIENT, 0, 100*time.Millisecond),
+			server:    newSpan(2, 1, tracepb.Span_SERVER, 10*time.Millisecond, 90*time.Millisecond),
+			wantStart: 10 * time.Millisecond,
+			wantEnd:   90 * time.Millisecond,
+		},
+		{
+			name:       "starts_before_parent",
+			client:     newSpan(1, 0, tracepb.Span_CLIENT, 0, 100*time.Millisecond),
+			server:     newSpan(2, 1, tracepb.Span_SERVER, -1*time.Second, -1*time.Second+60*time.Millisecond),
+			wantStart:  20 * time.Millisecond,
+			wantEnd:    80 * time.Millisecond,
+			wantAdjust: time.Second + 20*time.Millisecond,
+		},
+		{
+			name:       "ends_after_parent",
+			client:     newSpan(1, 0, tracepb.Span_CLIENT, 0, 100*time.Millisecond),
+			server:     newSpan(2, 1, tracepb.Span_SERVER, 80*time.Millisecond, 140*time.Millisecond),
+			wantStart:  20 * time.Millisecond,
+			wantEnd:    80 * time.Millisecond,
+			wantAdjust: -60 * time.Millisecond,
+		},
+		{
+			name:       "longer_than_parent",
+			client:     newSpan(1, 0, tracepb.Span_CLIENT, 0, 100*time.Millisecond),
+			server:     newSpan(2, 1, tracepb.Span_SERVER, -50*time.Millisecond, 150*time.Millisecond),
+			wantStart:  0,
+			wantEnd:    200 * time.Millisecond,
+			wantAdjust: 50 * time.Millisecond,
+		},
+		{
+			name:      "longer_than_parent_starting_after_it",
+			client:    newSpan(1, 0, tracepb.Span_CLIENT, 0, 100*time.Millisecond),
+			server:    newSpan(2, 1, tracepb.Span_SERVER, 50*time.Millisecond, 250*time.Millisecond),
+			wantStart: 50 * time.Millisecond,
+			wantEnd:   250 * time.Millisecond,
+		},
+		{
+			name:      "not_client_server_pair",
+			client:    newSpan(1, 0, tracepb.Span_SERVER, 0, 100*time.Millisecond),
+			server:    newSpan(2, 1, tracepb.Span_SPAN_KIND_UNSPECIFIED, -1*time.Second, -900*time.Millisecond),
+			wantStart: -1 * time.Second,
+			wantEnd:   -900 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &exportertest.SinkTraceExporter{}
+			csp, err := NewTraceProcessor(sink)
+			require.NoError(t, err)
+
+			td := data.TraceData{Spans: []*tracepb.Span{tt.server, tt.client}}
+			require.NoError(t, csp.ConsumeTraceData(context.Background(), td))
+			require.Equal(t, []data.TraceData{td}, sink.AllTraces())
+
+			start, end := offsets(tt.server)
+			assert.Equal(t, tt.wantStart, start)
+			assert.Equal(t, tt.wantEnd, end)
+
+			adjust, ok := adjustment(tt.server)
+			assert.Equal(t, tt.wantAdjust != 0, ok)
+			assert.Equal(t, int64(tt.wantAdjust), adjust)
+
+			_, ok = adjustment(tt.client)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestConsumeTraceData_ShiftsSubtree(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	csp, err := NewTraceProcessor(sink)
+	require.NoError(t, err)
+
+	root := newSpan(1, 0, tracepb.Span_SERVER, 0, time.Second)
+	client := newSpan(2, 1, tracepb.Span_CLIENT, 100*time.Millisecond, 900*time.Millisecond)
+	// The remote host is 10s behind.
+	server := newSpan(3, 2, tracepb.Span_SERVER, -10*time.Second+200*time.Millisecond, -10*time.Second+800*time.Millisecond)
+	server.TimeEvents = &tracepb.Span_TimeEvents{
+		TimeEvent: []*tracepb.Span_TimeEvent{
+			{Time: internal.TimeToTimestamp(baseTime.Add(-10*time.Second + 300*time.Millisecond))},
+			nil,
+		},
+	}
+	internalSpan := newSpan(4, 3, tracepb.Span_SPAN_KIND_UNSPECIFIED, -10*time.Second+300*time.Millisecond, -10*time.Second+400*time.Millisecond)
+	// A second hop to a host 10s ahead of the first one, i.e. in sync with the
+	// local one, must not be shifted.
+	nestedClient := newSpan(5, 3, tracepb.Span_CLIENT, -10*time.Second+500*time.Millisecond, -10*time.Second+700*time.Millisecond)
+	nestedServer := newSpan(6, 5, tracepb.Span_SERVER, 550*time.Millisecond, 650*time.Millisecond)
+
+	td := data.TraceData{Spans: []*tracepb.Span{nestedServer, internalSpan, server, root, nestedClient, client}}
+	require.NoError(t, csp.ConsumeTraceData(context.Background(), td))
+
+	start, end := offsets(server)
+	assert.Equal(t, 200*time.Millisecond, start)
+	assert.Equal(t, 800*time.Millisecond, end)
+	assert.Equal(t, 300*time.Millisecond, toTime(server.TimeEvents.TimeEvent[0].Time).Sub(baseTime))
+	adjust, ok := adjustment(server)
+	assert.True(t, ok)
+	assert.Equal(t, int64(10*time.Second), adjust)
+
+	start, end = offsets(internalSpan)
+	assert.Equal(t, 300*time.Millisecond, start)
+	assert.Equal(t, 400*time.Millisecond, end)
+	_, ok = adjustment(internalSpan)
+	assert.False(t, ok)
+
+	start, end = offsets(nestedClient)
+	assert.Equal(t, 500*time.Millisecond, start)
+	assert.Equal(t, 700*time.Millisecond, end)
+
+	start, end = offsets(nestedServer)
+	assert.Equal(t, 550*time.Millisecond, start)
+	assert.Equal(t, 650*time.Millisecond, end)
+	_, ok = adjustment(nestedServer)
+	assert.False(t, ok)
+
+	start, end = offsets(root)
+	assert.Equal(t, time.Duration(0), start)
+	assert.Equal(t, time.Second, end)
+}
+
+func TestConsumeTraceData_Cycle(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	csp, err := NewTraceProcessor(sink)
+	require.NoError(t, err)
+
+	a := newSpan(1, 2, tracepb.Span_CLIENT, 0, 100*time.Millisecond)
+	b := newSpan(2, 1, tracepb.Span_SERVER, -time.Second, -900*time.Millisecond)
+	td := data.TraceData{Spans: []*tracepb.Span{a, b, nil}}
+	require.NoError(t, csp.ConsumeTraceData(context.Background(), td))
+
+	start, _ := offsets(b)
+	assert.Equal(t, -time.Second, start)
+	assert.Len(t, sink.AllTraces(), 1)
+}
@@ -0,0 +1,29 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package criticalpathprocessor
+
+import "github.com/census-instrumentation/opencensus-service/internal/configmodels"
+
+// ConfigV2 defines configuration for the critical path processor.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// Metrics enables recording the critical path contribution of the spans
+	// per service and operation.
+	Metrics bool `mapstructure:"metrics"`
+	// ServiceAttribute is the key of the span attribute holding the service
+	// of the span, used for the metrics. The service of the Node is used if
+	// empty or absent from the span.
+	ServiceAttribute string `mapstructure:"service_attribute"`
+}
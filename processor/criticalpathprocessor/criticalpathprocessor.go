@@ -0,0 +1,304 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package criticalpathprocessor contains a trace processor that computes the
+// critical path of each trace, i.e. the chain of work that determined the
+// duration of its root span, and adds the self-time and critical path
+// contribution of each span to its attributes.
+//
+// Each batch must hold entire traces, as set up in processor/internal/traces.
+// Traces without a root span are left untouched, and so are the spans
+// unreachable from the root, e.g. whose parent was lost.
+package criticalpathprocessor
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	processormetrics "github.com/census-instrumentation/opencensus-service/internal/collector/processor"
+	"github.com/census-instrumentation/opencensus-service/processor"
+	"github.com/census-instrumentation/opencensus-service/processor/internal/traces"
+)
+
+// Span attributes added by the processor.
+const (
+	// AttributeCriticalPath is set to true on the spans on the critical path.
+	AttributeCriticalPath = "critical_path"
+	// AttributeContribution is the time, in nanoseconds, the critical path
+	// spent in the span itself rather than in its children.
+	AttributeContribution = "critical_path.contribution_ns"
+	// AttributeSelfTime is the time, in nanoseconds, during which the span
+	// had none of its children running.
+	AttributeSelfTime = "self_time_ns"
+)
+
+// DefaultServiceAttribute is the span attribute the service of a span is read
+// from by default for the metrics.
+const DefaultServiceAttribute = traces.DefaultServiceAttribute
+
+type criticalpathprocessor struct {
+	nextConsumer     consumer.TraceConsumer
+	metrics          bool
+	serviceAttribute string
+}
+
+// Option represents options that can be applied to the critical path
+// processor.
+type Option func(*criticalpathprocessor) error
+
+// WithMetrics returns an Option to record the critical path contribution of
+// the spans per service and operation. The number of distinct span names
+// must be bounded for the metrics to be.
+func WithMetrics(metrics bool) Option {
+	return func(cpp *criticalpathprocessor) error {
+		cpp.metrics = metrics
+		return nil
+	}
+}
+
+// WithServiceAttribute returns an Option to read the service of the spans,
+// for the metrics, from the span attribute with the given key, falling back
+// to the service of the Node when absent.
+func WithServiceAttribute(key string) Option {
+	return func(cpp *criticalpathprocessor) error {
+		cpp.serviceAttribute = key
+		return nil
+	}
+}
+
+var _ processor.TraceProcessor = (*criticalpathprocessor)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that computes the
+// critical path of the traces of each batch, which must hold entire traces.
+func NewTraceProcessor(nextConsumer consumer.TraceConsumer, options ...Option) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+
+	cpp := &criticalpathprocessor{
+		nextConsumer:     nextConsumer,
+		serviceAttribute: DefaultServiceAttribute,
+	}
+	for _, opt := range options {
+		if err := opt(cpp); err != nil {
+			return nil, err
+		}
+	}
+	return cpp, nil
+}
+
+// node is a span of the tree of a trace, with its interval clipped to the
+// one of its parent: the work of a child that outlives its parent, e.g. a
+// fire-and-forget call, can't be on the critical path of the parent.
+type node struct {
+	span     *tracepb.Span
+	start    time.Time
+	end      time.Time
+	children []*node
+
+	selfTime     time.Duration
+	contribution time.Duration
+	critical     bool
+}
+
+func (cpp *criticalpathprocessor) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	var nodeService string
+	if cpp.metrics {
+		nodeService = processormetrics.ServiceNameForNode(td.Node)
+	}
+	for _, spans := range traces.GroupByTraceID(td.Spans) {
+		nodes := buildTree(spans)
+		for _, n := range nodes {
+			n.addAttributes()
+			if cpp.metrics && n.critical {
+				recordContribution(ctx, traces.SpanService(n.span, cpp.serviceAttribute, nodeService), n.span.GetName().GetValue(), n.contribution)
+			}
+		}
+	}
+
+	return cpp.nextConsumer.ConsumeTraceData(ctx, td)
+}
+
+// spanKey identifies a span of a trace. Both halves of a Zipkin shared span
+// have the same ID, so the kind is needed to tell them apart.
+type spanKey struct {
+	id   string
+	kind tracepb.Span_SpanKind
+}
+
+// parentKinds is the order the kinds of the spans with the parent ID of a
+// span are looked up in. The children of a shared span are created under its
+// server half.
+var parentKinds = []tracepb.Span_SpanKind{
+	tracepb.Span_SERVER,
+	tracepb.Span_CLIENT,
+	tracepb.Span_SPAN_KIND_UNSPECIFIED,
+}
+
+// buildTree returns the nodes of the tree rooted at the root span of the
+// trace, with their self-time and critical path contribution computed, or
+// nil if the trace has no usable root span.
+func buildTree(spans []*tracepb.Span) []*node {
+	var root *node
+	byKey := make(map[spanKey]*node, len(spans))
+	for _, span := range spans {
+		start, err := ptypes.Timestamp(span.StartTime)
+		if err != nil {
+			continue
+		}
+		end, err := ptypes.Timestamp(span.EndTime)
+		if err != nil || end.Before(start) {
+			continue
+		}
+		n := &node{span: span, start: start, end: end}
+		byKey[spanKey{id: string(span.SpanId), kind: span.Kind}] = n
+		if len(span.ParentSpanId) == 0 && root == nil {
+			root = n
+		}
+	}
+	if root == nil {
+		return nil
+	}
+	for _, n := range byKey {
+		if n == root {
+			continue
+		}
+		// The server half of a shared span is a child of its client half.
+		var parent *node
+		if n.span.Kind == tracepb.Span_SERVER {
+			parent = byKey[spanKey{id: string(n.span.SpanId), kind: tracepb.Span_CLIENT}]
+		}
+		if parent == nil && len(n.span.ParentSpanId) != 0 {
+			for _, kind := range parentKinds {
+				if parent = byKey[spanKey{id: string(n.span.ParentSpanId), kind: kind}]; parent != nil {
+					break
+				}
+			}
+		}
+		if parent != nil && parent != n {
+			parent.children = append(parent.children, n)
+		}
+	}
+
+	// Walk the tree from the root, which also guards against cycles.
+	var nodes []*node
+	visited := make(map[*node]bool, len(byKey))
+	var clip func(n *node)
+	clip = func(n *node) {
+		visited[n] = true
+		nodes = append(nodes, n)
+		children := n.children[:0]
+		for _, child := range n.children {
+			if visited[child] {
+				continue
+			}
+			if child.start.Before(n.start) {
+				child.start = n.start
+			}
+			if child.end.After(n.end) {
+				child.end = n.end
+			}
+			if child.end.Before(child.start) {
+				child.end = child.start
+			}
+			children = append(children, child)
+			clip(child)
+		}
+		n.children = children
+		n.selfTime = selfTime(n)
+	}
+	clip(root)
+
+	criticalPath(root, root.end)
+	return nodes
+}
+
+// selfTime returns the duration of the span not covered by any child.
+func selfTime(n *node) time.Duration {
+	children := append([]*node(nil), n.children...)
+	sort.Slice(children, func(i, j int) bool { return children[i].start.Before(children[j].start) })
+
+	covered := time.Duration(0)
+	var cursor time.Time
+	for _, child := range children {
+		start, end := child.start, child.end
+		if start.Before(cursor) {
+			start = cursor
+		}
+		if end.After(start) {
+			covered += end.Sub(start)
+			cursor = end
+		}
+	}
+	return n.end.Sub(n.start) - covered
+}
+
+// criticalPath marks n and its children on the critical path ending at end.
+// Walking back from end, the path goes through the last child to finish
+// before the current point, since the span was waiting on it, and through
+// the span itself in the gaps between such children. Children running
+// concurrently with the one on the path didn't delay the span.
+func criticalPath(n *node, end time.Time) {
+	n.critical = true
+	children := append([]*node(nil), n.children...)
+	sort.Slice(children, func(i, j int) bool { return children[i].end.After(children[j].end) })
+
+	cursor := end
+	for _, child := range children {
+		if child.end.After(cursor) || child.end.Equal(child.start) {
+			continue
+		}
+		if !cursor.After(n.start) {
+			break
+		}
+		n.contribution += cursor.Sub(child.end)
+		criticalPath(child, child.end)
+		cursor = child.start
+	}
+	if cursor.After(n.start) {
+		n.contribution += cursor.Sub(n.start)
+	}
+}
+
+// addAttributes adds the self-time and, for the spans on the critical path,
+// the contribution to the span attributes.
+func (n *node) addAttributes() {
+	span := n.span
+	if span.Attributes == nil {
+		span.Attributes = &tracepb.Span_Attributes{}
+	}
+	if span.Attributes.AttributeMap == nil {
+		span.Attributes.AttributeMap = make(map[string]*tracepb.AttributeValue)
+	}
+	attrs := span.Attributes.AttributeMap
+	attrs[AttributeSelfTime] = &tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_IntValue{IntValue: int64(n.selfTime)},
+	}
+	if !n.critical {
+		return
+	}
+	attrs[AttributeCriticalPath] = &tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_BoolValue{BoolValue: true},
+	}
+	attrs[AttributeContribution] = &tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_IntValue{IntValue: int64(n.contribution)},
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package criticalpathprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+	"github.com/census-instrumentation/opencensus-service/internal/collector/telemetry"
+)
+
+var base = time.Date(2019, 7, 1, 12, 0, 0, 0, time.UTC)
+
+// newSpan returns a span of trace 1 running between the given milliseconds.
+func newSpan(name string, spanID, parentID byte, start, end int) *tracepb.Span {
+	span := &tracepb.Span{
+		TraceId: []byte{1},
+		SpanId:  []byte{spanID},
+		Name:    &tracepb.TruncatableString{Value: name},
+	}
+	if parentID != 0 {
+		span.ParentSpanId = []byte{parentID}
+	}
+	span.StartTime, _ = ptypes.TimestampProto(base.Add(time.Duration(start) * time.Millisecond))
+	span.EndTime, _ = ptypes.TimestampProto(base.Add(time.Duration(end) * time.Millisecond))
+	return span
+}
+
+func intAttribute(span *tracepb.Span, key string) (time.Duration, bool) {
+	value, ok := span.GetAttributes().GetAttributeMap()[key]
+	return time.Duration(value.GetIntValue()), ok
+}
+
+func TestNewTraceProcessor(t *testing.T) {
+	_, err := NewTraceProcessor(nil)
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(&exportertest.SinkTraceExporter{}, WithMetrics(true), WithServiceAttribute(""))
+	assert.NoError(t, err)
+}
+
+func TestCriticalPath(t *testing.T) {
+	views := MetricViews(telemetry.Normal)
+	require.NoError(t, view.Register(views...))
+	defer view.Unregister(views...)
+
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(sink, WithMetrics(true))
+	require.NoError(t, err)
+
+	var (
+		a = newSpan("a", 1, 0, 0, 100)
+		// b runs concurrently with c, which finishes later.
+		b = newSpan("b", 2, 1, 10, 40)
+		c = newSpan("c", 3, 1, 20, 70)
+		f = newSpan("f", 6, 3, 30, 60)
+		// d runs concurrently with e, which outlives a.
+		d = newSpan("d", 4, 1, 75, 90)
+		e = newSpan("e", 5, 1, 80, 120)
+		// g lost its parent.
+		g = newSpan("g", 7, 9, 0, 10)
+	)
+	td := data.TraceData{Spans: []*tracepb.Span{e, d, c, b, a, f, g, nil}}
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), td))
+	require.Len(t, sink.AllTraces(), 1)
+
+	ms := time.Millisecond
+	tests := []struct {
+		span         *tracepb.Span
+		critical     bool
+		contribution time.Duration
+		selfTime     time.Duration
+	}{
+		{a, true, 30 * ms, 15 * ms},
+		{b, false, 0, 30 * ms},
+		{c, true, 20 * ms, 20 * ms},
+		{d, false, 0, 15 * ms},
+		{e, true, 20 * ms, 20 * ms},
+		{f, true, 30 * ms, 30 * ms},
+	}
+	for _, tt := range tests {
+		name := tt.span.Name.Value
+		selfTime, ok := intAttribute(tt.span, AttributeSelfTime)
+		assert.True(t, ok, name)
+		assert.Equal(t, tt.selfTime, selfTime, name)
+		contribution, ok := intAttribute(tt.span, AttributeContribution)
+		assert.Equal(t, tt.critical, ok, name)
+		assert.Equal(t, tt.contribution, contribution, name)
+		_, ok = tt.span.Attributes.AttributeMap[AttributeCriticalPath]
+		assert.Equal(t, tt.critical, ok, name)
+	}
+	assert.Nil(t, g.Attributes)
+
+	rows, err := view.RetrieveData(statContribution.Name())
+	require.NoError(t, err)
+	means := make(map[string]float64)
+	for _, row := range rows {
+		means[row.Tags[0].Value] = row.Data.(*view.DistributionData).Mean
+	}
+	assert.Equal(t, map[string]float64{"a": 30, "c": 20, "e": 20, "f": 30}, means)
+}
+
+func TestCriticalPathSharedSpan(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(sink)
+	require.NoError(t, err)
+
+	// The client and server halves of a shared span have the same ID, d was
+	// created under the server half.
+	var (
+		a = newSpan("a", 1, 0, 0, 100)
+		c = newSpan("c", 2, 1, 10, 90)
+		s = newSpan("s", 2, 1, 20, 80)
+		d = newSpan("d", 3, 2, 30, 60)
+	)
+	c.Kind = tracepb.Span_CLIENT
+	s.Kind = tracepb.Span_SERVER
+	td := data.TraceData{Spans: []*tracepb.Span{a, c, s, d}}
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), td))
+
+	ms := time.Millisecond
+	tests := []struct {
+		span         *tracepb.Span
+		contribution time.Duration
+		selfTime     time.Duration
+	}{
+		{a, 20 * ms, 20 * ms},
+		{c, 20 * ms, 20 * ms},
+		{s, 30 * ms, 30 * ms},
+		{d, 30 * ms, 30 * ms},
+	}
+	for _, tt := range tests {
+		name := tt.span.Name.Value
+		selfTime, ok := intAttribute(tt.span, AttributeSelfTime)
+		assert.True(t, ok, name)
+		assert.Equal(t, tt.selfTime, selfTime, name)
+		contribution, ok := intAttribute(tt.span, AttributeContribution)
+		assert.True(t, ok, name)
+		assert.Equal(t, tt.contribution, contribution, name)
+	}
+}
+
+func TestCriticalPathWithoutRoot(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(sink)
+	require.NoError(t, err)
+
+	span := newSpan("b", 2, 1, 10, 40)
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), data.TraceData{Spans: []*tracepb.Span{span}}))
+	assert.Nil(t, span.Attributes)
+}
@@ -0,0 +1,61 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package criticalpathprocessor
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/census-instrumentation/opencensus-service/internal/collector/processor"
+	"github.com/census-instrumentation/opencensus-service/internal/collector/telemetry"
+)
+
+// Variables related to metrics specific to the critical path processor.
+var (
+	tagOperationKey, _ = tag.NewKey("operation")
+
+	statContribution = stats.Float64("criticalpath_contribution", "Time the critical path of the traces spent in the spans themselves", stats.UnitMilliseconds)
+)
+
+func recordContribution(ctx context.Context, service, operation string, contribution time.Duration) {
+	_ = stats.RecordWithTags(
+		ctx,
+		[]tag.Mutator{
+			tag.Upsert(processor.TagServiceNameKey, service),
+			tag.Upsert(tagOperationKey, operation),
+		},
+		statContribution.M(float64(contribution)/float64(time.Millisecond)))
+}
+
+// MetricViews return the metrics views according to given telemetry level.
+// Nothing is recorded unless the metrics are enabled on the processor.
+func MetricViews(level telemetry.Level) []*view.View {
+	if level == telemetry.None {
+		return nil
+	}
+
+	contributionView := &view.View{
+		Name:        statContribution.Name(),
+		Measure:     statContribution,
+		Description: statContribution.Description(),
+		TagKeys:     []tag.Key{processor.TagServiceNameKey, tagOperationKey},
+		Aggregation: view.Distribution(1, 2, 5, 10, 25, 50, 75, 100, 150, 200, 300, 400, 500, 750, 1000, 2000, 3000, 4000, 5000, 10000, 20000, 30000, 50000),
+	}
+	return []*view.View{contributionView}
+}
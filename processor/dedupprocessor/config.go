@@ -0,0 +1,35 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedupprocessor
+
+import (
+	"time"
+
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+)
+
+// ConfigV2 defines configuration for the span deduplication processor.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// Window is for how long a span is remembered to drop its duplicates.
+	Window time.Duration `mapstructure:"window"`
+	// MaxEntries is the maximum number of spans remembered, the least
+	// recently seen ones are forgotten first.
+	MaxEntries int `mapstructure:"max_entries"`
+	// MergeSharedSpans enables giving the server side of Zipkin-style shared
+	// spans its own span ID, child of the client side. Only the server sides
+	// received with or after their client side are changed.
+	MergeSharedSpans bool `mapstructure:"merge_shared_spans"`
+}
@@ -0,0 +1,258 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dedupprocessor contains a trace processor that drops spans already
+// seen, e.g. when applications ship their spans to more than one collector,
+// and that gives the server side of Zipkin-style shared spans its own span ID.
+//
+// A server span is only known to be shared once its client span was seen, so
+// the server side is only split when the client side arrives first or in the
+// same batch. A server side arriving first is passed along unchanged, and so
+// is its client side arriving later. To split all of them the processor must
+// follow the groupbytrace processor, which batches both sides together.
+package dedupprocessor
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/processor"
+	"github.com/census-instrumentation/opencensus-service/processor/internal/lru"
+)
+
+const (
+	defaultWindow     = time.Minute
+	defaultMaxEntries = 100000
+)
+
+// spanKey identifies a span for the purpose of finding its duplicates. The
+// kind is part of it so the two sides of a shared span are not mistaken for
+// duplicates.
+type spanKey struct {
+	traceID   string
+	spanID    string
+	kind      tracepb.Span_SpanKind
+	startTime int64
+}
+
+// sharedKey identifies the client side of a span possibly shared with a
+// server.
+type sharedKey struct {
+	traceID string
+	spanID  string
+}
+
+type dedupprocessor struct {
+	nextConsumer     consumer.TraceConsumer
+	window           time.Duration
+	mergeSharedSpans bool
+
+	// mu protects seen, which holds both spanKeys and sharedKeys mapped to the
+	// time they were first seen.
+	mu   sync.Mutex
+	seen *lru.Cache
+
+	now func() time.Time
+}
+
+// Option represents options that can be applied to the span deduplication
+// processor.
+type Option func(*dedupprocessor) error
+
+// WithWindow returns an Option to configure for how long a span is
+// remembered to drop its duplicates.
+func WithWindow(window time.Duration) Option {
+	return func(dp *dedupprocessor) error {
+		if window <= 0 {
+			return errors.New("window must be positive")
+		}
+		dp.window = window
+		return nil
+	}
+}
+
+// WithMaxEntries returns an Option to configure the maximum number of spans
+// remembered. When full the least recently seen spans are forgotten first.
+func WithMaxEntries(maxEntries int) Option {
+	return func(dp *dedupprocessor) error {
+		if maxEntries <= 0 {
+			return errors.New("max entries must be positive")
+		}
+		dp.seen = lru.New(maxEntries)
+		return nil
+	}
+}
+
+// WithMergeSharedSpans returns an Option to give the server side of a span
+// shared with its client, as reported by Zipkin instrumentation, a span ID of
+// its own and to make it a child of the client side. Only the server sides
+// received in the same batch as the client side, or after it, are changed,
+// see the package documentation.
+func WithMergeSharedSpans(mergeSharedSpans bool) Option {
+	return func(dp *dedupprocessor) error {
+		dp.mergeSharedSpans = mergeSharedSpans
+		return nil
+	}
+}
+
+var _ processor.TraceProcessor = (*dedupprocessor)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that drops the spans
+// whose trace ID, span ID, kind and start time were already seen within the
+// configured window.
+func NewTraceProcessor(nextConsumer consumer.TraceConsumer, options ...Option) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+
+	dp := &dedupprocessor{
+		nextConsumer:     nextConsumer,
+		window:           defaultWindow,
+		mergeSharedSpans: true,
+		seen:             lru.New(defaultMaxEntries),
+		now:              time.Now,
+	}
+	for _, opt := range options {
+		if err := opt(dp); err != nil {
+			return nil, err
+		}
+	}
+	return dp, nil
+}
+
+func (dp *dedupprocessor) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	now := dp.now()
+	var dropped, merged int64
+
+	// The keys of the spans passed along are reserved while checking them,
+	// so concurrent batches do not both pass the same span along, and
+	// released if the next consumer fails, so the spans sent again after
+	// the error are not dropped as duplicates.
+	var keys []interface{}
+	batch := make(map[interface{}]bool, len(td.Spans))
+
+	dp.mu.Lock()
+	spans := make([]*tracepb.Span, 0, len(td.Spans))
+	for _, span := range td.Spans {
+		if span == nil {
+			continue
+		}
+		key := spanKey{
+			traceID: string(span.TraceId),
+			spanID:  string(span.SpanId),
+			kind:    span.Kind,
+		}
+		if span.StartTime != nil {
+			key.startTime = span.StartTime.Seconds*int64(time.Second) + int64(span.StartTime.Nanos)
+		}
+		if batch[key] || dp.seenWithinWindow(key, now) {
+			dropped++
+			continue
+		}
+		batch[key] = true
+		keys = append(keys, key)
+		spans = append(spans, span)
+	}
+
+	if dp.mergeSharedSpans {
+		// Collect the client spans first so the server spans sharing their
+		// IDs are found regardless of their order in the batch.
+		for _, span := range spans {
+			if span.Kind == tracepb.Span_CLIENT {
+				key := sharedKey{traceID: string(span.TraceId), spanID: string(span.SpanId)}
+				batch[key] = true
+				keys = append(keys, key)
+			}
+		}
+		for _, span := range spans {
+			if span.Kind != tracepb.Span_SERVER {
+				continue
+			}
+			key := sharedKey{traceID: string(span.TraceId), spanID: string(span.SpanId)}
+			if batch[key] || dp.seenWithinWindow(key, now) {
+				splitSharedSpan(span)
+				merged++
+			}
+		}
+	}
+	reserved := keys[:0]
+	for _, key := range keys {
+		if dp.record(key, now) {
+			reserved = append(reserved, key)
+		}
+	}
+	dp.mu.Unlock()
+
+	recordCounts(ctx, td.Node, dropped, merged)
+	if len(spans) == 0 {
+		return nil
+	}
+	td.Spans = spans
+	if err := dp.nextConsumer.ConsumeTraceData(ctx, td); err != nil {
+		dp.mu.Lock()
+		for _, key := range reserved {
+			dp.seen.Remove(key)
+		}
+		dp.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// seenWithinWindow returns true if key was first seen less than the window
+// ago.
+func (dp *dedupprocessor) seenWithinWindow(key interface{}, now time.Time) bool {
+	seenAt, ok := dp.seen.Get(key)
+	return ok && now.Sub(seenAt.(time.Time)) <= dp.window
+}
+
+// record records key as seen now, unless it was already seen within the
+// window. It returns true if the key was recorded.
+func (dp *dedupprocessor) record(key interface{}, now time.Time) bool {
+	if dp.seenWithinWindow(key, now) {
+		return false
+	}
+	dp.seen.Add(key, now)
+	return true
+}
+
+// splitSharedSpan makes the server side of a shared span a remote child of
+// the client side. Children of the server side keep pointing to the shared
+// span ID, so they become children of the client side.
+func splitSharedSpan(span *tracepb.Span) {
+	span.ParentSpanId = span.SpanId
+	span.SpanId = serverSpanID(span.TraceId, span.SpanId)
+	span.SameProcessAsParentSpan = &wrappers.BoolValue{Value: false}
+}
+
+// serverSpanID derives the span ID of the server side of a shared span. It is
+// deterministic so all the collectors receiving the span agree on it.
+func serverSpanID(traceID, spanID []byte) []byte {
+	h := fnv.New64a()
+	h.Write(traceID)
+	h.Write(spanID)
+	h.Write([]byte("server"))
+	id := make([]byte, 8)
+	binary.BigEndian.PutUint64(id, h.Sum64())
+	return id
+}
@@ -0,0 +1,253 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedupprocessor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+	"github.com/census-instrumentation/opencensus-service/internal"
+)
+
+var (
+	traceID  = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	baseTime = time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func newSpan(id byte, kind tracepb.Span_SpanKind, start time.Duration) *tracepb.Span {
+	return &tracepb.Span{
+		TraceId:      traceID,
+		SpanId:       []byte{0, 0, 0, 0, 0, 0, 0, id},
+		ParentSpanId: []byte{0, 0, 0, 0, 0, 0, 1, 0},
+		Kind:         kind,
+		StartTime:    internal.TimeToTimestamp(baseTime.Add(start)),
+	}
+}
+
+func newTestProcessor(t *testing.T, sink *exportertest.SinkTraceExporter, now *time.Time, options ...Option) *dedupprocessor {
+	tp, err := NewTraceProcessor(sink, options...)
+	require.NoError(t, err)
+	dp := tp.(*dedupprocessor)
+	dp.now = func() time.Time { return *now }
+	return dp
+}
+
+func TestNewTraceProcessor(t *testing.T) {
+	_, err := NewTraceProcessor(nil)
+	assert.Error(t, err)
+
+	sink := &exportertest.SinkTraceExporter{}
+	_, err = NewTraceProcessor(sink, WithWindow(0))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithMaxEntries(0))
+	assert.Error(t, err)
+}
+
+func TestDropDuplicates(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	now := baseTime
+	dp := newTestProcessor(t, sink, &now, WithWindow(time.Minute))
+
+	span := newSpan(1, tracepb.Span_SPAN_KIND_UNSPECIFIED, 0)
+	// Same IDs but a different start time, e.g. a retried operation.
+	restarted := newSpan(1, tracepb.Span_SPAN_KIND_UNSPECIFIED, time.Second)
+	td := data.TraceData{Spans: []*tracepb.Span{span, proto.Clone(span).(*tracepb.Span), nil, restarted}}
+	require.NoError(t, dp.ConsumeTraceData(context.Background(), td))
+
+	// The duplicate arrives from another agent within the window.
+	now = now.Add(30 * time.Second)
+	td = data.TraceData{Spans: []*tracepb.Span{proto.Clone(span).(*tracepb.Span)}}
+	require.NoError(t, dp.ConsumeTraceData(context.Background(), td))
+
+	// After the window the span is not considered a duplicate anymore.
+	now = now.Add(time.Minute)
+	td = data.TraceData{Spans: []*tracepb.Span{proto.Clone(span).(*tracepb.Span)}}
+	require.NoError(t, dp.ConsumeTraceData(context.Background(), td))
+
+	got := sink.AllTraces()
+	require.Len(t, got, 2)
+	assert.Equal(t, []*tracepb.Span{span, restarted}, got[0].Spans)
+	assert.Equal(t, []*tracepb.Span{span}, got[1].Spans)
+}
+
+func TestDropDuplicates_Evicted(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	now := baseTime
+	dp := newTestProcessor(t, sink, &now, WithMaxEntries(1), WithMergeSharedSpans(false))
+
+	for _, id := range []byte{1, 2, 1} {
+		td := data.TraceData{Spans: []*tracepb.Span{newSpan(id, tracepb.Span_SPAN_KIND_UNSPECIFIED, 0)}}
+		require.NoError(t, dp.ConsumeTraceData(context.Background(), td))
+	}
+	// Span 1 was forgotten to make room for span 2.
+	assert.Len(t, sink.AllTraces(), 3)
+}
+
+// failingConsumer fails the first calls, then passes the data to the sink.
+type failingConsumer struct {
+	failures int
+	sink     *exportertest.SinkTraceExporter
+}
+
+var _ consumer.TraceConsumer = (*failingConsumer)(nil)
+
+func (fc *failingConsumer) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	if fc.failures > 0 {
+		fc.failures--
+		return errors.New("temporary failure")
+	}
+	return fc.sink.ConsumeTraceData(ctx, td)
+}
+
+func TestDropDuplicates_NextConsumerError(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(&failingConsumer{failures: 1, sink: sink})
+	require.NoError(t, err)
+
+	client := newSpan(1, tracepb.Span_CLIENT, 0)
+	td := data.TraceData{Spans: []*tracepb.Span{client}}
+	require.Error(t, tp.ConsumeTraceData(context.Background(), td))
+
+	// The spans sent again after the error are not dropped as duplicates.
+	td = data.TraceData{Spans: []*tracepb.Span{proto.Clone(client).(*tracepb.Span)}}
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), td))
+	require.Len(t, sink.AllTraces(), 1)
+	assert.Len(t, sink.AllTraces()[0].Spans, 1)
+
+	// Once they were accepted, they are.
+	td = data.TraceData{Spans: []*tracepb.Span{proto.Clone(client).(*tracepb.Span)}}
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), td))
+	assert.Len(t, sink.AllTraces(), 1)
+}
+
+// slowConsumer passes the data to the sink after a delay, so concurrent
+// batches overlap.
+type slowConsumer struct {
+	sink *exportertest.SinkTraceExporter
+}
+
+var _ consumer.TraceConsumer = (*slowConsumer)(nil)
+
+func (sc *slowConsumer) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	time.Sleep(10 * time.Millisecond)
+	return sc.sink.ConsumeTraceData(ctx, td)
+}
+
+func TestDropDuplicates_Concurrent(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(&slowConsumer{sink: sink})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			td := data.TraceData{Spans: []*tracepb.Span{newSpan(1, tracepb.Span_CLIENT, 0)}}
+			assert.NoError(t, tp.ConsumeTraceData(context.Background(), td))
+		}()
+	}
+	wg.Wait()
+
+	// Only one of the batches passed the span along.
+	assert.Len(t, sink.AllTraces(), 1)
+}
+
+func TestMergeSharedSpans(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	now := baseTime
+	dp := newTestProcessor(t, sink, &now)
+
+	client := newSpan(1, tracepb.Span_CLIENT, 0)
+	server := newSpan(1, tracepb.Span_SERVER, 10*time.Millisecond)
+	td := data.TraceData{Spans: []*tracepb.Span{server, client}}
+	require.NoError(t, dp.ConsumeTraceData(context.Background(), td))
+
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 1}, client.SpanId)
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 1, 0}, client.ParentSpanId)
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 1}, server.ParentSpanId)
+	assert.Equal(t, serverSpanID(traceID, client.SpanId), server.SpanId)
+	assert.NotEqual(t, client.SpanId, server.SpanId)
+	assert.Equal(t, &wrappers.BoolValue{Value: false}, server.SameProcessAsParentSpan)
+
+	// The server side reported to another agent later is a duplicate.
+	td = data.TraceData{Spans: []*tracepb.Span{newSpan(1, tracepb.Span_SERVER, 10*time.Millisecond)}}
+	require.NoError(t, dp.ConsumeTraceData(context.Background(), td))
+	assert.Len(t, sink.AllTraces(), 1)
+}
+
+func TestMergeSharedSpans_ServerInLaterBatch(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	now := baseTime
+	dp := newTestProcessor(t, sink, &now)
+
+	td := data.TraceData{Spans: []*tracepb.Span{newSpan(1, tracepb.Span_CLIENT, 0)}}
+	require.NoError(t, dp.ConsumeTraceData(context.Background(), td))
+
+	server := newSpan(1, tracepb.Span_SERVER, 10*time.Millisecond)
+	unrelated := newSpan(2, tracepb.Span_SERVER, 10*time.Millisecond)
+	td = data.TraceData{Spans: []*tracepb.Span{server, unrelated}}
+	require.NoError(t, dp.ConsumeTraceData(context.Background(), td))
+
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 1}, server.ParentSpanId)
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 2}, unrelated.SpanId)
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 1, 0}, unrelated.ParentSpanId)
+}
+
+func TestMergeSharedSpans_ServerInEarlierBatch(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	now := baseTime
+	dp := newTestProcessor(t, sink, &now)
+
+	// The server side is not known to be shared before its client side is
+	// seen, so neither side is changed.
+	server := newSpan(1, tracepb.Span_SERVER, 10*time.Millisecond)
+	td := data.TraceData{Spans: []*tracepb.Span{server}}
+	require.NoError(t, dp.ConsumeTraceData(context.Background(), td))
+
+	client := newSpan(1, tracepb.Span_CLIENT, 0)
+	td = data.TraceData{Spans: []*tracepb.Span{client}}
+	require.NoError(t, dp.ConsumeTraceData(context.Background(), td))
+
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 1}, server.SpanId)
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 1, 0}, server.ParentSpanId)
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 1}, client.SpanId)
+	assert.Len(t, sink.AllTraces(), 2)
+}
+
+func TestMergeSharedSpans_Disabled(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	now := baseTime
+	dp := newTestProcessor(t, sink, &now, WithMergeSharedSpans(false))
+
+	server := newSpan(1, tracepb.Span_SERVER, 10*time.Millisecond)
+	td := data.TraceData{Spans: []*tracepb.Span{newSpan(1, tracepb.Span_CLIENT, 0), server}}
+	require.NoError(t, dp.ConsumeTraceData(context.Background(), td))
+
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0, 1}, server.SpanId)
+	assert.Len(t, sink.AllTraces()[0].Spans, 2)
+}
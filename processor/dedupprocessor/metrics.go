@@ -0,0 +1,72 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedupprocessor
+
+import (
+	"context"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/census-instrumentation/opencensus-service/internal/collector/processor"
+	"github.com/census-instrumentation/opencensus-service/internal/collector/telemetry"
+)
+
+// Variables related to metrics specific to the span deduplication processor.
+var (
+	statDuplicateSpansDropped = stats.Int64("dedup_duplicate_spans_dropped", "Count of spans dropped for having been seen before", stats.UnitDimensionless)
+	statSharedSpansMerged     = stats.Int64("dedup_shared_spans_merged", "Count of server spans given their own ID for sharing it with their client span", stats.UnitDimensionless)
+)
+
+func recordCounts(ctx context.Context, node *commonpb.Node, dropped, merged int64) {
+	if dropped == 0 && merged == 0 {
+		return
+	}
+	_ = stats.RecordWithTags(
+		ctx,
+		[]tag.Mutator{tag.Upsert(processor.TagServiceNameKey, processor.ServiceNameForNode(node))},
+		statDuplicateSpansDropped.M(dropped),
+		statSharedSpansMerged.M(merged))
+}
+
+// MetricViews return the metrics views according to given telemetry level.
+func MetricViews(level telemetry.Level) []*view.View {
+	if level == telemetry.None {
+		return nil
+	}
+
+	var tagKeys []tag.Key
+	if level == telemetry.Detailed {
+		tagKeys = append(tagKeys, processor.TagServiceNameKey)
+	}
+
+	droppedView := &view.View{
+		Name:        statDuplicateSpansDropped.Name(),
+		Measure:     statDuplicateSpansDropped,
+		Description: statDuplicateSpansDropped.Description(),
+		TagKeys:     tagKeys,
+		Aggregation: view.Sum(),
+	}
+	mergedView := &view.View{
+		Name:        statSharedSpansMerged.Name(),
+		Measure:     statSharedSpansMerged,
+		Description: statSharedSpansMerged.Description(),
+		TagKeys:     tagKeys,
+		Aggregation: view.Sum(),
+	}
+	return []*view.View{droppedView, mergedView}
+}
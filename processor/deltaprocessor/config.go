@@ -0,0 +1,32 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deltaprocessor
+
+import (
+	"time"
+
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+)
+
+// ConfigV2 defines configuration for the delta processor.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// Mode selects the direction of the conversion, either "cumulative_to_delta"
+	// or "delta_to_cumulative".
+	Mode Mode `mapstructure:"mode"`
+	// MaxStaleness is how long the state of a timeseries is kept after its
+	// last point was received.
+	MaxStaleness time.Duration `mapstructure:"max_staleness"`
+}
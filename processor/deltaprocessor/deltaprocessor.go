@@ -0,0 +1,244 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package deltaprocessor contains a metrics processor that converts cumulative
+// metrics to deltas and deltas to cumulative metrics.
+//
+// The OpenCensus metrics proto has no delta metric type: a delta is carried as
+// a point of a CUMULATIVE_* metric (or SUMMARY) whose timeseries start
+// timestamp is the timestamp of the previous point, i.e. the point holds the
+// value accumulated only over the interval [StartTimestamp, Timestamp].
+// Gauges are passed through unchanged.
+package deltaprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/processor"
+	"github.com/census-instrumentation/opencensus-service/processor/internal/signature"
+)
+
+// Mode selects the direction of the conversion performed by the processor.
+type Mode string
+
+const (
+	// CumulativeToDelta converts each cumulative point to the difference from
+	// the previous point of the same timeseries.
+	CumulativeToDelta Mode = "cumulative_to_delta"
+	// DeltaToCumulative accumulates the delta points of each timeseries into
+	// cumulative points.
+	DeltaToCumulative Mode = "delta_to_cumulative"
+)
+
+const defaultMaxStaleness = 5 * time.Minute
+
+type deltaprocessor struct {
+	nextConsumer consumer.MetricsConsumer
+	mode         Mode
+	maxStaleness time.Duration
+
+	// mu protects series.
+	mu     sync.Mutex
+	series *timeseriesMap
+}
+
+// Option represents options that can be applied to the delta processor.
+type Option func(*deltaprocessor) error
+
+// WithMode returns an Option to configure the direction of the conversion.
+func WithMode(mode Mode) Option {
+	return func(dp *deltaprocessor) error {
+		switch mode {
+		case CumulativeToDelta, DeltaToCumulative:
+			dp.mode = mode
+			return nil
+		default:
+			return fmt.Errorf("unknown mode %q, must be %q or %q", mode, CumulativeToDelta, DeltaToCumulative)
+		}
+	}
+}
+
+// WithMaxStaleness returns an Option to configure how long the state of a
+// timeseries is kept after its last point was received. A timeseries that
+// receives a point after its state was dropped is treated as a new one.
+func WithMaxStaleness(maxStaleness time.Duration) Option {
+	return func(dp *deltaprocessor) error {
+		if maxStaleness <= 0 {
+			return errors.New("max staleness must be positive")
+		}
+		dp.maxStaleness = maxStaleness
+		return nil
+	}
+}
+
+var _ processor.MetricsProcessor = (*deltaprocessor)(nil)
+
+// NewMetricsProcessor returns a processor.MetricsProcessor that converts
+// cumulative metrics to deltas, or the reverse, according to the given options.
+// By default it converts cumulative metrics to deltas.
+func NewMetricsProcessor(nextConsumer consumer.MetricsConsumer, options ...Option) (processor.MetricsProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+
+	dp := &deltaprocessor{
+		nextConsumer: nextConsumer,
+		mode:         CumulativeToDelta,
+		maxStaleness: defaultMaxStaleness,
+	}
+	for _, opt := range options {
+		if err := opt(dp); err != nil {
+			return nil, err
+		}
+	}
+	// The mark-and-sweep gc keeps entries for up to two intervals, halve the
+	// interval so entries are dropped between maxStaleness/2 and maxStaleness.
+	dp.series = newTimeseriesMap(dp.maxStaleness / 2)
+	return dp, nil
+}
+
+func (dp *deltaprocessor) ConsumeMetricsData(ctx context.Context, md data.MetricsData) error {
+	batchSig := signature.Batch(md.Node, md.Resource)
+	metrics := make([]*metricspb.Metric, 0, len(md.Metrics))
+
+	dp.mu.Lock()
+	for _, metric := range md.Metrics {
+		if converted := dp.convertMetric(batchSig, metric); converted != nil {
+			metrics = append(metrics, converted)
+		}
+	}
+	dp.series.maybeGC(time.Now())
+	dp.mu.Unlock()
+
+	if len(metrics) == 0 {
+		return nil
+	}
+	return dp.nextConsumer.ConsumeMetricsData(ctx, data.MetricsData{
+		Node:     md.Node,
+		Resource: md.Resource,
+		Metrics:  metrics,
+	})
+}
+
+// convertMetric returns the converted metric or nil if none of its points
+// produced an output.
+func (dp *deltaprocessor) convertMetric(batchSig string, metric *metricspb.Metric) *metricspb.Metric {
+	if metric == nil {
+		return nil
+	}
+	switch metric.GetMetricDescriptor().GetType() {
+	case metricspb.MetricDescriptor_CUMULATIVE_INT64,
+		metricspb.MetricDescriptor_CUMULATIVE_DOUBLE,
+		metricspb.MetricDescriptor_CUMULATIVE_DISTRIBUTION,
+		metricspb.MetricDescriptor_SUMMARY:
+	default:
+		// Gauges and unspecified types are not affected by the conversion.
+		return metric
+	}
+
+	var tss []*metricspb.TimeSeries
+	for _, ts := range metric.Timeseries {
+		sig := signature.Timeseries(batchSig, metric, ts.LabelValues)
+		for _, point := range ts.Points {
+			if point == nil || point.Value == nil {
+				continue
+			}
+			state := dp.series.get(sig)
+			var converted *metricspb.TimeSeries
+			if dp.mode == CumulativeToDelta {
+				converted = toDelta(state, ts, point)
+			} else {
+				converted = toCumulative(state, ts, point)
+			}
+			if converted != nil {
+				tss = append(tss, converted)
+			}
+		}
+	}
+
+	if len(tss) == 0 {
+		return nil
+	}
+	return &metricspb.Metric{
+		MetricDescriptor: metric.MetricDescriptor,
+		Resource:         metric.Resource,
+		Timeseries:       tss,
+	}
+}
+
+// toDelta converts a cumulative point to a delta from the previous point of
+// the timeseries. Each delta is emitted on its own timeseries since its start
+// timestamp differs from the one of any other point.
+func toDelta(state *timeseriesState, ts *metricspb.TimeSeries, point *metricspb.Point) *metricspb.TimeSeries {
+	previous := state.last
+	previousStart := state.start
+	state.start = ts.StartTimestamp
+	state.last = point
+
+	if previous == nil {
+		// First point of the series, there is nothing to compute a difference against.
+		return nil
+	}
+
+	if timestampChanged(ts.StartTimestamp, previousStart) || decreased(point, previous) {
+		// The series was reset. If it has a start time that happened after the
+		// previous point, the current value is the delta since the reset.
+		if timestampChanged(ts.StartTimestamp, previousStart) && !timestampBefore(ts.StartTimestamp, previous.Timestamp) {
+			return &metricspb.TimeSeries{
+				StartTimestamp: ts.StartTimestamp,
+				LabelValues:    ts.LabelValues,
+				Points:         []*metricspb.Point{point},
+			}
+		}
+		return nil
+	}
+
+	return &metricspb.TimeSeries{
+		StartTimestamp: previous.Timestamp,
+		LabelValues:    ts.LabelValues,
+		Points:         []*metricspb.Point{subtractPoints(point, previous)},
+	}
+}
+
+// toCumulative accumulates a delta point on top of the previous points of the
+// timeseries. The accumulation restarts if the delta interval overlaps the
+// previous one, which indicates that the source restarted, or if the point is
+// of a different shape than the accumulated one.
+func toCumulative(state *timeseriesState, ts *metricspb.TimeSeries, point *metricspb.Point) *metricspb.TimeSeries {
+	if state.last == nil ||
+		timestampBefore(ts.StartTimestamp, state.last.Timestamp) ||
+		incompatible(point, state.last) {
+		state.start = ts.StartTimestamp
+		if state.start == nil {
+			state.start = point.Timestamp
+		}
+		state.last = point
+	} else {
+		state.last = addPoints(point, state.last)
+	}
+
+	return &metricspb.TimeSeries{
+		StartTimestamp: state.start,
+		LabelValues:    ts.LabelValues,
+		Points:         []*metricspb.Point{state.last},
+	}
+}
@@ -0,0 +1,387 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deltaprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+)
+
+type deltaTest struct {
+	description string
+	metrics     []*metricspb.Metric
+	want        []*metricspb.Metric
+}
+
+func TestNewMetricsProcessor(t *testing.T) {
+	_, err := NewMetricsProcessor(nil)
+	assert.Error(t, err)
+
+	_, err = NewMetricsProcessor(exportertest.NewNopMetricsExporter(), WithMode("sideways"))
+	assert.Error(t, err)
+
+	_, err = NewMetricsProcessor(exportertest.NewNopMetricsExporter(), WithMaxStaleness(0))
+	assert.Error(t, err)
+
+	mp, err := NewMetricsProcessor(exportertest.NewNopMetricsExporter(), WithMode(DeltaToCumulative))
+	assert.NoError(t, err)
+	assert.NotNil(t, mp)
+}
+
+func TestCumulativeToDelta_Gauge(t *testing.T) {
+	script := []*deltaTest{{
+		"Gauge: round 1 - passed through",
+		[]*metricspb.Metric{gauge(timeseries(0, double(1, 44)))},
+		[]*metricspb.Metric{gauge(timeseries(0, double(1, 44)))},
+	}, {
+		"Gauge: round 2 - passed through even if value decreased",
+		[]*metricspb.Metric{gauge(timeseries(0, double(2, 22)))},
+		[]*metricspb.Metric{gauge(timeseries(0, double(2, 22)))},
+	}}
+	runScript(t, CumulativeToDelta, script)
+}
+
+func TestCumulativeToDelta_Double(t *testing.T) {
+	script := []*deltaTest{{
+		"Cumulative: round 1 - initial point, nothing to compare to",
+		[]*metricspb.Metric{cumulative(timeseries(1, double(1, 44)))},
+		nil,
+	}, {
+		"Cumulative: round 2 - delta from round 1",
+		[]*metricspb.Metric{cumulative(timeseries(1, double(2, 66)))},
+		[]*metricspb.Metric{cumulative(timeseries(1, double(2, 22)))},
+	}, {
+		"Cumulative: round 3 - value decreased without new start time, reset dropped",
+		[]*metricspb.Metric{cumulative(timeseries(1, double(3, 55)))},
+		nil,
+	}, {
+		"Cumulative: round 4 - delta from round 3",
+		[]*metricspb.Metric{cumulative(timeseries(1, double(4, 72)))},
+		[]*metricspb.Metric{cumulative(timeseries(3, double(4, 17)))},
+	}, {
+		"Cumulative: round 5 - new start time, value is the delta since the reset",
+		[]*metricspb.Metric{cumulative(timeseries(5, double(6, 3)))},
+		[]*metricspb.Metric{cumulative(timeseries(5, double(6, 3)))},
+	}, {
+		"Cumulative: round 6 - delta from round 5",
+		[]*metricspb.Metric{cumulative(timeseries(5, double(7, 10)))},
+		[]*metricspb.Metric{cumulative(timeseries(6, double(7, 7)))},
+	}}
+	runScript(t, CumulativeToDelta, script)
+}
+
+func TestCumulativeToDelta_Int64(t *testing.T) {
+	script := []*deltaTest{{
+		"CumulativeInt: round 1 - initial point",
+		[]*metricspb.Metric{cumulativeInt(timeseries(1, int64Point(1, 10)))},
+		nil,
+	}, {
+		"CumulativeInt: round 2 - two points in the same timeseries",
+		[]*metricspb.Metric{cumulativeInt(timeseries(1, int64Point(2, 15), int64Point(3, 21)))},
+		[]*metricspb.Metric{cumulativeInt(
+			timeseries(1, int64Point(2, 5)),
+			timeseries(2, int64Point(3, 6)),
+		)},
+	}}
+	runScript(t, CumulativeToDelta, script)
+}
+
+func TestCumulativeToDelta_Distribution(t *testing.T) {
+	script := []*deltaTest{{
+		"CumulativeDist: round 1 - initial point",
+		[]*metricspb.Metric{cumulativeDist(timeseries(1, dist(1, bounds0, []int64{4, 2, 3, 7})))},
+		nil,
+	}, {
+		"CumulativeDist: round 2 - delta from round 1",
+		[]*metricspb.Metric{cumulativeDist(timeseries(1, dist(2, bounds0, []int64{6, 3, 4, 8})))},
+		[]*metricspb.Metric{cumulativeDist(timeseries(1, dist(2, bounds0, []int64{2, 1, 1, 1})))},
+	}, {
+		"CumulativeDist: round 3 - bucket layout changed, reset dropped",
+		[]*metricspb.Metric{cumulativeDist(timeseries(1, dist(3, bounds1, []int64{7, 3, 4, 8})))},
+		nil,
+	}, {
+		"CumulativeDist: round 4 - delta from round 3",
+		[]*metricspb.Metric{cumulativeDist(timeseries(1, dist(4, bounds1, []int64{7, 4, 4, 9})))},
+		[]*metricspb.Metric{cumulativeDist(timeseries(3, dist(4, bounds1, []int64{0, 1, 0, 1})))},
+	}}
+	runScript(t, CumulativeToDelta, script)
+}
+
+func TestCumulativeToDelta_Summary(t *testing.T) {
+	script := []*deltaTest{{
+		"Summary: round 1 - initial point",
+		[]*metricspb.Metric{summary(timeseries(1, summ(1, 10, 40)))},
+		nil,
+	}, {
+		"Summary: round 2 - delta from round 1",
+		[]*metricspb.Metric{summary(timeseries(1, summ(2, 15, 70)))},
+		[]*metricspb.Metric{summary(timeseries(1, summ(2, 5, 30)))},
+	}, {
+		"Summary: round 3 - count decreased, reset dropped",
+		[]*metricspb.Metric{summary(timeseries(1, summ(3, 2, 8)))},
+		nil,
+	}}
+	runScript(t, CumulativeToDelta, script)
+}
+
+func TestCumulativeToDelta_SeparateNodes(t *testing.T) {
+	sink := &exportertest.SinkMetricsExporter{}
+	mp, err := NewMetricsProcessor(sink)
+	require.NoError(t, err)
+
+	node1 := &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "svc1"}}
+	node2 := &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "svc2"}}
+	consume := func(node *commonpb.Node, metric *metricspb.Metric) {
+		err := mp.ConsumeMetricsData(context.Background(), data.MetricsData{
+			Node:    node,
+			Metrics: []*metricspb.Metric{metric},
+		})
+		require.NoError(t, err)
+	}
+
+	consume(node1, cumulative(timeseries(1, double(1, 10))))
+	consume(node2, cumulative(timeseries(1, double(1, 100))))
+	consume(node1, cumulative(timeseries(1, double(2, 15))))
+	consume(node2, cumulative(timeseries(1, double(2, 130))))
+
+	got := sink.AllMetrics()
+	require.Len(t, got, 2)
+	assert.Equal(t, node1, got[0].Node)
+	assertMetricsEqual(t, []*metricspb.Metric{cumulative(timeseries(1, double(2, 5)))}, got[0].Metrics)
+	assert.Equal(t, node2, got[1].Node)
+	assertMetricsEqual(t, []*metricspb.Metric{cumulative(timeseries(1, double(2, 30)))}, got[1].Metrics)
+}
+
+func TestDeltaToCumulative_Double(t *testing.T) {
+	script := []*deltaTest{{
+		"Delta: round 1 - initial point starts the accumulation",
+		[]*metricspb.Metric{cumulative(timeseries(0, double(1, 4)))},
+		[]*metricspb.Metric{cumulative(timeseries(0, double(1, 4)))},
+	}, {
+		"Delta: round 2 - accumulated on top of round 1",
+		[]*metricspb.Metric{cumulative(timeseries(1, double(2, 6)))},
+		[]*metricspb.Metric{cumulative(timeseries(0, double(2, 10)))},
+	}, {
+		"Delta: round 3 - accumulated on top of round 2",
+		[]*metricspb.Metric{cumulative(timeseries(2, double(3, 1)))},
+		[]*metricspb.Metric{cumulative(timeseries(0, double(3, 11)))},
+	}, {
+		"Delta: round 4 - interval overlaps the previous one, accumulation restarts",
+		[]*metricspb.Metric{cumulative(timeseries(1, double(4, 2)))},
+		[]*metricspb.Metric{cumulative(timeseries(1, double(4, 2)))},
+	}, {
+		"Delta: round 5 - accumulated on top of round 4",
+		[]*metricspb.Metric{cumulative(timeseries(4, double(5, 3)))},
+		[]*metricspb.Metric{cumulative(timeseries(1, double(5, 5)))},
+	}}
+	runScript(t, DeltaToCumulative, script)
+}
+
+func TestDeltaToCumulative_Distribution(t *testing.T) {
+	script := []*deltaTest{{
+		"DeltaDist: round 1 - initial point starts the accumulation",
+		[]*metricspb.Metric{cumulativeDist(timeseries(0, dist(1, bounds0, []int64{1, 0, 2, 0})))},
+		[]*metricspb.Metric{cumulativeDist(timeseries(0, dist(1, bounds0, []int64{1, 0, 2, 0})))},
+	}, {
+		"DeltaDist: round 2 - accumulated on top of round 1",
+		[]*metricspb.Metric{cumulativeDist(timeseries(1, dist(2, bounds0, []int64{0, 1, 1, 3})))},
+		[]*metricspb.Metric{cumulativeDist(timeseries(0, dist(2, bounds0, []int64{1, 1, 3, 3})))},
+	}, {
+		"DeltaDist: round 3 - bucket layout changed, accumulation restarts",
+		[]*metricspb.Metric{cumulativeDist(timeseries(2, dist(3, bounds1, []int64{1, 1, 1, 1})))},
+		[]*metricspb.Metric{cumulativeDist(timeseries(2, dist(3, bounds1, []int64{1, 1, 1, 1})))},
+	}}
+	runScript(t, DeltaToCumulative, script)
+}
+
+func TestDeltaToCumulative_NoStartTimestamp(t *testing.T) {
+	script := []*deltaTest{{
+		"DeltaInt: round 1 - start of accumulation taken from the point",
+		[]*metricspb.Metric{cumulativeInt(timeseriesNoStart(int64Point(1, 3)))},
+		[]*metricspb.Metric{cumulativeInt(timeseries(1, int64Point(1, 3)))},
+	}, {
+		"DeltaInt: round 2 - accumulated on top of round 1",
+		[]*metricspb.Metric{cumulativeInt(timeseriesNoStart(int64Point(2, 4)))},
+		[]*metricspb.Metric{cumulativeInt(timeseries(1, int64Point(2, 7)))},
+	}}
+	runScript(t, DeltaToCumulative, script)
+}
+
+func TestTimeseriesGC(t *testing.T) {
+	tsm := newTimeseriesMap(time.Minute)
+	start := tsm.lastGC
+
+	tsm.get("a").last = double(1, 1)
+	tsm.get("b").last = double(1, 1)
+
+	// First gc only unmarks the entries.
+	tsm.maybeGC(start.Add(time.Minute))
+	assert.Len(t, tsm.states, 2)
+
+	// Touch only "a", "b" is removed on the next gc.
+	tsm.get("a")
+	tsm.maybeGC(start.Add(90 * time.Second))
+	assert.Len(t, tsm.states, 2, "gc must not run before the interval elapses")
+	tsm.maybeGC(start.Add(2 * time.Minute))
+	assert.Len(t, tsm.states, 1)
+	assert.NotNil(t, tsm.states["a"])
+}
+
+func runScript(t *testing.T, mode Mode, script []*deltaTest) {
+	sink := &exportertest.SinkMetricsExporter{}
+	mp, err := NewMetricsProcessor(sink, WithMode(mode))
+	require.NoError(t, err)
+
+	for _, test := range script {
+		before := len(sink.AllMetrics())
+		err := mp.ConsumeMetricsData(context.Background(), data.MetricsData{Metrics: test.metrics})
+		require.NoError(t, err, test.description)
+
+		all := sink.AllMetrics()
+		if test.want == nil {
+			assert.Equal(t, before, len(all), test.description)
+			continue
+		}
+		require.Equal(t, before+1, len(all), test.description)
+		assertMetricsEqual(t, test.want, all[len(all)-1].Metrics, test.description)
+	}
+}
+
+func assertMetricsEqual(t *testing.T, want, got []*metricspb.Metric, msgAndArgs ...interface{}) {
+	require.Equal(t, len(want), len(got), msgAndArgs...)
+	for i := range want {
+		assert.True(t, proto.Equal(want[i], got[i]), "want %v, got %v", want[i], got[i])
+	}
+}
+
+var (
+	bounds0 = []float64{1, 2, 4}
+	bounds1 = []float64{1, 5, 10}
+)
+
+func gauge(timeseries ...*metricspb.TimeSeries) *metricspb.Metric {
+	return metric(metricspb.MetricDescriptor_GAUGE_DOUBLE, timeseries)
+}
+
+func cumulative(timeseries ...*metricspb.TimeSeries) *metricspb.Metric {
+	return metric(metricspb.MetricDescriptor_CUMULATIVE_DOUBLE, timeseries)
+}
+
+func cumulativeInt(timeseries ...*metricspb.TimeSeries) *metricspb.Metric {
+	return metric(metricspb.MetricDescriptor_CUMULATIVE_INT64, timeseries)
+}
+
+func cumulativeDist(timeseries ...*metricspb.TimeSeries) *metricspb.Metric {
+	return metric(metricspb.MetricDescriptor_CUMULATIVE_DISTRIBUTION, timeseries)
+}
+
+func summary(timeseries ...*metricspb.TimeSeries) *metricspb.Metric {
+	return metric(metricspb.MetricDescriptor_SUMMARY, timeseries)
+}
+
+func metric(ty metricspb.MetricDescriptor_Type, timeseries []*metricspb.TimeSeries) *metricspb.Metric {
+	return &metricspb.Metric{
+		MetricDescriptor: &metricspb.MetricDescriptor{
+			Name:      "test_metric",
+			Type:      ty,
+			LabelKeys: []*metricspb.LabelKey{{Key: "k1"}},
+		},
+		Timeseries: timeseries,
+	}
+}
+
+func timeseries(startMs int64, points ...*metricspb.Point) *metricspb.TimeSeries {
+	ts := timeseriesNoStart(points...)
+	ts.StartTimestamp = toTS(startMs)
+	return ts
+}
+
+func timeseriesNoStart(points ...*metricspb.Point) *metricspb.TimeSeries {
+	return &metricspb.TimeSeries{
+		LabelValues: []*metricspb.LabelValue{{Value: "v1", HasValue: true}},
+		Points:      points,
+	}
+}
+
+func double(ts int64, value float64) *metricspb.Point {
+	return &metricspb.Point{Timestamp: toTS(ts), Value: &metricspb.Point_DoubleValue{DoubleValue: value}}
+}
+
+func int64Point(ts int64, value int64) *metricspb.Point {
+	return &metricspb.Point{Timestamp: toTS(ts), Value: &metricspb.Point_Int64Value{Int64Value: value}}
+}
+
+func dist(ts int64, bounds []float64, counts []int64) *metricspb.Point {
+	var count int64
+	var sum float64
+	buckets := make([]*metricspb.DistributionValue_Bucket, len(counts))
+	for i, bcount := range counts {
+		count += bcount
+		buckets[i] = &metricspb.DistributionValue_Bucket{Count: bcount}
+		// Use lower bound of each bucket as the value to compute the sum.
+		if i > 0 {
+			sum += float64(bcount) * bounds[i-1]
+		}
+	}
+	return &metricspb.Point{
+		Timestamp: toTS(ts),
+		Value: &metricspb.Point_DistributionValue{
+			DistributionValue: &metricspb.DistributionValue{
+				BucketOptions: &metricspb.DistributionValue_BucketOptions{
+					Type: &metricspb.DistributionValue_BucketOptions_Explicit_{
+						Explicit: &metricspb.DistributionValue_BucketOptions_Explicit{
+							Bounds: bounds,
+						},
+					},
+				},
+				Count:   count,
+				Sum:     sum,
+				Buckets: buckets,
+			},
+		},
+	}
+}
+
+func summ(ts, count int64, sum float64) *metricspb.Point {
+	return &metricspb.Point{
+		Timestamp: toTS(ts),
+		Value: &metricspb.Point_SummaryValue{
+			SummaryValue: &metricspb.SummaryValue{
+				Count: &wrappers.Int64Value{Value: count},
+				Sum:   &wrappers.DoubleValue{Value: sum},
+			},
+		},
+	}
+}
+
+func toTS(timeAtMs int64) *timestamp.Timestamp {
+	secs, ns := timeAtMs/1e3, (timeAtMs%1e3)*1e6
+	return &timestamp.Timestamp{
+		Seconds: secs,
+		Nanos:   int32(ns),
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deltaprocessor
+
+import (
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+// decreased returns true if current can't be the continuation of previous in
+// a cumulative series, either because one of its counters went down or
+// because its shape (type, bucket layout) changed.
+func decreased(current, previous *metricspb.Point) bool {
+	switch cur := current.Value.(type) {
+	case *metricspb.Point_Int64Value:
+		prev, ok := previous.Value.(*metricspb.Point_Int64Value)
+		return !ok || cur.Int64Value < prev.Int64Value
+	case *metricspb.Point_DoubleValue:
+		prev, ok := previous.Value.(*metricspb.Point_DoubleValue)
+		return !ok || cur.DoubleValue < prev.DoubleValue
+	case *metricspb.Point_DistributionValue:
+		prev, ok := previous.Value.(*metricspb.Point_DistributionValue)
+		if !ok || !sameBuckets(cur.DistributionValue, prev.DistributionValue) {
+			return true
+		}
+		if cur.DistributionValue.Count < prev.DistributionValue.Count {
+			return true
+		}
+		for i, bucket := range cur.DistributionValue.Buckets {
+			if bucket.Count < prev.DistributionValue.Buckets[i].Count {
+				return true
+			}
+		}
+		return false
+	case *metricspb.Point_SummaryValue:
+		prev, ok := previous.Value.(*metricspb.Point_SummaryValue)
+		return !ok ||
+			cur.SummaryValue.GetCount().GetValue() < prev.SummaryValue.GetCount().GetValue()
+	default:
+		return true
+	}
+}
+
+// incompatible returns true if current can't be accumulated on top of previous.
+func incompatible(current, previous *metricspb.Point) bool {
+	switch cur := current.Value.(type) {
+	case *metricspb.Point_Int64Value:
+		_, ok := previous.Value.(*metricspb.Point_Int64Value)
+		return !ok
+	case *metricspb.Point_DoubleValue:
+		_, ok := previous.Value.(*metricspb.Point_DoubleValue)
+		return !ok
+	case *metricspb.Point_DistributionValue:
+		prev, ok := previous.Value.(*metricspb.Point_DistributionValue)
+		return !ok || !sameBuckets(cur.DistributionValue, prev.DistributionValue)
+	case *metricspb.Point_SummaryValue:
+		_, ok := previous.Value.(*metricspb.Point_SummaryValue)
+		return !ok
+	default:
+		return true
+	}
+}
+
+func sameBuckets(a, b *metricspb.DistributionValue) bool {
+	return len(a.Buckets) == len(b.Buckets) && proto.Equal(a.BucketOptions, b.BucketOptions)
+}
+
+// subtractPoints returns a new point with the difference current - previous
+// and the timestamp of current. The points must not be decreased(). The sum of
+// squared deviation of distributions can't be derived from the difference and
+// is left unset.
+func subtractPoints(current, previous *metricspb.Point) *metricspb.Point {
+	return combinePoints(current, previous, -1)
+}
+
+// addPoints returns a new point with the sum current + previous and the
+// timestamp of current. The points must not be incompatible(). The sum of
+// squared deviation of distributions can't be derived from the sum and is left
+// unset.
+func addPoints(current, previous *metricspb.Point) *metricspb.Point {
+	return combinePoints(current, previous, 1)
+}
+
+func combinePoints(current, previous *metricspb.Point, sign int64) *metricspb.Point {
+	point := &metricspb.Point{Timestamp: current.Timestamp}
+	switch cur := current.Value.(type) {
+	case *metricspb.Point_Int64Value:
+		prev := previous.GetInt64Value()
+		point.Value = &metricspb.Point_Int64Value{Int64Value: cur.Int64Value + sign*prev}
+	case *metricspb.Point_DoubleValue:
+		prev := previous.GetDoubleValue()
+		point.Value = &metricspb.Point_DoubleValue{DoubleValue: cur.DoubleValue + float64(sign)*prev}
+	case *metricspb.Point_DistributionValue:
+		curDist := cur.DistributionValue
+		prevDist := previous.GetDistributionValue()
+		dist := &metricspb.DistributionValue{
+			Count:         curDist.Count + sign*prevDist.Count,
+			Sum:           curDist.Sum + float64(sign)*prevDist.Sum,
+			BucketOptions: curDist.BucketOptions,
+			Buckets:       make([]*metricspb.DistributionValue_Bucket, len(curDist.Buckets)),
+		}
+		for i, bucket := range curDist.Buckets {
+			dist.Buckets[i] = &metricspb.DistributionValue_Bucket{
+				Count:    bucket.Count + sign*prevDist.Buckets[i].Count,
+				Exemplar: bucket.Exemplar,
+			}
+		}
+		point.Value = &metricspb.Point_DistributionValue{DistributionValue: dist}
+	case *metricspb.Point_SummaryValue:
+		curSummary := cur.SummaryValue
+		prevSummary := previous.GetSummaryValue()
+		summary := &metricspb.SummaryValue{
+			// The snapshot describes a recent window and is not cumulative,
+			// keep the one from the current point.
+			Snapshot: curSummary.Snapshot,
+		}
+		if curSummary.Count != nil {
+			summary.Count = &wrappers.Int64Value{
+				Value: curSummary.Count.Value + sign*prevSummary.GetCount().GetValue(),
+			}
+		}
+		if curSummary.Sum != nil {
+			summary.Sum = &wrappers.DoubleValue{
+				Value: curSummary.Sum.Value + float64(sign)*prevSummary.GetSum().GetValue(),
+			}
+		}
+		point.Value = &metricspb.Point_SummaryValue{SummaryValue: summary}
+	}
+	return point
+}
@@ -0,0 +1,96 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deltaprocessor
+
+import (
+	"time"
+
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+	"github.com/golang/protobuf/ptypes/timestamp"
+)
+
+// timeseriesState is the information kept for a single timeseries between
+// consecutive points.
+type timeseriesState struct {
+	mark bool
+	// start is the start timestamp of the cumulative interval ending at last.
+	start *timestamp.Timestamp
+	// last is the last point of the timeseries in its cumulative form.
+	last *metricspb.Point
+}
+
+// timeseriesMap maps from a timeseries signature to its state. Entries are
+// garbage collected with a mark-and-sweep approach similar to the one used by
+// the Prometheus receiver: each access marks the entry and on every gc the
+// unmarked entries are removed and the remaining ones are unmarked. This keeps
+// an entry alive for at least one and at most two gc intervals after its last
+// access.
+type timeseriesMap struct {
+	gcInterval time.Duration
+	lastGC     time.Time
+	states     map[string]*timeseriesState
+}
+
+func newTimeseriesMap(gcInterval time.Duration) *timeseriesMap {
+	return &timeseriesMap{
+		gcInterval: gcInterval,
+		lastGC:     time.Now(),
+		states:     make(map[string]*timeseriesState),
+	}
+}
+
+// get returns the state for the given signature, creating it if needed.
+func (tsm *timeseriesMap) get(sig string) *timeseriesState {
+	tsi, ok := tsm.states[sig]
+	if !ok {
+		tsi = &timeseriesState{}
+		tsm.states[sig] = tsi
+	}
+	tsi.mark = true
+	return tsi
+}
+
+// maybeGC removes the timeseries that were not accessed since the last gc if
+// the gc interval has elapsed.
+func (tsm *timeseriesMap) maybeGC(now time.Time) {
+	if now.Sub(tsm.lastGC) < tsm.gcInterval {
+		return
+	}
+	for sig, tsi := range tsm.states {
+		if !tsi.mark {
+			delete(tsm.states, sig)
+		} else {
+			tsi.mark = false
+		}
+	}
+	tsm.lastGC = now
+}
+
+// timestampBefore returns true if a is strictly before b. Nil timestamps are
+// never before or after any other timestamp.
+func timestampBefore(a, b *timestamp.Timestamp) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	return a.Seconds < b.Seconds || (a.Seconds == b.Seconds && a.Nanos < b.Nanos)
+}
+
+// timestampChanged returns true if both timestamps are set and differ.
+func timestampChanged(a, b *timestamp.Timestamp) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	return a.Seconds != b.Seconds || a.Nanos != b.Nanos
+}
@@ -0,0 +1,32 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package downsamplingprocessor
+
+import (
+	"time"
+
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+)
+
+// ConfigV2 defines configuration for the downsampling processor.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// Window is the interval over which the points of each timeseries are
+	// aggregated into a single point.
+	Window time.Duration `mapstructure:"window"`
+	// GaugeAggregation is the aggregation applied to the points of int64 and
+	// double gauges, one of "last", "min", "max" or "avg".
+	GaugeAggregation Aggregation `mapstructure:"gauge_aggregation"`
+}
@@ -0,0 +1,339 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package downsamplingprocessor contains a metrics processor that reduces the
+// resolution of metrics by aggregating the points received for each timeseries
+// during a time window into a single point.
+package downsamplingprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+	"github.com/golang/protobuf/ptypes/timestamp"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/processor"
+	"github.com/census-instrumentation/opencensus-service/processor/internal/signature"
+)
+
+// Aggregation selects how the points of a gauge received during a window are
+// combined into one point.
+type Aggregation string
+
+const (
+	// AggregationLast keeps the last point received.
+	AggregationLast Aggregation = "last"
+	// AggregationMin keeps the smallest value received.
+	AggregationMin Aggregation = "min"
+	// AggregationMax keeps the largest value received.
+	AggregationMax Aggregation = "max"
+	// AggregationAvg computes the average of the values received. The average
+	// of int64 gauges is truncated towards zero.
+	AggregationAvg Aggregation = "avg"
+)
+
+const defaultWindow = 60 * time.Second
+
+type downsamplingprocessor struct {
+	nextConsumer     consumer.MetricsConsumer
+	window           time.Duration
+	gaugeAggregation Aggregation
+	start            sync.Once
+	stopOnce         sync.Once
+	stopCh           chan struct{}
+	// done is closed once the goroutine flushing the windows, if started,
+	// exited.
+	done chan struct{}
+
+	// mu protects batches and order.
+	mu      sync.Mutex
+	batches map[string]*batchState
+	order   []*batchState
+}
+
+// batchState buffers the metrics received for a node and resource.
+type batchState struct {
+	node     *commonpb.Node
+	resource *resourcepb.Resource
+	metrics  map[string]*metricState
+	order    []*metricState
+}
+
+// metricState buffers the timeseries of a metric.
+type metricState struct {
+	descriptor *metricspb.MetricDescriptor
+	resource   *resourcepb.Resource
+	series     map[string]*seriesState
+	order      []*seriesState
+}
+
+// seriesState holds the aggregated point of a timeseries for the current
+// window.
+type seriesState struct {
+	start       *timestamp.Timestamp
+	labelValues []*metricspb.LabelValue
+	point       *metricspb.Point
+	// count and sum are used by AggregationAvg.
+	count int64
+	sum   float64
+}
+
+// Option represents options that can be applied to the downsampling processor.
+type Option func(*downsamplingprocessor) error
+
+// WithWindow returns an Option to configure the interval over which points are
+// aggregated.
+func WithWindow(window time.Duration) Option {
+	return func(dsp *downsamplingprocessor) error {
+		if window <= 0 {
+			return errors.New("window must be positive")
+		}
+		dsp.window = window
+		return nil
+	}
+}
+
+// WithGaugeAggregation returns an Option to configure the aggregation applied
+// to int64 and double gauges.
+func WithGaugeAggregation(aggregation Aggregation) Option {
+	return func(dsp *downsamplingprocessor) error {
+		switch aggregation {
+		case AggregationLast, AggregationMin, AggregationMax, AggregationAvg:
+			dsp.gaugeAggregation = aggregation
+			return nil
+		default:
+			return fmt.Errorf(
+				"unknown gauge aggregation %q, must be one of %q, %q, %q or %q",
+				aggregation, AggregationLast, AggregationMin, AggregationMax, AggregationAvg)
+		}
+	}
+}
+
+var _ processor.MetricsProcessor = (*downsamplingprocessor)(nil)
+
+// NewMetricsProcessor returns a processor.MetricsProcessor that buffers the
+// received points and, once per window, emits one point per timeseries:
+// int64 and double gauges are aggregated according to the configured gauge
+// aggregation, gauge distributions are merged, and cumulative metrics and
+// summaries keep their last point.
+func NewMetricsProcessor(nextConsumer consumer.MetricsConsumer, options ...Option) (processor.MetricsProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+
+	dsp := &downsamplingprocessor{
+		nextConsumer:     nextConsumer,
+		window:           defaultWindow,
+		gaugeAggregation: AggregationLast,
+		stopCh:           make(chan struct{}),
+		batches:          make(map[string]*batchState),
+	}
+	for _, opt := range options {
+		if err := opt(dsp); err != nil {
+			return nil, err
+		}
+	}
+	return dsp, nil
+}
+
+func (dsp *downsamplingprocessor) ConsumeMetricsData(ctx context.Context, md data.MetricsData) error {
+	dsp.start.Do(func() {
+		dsp.done = make(chan struct{})
+		go dsp.flushEveryWindow()
+	})
+
+	batchSig := signature.Batch(md.Node, md.Resource)
+
+	dsp.mu.Lock()
+	defer dsp.mu.Unlock()
+
+	batch, ok := dsp.batches[batchSig]
+	if !ok {
+		batch = &batchState{
+			node:     md.Node,
+			resource: md.Resource,
+			metrics:  make(map[string]*metricState),
+		}
+		dsp.batches[batchSig] = batch
+		dsp.order = append(dsp.order, batch)
+	}
+
+	for _, metric := range md.Metrics {
+		if metric == nil || metric.MetricDescriptor == nil {
+			continue
+		}
+		metricSig := signature.Timeseries(batchSig, metric, nil)
+		ms, ok := batch.metrics[metricSig]
+		if !ok {
+			ms = &metricState{
+				resource: metric.Resource,
+				series:   make(map[string]*seriesState),
+			}
+			batch.metrics[metricSig] = ms
+			batch.order = append(batch.order, ms)
+		} else if ms.descriptor.Type != metric.MetricDescriptor.Type {
+			// The points received before the metric changed its type can't be
+			// aggregated with the new ones, drop them.
+			ms.series = make(map[string]*seriesState)
+			ms.order = nil
+		}
+		ms.descriptor = metric.MetricDescriptor
+
+		for _, ts := range metric.Timeseries {
+			if ts == nil {
+				continue
+			}
+			sig := signature.Timeseries(batchSig, metric, ts.LabelValues)
+			ss, ok := ms.series[sig]
+			if !ok {
+				ss = &seriesState{labelValues: ts.LabelValues}
+				ms.series[sig] = ss
+				ms.order = append(ms.order, ss)
+			}
+			for _, point := range ts.Points {
+				if point == nil || point.Value == nil {
+					continue
+				}
+				ss.start = ts.StartTimestamp
+				dsp.aggregate(ms.descriptor.Type, ss, point)
+			}
+		}
+	}
+	return nil
+}
+
+// flushEveryWindow flushes the aggregated points once per window until the
+// processor is stopped.
+func (dsp *downsamplingprocessor) flushEveryWindow() {
+	defer close(dsp.done)
+	ticker := time.NewTicker(dsp.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			dsp.flush()
+		case <-dsp.stopCh:
+			return
+		}
+	}
+}
+
+// stop stops flushing the windows and sends the points aggregated during the
+// current one to the next consumer.
+func (dsp *downsamplingprocessor) stop() {
+	dsp.stopOnce.Do(func() {
+		// Keep the goroutine from being started after the processor stopped.
+		dsp.start.Do(func() {})
+		close(dsp.stopCh)
+		if dsp.done != nil {
+			<-dsp.done
+		}
+		dsp.flush()
+	})
+}
+
+// flush sends the points aggregated during the current window to the next
+// consumer, one MetricsData per node and resource, and starts a new window.
+func (dsp *downsamplingprocessor) flush() {
+	dsp.mu.Lock()
+	order := dsp.order
+	dsp.batches = make(map[string]*batchState)
+	dsp.order = nil
+	dsp.mu.Unlock()
+
+	for _, batch := range order {
+		metrics := make([]*metricspb.Metric, 0, len(batch.order))
+		for _, ms := range batch.order {
+			tss := make([]*metricspb.TimeSeries, 0, len(ms.order))
+			for _, ss := range ms.order {
+				if ss.point == nil {
+					continue
+				}
+				tss = append(tss, &metricspb.TimeSeries{
+					StartTimestamp: ss.start,
+					LabelValues:    ss.labelValues,
+					Points:         []*metricspb.Point{ss.point},
+				})
+			}
+			if len(tss) == 0 {
+				continue
+			}
+			metrics = append(metrics, &metricspb.Metric{
+				MetricDescriptor: ms.descriptor,
+				Resource:         ms.resource,
+				Timeseries:       tss,
+			})
+		}
+		if len(metrics) == 0 {
+			continue
+		}
+		// Errors are not propagated to a caller, the next consumer is
+		// responsible for handling its own failures.
+		_ = dsp.nextConsumer.ConsumeMetricsData(context.Background(), data.MetricsData{
+			Node:     batch.node,
+			Resource: batch.resource,
+			Metrics:  metrics,
+		})
+	}
+}
+
+// aggregate combines point into the state of its timeseries according to the
+// metric type.
+func (dsp *downsamplingprocessor) aggregate(
+	metricType metricspb.MetricDescriptor_Type,
+	ss *seriesState,
+	point *metricspb.Point,
+) {
+	previous := ss.point
+	switch metricType {
+	case metricspb.MetricDescriptor_GAUGE_INT64, metricspb.MetricDescriptor_GAUGE_DOUBLE:
+		value, ok := numericValue(point)
+		if !ok {
+			return
+		}
+		if previous == nil || incompatible(point, previous) {
+			ss.point = point
+			ss.count = 1
+			ss.sum = value
+			return
+		}
+		ss.count++
+		ss.sum += value
+		ss.point = aggregateGauge(dsp.gaugeAggregation, previous, point, ss.sum/float64(ss.count))
+	case metricspb.MetricDescriptor_GAUGE_DISTRIBUTION:
+		if previous == nil || incompatible(point, previous) {
+			ss.point = point
+			return
+		}
+		ss.point = &metricspb.Point{
+			Timestamp: point.Timestamp,
+			Value: &metricspb.Point_DistributionValue{
+				DistributionValue: mergeDistributions(
+					previous.GetDistributionValue(), point.GetDistributionValue()),
+			},
+		}
+	default:
+		// Cumulative metrics and summaries already carry the whole history of
+		// the timeseries in their last point.
+		ss.point = point
+	}
+}
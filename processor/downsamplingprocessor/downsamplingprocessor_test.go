@@ -0,0 +1,276 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package downsamplingprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+)
+
+func TestNewMetricsProcessor(t *testing.T) {
+	_, err := NewMetricsProcessor(nil)
+	assert.Error(t, err)
+
+	_, err = NewMetricsProcessor(exportertest.NewNopMetricsExporter(), WithWindow(0))
+	assert.Error(t, err)
+
+	_, err = NewMetricsProcessor(exportertest.NewNopMetricsExporter(), WithGaugeAggregation("median"))
+	assert.Error(t, err)
+
+	mp, err := NewMetricsProcessor(exportertest.NewNopMetricsExporter(), WithGaugeAggregation(AggregationAvg))
+	assert.NoError(t, err)
+	assert.NotNil(t, mp)
+}
+
+func TestGaugeAggregation(t *testing.T) {
+	tests := []struct {
+		aggregation Aggregation
+		points      []*metricspb.Point
+		want        *metricspb.Point
+	}{
+		{AggregationLast, []*metricspb.Point{double(1, 3), double(2, 1), double(3, 5), double(4, 2)}, double(4, 2)},
+		{AggregationMin, []*metricspb.Point{double(1, 3), double(2, 1), double(3, 5), double(4, 2)}, double(4, 1)},
+		{AggregationMax, []*metricspb.Point{double(1, 3), double(2, 1), double(3, 5), double(4, 2)}, double(4, 5)},
+		{AggregationAvg, []*metricspb.Point{double(1, 3), double(2, 1), double(3, 5), double(4, 2)}, double(4, 2.75)},
+		{AggregationMin, []*metricspb.Point{int64Point(1, 7), int64Point(2, 4), int64Point(3, 9)}, int64Point(3, 4)},
+		{AggregationMax, []*metricspb.Point{int64Point(1, 7), int64Point(2, 4), int64Point(3, 9)}, int64Point(3, 9)},
+		{AggregationAvg, []*metricspb.Point{int64Point(1, 1), int64Point(2, 2)}, int64Point(2, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.aggregation), func(t *testing.T) {
+			sink := &exportertest.SinkMetricsExporter{}
+			dsp := newTestProcessor(t, sink, WithGaugeAggregation(tt.aggregation))
+
+			ty := metricspb.MetricDescriptor_GAUGE_DOUBLE
+			if _, ok := tt.points[0].Value.(*metricspb.Point_Int64Value); ok {
+				ty = metricspb.MetricDescriptor_GAUGE_INT64
+			}
+			// Send the points in separate requests, like a frequent scrape would.
+			for _, point := range tt.points {
+				consume(t, dsp, nil, metric(ty, timeseries(nil, point)))
+			}
+			assert.Equal(t, 0, len(sink.AllMetrics()), "points must be buffered until the window ends")
+
+			dsp.flush()
+			got := sink.AllMetrics()
+			require.Equal(t, 1, len(got))
+			assertMetricsEqual(t, []*metricspb.Metric{metric(ty, timeseries(nil, tt.want))}, got[0].Metrics)
+		})
+	}
+}
+
+func TestCumulativeAndSummaryKeepLast(t *testing.T) {
+	sink := &exportertest.SinkMetricsExporter{}
+	dsp := newTestProcessor(t, sink, WithGaugeAggregation(AggregationMax))
+
+	cumulative := metricspb.MetricDescriptor_CUMULATIVE_DOUBLE
+	summary := metricspb.MetricDescriptor_SUMMARY
+	consume(t, dsp, nil,
+		metric(cumulative, timeseries(toTS(1), double(2, 10))),
+		metric(summary, timeseries(toTS(1), summ(2, 4, 20))))
+	consume(t, dsp, nil,
+		metric(cumulative, timeseries(toTS(1), double(3, 15), double(4, 12))),
+		metric(summary, timeseries(toTS(3), summ(4, 1, 2))))
+
+	dsp.flush()
+	got := sink.AllMetrics()
+	require.Equal(t, 1, len(got))
+	assertMetricsEqual(t, []*metricspb.Metric{
+		metric(cumulative, timeseries(toTS(1), double(4, 12))),
+		metric(summary, timeseries(toTS(3), summ(4, 1, 2))),
+	}, got[0].Metrics)
+}
+
+func TestDistributionMerge(t *testing.T) {
+	sink := &exportertest.SinkMetricsExporter{}
+	dsp := newTestProcessor(t, sink)
+
+	ty := metricspb.MetricDescriptor_GAUGE_DISTRIBUTION
+	// Values {2, 4} and {4, 6}, each with a sum of squared deviations of 2.
+	point1 := dist(1, bounds0, 2, 6, []int64{1, 1, 0, 0})
+	point1.GetDistributionValue().SumOfSquaredDeviation = 2
+	point2 := dist(2, bounds0, 2, 10, []int64{0, 1, 1, 0})
+	point2.GetDistributionValue().SumOfSquaredDeviation = 2
+	consume(t, dsp, nil, metric(ty, timeseries(nil, point1)))
+	consume(t, dsp, nil, metric(ty, timeseries(nil, point2)))
+
+	dsp.flush()
+	got := sink.AllMetrics()
+	require.Equal(t, 1, len(got))
+	// Merged values {2, 4, 4, 6} have a mean of 4, squared deviations 4 + 0 + 0 + 4.
+	want := dist(2, bounds0, 4, 16, []int64{1, 2, 1, 0})
+	want.GetDistributionValue().SumOfSquaredDeviation = 8
+	assertMetricsEqual(t, []*metricspb.Metric{metric(ty, timeseries(nil, want))}, got[0].Metrics)
+
+	// Points with a different bucket layout can't be merged, the newest one wins.
+	consume(t, dsp, nil, metric(ty, timeseries(nil, dist(3, bounds0, 1, 1, []int64{1, 0, 0, 0}))))
+	consume(t, dsp, nil, metric(ty, timeseries(nil, dist(4, bounds1, 1, 7, []int64{0, 0, 1, 0}))))
+
+	dsp.flush()
+	got = sink.AllMetrics()
+	require.Equal(t, 2, len(got))
+	assertMetricsEqual(t,
+		[]*metricspb.Metric{metric(ty, timeseries(nil, dist(4, bounds1, 1, 7, []int64{0, 0, 1, 0})))},
+		got[1].Metrics)
+}
+
+func TestFlushPerNode(t *testing.T) {
+	sink := &exportertest.SinkMetricsExporter{}
+	dsp := newTestProcessor(t, sink)
+
+	node1 := &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "svc1"}}
+	node2 := &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "svc2"}}
+	ty := metricspb.MetricDescriptor_GAUGE_DOUBLE
+	consume(t, dsp, node1, metric(ty, timeseries(nil, double(1, 1))))
+	consume(t, dsp, node2, metric(ty, timeseries(nil, double(1, 2))))
+	consume(t, dsp, node1, metric(ty, timeseries(nil, double(2, 3))))
+
+	dsp.flush()
+	got := sink.AllMetrics()
+	require.Equal(t, 2, len(got))
+	assert.True(t, proto.Equal(node1, got[0].Node))
+	assertMetricsEqual(t, []*metricspb.Metric{metric(ty, timeseries(nil, double(2, 3)))}, got[0].Metrics)
+	assert.True(t, proto.Equal(node2, got[1].Node))
+	assertMetricsEqual(t, []*metricspb.Metric{metric(ty, timeseries(nil, double(1, 2)))}, got[1].Metrics)
+
+	// Nothing was received during the new window.
+	dsp.flush()
+	assert.Equal(t, 2, len(sink.AllMetrics()))
+}
+
+func TestStopFlushesWindow(t *testing.T) {
+	sink := &exportertest.SinkMetricsExporter{}
+	dsp := newTestProcessor(t, sink)
+
+	ty := metricspb.MetricDescriptor_GAUGE_DOUBLE
+	consume(t, dsp, nil, metric(ty, timeseries(nil, double(1, 1))))
+
+	dsp.stop()
+	got := sink.AllMetrics()
+	require.Equal(t, 1, len(got))
+	assertMetricsEqual(t, []*metricspb.Metric{metric(ty, timeseries(nil, double(1, 1)))}, got[0].Metrics)
+
+	// Stopping again does not flush again.
+	dsp.stop()
+	assert.Equal(t, 1, len(sink.AllMetrics()))
+}
+
+func newTestProcessor(t *testing.T, sink *exportertest.SinkMetricsExporter, options ...Option) *downsamplingprocessor {
+	// Use a long window so the ticker never flushes during a test.
+	options = append([]Option{WithWindow(time.Hour)}, options...)
+	mp, err := NewMetricsProcessor(sink, options...)
+	require.NoError(t, err)
+	return mp.(*downsamplingprocessor)
+}
+
+func consume(t *testing.T, dsp *downsamplingprocessor, node *commonpb.Node, metrics ...*metricspb.Metric) {
+	err := dsp.ConsumeMetricsData(context.Background(), data.MetricsData{Node: node, Metrics: metrics})
+	require.NoError(t, err)
+}
+
+func assertMetricsEqual(t *testing.T, want, got []*metricspb.Metric) {
+	require.Equal(t, len(want), len(got))
+	for i := range want {
+		assert.True(t, proto.Equal(want[i], got[i]), "want %v, got %v", want[i], got[i])
+	}
+}
+
+var (
+	bounds0 = []float64{3, 5, 7}
+	bounds1 = []float64{1, 5, 10}
+)
+
+func metric(ty metricspb.MetricDescriptor_Type, timeseries ...*metricspb.TimeSeries) *metricspb.Metric {
+	return &metricspb.Metric{
+		MetricDescriptor: &metricspb.MetricDescriptor{
+			Name:      "test_metric_" + ty.String(),
+			Type:      ty,
+			LabelKeys: []*metricspb.LabelKey{{Key: "k1"}},
+		},
+		Timeseries: timeseries,
+	}
+}
+
+func timeseries(start *timestamp.Timestamp, points ...*metricspb.Point) *metricspb.TimeSeries {
+	return &metricspb.TimeSeries{
+		StartTimestamp: start,
+		LabelValues:    []*metricspb.LabelValue{{Value: "v1", HasValue: true}},
+		Points:         points,
+	}
+}
+
+func double(ts int64, value float64) *metricspb.Point {
+	return &metricspb.Point{Timestamp: toTS(ts), Value: &metricspb.Point_DoubleValue{DoubleValue: value}}
+}
+
+func int64Point(ts int64, value int64) *metricspb.Point {
+	return &metricspb.Point{Timestamp: toTS(ts), Value: &metricspb.Point_Int64Value{Int64Value: value}}
+}
+
+func dist(ts int64, bounds []float64, count int64, sum float64, counts []int64) *metricspb.Point {
+	buckets := make([]*metricspb.DistributionValue_Bucket, len(counts))
+	for i, bcount := range counts {
+		buckets[i] = &metricspb.DistributionValue_Bucket{Count: bcount}
+	}
+	return &metricspb.Point{
+		Timestamp: toTS(ts),
+		Value: &metricspb.Point_DistributionValue{
+			DistributionValue: &metricspb.DistributionValue{
+				BucketOptions: &metricspb.DistributionValue_BucketOptions{
+					Type: &metricspb.DistributionValue_BucketOptions_Explicit_{
+						Explicit: &metricspb.DistributionValue_BucketOptions_Explicit{
+							Bounds: bounds,
+						},
+					},
+				},
+				Count:   count,
+				Sum:     sum,
+				Buckets: buckets,
+			},
+		},
+	}
+}
+
+func summ(ts, count int64, sum float64) *metricspb.Point {
+	return &metricspb.Point{
+		Timestamp: toTS(ts),
+		Value: &metricspb.Point_SummaryValue{
+			SummaryValue: &metricspb.SummaryValue{
+				Count: &wrappers.Int64Value{Value: count},
+				Sum:   &wrappers.DoubleValue{Value: sum},
+			},
+		},
+	}
+}
+
+func toTS(timeAtMs int64) *timestamp.Timestamp {
+	secs, ns := timeAtMs/1e3, (timeAtMs%1e3)*1e6
+	return &timestamp.Timestamp{
+		Seconds: secs,
+		Nanos:   int32(ns),
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package downsamplingprocessor
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+	"github.com/census-instrumentation/opencensus-service/internal/factories"
+	"github.com/census-instrumentation/opencensus-service/processor"
+)
+
+var _ = factories.RegisterProcessorFactory(&processorFactory{})
+
+var _ factories.StoppableProcessorFactory = (*processorFactory)(nil)
+
+const (
+	// The value of "type" key in configuration.
+	typeStr = "downsampling"
+)
+
+// processorFactory is the factory for the downsampling processor. It keeps
+// the processors it created, so they flush their last window on shutdown.
+type processorFactory struct {
+	mu         sync.Mutex
+	processors map[configmodels.Processor][]*downsamplingprocessor
+}
+
+// Type gets the type of the config created by this factory.
+func (f *processorFactory) Type() string {
+	return typeStr
+}
+
+// CreateDefaultConfig creates the default configuration for the processor.
+func (f *processorFactory) CreateDefaultConfig() configmodels.Processor {
+	return &ConfigV2{
+		ProcessorSettings: configmodels.ProcessorSettings{
+			TypeVal: typeStr,
+		},
+		Window:           defaultWindow,
+		GaugeAggregation: AggregationLast,
+	}
+}
+
+// CreateTraceProcessor creates a trace processor based on this config.
+func (f *processorFactory) CreateTraceProcessor(
+	nextConsumer consumer.TraceConsumer,
+	cfg configmodels.Processor,
+) (processor.TraceProcessor, error) {
+	return nil, factories.ErrDataTypeIsNotSupported
+}
+
+// CreateMetricsProcessor creates a metrics processor based on this config.
+func (f *processorFactory) CreateMetricsProcessor(
+	nextConsumer consumer.MetricsConsumer,
+	cfg configmodels.Processor,
+) (processor.MetricsProcessor, error) {
+	oCfg := cfg.(*ConfigV2)
+	mp, err := NewMetricsProcessor(
+		nextConsumer,
+		WithWindow(oCfg.Window),
+		WithGaugeAggregation(oCfg.GaugeAggregation),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.processors == nil {
+		f.processors = make(map[configmodels.Processor][]*downsamplingprocessor)
+	}
+	f.processors[cfg] = append(f.processors[cfg], mp.(*downsamplingprocessor))
+	return mp, nil
+}
+
+// StopProcessor stops one of the processors created from cfg, flushing the
+// points it aggregated during its current window.
+func (f *processorFactory) StopProcessor(cfg configmodels.Processor) error {
+	f.mu.Lock()
+	dsps := f.processors[cfg]
+	if len(dsps) == 0 {
+		f.mu.Unlock()
+		return fmt.Errorf("no running %s processor created from this config", typeStr)
+	}
+	dsp := dsps[len(dsps)-1]
+	if len(dsps) == 1 {
+		delete(f.processors, cfg)
+	} else {
+		f.processors[cfg] = dsps[:len(dsps)-1]
+	}
+	f.mu.Unlock()
+
+	dsp.stop()
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package downsamplingprocessor
+
+import (
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+)
+
+// numericValue returns the value of an int64 or double point as a float64.
+func numericValue(point *metricspb.Point) (float64, bool) {
+	switch v := point.Value.(type) {
+	case *metricspb.Point_Int64Value:
+		return float64(v.Int64Value), true
+	case *metricspb.Point_DoubleValue:
+		return v.DoubleValue, true
+	default:
+		return 0, false
+	}
+}
+
+// incompatible returns true if current can't be aggregated with previous
+// because their values are of different types or, for distributions, have
+// different bucket layouts.
+func incompatible(current, previous *metricspb.Point) bool {
+	switch cur := current.Value.(type) {
+	case *metricspb.Point_Int64Value:
+		_, ok := previous.Value.(*metricspb.Point_Int64Value)
+		return !ok
+	case *metricspb.Point_DoubleValue:
+		_, ok := previous.Value.(*metricspb.Point_DoubleValue)
+		return !ok
+	case *metricspb.Point_DistributionValue:
+		prev, ok := previous.Value.(*metricspb.Point_DistributionValue)
+		return !ok ||
+			len(cur.DistributionValue.Buckets) != len(prev.DistributionValue.Buckets) ||
+			!proto.Equal(cur.DistributionValue.BucketOptions, prev.DistributionValue.BucketOptions)
+	default:
+		return true
+	}
+}
+
+// lessThan returns true if the value of a is smaller than the value of b,
+// both points must hold int64 or double values.
+func lessThan(a, b *metricspb.Point) bool {
+	if ai, ok := a.Value.(*metricspb.Point_Int64Value); ok {
+		if bi, ok := b.Value.(*metricspb.Point_Int64Value); ok {
+			return ai.Int64Value < bi.Int64Value
+		}
+	}
+	av, _ := numericValue(a)
+	bv, _ := numericValue(b)
+	return av < bv
+}
+
+// aggregateGauge combines the current point of a gauge with the aggregate of
+// the previous points of the window. The aggregated point always carries the
+// timestamp of the current point. avg is the average of all the values of the
+// window, including the current one.
+func aggregateGauge(aggregation Aggregation, previous, current *metricspb.Point, avg float64) *metricspb.Point {
+	switch aggregation {
+	case AggregationMin:
+		if lessThan(current, previous) {
+			return current
+		}
+		return withTimestamp(previous, current.Timestamp)
+	case AggregationMax:
+		if lessThan(previous, current) {
+			return current
+		}
+		return withTimestamp(previous, current.Timestamp)
+	case AggregationAvg:
+		if _, ok := current.Value.(*metricspb.Point_Int64Value); ok {
+			return &metricspb.Point{
+				Timestamp: current.Timestamp,
+				Value:     &metricspb.Point_Int64Value{Int64Value: int64(avg)},
+			}
+		}
+		return &metricspb.Point{
+			Timestamp: current.Timestamp,
+			Value:     &metricspb.Point_DoubleValue{DoubleValue: avg},
+		}
+	default:
+		return current
+	}
+}
+
+func withTimestamp(point *metricspb.Point, ts *timestamp.Timestamp) *metricspb.Point {
+	return &metricspb.Point{Timestamp: ts, Value: point.Value}
+}
+
+// mergeDistributions returns the distribution of the union of the values
+// recorded by a and b, which must have the same bucket layout. The exemplars
+// of b take precedence over the ones of a.
+func mergeDistributions(a, b *metricspb.DistributionValue) *metricspb.DistributionValue {
+	merged := &metricspb.DistributionValue{
+		Count:         a.Count + b.Count,
+		Sum:           a.Sum + b.Sum,
+		BucketOptions: b.BucketOptions,
+		Buckets:       make([]*metricspb.DistributionValue_Bucket, len(b.Buckets)),
+	}
+
+	// Combine the sums of squared deviations with the parallel algorithm of
+	// Chan et al.
+	merged.SumOfSquaredDeviation = a.SumOfSquaredDeviation + b.SumOfSquaredDeviation
+	if a.Count > 0 && b.Count > 0 {
+		na, nb := float64(a.Count), float64(b.Count)
+		delta := b.Sum/nb - a.Sum/na
+		merged.SumOfSquaredDeviation += delta * delta * na * nb / (na + nb)
+	}
+
+	for i, bucket := range b.Buckets {
+		exemplar := bucket.GetExemplar()
+		if exemplar == nil {
+			exemplar = a.Buckets[i].GetExemplar()
+		}
+		merged.Buckets[i] = &metricspb.DistributionValue_Bucket{
+			Count:    a.Buckets[i].GetCount() + bucket.GetCount(),
+			Exemplar: exemplar,
+		}
+	}
+	return merged
+}
@@ -0,0 +1,40 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geoipprocessor
+
+import (
+	"time"
+
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+)
+
+// ConfigV2 defines configuration for the GeoIP processor.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// Database is the path of the GeoLite2 or GeoIP2 City or Country
+	// database. It is reloaded when it changes, and must be replaced
+	// atomically, e.g. renamed over, since it is memory mapped.
+	Database string `mapstructure:"database"`
+	// ASNDatabase is the path of the GeoLite2 or GeoIP2 ASN database,
+	// reloaded like Database.
+	ASNDatabase string `mapstructure:"asn_database"`
+	// IPAttributes are the keys of the span attributes, or else of the Node
+	// attributes, holding the IP to look up. The first one found is used.
+	// DefaultIPAttributes are used if empty.
+	IPAttributes []string `mapstructure:"ip_attributes"`
+	// CheckInterval is how often the databases are checked for changes. Zero
+	// disables the reloading.
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+}
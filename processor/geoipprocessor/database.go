@@ -0,0 +1,88 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geoipprocessor
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// database is a MaxMind database that can be reloaded while being looked up.
+type database struct {
+	path string
+
+	mu      sync.RWMutex
+	reader  *maxminddb.Reader
+	modTime time.Time
+	size    int64
+}
+
+func openDatabase(path string) (*database, error) {
+	db := &database{path: path}
+	if _, err := db.reloadIfChanged(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// lookup decodes the record of the network of ip into result, which is left
+// as is if there is none.
+func (db *database) lookup(ip net.IP, result interface{}) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.reader.Lookup(ip, result)
+}
+
+// reloadIfChanged reopens the database if its file changed since it was
+// opened. The current database is kept if the new one can't be opened.
+func (db *database) reloadIfChanged() (bool, error) {
+	info, err := os.Stat(db.path)
+	if err != nil {
+		return false, err
+	}
+	db.mu.RLock()
+	unchanged := db.reader != nil && info.ModTime().Equal(db.modTime) && info.Size() == db.size
+	db.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	reader, err := maxminddb.Open(db.path)
+	if err != nil {
+		return false, err
+	}
+
+	db.mu.Lock()
+	old := db.reader
+	db.reader, db.modTime, db.size = reader, info.ModTime(), info.Size()
+	db.mu.Unlock()
+
+	// No lookup uses the old database anymore.
+	if old != nil {
+		old.Close()
+	}
+	return true, nil
+}
+
+// close closes the database, the lookups made afterwards fail.
+func (db *database) close() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.reader.Close()
+}
@@ -0,0 +1,223 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geoipprocessor
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testNetwork is a network of a test database and its record.
+type testNetwork struct {
+	cidr   string
+	record map[string]interface{}
+}
+
+// writeTestDatabase writes an IPv4 MaxMind database with the given networks,
+// which must not overlap, to path.
+func writeTestDatabase(t *testing.T, path, databaseType string, networks []testNetwork) {
+	var dataSection bytes.Buffer
+	// The records of the nodes of the search tree are 0 if empty, since the
+	// root node 0 can't be a child, n > 0 for the node n and -(offset+1) for
+	// the data at offset in the data section.
+	nodes := [][2]int{{0, 0}}
+	for _, network := range networks {
+		_, ipNet, err := net.ParseCIDR(network.cidr)
+		require.NoError(t, err)
+		ip := ipNet.IP.To4()
+		ones, _ := ipNet.Mask.Size()
+
+		offset := dataSection.Len()
+		encodeTestValue(&dataSection, network.record)
+
+		node := 0
+		for i := 0; i < ones; i++ {
+			bit := (ip[i/8] >> (7 - uint(i%8))) & 1
+			if i == ones-1 {
+				nodes[node][bit] = -(offset + 1)
+				break
+			}
+			if nodes[node][bit] == 0 {
+				nodes = append(nodes, [2]int{})
+				nodes[node][bit] = len(nodes) - 1
+			}
+			node = nodes[node][bit]
+		}
+	}
+
+	var buf bytes.Buffer
+	nodeCount := len(nodes)
+	for _, node := range nodes {
+		for _, record := range node {
+			value := record
+			switch {
+			case record == 0:
+				value = nodeCount
+			case record < 0:
+				value = nodeCount + 16 + (-record - 1)
+			}
+			buf.Write([]byte{byte(value >> 16), byte(value >> 8), byte(value)})
+		}
+	}
+	buf.Write(make([]byte, 16))
+	buf.Write(dataSection.Bytes())
+	buf.WriteString("\xab\xcd\xefMaxMind.com")
+	encodeTestValue(&buf, map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(time.Now().Unix()),
+		"database_type":               databaseType,
+		"description":                 map[string]interface{}{"en": "Test database"},
+		"ip_version":                  uint16(4),
+		"languages":                   []interface{}{"en"},
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+	})
+
+	// The database is replaced atomically, as it is memory mapped.
+	tmp := path + ".tmp"
+	require.NoError(t, ioutil.WriteFile(tmp, buf.Bytes(), 0600))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+// encodeTestValue appends value to buf in the MaxMind DB data format. Only
+// the types and sizes used by the tests are supported.
+func encodeTestValue(buf *bytes.Buffer, value interface{}) {
+	writeControl := func(typeNum, size int) {
+		sizeBits, extra := size, []byte(nil)
+		if size >= 29 {
+			sizeBits, extra = 29, []byte{byte(size - 29)}
+		}
+		if typeNum <= 7 {
+			buf.WriteByte(byte(typeNum<<5 | sizeBits))
+		} else {
+			buf.WriteByte(byte(sizeBits))
+			buf.WriteByte(byte(typeNum - 7))
+		}
+		buf.Write(extra)
+	}
+	writeUint := func(typeNum int, v uint64) {
+		var b []byte
+		for ; v > 0; v >>= 8 {
+			b = append([]byte{byte(v)}, b...)
+		}
+		writeControl(typeNum, len(b))
+		buf.Write(b)
+	}
+
+	switch v := value.(type) {
+	case string:
+		writeControl(2, len(v))
+		buf.WriteString(v)
+	case uint16:
+		writeUint(5, uint64(v))
+	case uint32:
+		writeUint(6, uint64(v))
+	case uint64:
+		writeUint(9, v)
+	case map[string]interface{}:
+		writeControl(7, len(v))
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			encodeTestValue(buf, key)
+			encodeTestValue(buf, v[key])
+		}
+	case []interface{}:
+		writeControl(11, len(v))
+		for _, elem := range v {
+			encodeTestValue(buf, elem)
+		}
+	default:
+		panic("unsupported type")
+	}
+}
+
+func cityNetwork(cidr, country, region, city string) testNetwork {
+	return testNetwork{
+		cidr: cidr,
+		record: map[string]interface{}{
+			"country":      map[string]interface{}{"iso_code": country},
+			"subdivisions": []interface{}{map[string]interface{}{"iso_code": region}},
+			"city":         map[string]interface{}{"names": map[string]interface{}{"en": city}},
+		},
+	}
+}
+
+func asnNetwork(cidr string, number uint32, organization string) testNetwork {
+	return testNetwork{
+		cidr: cidr,
+		record: map[string]interface{}{
+			"autonomous_system_number":       number,
+			"autonomous_system_organization": organization,
+		},
+	}
+}
+
+func TestDatabaseReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geoip")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "GeoLite2-City.mmdb")
+
+	writeTestDatabase(t, path, "GeoLite2-City", []testNetwork{
+		cityNetwork("81.2.69.0/24", "GB", "ENG", "London"),
+	})
+	db, err := openDatabase(path)
+	require.NoError(t, err)
+
+	var record cityRecord
+	require.NoError(t, db.lookup(net.ParseIP("81.2.69.142"), &record))
+	assert.Equal(t, "London", record.City.Names["en"])
+
+	reloaded, err := db.reloadIfChanged()
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+
+	writeTestDatabase(t, path, "GeoLite2-City", []testNetwork{
+		cityNetwork("81.2.69.0/24", "GB", "ENG", "Westminster"),
+		cityNetwork("2.125.160.0/24", "GB", "ENG", "Boxford"),
+	})
+	reloaded, err = db.reloadIfChanged()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+
+	record = cityRecord{}
+	require.NoError(t, db.lookup(net.ParseIP("81.2.69.142"), &record))
+	assert.Equal(t, "Westminster", record.City.Names["en"])
+
+	// An invalid database is not loaded.
+	require.NoError(t, ioutil.WriteFile(path+".tmp", []byte("not a database"), 0600))
+	require.NoError(t, os.Rename(path+".tmp", path))
+	reloaded, err = db.reloadIfChanged()
+	assert.Error(t, err)
+	assert.False(t, reloaded)
+
+	record = cityRecord{}
+	require.NoError(t, db.lookup(net.ParseIP("2.125.160.216"), &record))
+	assert.Equal(t, "Boxford", record.City.Names["en"])
+}
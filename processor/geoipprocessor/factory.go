@@ -0,0 +1,114 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geoipprocessor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+	"github.com/census-instrumentation/opencensus-service/internal/factories"
+	"github.com/census-instrumentation/opencensus-service/processor"
+)
+
+var _ = factories.RegisterProcessorFactory(&processorFactory{})
+
+var _ factories.StoppableProcessorFactory = (*processorFactory)(nil)
+
+const (
+	// The value of "type" key in configuration.
+	typeStr = "geoip"
+)
+
+// processorFactory is the factory for the GeoIP processor. It keeps the
+// processors it created, so their databases are closed on shutdown.
+type processorFactory struct {
+	mu         sync.Mutex
+	processors map[configmodels.Processor][]*geoipprocessor
+}
+
+// Type gets the type of the config created by this factory.
+func (f *processorFactory) Type() string {
+	return typeStr
+}
+
+// CreateDefaultConfig creates the default configuration for the processor.
+func (f *processorFactory) CreateDefaultConfig() configmodels.Processor {
+	return &ConfigV2{
+		ProcessorSettings: configmodels.ProcessorSettings{
+			TypeVal: typeStr,
+		},
+		CheckInterval: time.Minute,
+	}
+}
+
+// CreateTraceProcessor creates a trace processor based on this config.
+func (f *processorFactory) CreateTraceProcessor(
+	nextConsumer consumer.TraceConsumer,
+	cfg configmodels.Processor,
+) (processor.TraceProcessor, error) {
+	oCfg := cfg.(*ConfigV2)
+	opts := []Option{
+		WithDatabase(oCfg.Database),
+		WithASNDatabase(oCfg.ASNDatabase),
+		WithCheckInterval(oCfg.CheckInterval),
+	}
+	if len(oCfg.IPAttributes) > 0 {
+		opts = append(opts, WithIPAttributes(oCfg.IPAttributes...))
+	}
+	tp, err := NewTraceProcessor(nextConsumer, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.processors == nil {
+		f.processors = make(map[configmodels.Processor][]*geoipprocessor)
+	}
+	f.processors[cfg] = append(f.processors[cfg], tp.(*geoipprocessor))
+	return tp, nil
+}
+
+// CreateMetricsProcessor creates a metrics processor based on this config.
+func (f *processorFactory) CreateMetricsProcessor(
+	nextConsumer consumer.MetricsConsumer,
+	cfg configmodels.Processor,
+) (processor.MetricsProcessor, error) {
+	return nil, factories.ErrDataTypeIsNotSupported
+}
+
+// StopProcessor stops one of the processors created from cfg, closing its
+// databases.
+func (f *processorFactory) StopProcessor(cfg configmodels.Processor) error {
+	f.mu.Lock()
+	gps := f.processors[cfg]
+	if len(gps) == 0 {
+		f.mu.Unlock()
+		return fmt.Errorf("no running %s processor created from this config", typeStr)
+	}
+	gp := gps[len(gps)-1]
+	if len(gps) == 1 {
+		delete(f.processors, cfg)
+	} else {
+		f.processors[cfg] = gps[:len(gps)-1]
+	}
+	f.mu.Unlock()
+
+	gp.stop()
+	return nil
+}
@@ -0,0 +1,337 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package geoipprocessor contains a trace processor that adds the location
+// and autonomous system of the IPs found in the span attributes, e.g. of the
+// clients, looked up in local MaxMind databases.
+package geoipprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/processor"
+)
+
+// DefaultIPAttributes are the attributes the IP is read from by default,
+// including the ones the Zipkin receiver sets from the remote endpoint.
+var DefaultIPAttributes = []string{
+	"http.client_ip",
+	"peer.ipv4",
+	"peer.ipv6",
+	"zipkin.remoteEndpoint.ipv4",
+	"zipkin.remoteEndpoint.ipv6",
+}
+
+// Span attributes added by the processor.
+const (
+	AttributeCountry        = "geo.country.iso_code"
+	AttributeRegion         = "geo.region.iso_code"
+	AttributeCity           = "geo.city.name"
+	AttributeASNumber       = "geo.as.number"
+	AttributeASOrganization = "geo.as.organization"
+)
+
+// cityRecord holds the fields of the City and Country databases records used
+// by the processor.
+type cityRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// asnRecord holds the fields of the ASN database records.
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+type geoipprocessor struct {
+	nextConsumer  consumer.TraceConsumer
+	databasePath  string
+	asnPath       string
+	ipAttributes  []string
+	checkInterval time.Duration
+
+	city *database
+	asn  *database
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	// done is closed once the goroutine reloading the databases, if any,
+	// exited.
+	done chan struct{}
+}
+
+// Option represents options that can be applied to the GeoIP processor.
+type Option func(*geoipprocessor) error
+
+// WithDatabase returns an Option to look up the location of the IPs in the
+// City or Country database at the given path.
+func WithDatabase(path string) Option {
+	return func(gp *geoipprocessor) error {
+		gp.databasePath = path
+		return nil
+	}
+}
+
+// WithASNDatabase returns an Option to look up the autonomous system of the
+// IPs in the ASN database at the given path.
+func WithASNDatabase(path string) Option {
+	return func(gp *geoipprocessor) error {
+		gp.asnPath = path
+		return nil
+	}
+}
+
+// WithIPAttributes returns an Option to read the IP from the given span
+// attributes, or else Node attributes. The first one found is used.
+func WithIPAttributes(keys ...string) Option {
+	return func(gp *geoipprocessor) error {
+		gp.ipAttributes = keys
+		return nil
+	}
+}
+
+// WithCheckInterval returns an Option to check the databases for changes at
+// the given interval, and reload them. Zero disables the reloading.
+func WithCheckInterval(interval time.Duration) Option {
+	return func(gp *geoipprocessor) error {
+		if interval < 0 {
+			return fmt.Errorf("negative check interval %v", interval)
+		}
+		gp.checkInterval = interval
+		return nil
+	}
+}
+
+var _ processor.TraceProcessor = (*geoipprocessor)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that adds the location
+// and autonomous system of the IP of each span to its attributes. Attributes
+// the spans already have are kept.
+func NewTraceProcessor(nextConsumer consumer.TraceConsumer, options ...Option) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+
+	gp := &geoipprocessor{
+		nextConsumer: nextConsumer,
+		ipAttributes: DefaultIPAttributes,
+		stopCh:       make(chan struct{}),
+	}
+	for _, opt := range options {
+		if err := opt(gp); err != nil {
+			return nil, err
+		}
+	}
+	if gp.databasePath == "" && gp.asnPath == "" {
+		return nil, errors.New("no database to look up the IPs in")
+	}
+
+	var err error
+	if gp.databasePath != "" {
+		if gp.city, err = openDatabase(gp.databasePath); err != nil {
+			return nil, err
+		}
+	}
+	if gp.asnPath != "" {
+		if gp.asn, err = openDatabase(gp.asnPath); err != nil {
+			if gp.city != nil {
+				gp.city.close()
+			}
+			return nil, err
+		}
+	}
+
+	if gp.checkInterval > 0 {
+		gp.done = make(chan struct{})
+		go gp.reloadDatabases()
+	}
+	return gp, nil
+}
+
+func (gp *geoipprocessor) reloadDatabases() {
+	defer close(gp.done)
+	ticker := time.NewTicker(gp.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			gp.checkDatabases()
+		case <-gp.stopCh:
+			return
+		}
+	}
+}
+
+// stop stops reloading the databases and closes them. The spans consumed
+// afterwards are passed along without a location.
+func (gp *geoipprocessor) stop() {
+	gp.stopOnce.Do(func() {
+		close(gp.stopCh)
+		if gp.done != nil {
+			<-gp.done
+		}
+		for _, db := range []*database{gp.city, gp.asn} {
+			if db != nil {
+				db.close()
+			}
+		}
+	})
+}
+
+// checkDatabases reloads the databases that changed.
+func (gp *geoipprocessor) checkDatabases() {
+	for _, db := range []*database{gp.city, gp.asn} {
+		if db == nil {
+			continue
+		}
+		reloaded, err := db.reloadIfChanged()
+		recordReload(db.path, reloaded, err)
+	}
+}
+
+func (gp *geoipprocessor) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	// The IP of the Node, if any, is looked up once for all the spans.
+	var nodeLoc *location
+	nodeLooked := false
+
+	for _, span := range td.Spans {
+		if span == nil {
+			continue
+		}
+		var loc *location
+		if ip := gp.spanIP(span); ip != nil {
+			loc = gp.lookup(ip)
+		} else {
+			if !nodeLooked {
+				if ip := gp.nodeIP(td.Node); ip != nil {
+					nodeLoc = gp.lookup(ip)
+				}
+				nodeLooked = true
+			}
+			loc = nodeLoc
+		}
+		if loc != nil {
+			loc.addAttributes(span)
+		}
+	}
+	return gp.nextConsumer.ConsumeTraceData(ctx, td)
+}
+
+func (gp *geoipprocessor) spanIP(span *tracepb.Span) net.IP {
+	attrs := span.GetAttributes().GetAttributeMap()
+	for _, key := range gp.ipAttributes {
+		if ip := net.ParseIP(attrs[key].GetStringValue().GetValue()); ip != nil {
+			return ip
+		}
+	}
+	return nil
+}
+
+func (gp *geoipprocessor) nodeIP(node *commonpb.Node) net.IP {
+	attrs := node.GetAttributes()
+	for _, key := range gp.ipAttributes {
+		if ip := net.ParseIP(attrs[key]); ip != nil {
+			return ip
+		}
+	}
+	return nil
+}
+
+// location is what the databases have about an IP.
+type location struct {
+	country        string
+	region         string
+	city           string
+	asNumber       uint
+	asOrganization string
+}
+
+// lookup returns the location of ip, or nil for the IPs not in the databases,
+// e.g. private ones.
+func (gp *geoipprocessor) lookup(ip net.IP) *location {
+	var loc location
+	if gp.city != nil {
+		var record cityRecord
+		if err := gp.city.lookup(ip, &record); err == nil {
+			loc.country = record.Country.ISOCode
+			if len(record.Subdivisions) > 0 {
+				loc.region = record.Subdivisions[0].ISOCode
+			}
+			loc.city = record.City.Names["en"]
+		}
+	}
+	if gp.asn != nil {
+		var record asnRecord
+		if err := gp.asn.lookup(ip, &record); err == nil {
+			loc.asNumber = record.Number
+			loc.asOrganization = record.Organization
+		}
+	}
+	if loc == (location{}) {
+		return nil
+	}
+	return &loc
+}
+
+// addAttributes adds the location to the attributes of the span, keeping the
+// ones the span already has.
+func (loc *location) addAttributes(span *tracepb.Span) {
+	if span.Attributes == nil {
+		span.Attributes = &tracepb.Span_Attributes{}
+	}
+	if span.Attributes.AttributeMap == nil {
+		span.Attributes.AttributeMap = make(map[string]*tracepb.AttributeValue)
+	}
+	attrs := span.Attributes.AttributeMap
+	add := func(key string, value *tracepb.AttributeValue) {
+		if _, exists := attrs[key]; !exists {
+			attrs[key] = value
+		}
+	}
+	for key, value := range map[string]string{
+		AttributeCountry:        loc.country,
+		AttributeRegion:         loc.region,
+		AttributeCity:           loc.city,
+		AttributeASOrganization: loc.asOrganization,
+	} {
+		if value != "" {
+			add(key, &tracepb.AttributeValue{
+				Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: value}},
+			})
+		}
+	}
+	if loc.asNumber != 0 {
+		add(AttributeASNumber, &tracepb.AttributeValue{
+			Value: &tracepb.AttributeValue_IntValue{IntValue: int64(loc.asNumber)},
+		})
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geoipprocessor
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+)
+
+func stringValue(s string) *tracepb.AttributeValue {
+	return &tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: s}},
+	}
+}
+
+func intValue(i int64) *tracepb.AttributeValue {
+	return &tracepb.AttributeValue{Value: &tracepb.AttributeValue_IntValue{IntValue: i}}
+}
+
+// writeTestDatabases writes a City and an ASN test database to dir and
+// returns their paths.
+func writeTestDatabases(t *testing.T, dir string) (string, string) {
+	cityPath := filepath.Join(dir, "GeoLite2-City.mmdb")
+	writeTestDatabase(t, cityPath, "GeoLite2-City", []testNetwork{
+		cityNetwork("81.2.69.0/24", "GB", "ENG", "London"),
+		cityNetwork("216.160.83.0/24", "US", "WA", "Milton"),
+	})
+	asnPath := filepath.Join(dir, "GeoLite2-ASN.mmdb")
+	writeTestDatabase(t, asnPath, "GeoLite2-ASN", []testNetwork{
+		asnNetwork("81.2.69.0/24", 20712, "Andrews & Arnold Ltd"),
+		asnNetwork("1.128.0.0/11", 1221, "Telstra Pty Ltd"),
+	})
+	return cityPath, asnPath
+}
+
+func TestNewTraceProcessor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geoip")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cityPath, _ := writeTestDatabases(t, dir)
+
+	sink := &exportertest.SinkTraceExporter{}
+	_, err = NewTraceProcessor(nil, WithDatabase(cityPath))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink)
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithDatabase(filepath.Join(dir, "missing.mmdb")))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithDatabase(cityPath), WithCheckInterval(-1))
+	assert.Error(t, err)
+}
+
+func TestConsumeTraceData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geoip")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cityPath, asnPath := writeTestDatabases(t, dir)
+
+	tests := []struct {
+		name  string
+		node  *commonpb.Node
+		attrs map[string]*tracepb.AttributeValue
+		want  map[string]*tracepb.AttributeValue
+	}{
+		{
+			name:  "client_ip",
+			attrs: map[string]*tracepb.AttributeValue{"http.client_ip": stringValue("81.2.69.142")},
+			want: map[string]*tracepb.AttributeValue{
+				"http.client_ip":        stringValue("81.2.69.142"),
+				AttributeCountry:        stringValue("GB"),
+				AttributeRegion:         stringValue("ENG"),
+				AttributeCity:           stringValue("London"),
+				AttributeASNumber:       intValue(20712),
+				AttributeASOrganization: stringValue("Andrews & Arnold Ltd"),
+			},
+		},
+		{
+			name: "first_attribute_wins",
+			attrs: map[string]*tracepb.AttributeValue{
+				"http.client_ip": stringValue("216.160.83.56"),
+				"peer.ipv4":      stringValue("81.2.69.142"),
+			},
+			want: map[string]*tracepb.AttributeValue{
+				"http.client_ip": stringValue("216.160.83.56"),
+				"peer.ipv4":      stringValue("81.2.69.142"),
+				AttributeCountry: stringValue("US"),
+				AttributeRegion:  stringValue("WA"),
+				AttributeCity:    stringValue("Milton"),
+			},
+		},
+		{
+			name: "existing_attributes_kept",
+			attrs: map[string]*tracepb.AttributeValue{
+				"peer.ipv4":      stringValue("216.160.83.56"),
+				AttributeCountry: stringValue("CA"),
+			},
+			want: map[string]*tracepb.AttributeValue{
+				"peer.ipv4":      stringValue("216.160.83.56"),
+				AttributeCountry: stringValue("CA"),
+				AttributeRegion:  stringValue("WA"),
+				AttributeCity:    stringValue("Milton"),
+			},
+		},
+		{
+			name:  "private_ip",
+			attrs: map[string]*tracepb.AttributeValue{"peer.ipv4": stringValue("10.0.0.1")},
+			want:  map[string]*tracepb.AttributeValue{"peer.ipv4": stringValue("10.0.0.1")},
+		},
+		{
+			name:  "ipv6_not_in_database",
+			attrs: map[string]*tracepb.AttributeValue{"peer.ipv6": stringValue("2001:db8::1")},
+			want:  map[string]*tracepb.AttributeValue{"peer.ipv6": stringValue("2001:db8::1")},
+		},
+		{
+			name: "zipkin_remote_endpoint",
+			node: &commonpb.Node{Attributes: map[string]string{"zipkin.remoteEndpoint.ipv4": "1.128.0.1"}},
+			want: map[string]*tracepb.AttributeValue{
+				AttributeASNumber:       intValue(1221),
+				AttributeASOrganization: stringValue("Telstra Pty Ltd"),
+			},
+		},
+		{
+			name: "span_ip_before_node_ip",
+			node: &commonpb.Node{Attributes: map[string]string{"zipkin.remoteEndpoint.ipv4": "1.128.0.1"}},
+			attrs: map[string]*tracepb.AttributeValue{
+				"http.client_ip": stringValue("216.160.83.56"),
+			},
+			want: map[string]*tracepb.AttributeValue{
+				"http.client_ip": stringValue("216.160.83.56"),
+				AttributeCountry: stringValue("US"),
+				AttributeRegion:  stringValue("WA"),
+				AttributeCity:    stringValue("Milton"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &exportertest.SinkTraceExporter{}
+			gp, err := NewTraceProcessor(sink, WithDatabase(cityPath), WithASNDatabase(asnPath), WithCheckInterval(0))
+			require.NoError(t, err)
+
+			span := &tracepb.Span{}
+			if tt.attrs != nil {
+				span.Attributes = &tracepb.Span_Attributes{AttributeMap: tt.attrs}
+			}
+			td := data.TraceData{Node: tt.node, Spans: []*tracepb.Span{span}}
+			require.NoError(t, gp.ConsumeTraceData(context.Background(), td))
+
+			got := sink.AllTraces()
+			require.Len(t, got, 1)
+			assert.Equal(t, tt.want, got[0].Spans[0].GetAttributes().GetAttributeMap())
+		})
+	}
+}
+
+func TestConsumeTraceDataReloaded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geoip")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cityPath, _ := writeTestDatabases(t, dir)
+
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(sink, WithDatabase(cityPath), WithCheckInterval(0))
+	require.NoError(t, err)
+	gp := tp.(*geoipprocessor)
+
+	consume := func() string {
+		span := &tracepb.Span{Attributes: &tracepb.Span_Attributes{
+			AttributeMap: map[string]*tracepb.AttributeValue{"peer.ipv4": stringValue("81.2.69.142")},
+		}}
+		require.NoError(t, gp.ConsumeTraceData(context.Background(), data.TraceData{Spans: []*tracepb.Span{span}}))
+		return span.Attributes.AttributeMap[AttributeCity].GetStringValue().GetValue()
+	}
+	assert.Equal(t, "London", consume())
+
+	writeTestDatabase(t, cityPath, "GeoLite2-City", []testNetwork{
+		cityNetwork("81.2.69.0/24", "GB", "ENG", "Westminster"),
+	})
+	gp.checkDatabases()
+	assert.Equal(t, "Westminster", consume())
+}
+
+func TestStop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geoip")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cityPath, _ := writeTestDatabases(t, dir)
+
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(sink, WithDatabase(cityPath), WithCheckInterval(time.Millisecond))
+	require.NoError(t, err)
+	gp := tp.(*geoipprocessor)
+
+	gp.stop()
+	select {
+	case <-gp.done:
+	default:
+		t.Fatal("the databases are still being reloaded")
+	}
+
+	// The spans are still passed along, without a location.
+	span := &tracepb.Span{Attributes: &tracepb.Span_Attributes{
+		AttributeMap: map[string]*tracepb.AttributeValue{"peer.ipv4": stringValue("81.2.69.142")},
+	}}
+	require.NoError(t, gp.ConsumeTraceData(context.Background(), data.TraceData{Spans: []*tracepb.Span{span}}))
+	assert.Len(t, span.Attributes.AttributeMap, 1)
+	assert.Len(t, sink.AllTraces(), 1)
+
+	// Stopping again is a no-op.
+	gp.stop()
+}
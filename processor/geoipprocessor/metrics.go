@@ -0,0 +1,74 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geoipprocessor
+
+import (
+	"context"
+	"path/filepath"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/census-instrumentation/opencensus-service/internal/collector/telemetry"
+)
+
+// Variables related to metrics specific to the GeoIP processor.
+var (
+	tagDatabaseKey, _ = tag.NewKey("database")
+	tagResultKey, _   = tag.NewKey("result")
+
+	statReloads = stats.Int64("geoip_database_reloads", "Count of attempts to reload a changed database", stats.UnitDimensionless)
+)
+
+// Values of tagResultKey.
+const (
+	reloadSucceeded = "success"
+	reloadFailed    = "failure"
+)
+
+// recordReload records an attempt to reload the database at path, if it
+// changed or couldn't be checked.
+func recordReload(path string, reloaded bool, err error) {
+	result := reloadSucceeded
+	if err != nil {
+		result = reloadFailed
+	} else if !reloaded {
+		return
+	}
+	_ = stats.RecordWithTags(
+		context.Background(),
+		[]tag.Mutator{
+			tag.Upsert(tagDatabaseKey, filepath.Base(path)),
+			tag.Upsert(tagResultKey, result),
+		},
+		statReloads.M(1))
+}
+
+// MetricViews return the metrics views according to given telemetry level.
+func MetricViews(level telemetry.Level) []*view.View {
+	if level == telemetry.None {
+		return nil
+	}
+
+	reloadsView := &view.View{
+		Name:        statReloads.Name(),
+		Measure:     statReloads,
+		Description: statReloads.Description(),
+		TagKeys:     []tag.Key{tagDatabaseKey, tagResultKey},
+		Aggregation: view.Sum(),
+	}
+	return []*view.View{reloadsView}
+}
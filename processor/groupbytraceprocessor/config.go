@@ -0,0 +1,36 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groupbytraceprocessor
+
+import (
+	"time"
+
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+)
+
+// ConfigV2 defines configuration for the group-by-trace processor.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// WaitDuration is how long the spans of a trace are buffered, counting
+	// from the arrival of its first span, before the trace is emitted.
+	WaitDuration time.Duration `mapstructure:"wait_duration"`
+	// NumTraces is the maximum number of traces kept in memory. When the limit
+	// is reached the oldest trace is emitted before its wait duration elapses.
+	NumTraces int `mapstructure:"num_traces"`
+	// SingleBatch emits each trace as a single TraceData carrying the node and
+	// resource of the batch that contained its root span, instead of one
+	// TraceData per node and resource of the trace.
+	SingleBatch bool `mapstructure:"single_batch"`
+}
@@ -0,0 +1,338 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package groupbytraceprocessor contains a trace processor that buffers spans
+// until their traces are complete and then emits each trace as a whole, so the
+// processors following it can work on entire traces.
+package groupbytraceprocessor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"go.opencensus.io/stats"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/internal/collector/processor/idbatcher"
+	"github.com/census-instrumentation/opencensus-service/processor"
+	"github.com/census-instrumentation/opencensus-service/processor/internal/signature"
+)
+
+const (
+	defaultWaitDuration = 30 * time.Second
+	defaultNumTraces    = 50000
+
+	// tickInterval is the granularity of the wait duration.
+	tickInterval = time.Second
+)
+
+// traceState holds the spans received for a trace, in the batches they were
+// received in.
+type traceState struct {
+	id      string
+	batches []data.TraceData
+}
+
+type groupbytraceprocessor struct {
+	nextConsumer consumer.TraceConsumer
+	waitDuration time.Duration
+	numTraces    int
+	singleBatch  bool
+
+	start    sync.Once
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	// done is closed once the goroutine emitting the traces, if started,
+	// exited.
+	done    chan struct{}
+	batcher idbatcher.Batcher
+
+	// mu protects traces and evictionQueue.
+	mu     sync.Mutex
+	traces map[string]*traceState
+	// evictionQueue holds the traces in order of arrival to emit the oldest
+	// one when the limit of traces is reached. It can hold traces that were
+	// already emitted, they are skipped on eviction.
+	evictionQueue chan *traceState
+}
+
+// Option represents options that can be applied to the group-by-trace
+// processor.
+type Option func(*groupbytraceprocessor) error
+
+// WithWaitDuration returns an Option to configure how long the spans of a
+// trace are buffered before the trace is emitted. It has a granularity of one
+// second.
+func WithWaitDuration(waitDuration time.Duration) Option {
+	return func(gbt *groupbytraceprocessor) error {
+		if waitDuration < tickInterval {
+			return errors.New("wait duration must be at least one second")
+		}
+		gbt.waitDuration = waitDuration
+		return nil
+	}
+}
+
+// WithNumTraces returns an Option to configure the maximum number of traces
+// kept in memory.
+func WithNumTraces(numTraces int) Option {
+	return func(gbt *groupbytraceprocessor) error {
+		if numTraces <= 0 {
+			return errors.New("number of traces must be positive")
+		}
+		gbt.numTraces = numTraces
+		return nil
+	}
+}
+
+// WithSingleBatch returns an Option to emit each trace as a single TraceData.
+// The node and resource of the spans received in batches other than the one
+// with the root span of the trace are lost.
+func WithSingleBatch(singleBatch bool) Option {
+	return func(gbt *groupbytraceprocessor) error {
+		gbt.singleBatch = singleBatch
+		return nil
+	}
+}
+
+var _ processor.TraceProcessor = (*groupbytraceprocessor)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that groups spans by
+// trace and emits each trace once its wait duration elapsed.
+func NewTraceProcessor(nextConsumer consumer.TraceConsumer, options ...Option) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+
+	gbt := &groupbytraceprocessor{
+		nextConsumer: nextConsumer,
+		waitDuration: defaultWaitDuration,
+		numTraces:    defaultNumTraces,
+		stopCh:       make(chan struct{}),
+		traces:       make(map[string]*traceState),
+	}
+	for _, opt := range options {
+		if err := opt(gbt); err != nil {
+			return nil, err
+		}
+	}
+
+	numBatches := uint64(gbt.waitDuration / tickInterval)
+	batcher, err := idbatcher.New(numBatches, uint64(gbt.numTraces)/numBatches, 64)
+	if err != nil {
+		return nil, err
+	}
+	gbt.batcher = batcher
+	gbt.evictionQueue = make(chan *traceState, gbt.numTraces)
+	return gbt, nil
+}
+
+func (gbt *groupbytraceprocessor) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	gbt.start.Do(func() {
+		gbt.done = make(chan struct{})
+		go gbt.tickUntilStopped()
+	})
+
+	// Group spans per their trace ID to add them to each trace at once.
+	idToSpans := make(map[string][]*tracepb.Span)
+	var order []string
+	for _, span := range td.Spans {
+		if len(span.GetTraceId()) == 0 {
+			stats.Record(ctx, statSpansWithoutTraceID.M(1))
+			continue
+		}
+		id := string(span.TraceId)
+		if _, ok := idToSpans[id]; !ok {
+			order = append(order, id)
+		}
+		idToSpans[id] = append(idToSpans[id], span)
+	}
+
+	var evicted []*traceState
+	var newTraces int64
+	gbt.mu.Lock()
+	for _, id := range order {
+		trace, ok := gbt.traces[id]
+		if !ok {
+			trace = &traceState{id: id}
+			gbt.traces[id] = trace
+			gbt.batcher.AddToCurrentBatch(idbatcher.ID(id))
+			evicted = append(evicted, gbt.enqueueForEviction(trace)...)
+			newTraces++
+		}
+		batch := td
+		if len(idToSpans[id]) != len(td.Spans) {
+			batch = data.TraceData{
+				Node:         td.Node,
+				Resource:     td.Resource,
+				Spans:        idToSpans[id],
+				SourceFormat: td.SourceFormat,
+			}
+		}
+		trace.batches = append(trace.batches, batch)
+	}
+	gbt.mu.Unlock()
+
+	stats.Record(ctx, statNewTraces.M(newTraces), statTracesEvicted.M(int64(len(evicted))))
+	for _, trace := range evicted {
+		gbt.emit(trace)
+	}
+	return nil
+}
+
+// enqueueForEviction adds trace to the eviction queue and returns the traces
+// removed from memory to make room for it. Must be called with mu held.
+func (gbt *groupbytraceprocessor) enqueueForEviction(trace *traceState) []*traceState {
+	var evicted []*traceState
+	for {
+		select {
+		case gbt.evictionQueue <- trace:
+			return evicted
+		default:
+			oldest := <-gbt.evictionQueue
+			if gbt.traces[oldest.id] == oldest {
+				delete(gbt.traces, oldest.id)
+				evicted = append(evicted, oldest)
+			}
+		}
+	}
+}
+
+// tickUntilStopped emits the traces whose wait duration elapsed every tick,
+// until the processor is stopped.
+func (gbt *groupbytraceprocessor) tickUntilStopped() {
+	defer close(gbt.done)
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			gbt.onTick()
+		case <-gbt.stopCh:
+			return
+		}
+	}
+}
+
+// stop stops emitting the traces on ticks and emits all the traces still
+// waiting, in order of arrival.
+func (gbt *groupbytraceprocessor) stop() {
+	gbt.stopOnce.Do(func() {
+		// Keep the goroutine from being started after the processor stopped.
+		gbt.start.Do(func() {})
+		close(gbt.stopCh)
+		if gbt.done != nil {
+			<-gbt.done
+		}
+
+		var pending []*traceState
+		gbt.mu.Lock()
+		for len(gbt.evictionQueue) > 0 {
+			trace := <-gbt.evictionQueue
+			if gbt.traces[trace.id] == trace {
+				delete(gbt.traces, trace.id)
+				pending = append(pending, trace)
+			}
+		}
+		gbt.mu.Unlock()
+
+		for _, trace := range pending {
+			gbt.emit(trace)
+		}
+	})
+}
+
+// onTick emits the traces whose wait duration elapsed.
+func (gbt *groupbytraceprocessor) onTick() {
+	batch, _ := gbt.batcher.CloseCurrentAndTakeFirstBatch()
+
+	var ready []*traceState
+	gbt.mu.Lock()
+	for _, id := range batch {
+		// The trace might have been evicted already.
+		if trace, ok := gbt.traces[string(id)]; ok {
+			delete(gbt.traces, string(id))
+			ready = append(ready, trace)
+		}
+	}
+	gbt.mu.Unlock()
+
+	for _, trace := range ready {
+		gbt.emit(trace)
+	}
+}
+
+// emit sends the spans of the trace to the next consumer.
+func (gbt *groupbytraceprocessor) emit(trace *traceState) {
+	ctx := context.Background()
+	stats.Record(ctx, statTracesEmitted.M(1))
+	if gbt.singleBatch {
+		_ = gbt.nextConsumer.ConsumeTraceData(ctx, mergeBatches(trace.batches))
+		return
+	}
+	for _, td := range groupByNode(trace.batches) {
+		_ = gbt.nextConsumer.ConsumeTraceData(ctx, td)
+	}
+}
+
+// groupByNode merges the batches that have the same node, resource and source
+// format, keeping the order in which they were received.
+func groupByNode(batches []data.TraceData) []data.TraceData {
+	if len(batches) == 1 {
+		return batches
+	}
+	var grouped []data.TraceData
+	indexes := make(map[string]int)
+	for _, td := range batches {
+		key := signature.Batch(td.Node, td.Resource) + td.SourceFormat
+		if i, ok := indexes[key]; ok {
+			grouped[i].Spans = append(grouped[i].Spans, td.Spans...)
+			continue
+		}
+		indexes[key] = len(grouped)
+		grouped = append(grouped, data.TraceData{
+			Node:         td.Node,
+			Resource:     td.Resource,
+			Spans:        append([]*tracepb.Span(nil), td.Spans...),
+			SourceFormat: td.SourceFormat,
+		})
+	}
+	return grouped
+}
+
+// mergeBatches returns a single TraceData with the spans of all the batches,
+// the node, resource and source format are taken from the batch with the root
+// span or, if the root span wasn't received, from the first batch.
+func mergeBatches(batches []data.TraceData) data.TraceData {
+	if len(batches) == 1 {
+		return batches[0]
+	}
+	merged := batches[0]
+	merged.Spans = nil
+	for _, td := range batches {
+		for _, span := range td.Spans {
+			if len(span.ParentSpanId) == 0 {
+				merged.Node = td.Node
+				merged.Resource = td.Resource
+				merged.SourceFormat = td.SourceFormat
+			}
+		}
+		merged.Spans = append(merged.Spans, td.Spans...)
+	}
+	return merged
+}
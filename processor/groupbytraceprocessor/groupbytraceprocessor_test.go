@@ -0,0 +1,163 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groupbytraceprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+)
+
+func TestNewTraceProcessor(t *testing.T) {
+	_, err := NewTraceProcessor(nil)
+	assert.Error(t, err)
+
+	_, err = NewTraceProcessor(exportertest.NewNopTraceExporter(), WithWaitDuration(time.Millisecond))
+	assert.Error(t, err)
+
+	_, err = NewTraceProcessor(exportertest.NewNopTraceExporter(), WithNumTraces(0))
+	assert.Error(t, err)
+
+	tp, err := NewTraceProcessor(exportertest.NewNopTraceExporter(), WithWaitDuration(5*time.Second), WithNumTraces(10))
+	assert.NoError(t, err)
+	assert.NotNil(t, tp)
+}
+
+func TestGroupByNode(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	gbt := newTestProcessor(t, sink)
+
+	node1 := &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "svc1"}}
+	node2 := &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "svc2"}}
+	consume(t, gbt, node1, span(1, 1, 0), span(2, 1, 0))
+	consume(t, gbt, node2, span(1, 2, 1))
+	consume(t, gbt, node1, span(1, 3, 2))
+	assert.Equal(t, 0, len(sink.AllTraces()), "traces must be buffered for the wait duration")
+
+	got := tickUntil(t, gbt, sink, 3)
+	assert.Equal(t, node1, got[0].Node)
+	assert.Equal(t, []*tracepb.Span{span(1, 1, 0), span(1, 3, 2)}, got[0].Spans)
+	assert.Equal(t, node2, got[1].Node)
+	assert.Equal(t, []*tracepb.Span{span(1, 2, 1)}, got[1].Spans)
+	assert.Equal(t, node1, got[2].Node)
+	assert.Equal(t, []*tracepb.Span{span(2, 1, 0)}, got[2].Spans)
+}
+
+func TestSingleBatch(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	gbt := newTestProcessor(t, sink, WithSingleBatch(true))
+
+	node1 := &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "svc1"}}
+	node2 := &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "svc2"}}
+	consume(t, gbt, node1, span(1, 2, 1))
+	consume(t, gbt, node2, span(1, 1, 0))
+
+	got := tickUntil(t, gbt, sink, 1)
+	assert.Equal(t, node2, got[0].Node, "node must be the one of the root span")
+	assert.Equal(t, []*tracepb.Span{span(1, 2, 1), span(1, 1, 0)}, got[0].Spans)
+}
+
+func TestNumTracesLimit(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	gbt := newTestProcessor(t, sink, WithNumTraces(2))
+
+	consume(t, gbt, nil, span(1, 1, 0))
+	consume(t, gbt, nil, span(2, 1, 0))
+	consume(t, gbt, nil, span(3, 1, 0))
+
+	got := sink.AllTraces()
+	require.Equal(t, 1, len(got), "oldest trace must be emitted when the limit is reached")
+	assert.Equal(t, []*tracepb.Span{span(1, 1, 0)}, got[0].Spans)
+
+	got = tickUntil(t, gbt, sink, 3)
+	assert.Equal(t, []*tracepb.Span{span(2, 1, 0)}, got[1].Spans)
+	assert.Equal(t, []*tracepb.Span{span(3, 1, 0)}, got[2].Spans)
+}
+
+func TestSpansWithoutTraceID(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	gbt := newTestProcessor(t, sink)
+
+	consume(t, gbt, nil, &tracepb.Span{SpanId: []byte{1}}, span(1, 1, 0))
+
+	got := tickUntil(t, gbt, sink, 1)
+	assert.Equal(t, []*tracepb.Span{span(1, 1, 0)}, got[0].Spans)
+}
+
+func TestStopEmitsWaitingTraces(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	gbt := newTestProcessor(t, sink)
+
+	consume(t, gbt, nil, span(1, 1, 0))
+	consume(t, gbt, nil, span(2, 1, 0), span(1, 2, 1))
+
+	gbt.stop()
+	got := sink.AllTraces()
+	require.Equal(t, 2, len(got))
+	assert.Equal(t, []*tracepb.Span{span(1, 1, 0), span(1, 2, 1)}, got[0].Spans)
+	assert.Equal(t, []*tracepb.Span{span(2, 1, 0)}, got[1].Spans)
+
+	// The emitted traces are not emitted again.
+	gbt.onTick()
+	gbt.stop()
+	assert.Equal(t, 2, len(sink.AllTraces()))
+}
+
+func newTestProcessor(t *testing.T, sink *exportertest.SinkTraceExporter, options ...Option) *groupbytraceprocessor {
+	options = append([]Option{WithWaitDuration(time.Second)}, options...)
+	tp, err := NewTraceProcessor(sink, options...)
+	require.NoError(t, err)
+	gbt := tp.(*groupbytraceprocessor)
+	// Prevent the ticker from starting, tests call onTick directly.
+	gbt.start.Do(func() {})
+	return gbt
+}
+
+func consume(t *testing.T, gbt *groupbytraceprocessor, node *commonpb.Node, spans ...*tracepb.Span) {
+	err := gbt.ConsumeTraceData(context.Background(), data.TraceData{Node: node, Spans: spans})
+	require.NoError(t, err)
+}
+
+// tickUntil calls onTick until the sink received the given number of batches.
+// Trace IDs are added asynchronously to the idbatcher, so it may take a few
+// ticks.
+func tickUntil(t *testing.T, gbt *groupbytraceprocessor, sink *exportertest.SinkTraceExporter, numBatches int) []data.TraceData {
+	for i := 0; i < 100 && len(sink.AllTraces()) < numBatches; i++ {
+		gbt.onTick()
+		time.Sleep(time.Millisecond)
+	}
+	got := sink.AllTraces()
+	require.Equal(t, numBatches, len(got))
+	return got
+}
+
+func span(traceID, spanID, parentID byte) *tracepb.Span {
+	s := &tracepb.Span{
+		TraceId: []byte{traceID, 15: 0},
+		SpanId:  []byte{spanID, 7: 0},
+	}
+	if parentID != 0 {
+		s.ParentSpanId = []byte{parentID, 7: 0}
+	}
+	return s
+}
@@ -0,0 +1,53 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groupbytraceprocessor
+
+import (
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+
+	"github.com/census-instrumentation/opencensus-service/internal/collector/telemetry"
+)
+
+// Variables related to metrics specific to the group-by-trace processor.
+var (
+	statNewTraces           = stats.Int64("groupbytrace_new_traces", "Count of new traces received", stats.UnitDimensionless)
+	statTracesEmitted       = stats.Int64("groupbytrace_traces_emitted", "Count of traces emitted to the next consumer", stats.UnitDimensionless)
+	statTracesEvicted       = stats.Int64("groupbytrace_traces_evicted", "Count of traces emitted before their wait duration elapsed to respect the limit of traces in memory", stats.UnitDimensionless)
+	statSpansWithoutTraceID = stats.Int64("groupbytrace_spans_without_trace_id", "Count of spans dropped since they had no trace ID", stats.UnitDimensionless)
+)
+
+// MetricViews return the metrics views according to given telemetry level.
+func MetricViews(level telemetry.Level) []*view.View {
+	if level == telemetry.None {
+		return nil
+	}
+
+	var views []*view.View
+	for _, measure := range []*stats.Int64Measure{
+		statNewTraces,
+		statTracesEmitted,
+		statTracesEvicted,
+		statSpansWithoutTraceID,
+	} {
+		views = append(views, &view.View{
+			Name:        measure.Name(),
+			Measure:     measure,
+			Description: measure.Description(),
+			Aggregation: view.Sum(),
+		})
+	}
+	return views
+}
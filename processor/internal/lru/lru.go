@@ -0,0 +1,86 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lru implements a fixed size cache that evicts the least recently
+// used entry when full, for processors that need to remember a bounded number
+// of items.
+package lru
+
+import (
+	"container/list"
+)
+
+// Cache is a LRU cache. It is not safe for concurrent use.
+type Cache struct {
+	maxEntries int
+	ll         *list.List
+	items      map[interface{}]*list.Element
+}
+
+type entry struct {
+	key   interface{}
+	value interface{}
+}
+
+// New creates a Cache holding at most maxEntries entries. If maxEntries is
+// not positive the cache has no limit.
+func New(maxEntries int) *Cache {
+	return &Cache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[interface{}]*list.Element),
+	}
+}
+
+// Add adds or replaces the value of key, making it the most recently used
+// entry. It returns true if another entry was evicted to make room for it.
+func (c *Cache) Add(key, value interface{}) bool {
+	if elem, ok := c.items[key]; ok {
+		c.ll.MoveToFront(elem)
+		elem.Value.(*entry).value = value
+		return false
+	}
+
+	c.items[key] = c.ll.PushFront(&entry{key: key, value: value})
+	if c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry).key)
+		return true
+	}
+	return false
+}
+
+// Get returns the value of key, making it the most recently used entry.
+func (c *Cache) Get(key interface{}) (interface{}, bool) {
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return elem.Value.(*entry).value, true
+}
+
+// Remove removes key from the cache.
+func (c *Cache) Remove(key interface{}) {
+	if elem, ok := c.items[key]; ok {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (c *Cache) Len() int {
+	return c.ll.Len()
+}
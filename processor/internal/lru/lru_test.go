@@ -0,0 +1,55 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lru
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	c := New(2)
+	assert.False(t, c.Add("a", 1))
+	assert.False(t, c.Add("b", 2))
+
+	// Getting "a" makes "b" the least recently used entry.
+	value, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	assert.True(t, c.Add("c", 3))
+	assert.Equal(t, 2, c.Len())
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+
+	assert.False(t, c.Add("a", 4))
+	value, _ = c.Get("a")
+	assert.Equal(t, 4, value)
+
+	c.Remove("a")
+	c.Remove("missing")
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestCache_Unbounded(t *testing.T) {
+	c := New(0)
+	for i := 0; i < 100; i++ {
+		assert.False(t, c.Add(i, i))
+	}
+	assert.Equal(t, 100, c.Len())
+}
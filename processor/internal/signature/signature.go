@@ -0,0 +1,69 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package signature computes the identity of metric timeseries for the
+// metrics processors that keep state per timeseries.
+package signature
+
+import (
+	"crypto/md5"
+	"strings"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+	"github.com/golang/protobuf/proto"
+)
+
+// Batch creates the part of the timeseries signature shared by all metrics
+// with the same node and resource.
+func Batch(node *commonpb.Node, resource *resourcepb.Resource) string {
+	h := md5.New()
+	if node != nil {
+		h.Write(marshalDeterministic(node))
+	}
+	if resource != nil {
+		h.Write(marshalDeterministic(resource))
+	}
+	return string(h.Sum(nil))
+}
+
+// Timeseries creates a unique signature for the timeseries consisting of the
+// batch signature, the metric resource and name, and the label values.
+func Timeseries(batchSig string, metric *metricspb.Metric, values []*metricspb.LabelValue) string {
+	var sb strings.Builder
+	sb.WriteString(batchSig)
+	if metric.Resource != nil {
+		sb.WriteString(Batch(nil, metric.Resource))
+	}
+	sb.WriteString(metric.GetMetricDescriptor().GetName())
+	for _, value := range values {
+		sb.WriteByte(0)
+		if value.GetHasValue() {
+			sb.WriteString(value.GetValue())
+		}
+	}
+	return sb.String()
+}
+
+// marshalDeterministic marshals msg with a stable ordering of map entries,
+// both Node and Resource carry maps.
+func marshalDeterministic(msg proto.Message) []byte {
+	var buf proto.Buffer
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(msg); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,83 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tokenbucket implements the token bucket algorithm used by the
+// processors that limit the rate of the data passing through them.
+package tokenbucket
+
+import (
+	"time"
+)
+
+// Bucket is refilled with tokens at a constant rate up to its capacity. It is
+// not safe for concurrent use.
+type Bucket struct {
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+// New creates a full Bucket that is refilled with rate tokens per second, up
+// to capacity tokens.
+func New(rate, capacity float64, now time.Time) *Bucket {
+	return &Bucket{
+		rate:     rate,
+		capacity: capacity,
+		tokens:   capacity,
+		last:     now,
+	}
+}
+
+// Take takes n tokens from the bucket if it is not empty. The bucket may go
+// into debt, so batches larger than its capacity are not rejected forever,
+// while the average rate is still enforced.
+func (b *Bucket) Take(n float64, now time.Time) bool {
+	b.refill(now)
+	if b.tokens <= 0 {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
+
+// Charge takes n tokens from the bucket even if it is empty, e.g. for the data
+// let through over the rate anyway, so it is paid back before more is taken.
+func (b *Bucket) Charge(n float64, now time.Time) {
+	b.refill(now)
+	b.tokens -= n
+}
+
+// RetryAfter returns how long until the bucket is not empty anymore.
+func (b *Bucket) RetryAfter(now time.Time) time.Duration {
+	b.refill(now)
+	if b.tokens > 0 {
+		return 0
+	}
+	if b.rate <= 0 {
+		return time.Duration(1<<63 - 1)
+	}
+	// Add a nanosecond so that, once elapsed, the bucket holds a token.
+	return time.Duration(-b.tokens/b.rate*float64(time.Second)) + time.Nanosecond
+}
+
+func (b *Bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+		b.last = now
+	}
+}
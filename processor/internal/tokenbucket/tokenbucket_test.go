@@ -0,0 +1,66 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenbucket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucket(t *testing.T) {
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := New(10, 20, now)
+
+	assert.True(t, b.Take(15, now))
+	assert.True(t, b.Take(15, now), "a non-empty bucket can go into debt")
+	assert.False(t, b.Take(1, now))
+	assert.Equal(t, time.Second+time.Nanosecond, b.RetryAfter(now))
+
+	now = now.Add(time.Second)
+	assert.False(t, b.Take(1, now), "the debt is just paid")
+
+	now = now.Add(100 * time.Millisecond)
+	assert.Equal(t, time.Duration(0), b.RetryAfter(now))
+	assert.True(t, b.Take(1, now))
+
+	// The bucket does not fill above its capacity.
+	now = now.Add(time.Hour)
+	assert.True(t, b.Take(20, now))
+	assert.False(t, b.Take(1, now))
+}
+
+func TestBucket_Charge(t *testing.T) {
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := New(10, 10, now)
+
+	assert.True(t, b.Take(10, now))
+	b.Charge(10, now)
+	assert.False(t, b.Take(1, now))
+	assert.Equal(t, time.Second+time.Nanosecond, b.RetryAfter(now))
+
+	now = now.Add(2 * time.Second)
+	b.Charge(5, now)
+	assert.True(t, b.Take(1, now))
+}
+
+func TestBucket_ZeroRate(t *testing.T) {
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := New(0, 1, now)
+	assert.True(t, b.Take(1, now))
+	assert.False(t, b.Take(1, now.Add(time.Hour)))
+	assert.True(t, b.RetryAfter(now) > 24*time.Hour)
+}
@@ -0,0 +1,65 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package traces holds helpers for the processors that work on entire traces,
+// such as the trace completeness and critical path processors.
+//
+// These processors expect each batch to hold entire traces, so they must
+// follow the groupbytrace processor with single_batch enabled. Since merging
+// the batches of a trace keeps only one Node, the service of each span is
+// read from its service.name attribute when present, which the attribute
+// promotion processor can copy from the Node before the traces are grouped.
+package traces
+
+import (
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+)
+
+// DefaultServiceAttribute is the span attribute the service of a span is read
+// from by default, the one the attribute promotion processor sets.
+const DefaultServiceAttribute = "service.name"
+
+// GroupByTraceID returns the spans grouped per their trace ID, keeping the
+// order of the traces. Spans without trace ID are left out.
+func GroupByTraceID(spans []*tracepb.Span) [][]*tracepb.Span {
+	idToIndex := make(map[string]int)
+	var traces [][]*tracepb.Span
+	for _, span := range spans {
+		if len(span.GetTraceId()) == 0 {
+			continue
+		}
+		id := string(span.TraceId)
+		i, ok := idToIndex[id]
+		if !ok {
+			i = len(traces)
+			idToIndex[id] = i
+			traces = append(traces, nil)
+		}
+		traces[i] = append(traces[i], span)
+	}
+	return traces
+}
+
+// SpanService returns the service of the span, read from its attribute with
+// the given key if any, or else nodeService, the service of the Node.
+func SpanService(span *tracepb.Span, attribute, nodeService string) string {
+	if attribute == "" {
+		return nodeService
+	}
+	value := span.GetAttributes().GetAttributeMap()[attribute]
+	if service := value.GetStringValue().GetValue(); service != "" {
+		return service
+	}
+	return nodeService
+}
@@ -0,0 +1,48 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traces
+
+import (
+	"testing"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupByTraceID(t *testing.T) {
+	a1 := &tracepb.Span{TraceId: []byte{2}, SpanId: []byte{1}}
+	b1 := &tracepb.Span{TraceId: []byte{1}, SpanId: []byte{1}}
+	a2 := &tracepb.Span{TraceId: []byte{2}, SpanId: []byte{2}}
+	noTraceID := &tracepb.Span{SpanId: []byte{3}}
+
+	got := GroupByTraceID([]*tracepb.Span{a1, b1, noTraceID, nil, a2})
+	assert.Equal(t, [][]*tracepb.Span{{a1, a2}, {b1}}, got)
+	assert.Empty(t, GroupByTraceID(nil))
+}
+
+func TestSpanService(t *testing.T) {
+	span := &tracepb.Span{
+		Attributes: &tracepb.Span_Attributes{AttributeMap: map[string]*tracepb.AttributeValue{
+			DefaultServiceAttribute: {
+				Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: "backend"}},
+			},
+		}},
+	}
+
+	assert.Equal(t, "backend", SpanService(span, DefaultServiceAttribute, "gateway"))
+	assert.Equal(t, "gateway", SpanService(span, "", "gateway"))
+	assert.Equal(t, "gateway", SpanService(span, "peer.service", "gateway"))
+	assert.Equal(t, "gateway", SpanService(&tracepb.Span{}, DefaultServiceAttribute, "gateway"))
+}
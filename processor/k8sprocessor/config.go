@@ -0,0 +1,37 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sprocessor
+
+import (
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+)
+
+// ConfigV2 defines configuration for the Kubernetes metadata processor.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// Namespace restricts the watched pods to the ones of a namespace. The
+	// pods of all the namespaces are watched by default.
+	Namespace string `mapstructure:"namespace"`
+	// NodeFromEnvVar is the environment variable holding the name of the node
+	// the collector runs on, e.g. set from spec.nodeName with the downward
+	// API. When set, only the pods of that node are watched, which suits
+	// collectors deployed as a DaemonSet.
+	NodeFromEnvVar string `mapstructure:"node_from_env_var"`
+	// Labels are the keys of the pod labels added to the Resource labels.
+	Labels []string `mapstructure:"labels"`
+	// Annotations are the keys of the pod annotations added to the Resource
+	// labels.
+	Annotations []string `mapstructure:"annotations"`
+}
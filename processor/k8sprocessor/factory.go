@@ -0,0 +1,153 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sprocessor
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+	"github.com/census-instrumentation/opencensus-service/internal/factories"
+	"github.com/census-instrumentation/opencensus-service/processor"
+)
+
+var _ = factories.RegisterProcessorFactory(&processorFactory{})
+
+var _ factories.StoppableProcessorFactory = (*processorFactory)(nil)
+
+const (
+	// The value of "type" key in configuration.
+	typeStr = "k8s"
+)
+
+// processorFactory is the factory for the Kubernetes metadata processor. The
+// processors created from the same config, e.g. by several pipelines, share
+// the enricher and its watch cache of the pods.
+type processorFactory struct {
+	mu        sync.Mutex
+	enrichers map[configmodels.Processor]*sharedEnricher
+}
+
+// sharedEnricher is an enricher with the number of processors using it.
+type sharedEnricher struct {
+	*enricher
+	refs int
+}
+
+// Type gets the type of the config created by this factory.
+func (f *processorFactory) Type() string {
+	return typeStr
+}
+
+// CreateDefaultConfig creates the default configuration for the processor.
+func (f *processorFactory) CreateDefaultConfig() configmodels.Processor {
+	return &ConfigV2{
+		ProcessorSettings: configmodels.ProcessorSettings{
+			TypeVal: typeStr,
+		},
+	}
+}
+
+// CreateTraceProcessor creates a trace processor based on this config.
+func (f *processorFactory) CreateTraceProcessor(
+	nextConsumer consumer.TraceConsumer,
+	cfg configmodels.Processor,
+) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errNilNextConsumer
+	}
+	e, err := f.acquireEnricher(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &traceProcessor{enricher: e, nextConsumer: nextConsumer}, nil
+}
+
+// CreateMetricsProcessor creates a metrics processor based on this config.
+func (f *processorFactory) CreateMetricsProcessor(
+	nextConsumer consumer.MetricsConsumer,
+	cfg configmodels.Processor,
+) (processor.MetricsProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errNilNextConsumer
+	}
+	e, err := f.acquireEnricher(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsProcessor{enricher: e, nextConsumer: nextConsumer}, nil
+}
+
+// StopProcessor stops the watch cache of the processors created from cfg once
+// all of them are stopped.
+func (f *processorFactory) StopProcessor(cfg configmodels.Processor) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	shared, ok := f.enrichers[cfg]
+	if !ok {
+		return fmt.Errorf("no running %s processor created from this config", typeStr)
+	}
+	shared.refs--
+	if shared.refs == 0 {
+		shared.pods.stop()
+		delete(f.enrichers, cfg)
+	}
+	return nil
+}
+
+// acquireEnricher returns the enricher of the processors created from cfg,
+// creating it for the first one.
+func (f *processorFactory) acquireEnricher(cfg configmodels.Processor) (*enricher, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if shared, ok := f.enrichers[cfg]; ok {
+		shared.refs++
+		return shared.enricher, nil
+	}
+
+	opts, err := optionsFromConfig(cfg.(*ConfigV2))
+	if err != nil {
+		return nil, err
+	}
+	e, err := newEnricher(opts)
+	if err != nil {
+		return nil, err
+	}
+	if f.enrichers == nil {
+		f.enrichers = make(map[configmodels.Processor]*sharedEnricher)
+	}
+	f.enrichers[cfg] = &sharedEnricher{enricher: e, refs: 1}
+	return e, nil
+}
+
+func optionsFromConfig(cfg *ConfigV2) ([]Option, error) {
+	opts := []Option{
+		WithNamespace(cfg.Namespace),
+		WithLabels(cfg.Labels...),
+		WithAnnotations(cfg.Annotations...),
+	}
+	if cfg.NodeFromEnvVar != "" {
+		nodeName := os.Getenv(cfg.NodeFromEnvVar)
+		if nodeName == "" {
+			return nil, fmt.Errorf("environment variable %q with the node name is empty", cfg.NodeFromEnvVar)
+		}
+		opts = append(opts, WithNodeName(nodeName))
+	}
+	return opts, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sprocessor
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+	"github.com/census-instrumentation/opencensus-service/internal/factories"
+)
+
+func TestMain(m *testing.M) {
+	// There is no cluster to create the default client for.
+	newClientset = func() (kubernetes.Interface, error) {
+		return fake.NewSimpleClientset(), nil
+	}
+	os.Exit(m.Run())
+}
+
+func TestCreateDefaultConfig(t *testing.T) {
+	factory := factories.GetProcessorFactory(typeStr)
+	require.NotNil(t, factory)
+
+	cfg := factory.CreateDefaultConfig()
+	assert.NotNil(t, cfg, "failed to create default config")
+}
+
+func TestCreateProcessor(t *testing.T) {
+	factory := factories.GetProcessorFactory(typeStr)
+	require.NotNil(t, factory)
+
+	cfg := factory.CreateDefaultConfig()
+
+	tp, err := factory.CreateTraceProcessor(exportertest.NewNopTraceExporter(), cfg)
+	assert.NotNil(t, tp)
+	assert.NoError(t, err, "cannot create trace processor")
+
+	mp, err := factory.CreateMetricsProcessor(exportertest.NewNopMetricsExporter(), cfg)
+	assert.NotNil(t, mp)
+	assert.NoError(t, err, "cannot create metrics processor")
+
+	stoppable := factory.(factories.StoppableProcessorFactory)
+	assert.NoError(t, stoppable.StopProcessor(cfg))
+	assert.NoError(t, stoppable.StopProcessor(cfg))
+}
+
+func TestCreateProcessorSharesWatchCache(t *testing.T) {
+	factory := factories.GetProcessorFactory(typeStr)
+	require.NotNil(t, factory)
+	stoppable := factory.(factories.StoppableProcessorFactory)
+
+	cfg := factory.CreateDefaultConfig()
+	tp, err := factory.CreateTraceProcessor(exportertest.NewNopTraceExporter(), cfg)
+	require.NoError(t, err)
+	mp, err := factory.CreateMetricsProcessor(exportertest.NewNopMetricsExporter(), cfg)
+	require.NoError(t, err)
+	pods := tp.(*traceProcessor).pods
+	assert.True(t, pods == mp.(*metricsProcessor).pods)
+
+	otherCfg := factory.CreateDefaultConfig()
+	otherTp, err := factory.CreateTraceProcessor(exportertest.NewNopTraceExporter(), otherCfg)
+	require.NoError(t, err)
+	assert.True(t, pods != otherTp.(*traceProcessor).pods)
+	require.NoError(t, stoppable.StopProcessor(otherCfg))
+
+	// The watch cache runs until the last processor using it is stopped.
+	require.NoError(t, stoppable.StopProcessor(cfg))
+	assert.False(t, isClosed(pods.stopCh))
+	require.NoError(t, stoppable.StopProcessor(cfg))
+	assert.True(t, isClosed(pods.stopCh))
+	assert.Error(t, stoppable.StopProcessor(cfg))
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestCreateProcessorNodeFromEnvVar(t *testing.T) {
+	factory := factories.GetProcessorFactory(typeStr)
+	require.NotNil(t, factory)
+
+	cfg := factory.CreateDefaultConfig().(*ConfigV2)
+	cfg.NodeFromEnvVar = "K8SPROCESSOR_TEST_NODE_NAME"
+
+	tp, err := factory.CreateTraceProcessor(exportertest.NewNopTraceExporter(), cfg)
+	assert.Nil(t, tp)
+	assert.Error(t, err)
+
+	os.Setenv(cfg.NodeFromEnvVar, "node-1")
+	defer os.Unsetenv(cfg.NodeFromEnvVar)
+	tp, err = factory.CreateTraceProcessor(exportertest.NewNopTraceExporter(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "node-1", tp.(*traceProcessor).nodeName)
+	assert.NoError(t, factory.(factories.StoppableProcessorFactory).StopProcessor(cfg))
+}
@@ -0,0 +1,264 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package k8sprocessor contains a processor that adds the metadata of the
+// Kubernetes pod that sent each batch, e.g. its namespace, name and
+// deployment, to the Resource labels of the batch.
+//
+// The pod is looked up in a watch cache of the pods, by the name in the
+// LabelPodName Resource label or Node attribute of the batch if any, or else
+// by the IP the batch was received from, as put in the context by the
+// receivers. The name is preferred since batches forwarded by another
+// collector are received from the IP of that collector.
+package k8sprocessor
+
+import (
+	"context"
+	"errors"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/census-instrumentation/opencensus-service/client"
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/processor"
+)
+
+// Resource labels added by the processor.
+const (
+	// ResourceType is the type of the Resource created for the batches
+	// without one.
+	ResourceType = "k8s"
+
+	LabelNamespaceName  = "k8s.namespace.name"
+	LabelPodName        = "k8s.pod.name"
+	LabelPodUID         = "k8s.pod.uid"
+	LabelDeploymentName = "k8s.deployment.name"
+	LabelNodeName       = "k8s.node.name"
+	// LabelPodLabelPrefix prefixes the keys of the selected pod labels.
+	LabelPodLabelPrefix = "k8s.pod.labels."
+	// LabelPodAnnotationPrefix prefixes the keys of the selected pod
+	// annotations.
+	LabelPodAnnotationPrefix = "k8s.pod.annotations."
+)
+
+var errNilNextConsumer = errors.New("nextConsumer is nil")
+
+// enricher holds the watch cache and settings shared by the trace and metrics
+// processors.
+type enricher struct {
+	client      kubernetes.Interface
+	namespace   string
+	nodeName    string
+	labels      []string
+	annotations []string
+	pods        *podCache
+}
+
+// Option represents options that can be applied to the Kubernetes metadata
+// processor.
+type Option func(*enricher) error
+
+// WithClient returns an Option to watch the pods with the given client. By
+// default the client is created from the service account of the pod the
+// collector runs in.
+func WithClient(client kubernetes.Interface) Option {
+	return func(e *enricher) error {
+		if client == nil {
+			return errors.New("kubernetes client is nil")
+		}
+		e.client = client
+		return nil
+	}
+}
+
+// WithNamespace returns an Option to only watch the pods of the given
+// namespace. An empty namespace watches all of them.
+func WithNamespace(namespace string) Option {
+	return func(e *enricher) error {
+		e.namespace = namespace
+		return nil
+	}
+}
+
+// WithNodeName returns an Option to only watch the pods of the given node.
+func WithNodeName(nodeName string) Option {
+	return func(e *enricher) error {
+		e.nodeName = nodeName
+		return nil
+	}
+}
+
+// WithLabels returns an Option to add the pod labels with the given keys to
+// the Resource labels, prefixed by LabelPodLabelPrefix.
+func WithLabels(keys ...string) Option {
+	return func(e *enricher) error {
+		e.labels = keys
+		return nil
+	}
+}
+
+// WithAnnotations returns an Option to add the pod annotations with the given
+// keys to the Resource labels, prefixed by LabelPodAnnotationPrefix.
+func WithAnnotations(keys ...string) Option {
+	return func(e *enricher) error {
+		e.annotations = keys
+		return nil
+	}
+}
+
+func newEnricher(options []Option) (*enricher, error) {
+	e := &enricher{}
+	for _, opt := range options {
+		if err := opt(e); err != nil {
+			return nil, err
+		}
+	}
+	if e.client == nil {
+		client, err := newClientset()
+		if err != nil {
+			return nil, err
+		}
+		e.client = client
+	}
+
+	pods, err := newPodCache(e.client, e.namespace, e.nodeName)
+	if err != nil {
+		return nil, err
+	}
+	e.pods = pods
+	return e, nil
+}
+
+// lookup returns the pod that sent the batch received with ctx, or nil.
+func (e *enricher) lookup(ctx context.Context, node *commonpb.Node, resource *resourcepb.Resource) *corev1.Pod {
+	name, namespace := resource.GetLabels()[LabelPodName], resource.GetLabels()[LabelNamespaceName]
+	if name == "" {
+		name, namespace = node.GetAttributes()[LabelPodName], node.GetAttributes()[LabelNamespaceName]
+	}
+	if name != "" {
+		pod := e.pods.byName(namespace, name)
+		recordLookup(ctx, node, pod, lookupByName)
+		return pod
+	}
+
+	if c, ok := client.FromContext(ctx); ok {
+		pod := e.pods.byIP(c.IP)
+		recordLookup(ctx, node, pod, lookupByIP)
+		return pod
+	}
+	recordLookup(ctx, node, nil, lookupNoKey)
+	return nil
+}
+
+// enrich returns the Resource with the metadata of the pod added. Labels the
+// Resource already has are kept.
+func (e *enricher) enrich(resource *resourcepb.Resource, pod *corev1.Pod) *resourcepb.Resource {
+	metadata := map[string]string{
+		LabelNamespaceName: pod.Namespace,
+		LabelPodName:       pod.Name,
+		LabelPodUID:        string(pod.UID),
+		LabelNodeName:      pod.Spec.NodeName,
+	}
+	if deployment := deploymentName(pod); deployment != "" {
+		metadata[LabelDeploymentName] = deployment
+	}
+	for _, key := range e.labels {
+		if value, ok := pod.Labels[key]; ok {
+			metadata[LabelPodLabelPrefix+key] = value
+		}
+	}
+	for _, key := range e.annotations {
+		if value, ok := pod.Annotations[key]; ok {
+			metadata[LabelPodAnnotationPrefix+key] = value
+		}
+	}
+
+	// The Resource may be shared with other batches, e.g. the ones received
+	// on the same stream, so a copy is changed.
+	enriched := &resourcepb.Resource{
+		Type:   resource.GetType(),
+		Labels: make(map[string]string, len(resource.GetLabels())+len(metadata)),
+	}
+	if enriched.Type == "" {
+		enriched.Type = ResourceType
+	}
+	for k, v := range metadata {
+		if v != "" {
+			enriched.Labels[k] = v
+		}
+	}
+	for k, v := range resource.GetLabels() {
+		enriched.Labels[k] = v
+	}
+	return enriched
+}
+
+type traceProcessor struct {
+	*enricher
+	nextConsumer consumer.TraceConsumer
+}
+
+var _ processor.TraceProcessor = (*traceProcessor)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that adds the metadata
+// of the pod that sent each batch of spans to its Resource labels.
+func NewTraceProcessor(nextConsumer consumer.TraceConsumer, options ...Option) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errNilNextConsumer
+	}
+	e, err := newEnricher(options)
+	if err != nil {
+		return nil, err
+	}
+	return &traceProcessor{enricher: e, nextConsumer: nextConsumer}, nil
+}
+
+func (tp *traceProcessor) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	if pod := tp.lookup(ctx, td.Node, td.Resource); pod != nil {
+		td.Resource = tp.enrich(td.Resource, pod)
+	}
+	return tp.nextConsumer.ConsumeTraceData(ctx, td)
+}
+
+type metricsProcessor struct {
+	*enricher
+	nextConsumer consumer.MetricsConsumer
+}
+
+var _ processor.MetricsProcessor = (*metricsProcessor)(nil)
+
+// NewMetricsProcessor returns a processor.MetricsProcessor that adds the
+// metadata of the pod that sent each batch of metrics to its Resource labels.
+// Metrics with their own Resource are left as is.
+func NewMetricsProcessor(nextConsumer consumer.MetricsConsumer, options ...Option) (processor.MetricsProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errNilNextConsumer
+	}
+	e, err := newEnricher(options)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsProcessor{enricher: e, nextConsumer: nextConsumer}, nil
+}
+
+func (mp *metricsProcessor) ConsumeMetricsData(ctx context.Context, md data.MetricsData) error {
+	if pod := mp.lookup(ctx, md.Node, md.Resource); pod != nil {
+		md.Resource = mp.enrich(md.Resource, pod)
+	}
+	return mp.nextConsumer.ConsumeMetricsData(ctx, md)
+}
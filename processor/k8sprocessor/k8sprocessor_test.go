@@ -0,0 +1,240 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/census-instrumentation/opencensus-service/client"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+)
+
+func newPod(namespace, name, ip string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			UID:       types.UID("uid-" + name),
+		},
+		Spec:   corev1.PodSpec{NodeName: "node-1"},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: ip},
+	}
+}
+
+func newSyncedTraceProcessor(t *testing.T, sink *exportertest.SinkTraceExporter, clientset *fake.Clientset, options ...Option) *traceProcessor {
+	tp, err := NewTraceProcessor(sink, append([]Option{WithClient(clientset)}, options...)...)
+	require.NoError(t, err)
+	kp := tp.(*traceProcessor)
+	require.Eventually(t, kp.pods.hasSynced, time.Second, 10*time.Millisecond)
+	return kp
+}
+
+func TestNewTraceProcessor(t *testing.T) {
+	_, err := NewTraceProcessor(nil)
+	assert.Error(t, err)
+
+	_, err = NewTraceProcessor(&exportertest.SinkTraceExporter{}, WithClient(nil))
+	assert.Error(t, err)
+}
+
+func TestLookup(t *testing.T) {
+	deployed := newPod("shop", "frontend-7d4b9c6f5-x2x8z", "10.0.0.1")
+	deployed.Labels = map[string]string{"app": "frontend", "pod-template-hash": "7d4b9c6f5"}
+	deployed.Annotations = map[string]string{"team": "checkout", "ignored": "value"}
+	deployed.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: "apps/v1",
+		Kind:       "ReplicaSet",
+		Name:       "frontend-7d4b9c6f5",
+		Controller: func() *bool { b := true; return &b }(),
+	}}
+	completed := newPod("shop", "migration", "10.0.0.2")
+	completed.Status.Phase = corev1.PodSucceeded
+	replacement := newPod("shop", "worker", "10.0.0.2")
+	hostNetwork := newPod("kube-system", "proxy", "10.0.0.3")
+	hostNetwork.Spec.HostNetwork = true
+	sameNameOther := newPod("other", "worker", "10.0.0.4")
+
+	clientset := fake.NewSimpleClientset(deployed, completed, replacement, hostNetwork, sameNameOther)
+	sink := &exportertest.SinkTraceExporter{}
+	kp := newSyncedTraceProcessor(t, sink, clientset, WithLabels("app", "missing"), WithAnnotations("team"))
+	defer kp.pods.stop()
+
+	tests := []struct {
+		name     string
+		clientIP string
+		node     *commonpb.Node
+		resource *resourcepb.Resource
+		want     *resourcepb.Resource
+	}{
+		{
+			name:     "by_ip",
+			clientIP: "10.0.0.1",
+			want: &resourcepb.Resource{
+				Type: ResourceType,
+				Labels: map[string]string{
+					LabelNamespaceName:                "shop",
+					LabelPodName:                      "frontend-7d4b9c6f5-x2x8z",
+					LabelPodUID:                       "uid-frontend-7d4b9c6f5-x2x8z",
+					LabelNodeName:                     "node-1",
+					LabelDeploymentName:               "frontend",
+					LabelPodLabelPrefix + "app":       "frontend",
+					LabelPodAnnotationPrefix + "team": "checkout",
+				},
+			},
+		},
+		{
+			name:     "by_ip_reused",
+			clientIP: "10.0.0.2",
+			want: &resourcepb.Resource{
+				Type: ResourceType,
+				Labels: map[string]string{
+					LabelNamespaceName: "shop",
+					LabelPodName:       "worker",
+					LabelPodUID:        "uid-worker",
+					LabelNodeName:      "node-1",
+				},
+			},
+		},
+		{
+			name:     "by_ip_host_network",
+			clientIP: "10.0.0.3",
+		},
+		{
+			name: "no_ip",
+		},
+		{
+			name:     "by_resource_name_and_namespace",
+			clientIP: "10.0.0.1",
+			resource: &resourcepb.Resource{
+				Type: "container",
+				Labels: map[string]string{
+					LabelPodName:       "worker",
+					LabelNamespaceName: "other",
+					LabelNodeName:      "node-from-app",
+				},
+			},
+			want: &resourcepb.Resource{
+				Type: "container",
+				Labels: map[string]string{
+					LabelNamespaceName: "other",
+					LabelPodName:       "worker",
+					LabelPodUID:        "uid-worker",
+					LabelNodeName:      "node-from-app",
+				},
+			},
+		},
+		{
+			name:     "by_ambiguous_name",
+			clientIP: "10.0.0.1",
+			resource: &resourcepb.Resource{Labels: map[string]string{LabelPodName: "worker"}},
+			want:     &resourcepb.Resource{Labels: map[string]string{LabelPodName: "worker"}},
+		},
+		{
+			name: "by_node_attribute",
+			node: &commonpb.Node{Attributes: map[string]string{LabelPodName: "migration"}},
+			want: &resourcepb.Resource{
+				Type: ResourceType,
+				Labels: map[string]string{
+					LabelNamespaceName: "shop",
+					LabelPodName:       "migration",
+					LabelPodUID:        "uid-migration",
+					LabelNodeName:      "node-1",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.clientIP != "" {
+				ctx = client.NewContext(ctx, &client.Client{IP: tt.clientIP})
+			}
+			td := data.TraceData{Node: tt.node, Resource: tt.resource}
+			require.NoError(t, kp.ConsumeTraceData(ctx, td))
+
+			got := sink.AllTraces()
+			require.NotEmpty(t, got)
+			assert.Equal(t, tt.want, got[len(got)-1].Resource)
+		})
+	}
+}
+
+func TestLookupDoesNotChangeReceivedResource(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newPod("shop", "frontend", "10.0.0.1"))
+	sink := &exportertest.SinkTraceExporter{}
+	kp := newSyncedTraceProcessor(t, sink, clientset)
+	defer kp.pods.stop()
+
+	resource := &resourcepb.Resource{Type: "container", Labels: map[string]string{"container.name": "app"}}
+	ctx := client.NewContext(context.Background(), &client.Client{IP: "10.0.0.1"})
+	require.NoError(t, kp.ConsumeTraceData(ctx, data.TraceData{Resource: resource}))
+
+	assert.Equal(t, map[string]string{"container.name": "app"}, resource.Labels)
+	got := sink.AllTraces()
+	require.Len(t, got, 1)
+	assert.Equal(t, "frontend", got[0].Resource.Labels[LabelPodName])
+	assert.Equal(t, "app", got[0].Resource.Labels["container.name"])
+}
+
+func TestLookupWatchesPods(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	sink := &exportertest.SinkTraceExporter{}
+	kp := newSyncedTraceProcessor(t, sink, clientset)
+	defer kp.pods.stop()
+
+	require.NoError(t, clientset.Tracker().Add(newPod("shop", "frontend", "10.0.0.1")))
+
+	ctx := client.NewContext(context.Background(), &client.Client{IP: "10.0.0.1"})
+	assert.Eventually(t, func() bool {
+		return kp.pods.byIP("10.0.0.1") != nil
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, kp.ConsumeTraceData(ctx, data.TraceData{}))
+	got := sink.AllTraces()
+	require.Len(t, got, 1)
+	assert.Equal(t, "frontend", got[0].Resource.GetLabels()[LabelPodName])
+
+	require.NoError(t, clientset.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), "shop", "frontend"))
+	assert.Eventually(t, func() bool {
+		return kp.pods.byIP("10.0.0.1") == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestMetricsProcessor(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newPod("shop", "frontend", "10.0.0.1"))
+	sink := &exportertest.SinkMetricsExporter{}
+	mp, err := NewMetricsProcessor(sink, WithClient(clientset))
+	require.NoError(t, err)
+	kp := mp.(*metricsProcessor)
+	defer kp.pods.stop()
+	require.Eventually(t, kp.pods.hasSynced, time.Second, 10*time.Millisecond)
+
+	ctx := client.NewContext(context.Background(), &client.Client{IP: "10.0.0.1"})
+	require.NoError(t, mp.ConsumeMetricsData(ctx, data.MetricsData{}))
+	got := sink.AllMetrics()
+	require.Len(t, got, 1)
+	assert.Equal(t, "frontend", got[0].Resource.GetLabels()[LabelPodName])
+}
@@ -0,0 +1,80 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sprocessor
+
+import (
+	"context"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/census-instrumentation/opencensus-service/internal/collector/processor"
+	"github.com/census-instrumentation/opencensus-service/internal/collector/telemetry"
+)
+
+// Variables related to metrics specific to the Kubernetes metadata processor.
+var (
+	tagLookupKey, _ = tag.NewKey("lookup")
+	tagFoundKey, _  = tag.NewKey("found")
+
+	statPodLookups = stats.Int64("k8s_pod_lookups", "Count of batches whose pod was looked up", stats.UnitDimensionless)
+)
+
+// Values of tagLookupKey, one per way of looking up the pod.
+const (
+	lookupByName = "name"
+	lookupByIP   = "ip"
+	// lookupNoKey is used for the batches without pod name nor client IP.
+	lookupNoKey = "none"
+)
+
+func recordLookup(ctx context.Context, node *commonpb.Node, pod *corev1.Pod, lookup string) {
+	found := "false"
+	if pod != nil {
+		found = "true"
+	}
+	_ = stats.RecordWithTags(
+		ctx,
+		[]tag.Mutator{
+			tag.Upsert(tagLookupKey, lookup),
+			tag.Upsert(tagFoundKey, found),
+			tag.Upsert(processor.TagServiceNameKey, processor.ServiceNameForNode(node)),
+		},
+		statPodLookups.M(1))
+}
+
+// MetricViews return the metrics views according to given telemetry level.
+func MetricViews(level telemetry.Level) []*view.View {
+	if level == telemetry.None {
+		return nil
+	}
+
+	tagKeys := []tag.Key{tagLookupKey, tagFoundKey}
+	if level == telemetry.Detailed {
+		tagKeys = append(tagKeys, processor.TagServiceNameKey)
+	}
+
+	lookupsView := &view.View{
+		Name:        statPodLookups.Name(),
+		Measure:     statPodLookups,
+		Description: statPodLookups.Description(),
+		TagKeys:     tagKeys,
+		Aggregation: view.Sum(),
+	}
+	return []*view.View{lookupsView}
+}
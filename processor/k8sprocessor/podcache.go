@@ -0,0 +1,164 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sprocessor
+
+import (
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+// newClientset creates the client used when none is given, from the service
+// account of the pod the collector runs in.
+var newClientset = func() (kubernetes.Interface, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+const (
+	// resyncPeriod is how often the watch cache is refreshed from scratch.
+	resyncPeriod = 5 * time.Minute
+
+	podIPIndex   = "ip"
+	podNameIndex = "name"
+)
+
+// podCache is a watch cache of the pods, indexed by IP and by name.
+type podCache struct {
+	informer cache.SharedIndexInformer
+	stopCh   chan struct{}
+}
+
+func newPodCache(client kubernetes.Interface, namespace, nodeName string) (*podCache, error) {
+	var opts []informers.SharedInformerOption
+	if namespace != "" {
+		opts = append(opts, informers.WithNamespace(namespace))
+	}
+	if nodeName != "" {
+		opts = append(opts, informers.WithTweakListOptions(func(lo *metav1.ListOptions) {
+			lo.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", nodeName).String()
+		}))
+	}
+
+	informer := informers.NewSharedInformerFactoryWithOptions(client, resyncPeriod, opts...).Core().V1().Pods().Informer()
+	err := informer.AddIndexers(cache.Indexers{
+		podIPIndex:   indexPodByIP,
+		podNameIndex: indexPodByName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pc := &podCache{
+		informer: informer,
+		stopCh:   make(chan struct{}),
+	}
+	go informer.Run(pc.stopCh)
+	return pc, nil
+}
+
+func indexPodByIP(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	// Pods on the host network have the IP of their node.
+	if !ok || pod.Spec.HostNetwork {
+		return nil, nil
+	}
+	if pod.Status.PodIP == "" {
+		return nil, nil
+	}
+	return []string{pod.Status.PodIP}, nil
+}
+
+func indexPodByName(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, nil
+	}
+	return []string{pod.Name}, nil
+}
+
+// byIP returns the pod with the given IP. The IP of a completed pod can be
+// reused by a running one, which is preferred.
+func (pc *podCache) byIP(ip string) *corev1.Pod {
+	objs, err := pc.informer.GetIndexer().ByIndex(podIPIndex, ip)
+	if err != nil {
+		return nil
+	}
+	var found *corev1.Pod
+	for _, obj := range objs {
+		pod := obj.(*corev1.Pod)
+		if found == nil || (isCompleted(found) && !isCompleted(pod)) {
+			found = pod
+		}
+	}
+	return found
+}
+
+// byName returns the pod with the given name, in the given namespace if it
+// isn't empty. Without namespace the name must be unique among the watched
+// pods.
+func (pc *podCache) byName(namespace, name string) *corev1.Pod {
+	if namespace != "" {
+		obj, exists, err := pc.informer.GetIndexer().GetByKey(namespace + "/" + name)
+		if err != nil || !exists {
+			return nil
+		}
+		return obj.(*corev1.Pod)
+	}
+
+	objs, err := pc.informer.GetIndexer().ByIndex(podNameIndex, name)
+	if err != nil || len(objs) != 1 {
+		return nil
+	}
+	return objs[0].(*corev1.Pod)
+}
+
+func (pc *podCache) hasSynced() bool {
+	return pc.informer.HasSynced()
+}
+
+func (pc *podCache) stop() {
+	close(pc.stopCh)
+}
+
+func isCompleted(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+// deploymentName returns the name of the deployment of the pod, derived from
+// the name of the ReplicaSet controlling it, or an empty string.
+func deploymentName(pod *corev1.Pod) string {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.Kind != "ReplicaSet" {
+		return ""
+	}
+	// The ReplicaSets of a deployment are named after it with the hash of
+	// the pod template as suffix.
+	hash := pod.Labels["pod-template-hash"]
+	if hash == "" || !strings.HasSuffix(owner.Name, "-"+hash) {
+		return ""
+	}
+	return strings.TrimSuffix(owner.Name, "-"+hash)
+}
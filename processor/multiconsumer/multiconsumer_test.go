@@ -17,11 +17,13 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
 	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
 	"github.com/census-instrumentation/opencensus-service/consumer"
 	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/tenancy"
 )
 
 func TestTraceProcessorMultiplexing(t *testing.T) {
@@ -87,6 +89,20 @@ func TestTraceProcessorWhenOneErrors(t *testing.T) {
 	}
 }
 
+func TestTraceProcessorWhenAllRejectRetryable(t *testing.T) {
+	limitErr := &tenancy.LimitExceededError{Tenant: "acme", RetryAfter: time.Second}
+	processors := []consumer.TraceConsumer{
+		&erroringTraceConsumer{err: limitErr},
+		&erroringTraceConsumer{err: limitErr},
+	}
+
+	tdp := NewTraceProcessor(processors)
+	err := tdp.ConsumeTraceData(context.Background(), data.TraceData{Spans: make([]*tracepb.Span, 5)})
+	if !tenancy.IsRetryable(err) {
+		t.Errorf("Wanted the retryable error of both processors got %v", err)
+	}
+}
+
 func TestMetricsProcessorMultiplexing(t *testing.T) {
 	processors := make([]consumer.MetricsConsumer, 3)
 	for i := range processors {
@@ -166,6 +182,16 @@ func (p *mockTraceConsumer) ConsumeTraceData(ctx context.Context, td data.TraceD
 	return nil
 }
 
+type erroringTraceConsumer struct {
+	err error
+}
+
+var _ consumer.TraceConsumer = &erroringTraceConsumer{}
+
+func (p *erroringTraceConsumer) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	return p.err
+}
+
 type mockMetricsConsumer struct {
 	TotalMetrics int
 	MustFail     bool
@@ -0,0 +1,53 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimitprocessor
+
+import (
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+)
+
+// ConfigV2 defines configuration for the rate limit processor. A rate of zero
+// means no limit.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// KeyAttribute is the span attribute whose values are limited separately,
+	// the service of the spans if empty.
+	KeyAttribute string `mapstructure:"key_attribute"`
+	// ServiceAttribute is the span attribute the service is read from, before
+	// falling back to the one of the Node.
+	ServiceAttribute string `mapstructure:"service_attribute"`
+	// SpansPerSecond is the rate of spans accepted for each key.
+	SpansPerSecond float64 `mapstructure:"spans_per_second"`
+	// Burst is the number of spans accepted above the rate after a key was
+	// idle, one second worth of spans if zero.
+	Burst float64 `mapstructure:"burst"`
+	// Action is what is done with the spans over the limit: "drop" or
+	// "sample".
+	Action Action `mapstructure:"action"`
+	// MaxKeys is the maximum number of keys whose usage is tracked, the least
+	// recently seen ones are forgotten first.
+	MaxKeys int `mapstructure:"max_keys"`
+	// Overrides are the limits of the keys that do not use the ones above.
+	Overrides []KeyOverride `mapstructure:"overrides"`
+}
+
+// KeyOverride defines the limit of a single key.
+type KeyOverride struct {
+	// Key can not be "other", the value the metrics of the keys without an
+	// override are recorded under.
+	Key            string  `mapstructure:"key"`
+	SpansPerSecond float64 `mapstructure:"spans_per_second"`
+	Burst          float64 `mapstructure:"burst"`
+}
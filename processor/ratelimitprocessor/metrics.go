@@ -0,0 +1,94 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimitprocessor
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/census-instrumentation/opencensus-service/internal/collector/telemetry"
+)
+
+// Variables related to metrics specific to the rate limit processor.
+var (
+	tagRateLimitKey, _ = tag.NewKey("ratelimit_key")
+
+	statDroppedSpans = stats.Int64("ratelimit_dropped_spans", "Count of spans dropped for exceeding the rate limit of their key", stats.UnitDimensionless)
+	statSampledSpans = stats.Int64("ratelimit_sampled_spans", "Count of spans kept with a sampling rate for exceeding the rate limit of their key", stats.UnitDimensionless)
+)
+
+// otherKeys is the tag value the keys without a limit of their own are
+// recorded under, since their number is unbounded.
+const otherKeys = "other"
+
+type outcome int
+
+const (
+	dropped outcome = iota
+	sampled
+)
+
+type countKey struct {
+	key     string
+	outcome outcome
+}
+
+// counts accumulates the spans over the limits in a batch, per key.
+type counts map[countKey]int64
+
+func (c counts) add(key string, o outcome) {
+	c[countKey{key: key, outcome: o}]++
+}
+
+func (c counts) record(ctx context.Context) {
+	for key, count := range c {
+		measure := statDroppedSpans
+		if key.outcome == sampled {
+			measure = statSampledSpans
+		}
+		_ = stats.RecordWithTags(
+			ctx,
+			[]tag.Mutator{tag.Upsert(tagRateLimitKey, key.key)},
+			measure.M(count))
+	}
+}
+
+// MetricViews return the metrics views according to given telemetry level.
+func MetricViews(level telemetry.Level) []*view.View {
+	if level == telemetry.None {
+		return nil
+	}
+
+	tagKeys := []tag.Key{tagRateLimitKey}
+
+	droppedView := &view.View{
+		Name:        statDroppedSpans.Name(),
+		Measure:     statDroppedSpans,
+		Description: statDroppedSpans.Description(),
+		TagKeys:     tagKeys,
+		Aggregation: view.Sum(),
+	}
+	sampledView := &view.View{
+		Name:        statSampledSpans.Name(),
+		Measure:     statSampledSpans,
+		Description: statSampledSpans.Description(),
+		TagKeys:     tagKeys,
+		Aggregation: view.Sum(),
+	}
+	return []*view.View{droppedView, sampledView}
+}
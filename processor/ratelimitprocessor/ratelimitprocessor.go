@@ -0,0 +1,366 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ratelimitprocessor contains a trace processor that limits the rate
+// of spans per service, or per value of a span attribute, so a single noisy
+// service, e.g. in a retry storm, can not take the whole collector budget.
+// The spans over the limits are dropped or, to keep an unbiased view of the
+// traffic, sampled down with their sampling rate recorded in an attribute.
+package ratelimitprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	processormetrics "github.com/census-instrumentation/opencensus-service/internal/collector/processor"
+	"github.com/census-instrumentation/opencensus-service/processor"
+	"github.com/census-instrumentation/opencensus-service/processor/internal/lru"
+	"github.com/census-instrumentation/opencensus-service/processor/internal/tokenbucket"
+	"github.com/census-instrumentation/opencensus-service/processor/internal/traces"
+)
+
+const (
+	// DefaultServiceAttribute is the span attribute the service of a span is
+	// read from, before falling back to the one of its Node.
+	DefaultServiceAttribute = traces.DefaultServiceAttribute
+
+	// AttributeSamplingRate is the span attribute set on the sampled spans to
+	// the number of spans each of them represents.
+	AttributeSamplingRate = "sampling.rate"
+
+	defaultMaxKeys = 10000
+)
+
+// Limit is the rate of spans accepted for a key. A rate of zero means no limit.
+type Limit struct {
+	SpansPerSecond float64
+	// Burst is the number of spans accepted above the rate after the key was
+	// idle. Zero means one second worth of spans.
+	Burst float64
+}
+
+// Action is what is done with the spans over the limit of their key.
+type Action string
+
+const (
+	// Drop drops the spans over the limit.
+	Drop Action = "drop"
+	// Sample keeps 1 in N of the spans of a key over its limit, N being
+	// the ratio of its incoming rate to its limit, and sets their
+	// AttributeSamplingRate to N. The decision depends on the trace ID, so
+	// the spans of a trace are sampled together.
+	Sample Action = "sample"
+)
+
+// keyState holds the usage of a key.
+type keyState struct {
+	bucket *tokenbucket.Bucket
+	// The incoming rate of the key is measured over windows of a second.
+	windowStart time.Time
+	windowCount float64
+	rate        float64
+}
+
+type ratelimitprocessor struct {
+	nextConsumer     consumer.TraceConsumer
+	keyAttribute     string
+	serviceAttribute string
+	defaults         Limit
+	overrides        map[string]Limit
+	action           Action
+
+	// mu protects keys, which maps keys to *keyState.
+	mu   sync.Mutex
+	keys *lru.Cache
+
+	now func() time.Time
+}
+
+// Option represents options that can be applied to the rate limit processor.
+type Option func(*ratelimitprocessor) error
+
+// WithKeyAttribute returns an Option to limit the spans per value of the given
+// span attribute instead of per service. The spans without it share the
+// default limit under the empty key.
+func WithKeyAttribute(key string) Option {
+	return func(rp *ratelimitprocessor) error {
+		rp.keyAttribute = key
+		return nil
+	}
+}
+
+// WithServiceAttribute returns an Option to read the service of the spans
+// from the given attribute, falling back to the service of their Node. An
+// empty key only uses the Node.
+func WithServiceAttribute(key string) Option {
+	return func(rp *ratelimitprocessor) error {
+		rp.serviceAttribute = key
+		return nil
+	}
+}
+
+// WithDefaultLimit returns an Option to configure the limit of the keys
+// without their own.
+func WithDefaultLimit(limit Limit) Option {
+	return func(rp *ratelimitprocessor) error {
+		if err := checkLimit(limit); err != nil {
+			return err
+		}
+		rp.defaults = limit
+		return nil
+	}
+}
+
+// WithKeyLimit returns an Option to configure the limit of a given key. Only
+// the keys configured this way are told apart in the metrics.
+func WithKeyLimit(key string, limit Limit) Option {
+	return func(rp *ratelimitprocessor) error {
+		if err := checkLimit(limit); err != nil {
+			return err
+		}
+		if key == otherKeys {
+			return fmt.Errorf("key %q is reserved for the metrics of the keys without limits", otherKeys)
+		}
+		if _, ok := rp.overrides[key]; ok {
+			return fmt.Errorf("duplicate limit for key %q", key)
+		}
+		rp.overrides[key] = limit
+		return nil
+	}
+}
+
+// WithAction returns an Option to configure what is done with the spans over
+// the limit of their key.
+func WithAction(action Action) Option {
+	return func(rp *ratelimitprocessor) error {
+		switch action {
+		case Drop, Sample:
+		default:
+			return fmt.Errorf("unknown action %q", action)
+		}
+		rp.action = action
+		return nil
+	}
+}
+
+// WithMaxKeys returns an Option to configure the maximum number of keys whose
+// usage is tracked. The least recently seen keys are forgotten first.
+func WithMaxKeys(maxKeys int) Option {
+	return func(rp *ratelimitprocessor) error {
+		if maxKeys <= 0 {
+			return errors.New("max keys must be positive")
+		}
+		rp.keys = lru.New(maxKeys)
+		return nil
+	}
+}
+
+func checkLimit(limit Limit) error {
+	if limit.SpansPerSecond < 0 || limit.Burst < 0 {
+		return errors.New("limits must not be negative")
+	}
+	return nil
+}
+
+var _ processor.TraceProcessor = (*ratelimitprocessor)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that limits the rate of
+// the spans of each key, by default their service.
+func NewTraceProcessor(nextConsumer consumer.TraceConsumer, options ...Option) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+
+	rp := &ratelimitprocessor{
+		nextConsumer:     nextConsumer,
+		serviceAttribute: DefaultServiceAttribute,
+		overrides:        make(map[string]Limit),
+		action:           Drop,
+		keys:             lru.New(defaultMaxKeys),
+		now:              time.Now,
+	}
+	for _, opt := range options {
+		if err := opt(rp); err != nil {
+			return nil, err
+		}
+	}
+	return rp, nil
+}
+
+func (rp *ratelimitprocessor) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	nodeService := processormetrics.ServiceNameForNode(td.Node)
+	c := make(counts)
+	// kept is only allocated once a span is dropped.
+	var kept []*tracepb.Span
+
+	now := rp.now()
+	rp.mu.Lock()
+	for i, span := range td.Spans {
+		if span == nil {
+			continue
+		}
+		key := rp.spanKey(span, nodeService)
+		weight := rp.admit(key, span, now)
+		switch {
+		case weight == 0:
+			c.add(rp.metricsKey(key), dropped)
+		case weight > 1:
+			setSamplingRate(span, weight)
+			c.add(rp.metricsKey(key), sampled)
+		}
+		if weight == 0 {
+			if kept == nil {
+				kept = append(make([]*tracepb.Span, 0, len(td.Spans)), td.Spans[:i]...)
+			}
+			continue
+		}
+		if kept != nil {
+			kept = append(kept, span)
+		}
+	}
+	rp.mu.Unlock()
+	c.record(ctx)
+
+	if kept != nil {
+		if len(kept) == 0 {
+			return nil
+		}
+		td.Spans = kept
+	}
+	return rp.nextConsumer.ConsumeTraceData(ctx, td)
+}
+
+// spanKey returns the key the span is limited by.
+func (rp *ratelimitprocessor) spanKey(span *tracepb.Span, nodeService string) string {
+	if rp.keyAttribute != "" {
+		return attributeString(span.GetAttributes().GetAttributeMap()[rp.keyAttribute])
+	}
+	return traces.SpanService(span, rp.serviceAttribute, nodeService)
+}
+
+// metricsKey returns the value the key is recorded under in the metrics: the
+// configured keys are recorded by name, the other ones share a single value.
+func (rp *ratelimitprocessor) metricsKey(key string) string {
+	if _, ok := rp.overrides[key]; ok {
+		return key
+	}
+	return otherKeys
+}
+
+func attributeString(value *tracepb.AttributeValue) string {
+	switch v := value.GetValue().(type) {
+	case *tracepb.AttributeValue_StringValue:
+		return v.StringValue.GetValue()
+	case *tracepb.AttributeValue_IntValue:
+		return fmt.Sprint(v.IntValue)
+	case *tracepb.AttributeValue_BoolValue:
+		return fmt.Sprint(v.BoolValue)
+	case *tracepb.AttributeValue_DoubleValue:
+		return fmt.Sprint(v.DoubleValue)
+	}
+	return ""
+}
+
+// admit returns how many spans the span represents once let through: 1 for
+// the spans within the limit, the sampling rate for the sampled ones, and 0
+// for the ones to drop. It must be called with mu held.
+func (rp *ratelimitprocessor) admit(key string, span *tracepb.Span, now time.Time) int64 {
+	limit, ok := rp.overrides[key]
+	if !ok {
+		limit = rp.defaults
+	}
+	if limit.SpansPerSecond == 0 {
+		return 1
+	}
+
+	var state *keyState
+	if value, ok := rp.keys.Get(key); ok {
+		state = value.(*keyState)
+	}
+	if state == nil {
+		burst := limit.Burst
+		if burst == 0 {
+			burst = limit.SpansPerSecond
+		}
+		state = &keyState{
+			bucket:      tokenbucket.New(limit.SpansPerSecond, burst, now),
+			windowStart: now,
+		}
+		rp.keys.Add(key, state)
+	}
+	state.count(now)
+
+	if state.bucket.Take(1, now) {
+		return 1
+	}
+	if rp.action == Drop {
+		return 0
+	}
+
+	// Keep 1 in weight spans, and charge them to the bucket so the spans
+	// let through stay within the limit on average. The spans of the current
+	// window bound the incoming rate until it is first measured.
+	weight := int64(math.Ceil(math.Max(state.rate, state.windowCount) / limit.SpansPerSecond))
+	if weight < 1 {
+		weight = 1
+	}
+	if traceHash(span.TraceId)%uint64(weight) != 0 {
+		return 0
+	}
+	state.bucket.Charge(1, now)
+	return weight
+}
+
+// count counts a span arriving for the key, and updates its incoming rate
+// once a second.
+func (state *keyState) count(now time.Time) {
+	if elapsed := now.Sub(state.windowStart); elapsed >= time.Second {
+		state.rate = state.windowCount / elapsed.Seconds()
+		state.windowStart = now
+		state.windowCount = 0
+	}
+	state.windowCount++
+}
+
+func traceHash(traceID []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(traceID)
+	return h.Sum64()
+}
+
+// setSamplingRate multiplies the sampling rate of the span, which is 1 if it
+// has none, by weight.
+func setSamplingRate(span *tracepb.Span, weight int64) {
+	if span.Attributes == nil {
+		span.Attributes = &tracepb.Span_Attributes{}
+	}
+	if span.Attributes.AttributeMap == nil {
+		span.Attributes.AttributeMap = make(map[string]*tracepb.AttributeValue)
+	}
+	if rate := span.Attributes.AttributeMap[AttributeSamplingRate].GetIntValue(); rate > 1 {
+		weight *= rate
+	}
+	span.Attributes.AttributeMap[AttributeSamplingRate] = &tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_IntValue{IntValue: weight},
+	}
+}
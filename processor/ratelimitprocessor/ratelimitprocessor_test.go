@@ -0,0 +1,191 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimitprocessor
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+	"github.com/census-instrumentation/opencensus-service/internal/collector/telemetry"
+)
+
+// newSpans returns n spans of different traces, starting at the given trace
+// number.
+func newSpans(first, n int) []*tracepb.Span {
+	spans := make([]*tracepb.Span, n)
+	for i := range spans {
+		traceID := make([]byte, 16)
+		binary.BigEndian.PutUint64(traceID[8:], uint64(first+i))
+		spans[i] = &tracepb.Span{TraceId: traceID}
+	}
+	return spans
+}
+
+func withAttribute(spans []*tracepb.Span, key string, value *tracepb.AttributeValue) []*tracepb.Span {
+	for _, span := range spans {
+		span.Attributes = &tracepb.Span_Attributes{
+			AttributeMap: map[string]*tracepb.AttributeValue{key: value},
+		}
+	}
+	return spans
+}
+
+func stringValue(s string) *tracepb.AttributeValue {
+	return &tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: s}},
+	}
+}
+
+func countSpans(tds []data.TraceData) int {
+	n := 0
+	for _, td := range tds {
+		n += len(td.Spans)
+	}
+	return n
+}
+
+func TestNewTraceProcessor(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	_, err := NewTraceProcessor(nil)
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithDefaultLimit(Limit{SpansPerSecond: -1}))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithKeyLimit("a", Limit{Burst: -1}))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithKeyLimit("a", Limit{}), WithKeyLimit("a", Limit{}))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithKeyLimit(otherKeys, Limit{}))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithAction("delay"))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithMaxKeys(0))
+	assert.Error(t, err)
+}
+
+func TestDropSpans(t *testing.T) {
+	views := MetricViews(telemetry.Normal)
+	require.NoError(t, view.Register(views...))
+	defer view.Unregister(views...)
+
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(
+		sink,
+		WithDefaultLimit(Limit{SpansPerSecond: 10, Burst: 20}),
+		WithKeyLimit("unlimited", Limit{}),
+		WithKeyLimit("backend", Limit{SpansPerSecond: 1, Burst: 2}),
+	)
+	require.NoError(t, err)
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	tp.(*ratelimitprocessor).now = func() time.Time { return now }
+
+	frontend := &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "frontend"}}
+	unlimited := &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "unlimited"}}
+
+	// The bucket of each service holds 20 spans.
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), data.TraceData{Node: frontend, Spans: newSpans(0, 15)}))
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), data.TraceData{Node: frontend, Spans: newSpans(15, 15)}))
+	assert.Equal(t, 20, countSpans(sink.AllTraces()))
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), data.TraceData{Node: frontend, Spans: newSpans(30, 1)}))
+	assert.Len(t, sink.AllTraces(), 2, "batches without spans left are not passed on")
+
+	// The service attribute of the spans takes precedence over the Node.
+	backend := withAttribute(newSpans(31, 5), DefaultServiceAttribute, stringValue("backend"))
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), data.TraceData{Node: frontend, Spans: backend}))
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), data.TraceData{Node: unlimited, Spans: newSpans(36, 1000)}))
+	assert.Equal(t, 1022, countSpans(sink.AllTraces()))
+
+	// The rate refills the bucket.
+	now = now.Add(time.Second)
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), data.TraceData{Node: frontend, Spans: newSpans(1036, 15)}))
+	assert.Equal(t, 1032, countSpans(sink.AllTraces()))
+
+	// Only the configured keys are recorded by name.
+	rows, err := view.RetrieveData(statDroppedSpans.Name())
+	require.NoError(t, err)
+	droppedPerKey := make(map[string]float64)
+	for _, row := range rows {
+		droppedPerKey[row.Tags[0].Value] = row.Data.(*view.SumData).Value
+	}
+	assert.Equal(t, map[string]float64{"backend": 3, otherKeys: 16}, droppedPerKey)
+}
+
+func TestSampleSpans(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(
+		sink,
+		WithDefaultLimit(Limit{SpansPerSecond: 10}),
+		WithAction(Sample),
+	)
+	require.NoError(t, err)
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	tp.(*ratelimitprocessor).now = func() time.Time { return now }
+
+	// 1000 spans per second, i.e. 100 times the limit.
+	for i := 0; i < 10; i++ {
+		require.NoError(t, tp.ConsumeTraceData(context.Background(), data.TraceData{Spans: newSpans(i*1000, 1000)}))
+		now = now.Add(time.Second)
+	}
+
+	// Once the rate is measured, 1 in 100 spans is kept.
+	sink = &exportertest.SinkTraceExporter{}
+	tp.(*ratelimitprocessor).nextConsumer = sink
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), data.TraceData{Spans: newSpans(10000, 1000)}))
+	spans := sink.AllTraces()[0].Spans
+	assert.InDelta(t, 10, len(spans), 5)
+	for _, span := range spans {
+		rate := span.Attributes.AttributeMap[AttributeSamplingRate].GetIntValue()
+		assert.Equal(t, int64(100), rate)
+		assert.Zero(t, traceHash(span.TraceId)%100, "the sampling depends on the trace ID")
+	}
+}
+
+func TestKeyAttribute(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(
+		sink,
+		WithKeyAttribute("tenant"),
+		WithDefaultLimit(Limit{SpansPerSecond: 1}),
+		WithKeyLimit("42", Limit{SpansPerSecond: 2}),
+	)
+	require.NoError(t, err)
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	tp.(*ratelimitprocessor).now = func() time.Time { return now }
+
+	tenant42 := &tracepb.AttributeValue{Value: &tracepb.AttributeValue_IntValue{IntValue: 42}}
+	spans := append(withAttribute(newSpans(0, 3), "tenant", tenant42), newSpans(3, 3)...)
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), data.TraceData{Spans: spans}))
+	require.Len(t, sink.AllTraces(), 1)
+	assert.Equal(t, []*tracepb.Span{spans[0], spans[1], spans[3]}, sink.AllTraces()[0].Spans)
+}
+
+func TestSetSamplingRate(t *testing.T) {
+	span := &tracepb.Span{}
+	setSamplingRate(span, 10)
+	assert.Equal(t, int64(10), span.Attributes.AttributeMap[AttributeSamplingRate].GetIntValue())
+
+	// The spans sampled upstream represent even more spans.
+	setSamplingRate(span, 4)
+	assert.Equal(t, int64(40), span.Attributes.AttributeMap[AttributeSamplingRate].GetIntValue())
+}
@@ -0,0 +1,31 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rebucketprocessor
+
+import (
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+)
+
+// ConfigV2 defines configuration for the rebucket processor.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// Bounds are the bucket bounds, in increasing order, that all the
+	// distributions are converted to. The bucket layouts are kept if empty.
+	Bounds []float64 `mapstructure:"bounds"`
+	// Quantiles, if set, are the quantiles in the interval (0, 1] of the
+	// summaries derived from the distributions. No summary is produced if
+	// empty.
+	Quantiles []float64 `mapstructure:"quantiles"`
+}
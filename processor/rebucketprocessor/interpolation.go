@@ -0,0 +1,172 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rebucketprocessor
+
+import (
+	"math"
+	"sort"
+
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+)
+
+// The buckets of a distribution with bounds b[0..n-1] hold the values in
+// (-inf, b[0]), [b[0], b[1]), ..., [b[n-1], +inf). Values are assumed to be
+// uniformly distributed within each bucket. The unbounded buckets are handled
+// like Prometheus does for histogram quantiles: the first bucket is assumed to
+// start at 0 if its upper bound is positive, as is the case for latencies, and
+// the values of the last bucket are assumed to be equal to its lower bound.
+
+// rebucket returns the distribution with its buckets converted to the given
+// bounds. Count, sum and sum of squared deviations are kept as is, the counts
+// of the new buckets are rounded so they still add up to the count.
+// Distributions without explicit bounds are returned unchanged since nothing is
+// known about how their values are spread.
+func rebucket(dv *metricspb.DistributionValue, bounds []float64) *metricspb.DistributionValue {
+	src := explicitBounds(dv)
+	if len(src) == 0 || len(dv.Buckets) != len(src)+1 || equalBounds(src, bounds) {
+		return dv
+	}
+
+	counts := make([]float64, len(bounds)+1)
+	for i, bucket := range dv.Buckets {
+		count := float64(bucket.GetCount())
+		if count == 0 {
+			continue
+		}
+		switch {
+		case i == 0 && src[0] > 0:
+			spread(counts, bounds, 0, src[0], count)
+		case i == 0:
+			// Values just below src[0].
+			counts[sort.SearchFloat64s(bounds, src[0])] += count
+		case i == len(src):
+			counts[bucketIndex(bounds, src[i-1])] += count
+		default:
+			spread(counts, bounds, src[i-1], src[i], count)
+		}
+	}
+
+	buckets := make([]*metricspb.DistributionValue_Bucket, len(counts))
+	var cumulative float64
+	var rounded int64
+	for j, count := range counts {
+		// Rounding the cumulative counts instead of each count keeps the total
+		// equal to the original count.
+		cumulative += count
+		next := int64(math.Round(cumulative))
+		buckets[j] = &metricspb.DistributionValue_Bucket{Count: next - rounded}
+		rounded = next
+	}
+	for _, bucket := range dv.Buckets {
+		if exemplar := bucket.GetExemplar(); exemplar != nil {
+			if target := buckets[bucketIndex(bounds, exemplar.Value)]; target.Exemplar == nil {
+				target.Exemplar = exemplar
+			}
+		}
+	}
+
+	return &metricspb.DistributionValue{
+		Count:                 dv.Count,
+		Sum:                   dv.Sum,
+		SumOfSquaredDeviation: dv.SumOfSquaredDeviation,
+		BucketOptions: &metricspb.DistributionValue_BucketOptions{
+			Type: &metricspb.DistributionValue_BucketOptions_Explicit_{
+				Explicit: &metricspb.DistributionValue_BucketOptions_Explicit{
+					Bounds: bounds,
+				},
+			},
+		},
+		Buckets: buckets,
+	}
+}
+
+// quantile returns an estimation of the q-quantile of the values recorded by
+// the distribution, interpolating linearly within the bucket that holds it.
+// The mean is returned for distributions without explicit bounds.
+func quantile(dv *metricspb.DistributionValue, q float64) float64 {
+	if dv.Count == 0 {
+		return 0
+	}
+	bounds := explicitBounds(dv)
+	if len(bounds) == 0 || len(dv.Buckets) != len(bounds)+1 {
+		return dv.Sum / float64(dv.Count)
+	}
+
+	rank := q * float64(dv.Count)
+	var cumulative float64
+	for i, bucket := range dv.Buckets {
+		count := float64(bucket.GetCount())
+		if count == 0 || cumulative+count < rank {
+			cumulative += count
+			continue
+		}
+		var lower, upper float64
+		switch {
+		case i == 0 && bounds[0] > 0:
+			lower, upper = 0, bounds[0]
+		case i == 0:
+			return bounds[0]
+		case i == len(bounds):
+			return bounds[i-1]
+		default:
+			lower, upper = bounds[i-1], bounds[i]
+		}
+		return lower + (upper-lower)*(rank-cumulative)/count
+	}
+	// The bucket counts add up to less than the count.
+	return bounds[len(bounds)-1]
+}
+
+// spread adds count, uniformly distributed over [lower, upper), to the counts
+// of the buckets with the given bounds.
+func spread(counts []float64, bounds []float64, lower, upper, count float64) {
+	for j := bucketIndex(bounds, lower); j < len(counts); j++ {
+		bucketLower, bucketUpper := math.Inf(-1), math.Inf(1)
+		if j > 0 {
+			bucketLower = bounds[j-1]
+		}
+		if j < len(bounds) {
+			bucketUpper = bounds[j]
+		}
+		if overlap := math.Min(upper, bucketUpper) - math.Max(lower, bucketLower); overlap > 0 {
+			counts[j] += count * overlap / (upper - lower)
+		}
+		if bucketUpper >= upper {
+			return
+		}
+	}
+}
+
+// bucketIndex returns the index of the bucket with the given bounds that holds
+// the value x.
+func bucketIndex(bounds []float64, x float64) int {
+	return sort.Search(len(bounds), func(i int) bool { return bounds[i] > x })
+}
+
+func explicitBounds(dv *metricspb.DistributionValue) []float64 {
+	return dv.GetBucketOptions().GetExplicit().GetBounds()
+}
+
+func equalBounds(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,213 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rebucketprocessor contains a metrics processor that converts the
+// buckets of distributions to a configured set of bounds and optionally derives
+// summaries with quantiles from them, so distributions produced by different
+// client libraries can be aggregated together.
+package rebucketprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/processor"
+)
+
+// summarySuffix is appended to the name of a distribution metric to name the
+// summary metric derived from it.
+const summarySuffix = "_summary"
+
+type rebucketprocessor struct {
+	nextConsumer consumer.MetricsConsumer
+	bounds       []float64
+	quantiles    []float64
+}
+
+// Option represents options that can be applied to the rebucket processor.
+type Option func(*rebucketprocessor) error
+
+// WithBounds returns an Option to configure the bucket bounds distributions are
+// converted to. The bounds must be strictly increasing. If no bounds are set
+// the distributions keep their bucket layouts.
+func WithBounds(bounds []float64) Option {
+	return func(rp *rebucketprocessor) error {
+		for i := 1; i < len(bounds); i++ {
+			if bounds[i] <= bounds[i-1] {
+				return fmt.Errorf("bounds must be strictly increasing, got %v", bounds)
+			}
+		}
+		rp.bounds = bounds
+		return nil
+	}
+}
+
+// WithQuantiles returns an Option to configure the quantiles of the summaries
+// derived from the distributions. Each quantile must be in the interval (0, 1].
+// No summary is produced if no quantiles are set.
+func WithQuantiles(quantiles []float64) Option {
+	return func(rp *rebucketprocessor) error {
+		for _, q := range quantiles {
+			if q <= 0 || q > 1 {
+				return fmt.Errorf("quantile %v is not in the interval (0, 1]", q)
+			}
+		}
+		rp.quantiles = quantiles
+		return nil
+	}
+}
+
+var _ processor.MetricsProcessor = (*rebucketprocessor)(nil)
+
+// NewMetricsProcessor returns a processor.MetricsProcessor that converts the
+// distributions it receives according to the given options.
+func NewMetricsProcessor(nextConsumer consumer.MetricsConsumer, options ...Option) (processor.MetricsProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+
+	rp := &rebucketprocessor{nextConsumer: nextConsumer}
+	for _, opt := range options {
+		if err := opt(rp); err != nil {
+			return nil, err
+		}
+	}
+	return rp, nil
+}
+
+func (rp *rebucketprocessor) ConsumeMetricsData(ctx context.Context, md data.MetricsData) error {
+	if len(rp.bounds) == 0 && len(rp.quantiles) == 0 {
+		return rp.nextConsumer.ConsumeMetricsData(ctx, md)
+	}
+
+	metrics := make([]*metricspb.Metric, 0, len(md.Metrics))
+	for _, metric := range md.Metrics {
+		switch metric.GetMetricDescriptor().GetType() {
+		case metricspb.MetricDescriptor_GAUGE_DISTRIBUTION, metricspb.MetricDescriptor_CUMULATIVE_DISTRIBUTION:
+		default:
+			metrics = append(metrics, metric)
+			continue
+		}
+
+		if len(rp.bounds) > 0 {
+			metrics = append(metrics, rp.rebucketMetric(metric))
+		} else {
+			metrics = append(metrics, metric)
+		}
+		if len(rp.quantiles) > 0 {
+			// Quantiles are computed from the original buckets, which are at
+			// least as precise as the converted ones.
+			metrics = append(metrics, rp.summaryMetric(metric))
+		}
+	}
+
+	return rp.nextConsumer.ConsumeMetricsData(ctx, data.MetricsData{
+		Node:     md.Node,
+		Resource: md.Resource,
+		Metrics:  metrics,
+	})
+}
+
+// rebucketMetric returns a copy of the distribution metric with the buckets of
+// all its points converted to the configured bounds.
+func (rp *rebucketprocessor) rebucketMetric(metric *metricspb.Metric) *metricspb.Metric {
+	tss := make([]*metricspb.TimeSeries, 0, len(metric.Timeseries))
+	for _, ts := range metric.Timeseries {
+		points := make([]*metricspb.Point, 0, len(ts.GetPoints()))
+		for _, point := range ts.GetPoints() {
+			dv := point.GetDistributionValue()
+			if dv == nil {
+				points = append(points, point)
+				continue
+			}
+			points = append(points, &metricspb.Point{
+				Timestamp: point.Timestamp,
+				Value: &metricspb.Point_DistributionValue{
+					DistributionValue: rebucket(dv, rp.bounds),
+				},
+			})
+		}
+		tss = append(tss, &metricspb.TimeSeries{
+			StartTimestamp: ts.GetStartTimestamp(),
+			LabelValues:    ts.GetLabelValues(),
+			Points:         points,
+		})
+	}
+	return &metricspb.Metric{
+		MetricDescriptor: metric.MetricDescriptor,
+		Resource:         metric.Resource,
+		Timeseries:       tss,
+	}
+}
+
+// summaryMetric returns a SUMMARY metric with the configured quantiles of each
+// point of the distribution metric. The summary is named after the
+// distribution with the summarySuffix appended.
+func (rp *rebucketprocessor) summaryMetric(metric *metricspb.Metric) *metricspb.Metric {
+	descriptor := proto.Clone(metric.MetricDescriptor).(*metricspb.MetricDescriptor)
+	descriptor.Name += summarySuffix
+	descriptor.Type = metricspb.MetricDescriptor_SUMMARY
+
+	tss := make([]*metricspb.TimeSeries, 0, len(metric.Timeseries))
+	for _, ts := range metric.Timeseries {
+		points := make([]*metricspb.Point, 0, len(ts.GetPoints()))
+		for _, point := range ts.GetPoints() {
+			dv := point.GetDistributionValue()
+			if dv == nil {
+				continue
+			}
+			percentiles := make([]*metricspb.SummaryValue_Snapshot_ValueAtPercentile, len(rp.quantiles))
+			for i, q := range rp.quantiles {
+				percentiles[i] = &metricspb.SummaryValue_Snapshot_ValueAtPercentile{
+					Percentile: q * 100,
+					Value:      quantile(dv, q),
+				}
+			}
+			points = append(points, &metricspb.Point{
+				Timestamp: point.Timestamp,
+				Value: &metricspb.Point_SummaryValue{
+					SummaryValue: &metricspb.SummaryValue{
+						Count: &wrappers.Int64Value{Value: dv.Count},
+						Sum:   &wrappers.DoubleValue{Value: dv.Sum},
+						Snapshot: &metricspb.SummaryValue_Snapshot{
+							Count:            &wrappers.Int64Value{Value: dv.Count},
+							Sum:              &wrappers.DoubleValue{Value: dv.Sum},
+							PercentileValues: percentiles,
+						},
+					},
+				},
+			})
+		}
+		if len(points) == 0 {
+			continue
+		}
+		tss = append(tss, &metricspb.TimeSeries{
+			StartTimestamp: ts.GetStartTimestamp(),
+			LabelValues:    ts.GetLabelValues(),
+			Points:         points,
+		})
+	}
+	return &metricspb.Metric{
+		MetricDescriptor: descriptor,
+		Resource:         metric.Resource,
+		Timeseries:       tss,
+	}
+}
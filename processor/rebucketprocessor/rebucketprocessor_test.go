@@ -0,0 +1,255 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rebucketprocessor
+
+import (
+	"context"
+	"testing"
+
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+)
+
+func TestNewMetricsProcessor(t *testing.T) {
+	_, err := NewMetricsProcessor(nil)
+	assert.Error(t, err)
+
+	_, err = NewMetricsProcessor(exportertest.NewNopMetricsExporter(), WithBounds([]float64{1, 5, 5}))
+	assert.Error(t, err)
+
+	_, err = NewMetricsProcessor(exportertest.NewNopMetricsExporter(), WithQuantiles([]float64{0}))
+	assert.Error(t, err)
+
+	_, err = NewMetricsProcessor(exportertest.NewNopMetricsExporter(), WithQuantiles([]float64{1.5}))
+	assert.Error(t, err)
+
+	mp, err := NewMetricsProcessor(
+		exportertest.NewNopMetricsExporter(),
+		WithBounds([]float64{1, 5, 10}),
+		WithQuantiles([]float64{0.5, 1}))
+	assert.NoError(t, err)
+	assert.NotNil(t, mp)
+}
+
+func TestRebucket(t *testing.T) {
+	tests := []struct {
+		name   string
+		dv     *metricspb.DistributionValue
+		bounds []float64
+		want   []int64
+	}{
+		{
+			name:   "interpolate within buckets, last bucket at its lower bound",
+			dv:     distValue([]float64{1, 2, 4}, []int64{0, 2, 2, 4}),
+			bounds: []float64{1.5, 3},
+			want:   []int64{1, 2, 5},
+		},
+		{
+			name:   "first bucket starts at 0",
+			dv:     distValue([]float64{2}, []int64{4, 0}),
+			bounds: []float64{1, 2},
+			want:   []int64{2, 2, 0},
+		},
+		{
+			name:   "first bucket with non positive bound",
+			dv:     distValue([]float64{-1, 1}, []int64{3, 0, 0}),
+			bounds: []float64{-2, 0},
+			want:   []int64{0, 3, 0},
+		},
+		{
+			name:   "counts are rounded keeping the total",
+			dv:     distValue([]float64{0, 3}, []int64{0, 1, 0}),
+			bounds: []float64{1, 2},
+			want:   []int64{0, 1, 0},
+		},
+		{
+			name:   "same bounds",
+			dv:     distValue([]float64{1, 2}, []int64{1, 2, 3}),
+			bounds: []float64{1, 2},
+			want:   []int64{1, 2, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rebucket(tt.dv, tt.bounds)
+			assert.Equal(t, tt.bounds, explicitBounds(got))
+			assert.Equal(t, tt.want, bucketCounts(got))
+			assert.Equal(t, tt.dv.Count, got.Count)
+			assert.Equal(t, tt.dv.Sum, got.Sum)
+		})
+	}
+}
+
+func TestRebucket_Exemplars(t *testing.T) {
+	dv := distValue([]float64{1, 2}, []int64{0, 1, 1})
+	dv.Buckets[1].Exemplar = &metricspb.DistributionValue_Exemplar{Value: 1.2}
+	dv.Buckets[2].Exemplar = &metricspb.DistributionValue_Exemplar{Value: 7}
+
+	got := rebucket(dv, []float64{1.5, 5})
+	assert.True(t, proto.Equal(dv.Buckets[1].Exemplar, got.Buckets[0].Exemplar))
+	assert.Nil(t, got.Buckets[1].Exemplar)
+	assert.True(t, proto.Equal(dv.Buckets[2].Exemplar, got.Buckets[2].Exemplar))
+}
+
+func TestRebucket_NoBounds(t *testing.T) {
+	dv := &metricspb.DistributionValue{
+		Count:   2,
+		Sum:     3,
+		Buckets: []*metricspb.DistributionValue_Bucket{{Count: 2}},
+	}
+	assert.Equal(t, dv, rebucket(dv, []float64{1, 2}))
+}
+
+func TestQuantile(t *testing.T) {
+	dv := distValue([]float64{1, 2, 4}, []int64{0, 2, 2, 4})
+	assert.Equal(t, 2.0, quantile(dv, 0.25))
+	assert.Equal(t, 4.0, quantile(dv, 0.5))
+	assert.Equal(t, 4.0, quantile(dv, 0.9))
+
+	dv = distValue([]float64{2}, []int64{4, 0})
+	assert.Equal(t, 1.0, quantile(dv, 0.5))
+
+	assert.Equal(t, 0.0, quantile(&metricspb.DistributionValue{}, 0.5))
+	assert.Equal(t, 1.5, quantile(&metricspb.DistributionValue{Count: 2, Sum: 3}, 0.5))
+}
+
+func TestConsumeMetricsData(t *testing.T) {
+	sink := &exportertest.SinkMetricsExporter{}
+	mp, err := NewMetricsProcessor(sink, WithBounds([]float64{1.5, 3}), WithQuantiles([]float64{0.25, 0.5}))
+	require.NoError(t, err)
+
+	gauge := metric("queue_size", metricspb.MetricDescriptor_GAUGE_INT64, &metricspb.Point{
+		Timestamp: ts(2),
+		Value:     &metricspb.Point_Int64Value{Int64Value: 3},
+	})
+	latency := metric("latency", metricspb.MetricDescriptor_CUMULATIVE_DISTRIBUTION, &metricspb.Point{
+		Timestamp: ts(2),
+		Value: &metricspb.Point_DistributionValue{
+			DistributionValue: distValue([]float64{1, 2, 4}, []int64{0, 2, 2, 4}),
+		},
+	})
+	err = mp.ConsumeMetricsData(context.Background(), data.MetricsData{
+		Metrics: []*metricspb.Metric{gauge, latency},
+	})
+	require.NoError(t, err)
+
+	wantLatency := metric("latency", metricspb.MetricDescriptor_CUMULATIVE_DISTRIBUTION, &metricspb.Point{
+		Timestamp: ts(2),
+		Value: &metricspb.Point_DistributionValue{
+			DistributionValue: distValue([]float64{1.5, 3}, []int64{1, 2, 5}),
+		},
+	})
+	// The sum is kept from the original distribution.
+	wantLatency.Timeseries[0].Points[0].GetDistributionValue().Sum = latency.Timeseries[0].Points[0].GetDistributionValue().Sum
+	wantSummary := metric("latency_summary", metricspb.MetricDescriptor_SUMMARY, &metricspb.Point{
+		Timestamp: ts(2),
+		Value: &metricspb.Point_SummaryValue{
+			SummaryValue: &metricspb.SummaryValue{
+				Count: &wrappers.Int64Value{Value: 8},
+				Sum:   &wrappers.DoubleValue{Value: 22},
+				Snapshot: &metricspb.SummaryValue_Snapshot{
+					Count: &wrappers.Int64Value{Value: 8},
+					Sum:   &wrappers.DoubleValue{Value: 22},
+					PercentileValues: []*metricspb.SummaryValue_Snapshot_ValueAtPercentile{
+						{Percentile: 25, Value: 2},
+						{Percentile: 50, Value: 4},
+					},
+				},
+			},
+		},
+	})
+
+	got := sink.AllMetrics()
+	require.Equal(t, 1, len(got))
+	want := []*metricspb.Metric{gauge, wantLatency, wantSummary}
+	require.Equal(t, len(want), len(got[0].Metrics))
+	for i := range want {
+		assert.True(t, proto.Equal(want[i], got[0].Metrics[i]), "want %v, got %v", want[i], got[0].Metrics[i])
+	}
+}
+
+func TestConsumeMetricsData_PassThrough(t *testing.T) {
+	sink := &exportertest.SinkMetricsExporter{}
+	mp, err := NewMetricsProcessor(sink)
+	require.NoError(t, err)
+
+	md := data.MetricsData{Metrics: []*metricspb.Metric{
+		metric("latency", metricspb.MetricDescriptor_GAUGE_DISTRIBUTION, &metricspb.Point{
+			Timestamp: ts(2),
+			Value: &metricspb.Point_DistributionValue{
+				DistributionValue: distValue([]float64{1, 2}, []int64{1, 2, 3}),
+			},
+		}),
+	}}
+	require.NoError(t, mp.ConsumeMetricsData(context.Background(), md))
+	assert.Equal(t, []data.MetricsData{md}, sink.AllMetrics())
+}
+
+func metric(name string, ty metricspb.MetricDescriptor_Type, point *metricspb.Point) *metricspb.Metric {
+	return &metricspb.Metric{
+		MetricDescriptor: &metricspb.MetricDescriptor{
+			Name:      name,
+			Type:      ty,
+			LabelKeys: []*metricspb.LabelKey{{Key: "k1"}},
+		},
+		Timeseries: []*metricspb.TimeSeries{{
+			StartTimestamp: ts(1),
+			LabelValues:    []*metricspb.LabelValue{{Value: "v1", HasValue: true}},
+			Points:         []*metricspb.Point{point},
+		}},
+	}
+}
+
+// distValue returns a distribution with the given bounds and bucket counts,
+// its sum is computed using the lower bound of each bucket.
+func distValue(bounds []float64, counts []int64) *metricspb.DistributionValue {
+	dv := &metricspb.DistributionValue{
+		BucketOptions: &metricspb.DistributionValue_BucketOptions{
+			Type: &metricspb.DistributionValue_BucketOptions_Explicit_{
+				Explicit: &metricspb.DistributionValue_BucketOptions_Explicit{
+					Bounds: bounds,
+				},
+			},
+		},
+		Buckets: make([]*metricspb.DistributionValue_Bucket, len(counts)),
+	}
+	for i, count := range counts {
+		dv.Count += count
+		dv.Buckets[i] = &metricspb.DistributionValue_Bucket{Count: count}
+		if i > 0 {
+			dv.Sum += float64(count) * bounds[i-1]
+		}
+	}
+	return dv
+}
+
+func bucketCounts(dv *metricspb.DistributionValue) []int64 {
+	counts := make([]int64, len(dv.Buckets))
+	for i, bucket := range dv.Buckets {
+		counts[i] = bucket.Count
+	}
+	return counts
+}
+
+func ts(seconds int64) *timestamp.Timestamp {
+	return &timestamp.Timestamp{Seconds: seconds}
+}
@@ -0,0 +1,43 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcedetectionprocessor
+
+import (
+	"time"
+
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+)
+
+// ConfigV2 defines configuration for the resource detection processor.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// Detectors are the detectors run at startup, in order, among host, aws,
+	// gce and azure. A label found by a detector isn't replaced by the next
+	// ones.
+	Detectors []string `mapstructure:"detectors"`
+	// Override replaces the Resource labels the data already has with the
+	// detected ones. By default the labels of the data are kept.
+	Override bool `mapstructure:"override"`
+	// Timeout bounds the time spent querying the cloud metadata endpoints.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// MachineIDPath is the file the host detector reads the machine ID from.
+	MachineIDPath string `mapstructure:"machine_id_path"`
+	// AWSEndpoint is the base URL of the EC2 instance metadata service.
+	AWSEndpoint string `mapstructure:"aws_endpoint"`
+	// GCEEndpoint is the base URL of the GCE metadata server.
+	GCEEndpoint string `mapstructure:"gce_endpoint"`
+	// AzureEndpoint is the base URL of the Azure instance metadata service.
+	AzureEndpoint string `mapstructure:"azure_endpoint"`
+}
@@ -0,0 +1,250 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcedetectionprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+
+	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+)
+
+// Detector is a source of the identity of the host.
+type Detector string
+
+const (
+	// DetectorHost reads the host name, OS and machine ID of the host.
+	DetectorHost Detector = "host"
+	// DetectorAWS queries the EC2 instance metadata service.
+	DetectorAWS Detector = "aws"
+	// DetectorGCE queries the GCE metadata server.
+	DetectorGCE Detector = "gce"
+	// DetectorAzure queries the Azure instance metadata service.
+	DetectorAzure Detector = "azure"
+)
+
+const (
+	defaultMachineIDPath = "/etc/machine-id"
+	defaultAWSEndpoint   = "http://169.254.169.254"
+	defaultGCEEndpoint   = "http://metadata.google.internal"
+	defaultAzureEndpoint = "http://169.254.169.254"
+)
+
+// Resource types and labels set by the detectors.
+const (
+	ResourceTypeHost  = "host"
+	ResourceTypeCloud = "cloud"
+
+	LabelHostName  = "host.name"
+	LabelHostID    = "host.id"
+	LabelHostType  = "host.type"
+	LabelOSType    = "os.type"
+	LabelProvider  = "cloud.provider"
+	LabelAccountID = "cloud.account.id"
+	LabelRegion    = "cloud.region"
+	LabelZone      = "cloud.zone"
+)
+
+// Values of LabelProvider.
+const (
+	ProviderAWS   = "aws"
+	ProviderGCP   = "gcp"
+	ProviderAzure = "azure"
+)
+
+type detectFunc func(ctx context.Context, d *detection) (*resourcepb.Resource, error)
+
+var detectFuncs = map[Detector]detectFunc{
+	DetectorHost:  detectHost,
+	DetectorAWS:   detectAWS,
+	DetectorGCE:   detectGCE,
+	DetectorAzure: detectAzure,
+}
+
+func detectHost(ctx context.Context, d *detection) (*resourcepb.Resource, error) {
+	labels := map[string]string{LabelOSType: runtime.GOOS}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		labels[LabelHostName] = hostname
+	}
+	// The machine ID is missing on some systems, e.g. in containers.
+	if machineID, err := ioutil.ReadFile(d.machineIDPath); err == nil {
+		if id := strings.TrimSpace(string(machineID)); id != "" {
+			labels[LabelHostID] = id
+		}
+	}
+	return &resourcepb.Resource{Type: ResourceTypeHost, Labels: labels}, nil
+}
+
+// awsIdentityDocument holds the fields of the EC2 instance identity document
+// added to the Resource.
+type awsIdentityDocument struct {
+	AccountID        string `json:"accountId"`
+	Region           string `json:"region"`
+	AvailabilityZone string `json:"availabilityZone"`
+	InstanceID       string `json:"instanceId"`
+	InstanceType     string `json:"instanceType"`
+}
+
+func detectAWS(ctx context.Context, d *detection) (*resourcepb.Resource, error) {
+	endpoint := strings.TrimSuffix(d.endpoints[DetectorAWS], "/")
+
+	// IMDSv2 requires a session token, IMDSv1 doesn't: the document is
+	// requested without one if getting it fails.
+	header := http.Header{}
+	req, err := http.NewRequest(http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	if token, err := doMetadataRequest(ctx, req); err == nil {
+		header.Set("X-aws-ec2-metadata-token", string(token))
+	}
+
+	var doc awsIdentityDocument
+	if err := getMetadataJSON(ctx, endpoint+"/latest/dynamic/instance-identity/document", header, &doc); err != nil {
+		return nil, err
+	}
+	return cloudResource(ProviderAWS, map[string]string{
+		LabelAccountID: doc.AccountID,
+		LabelRegion:    doc.Region,
+		LabelZone:      doc.AvailabilityZone,
+		LabelHostID:    doc.InstanceID,
+		LabelHostType:  doc.InstanceType,
+	}), nil
+}
+
+func detectGCE(ctx context.Context, d *detection) (*resourcepb.Resource, error) {
+	endpoint := strings.TrimSuffix(d.endpoints[DetectorGCE], "/")
+	header := http.Header{"Metadata-Flavor": []string{"Google"}}
+
+	get := func(path string) (string, error) {
+		body, err := getMetadata(ctx, endpoint+"/computeMetadata/v1/"+path, header)
+		return strings.TrimSpace(string(body)), err
+	}
+	// The project ID is queried first: failing on it means this isn't GCE.
+	projectID, err := get("project/project-id")
+	if err != nil {
+		return nil, err
+	}
+	labels := map[string]string{LabelAccountID: projectID}
+	for path, label := range map[string]string{
+		"instance/id":           LabelHostID,
+		"instance/hostname":     LabelHostName,
+		"instance/zone":         LabelZone,
+		"instance/machine-type": LabelHostType,
+	} {
+		value, err := get(path)
+		if err != nil {
+			return nil, err
+		}
+		// The zone and machine type are resource paths, e.g.
+		// projects/123/zones/us-central1-a.
+		if label == LabelZone || label == LabelHostType {
+			value = value[strings.LastIndex(value, "/")+1:]
+		}
+		labels[label] = value
+	}
+	if i := strings.LastIndex(labels[LabelZone], "-"); i > 0 {
+		labels[LabelRegion] = labels[LabelZone][:i]
+	}
+	return cloudResource(ProviderGCP, labels), nil
+}
+
+// azureCompute holds the fields of the Azure instance compute metadata added
+// to the Resource.
+type azureCompute struct {
+	Location       string `json:"location"`
+	Name           string `json:"name"`
+	VMID           string `json:"vmId"`
+	VMSize         string `json:"vmSize"`
+	SubscriptionID string `json:"subscriptionId"`
+}
+
+func detectAzure(ctx context.Context, d *detection) (*resourcepb.Resource, error) {
+	endpoint := strings.TrimSuffix(d.endpoints[DetectorAzure], "/")
+	header := http.Header{"Metadata": []string{"true"}}
+
+	var compute azureCompute
+	err := getMetadataJSON(ctx, endpoint+"/metadata/instance/compute?api-version=2019-03-11&format=json", header, &compute)
+	if err != nil {
+		return nil, err
+	}
+	return cloudResource(ProviderAzure, map[string]string{
+		LabelAccountID: compute.SubscriptionID,
+		LabelRegion:    compute.Location,
+		LabelHostID:    compute.VMID,
+		LabelHostName:  compute.Name,
+		LabelHostType:  compute.VMSize,
+	}), nil
+}
+
+// cloudResource returns a Resource of the given cloud provider with the
+// non-empty labels.
+func cloudResource(provider string, labels map[string]string) *resourcepb.Resource {
+	res := &resourcepb.Resource{
+		Type:   ResourceTypeCloud,
+		Labels: map[string]string{LabelProvider: provider},
+	}
+	for k, v := range labels {
+		if v != "" {
+			res.Labels[k] = v
+		}
+	}
+	return res
+}
+
+func getMetadata(ctx context.Context, url string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	return doMetadataRequest(ctx, req)
+}
+
+func getMetadataJSON(ctx context.Context, url string, header http.Header, v interface{}) error {
+	body, err := getMetadata(ctx, url, header)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// metadataClient queries the metadata endpoints, which are local to the host:
+// going through a proxy would hit the wrong ones.
+var metadataClient = &http.Client{Transport: &http.Transport{}}
+
+func doMetadataRequest(ctx context.Context, req *http.Request) ([]byte, error) {
+	resp, err := metadataClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata request to %s failed with status %d", req.URL, resp.StatusCode)
+	}
+	return body, nil
+}
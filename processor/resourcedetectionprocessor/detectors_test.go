@@ -0,0 +1,225 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcedetectionprocessor
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDetection(t *testing.T, options ...Option) *detection {
+	d, err := newDetection(options)
+	require.NoError(t, err)
+	return d
+}
+
+func TestDetectHost(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resourcedetection")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	machineIDPath := filepath.Join(dir, "machine-id")
+	require.NoError(t, ioutil.WriteFile(machineIDPath, []byte("0123456789abcdef\n"), 0600))
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	d := newTestDetection(t, WithMachineIDPath(machineIDPath))
+	assert.Equal(t, &resourcepb.Resource{
+		Type: ResourceTypeHost,
+		Labels: map[string]string{
+			LabelHostName: hostname,
+			LabelHostID:   "0123456789abcdef",
+			LabelOSType:   runtime.GOOS,
+		},
+	}, d.resource)
+
+	// The machine ID is optional.
+	d = newTestDetection(t, WithMachineIDPath(filepath.Join(dir, "missing")))
+	_, ok := d.resource.Labels[LabelHostID]
+	assert.False(t, ok)
+}
+
+func TestDetectAWS(t *testing.T) {
+	for _, imdsV2 := range []bool{false, true} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+				if !imdsV2 {
+					http.NotFound(w, r)
+					return
+				}
+				assert.Equal(t, "60", r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds"))
+				w.Write([]byte("token"))
+			case r.Method == http.MethodGet && r.URL.Path == "/latest/dynamic/instance-identity/document":
+				if imdsV2 && r.Header.Get("X-aws-ec2-metadata-token") != "token" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.Write([]byte(`{
+					"accountId": "123456789012",
+					"availabilityZone": "us-west-2b",
+					"instanceId": "i-1234567890abcdef0",
+					"instanceType": "t2.micro",
+					"region": "us-west-2"
+				}`))
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+
+		d := newTestDetection(t, WithDetectors(DetectorAWS), WithEndpoint(DetectorAWS, server.URL))
+		assert.Equal(t, &resourcepb.Resource{
+			Type: ResourceTypeCloud,
+			Labels: map[string]string{
+				LabelProvider:  ProviderAWS,
+				LabelAccountID: "123456789012",
+				LabelRegion:    "us-west-2",
+				LabelZone:      "us-west-2b",
+				LabelHostID:    "i-1234567890abcdef0",
+				LabelHostType:  "t2.micro",
+			},
+		}, d.resource, "IMDSv2: %v", imdsV2)
+		server.Close()
+	}
+}
+
+func TestDetectGCE(t *testing.T) {
+	metadata := map[string]string{
+		"/computeMetadata/v1/project/project-id":    "my-project",
+		"/computeMetadata/v1/instance/id":           "4520031799277581759",
+		"/computeMetadata/v1/instance/hostname":     "vm-1.c.my-project.internal",
+		"/computeMetadata/v1/instance/zone":         "projects/123456789/zones/us-central1-a",
+		"/computeMetadata/v1/instance/machine-type": "projects/123456789/machineTypes/n1-standard-1",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value, ok := metadata[r.URL.Path]
+		if !ok || r.Header.Get("Metadata-Flavor") != "Google" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(value))
+	}))
+	defer server.Close()
+
+	d := newTestDetection(t, WithDetectors(DetectorGCE), WithEndpoint(DetectorGCE, server.URL))
+	assert.Equal(t, &resourcepb.Resource{
+		Type: ResourceTypeCloud,
+		Labels: map[string]string{
+			LabelProvider:  ProviderGCP,
+			LabelAccountID: "my-project",
+			LabelRegion:    "us-central1",
+			LabelZone:      "us-central1-a",
+			LabelHostID:    "4520031799277581759",
+			LabelHostName:  "vm-1.c.my-project.internal",
+			LabelHostType:  "n1-standard-1",
+		},
+	}, d.resource)
+}
+
+func TestDetectAzure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metadata/instance/compute" || r.Header.Get("Metadata") != "true" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{
+			"location": "westeurope",
+			"name": "vm-1",
+			"vmId": "02aab8a4-74ef-476e-8182-f6d2ba4166a6",
+			"vmSize": "Standard_A3",
+			"subscriptionId": "8d10da13-8125-4ba9-a717-bf7490507b3d"
+		}`))
+	}))
+	defer server.Close()
+
+	d := newTestDetection(t, WithDetectors(DetectorAzure), WithEndpoint(DetectorAzure, server.URL))
+	assert.Equal(t, &resourcepb.Resource{
+		Type: ResourceTypeCloud,
+		Labels: map[string]string{
+			LabelProvider:  ProviderAzure,
+			LabelAccountID: "8d10da13-8125-4ba9-a717-bf7490507b3d",
+			LabelRegion:    "westeurope",
+			LabelHostID:    "02aab8a4-74ef-476e-8182-f6d2ba4166a6",
+			LabelHostName:  "vm-1",
+			LabelHostType:  "Standard_A3",
+		},
+	}, d.resource)
+}
+
+func TestDetectOtherCloud(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	d := newTestDetection(t,
+		WithDetectors(DetectorAWS, DetectorGCE, DetectorAzure),
+		WithEndpoint(DetectorAWS, server.URL),
+		WithEndpoint(DetectorGCE, server.URL),
+		WithEndpoint(DetectorAzure, server.URL),
+	)
+	assert.Nil(t, d.resource)
+}
+
+func TestDetectTimeout(t *testing.T) {
+	blocked := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-blocked
+	}))
+	defer server.Close()
+	defer close(blocked)
+
+	d := newTestDetection(t,
+		WithDetectors(DetectorGCE, DetectorHost),
+		WithEndpoint(DetectorGCE, server.URL),
+		WithTimeout(50*time.Millisecond),
+	)
+	// The host is still detected.
+	require.NotNil(t, d.resource)
+	assert.Equal(t, ResourceTypeHost, d.resource.Type)
+}
+
+func TestDetectMerge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		values := map[string]string{
+			"/computeMetadata/v1/project/project-id":    "my-project",
+			"/computeMetadata/v1/instance/id":           "4520031799277581759",
+			"/computeMetadata/v1/instance/hostname":     "vm-1.c.my-project.internal",
+			"/computeMetadata/v1/instance/zone":         "projects/123456789/zones/us-central1-a",
+			"/computeMetadata/v1/instance/machine-type": "projects/123456789/machineTypes/n1-standard-1",
+		}
+		w.Write([]byte(values[r.URL.Path]))
+	}))
+	defer server.Close()
+
+	d := newTestDetection(t,
+		WithDetectors(DetectorGCE, DetectorHost),
+		WithEndpoint(DetectorGCE, server.URL),
+		WithMachineIDPath("testdata/missing"),
+	)
+	// The first detector wins.
+	assert.Equal(t, ResourceTypeCloud, d.resource.Type)
+	assert.Equal(t, "vm-1.c.my-project.internal", d.resource.Labels[LabelHostName])
+	assert.Equal(t, runtime.GOOS, d.resource.Labels[LabelOSType])
+	assert.Equal(t, "4520031799277581759", d.resource.Labels[LabelHostID])
+}
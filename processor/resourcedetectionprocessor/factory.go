@@ -0,0 +1,87 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcedetectionprocessor
+
+import (
+	"time"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+	"github.com/census-instrumentation/opencensus-service/internal/factories"
+	"github.com/census-instrumentation/opencensus-service/processor"
+)
+
+var _ = factories.RegisterProcessorFactory(&processorFactory{})
+
+const (
+	// The value of "type" key in configuration.
+	typeStr = "resourcedetection"
+)
+
+// processorFactory is the factory for the resource detection processor.
+type processorFactory struct {
+}
+
+// Type gets the type of the config created by this factory.
+func (f *processorFactory) Type() string {
+	return typeStr
+}
+
+// CreateDefaultConfig creates the default configuration for the processor.
+func (f *processorFactory) CreateDefaultConfig() configmodels.Processor {
+	return &ConfigV2{
+		ProcessorSettings: configmodels.ProcessorSettings{
+			TypeVal: typeStr,
+		},
+		Detectors:     []string{string(DetectorHost)},
+		Timeout:       5 * time.Second,
+		MachineIDPath: defaultMachineIDPath,
+		AWSEndpoint:   defaultAWSEndpoint,
+		GCEEndpoint:   defaultGCEEndpoint,
+		AzureEndpoint: defaultAzureEndpoint,
+	}
+}
+
+// CreateTraceProcessor creates a trace processor based on this config.
+func (f *processorFactory) CreateTraceProcessor(
+	nextConsumer consumer.TraceConsumer,
+	cfg configmodels.Processor,
+) (processor.TraceProcessor, error) {
+	return NewTraceProcessor(nextConsumer, optionsFromConfig(cfg.(*ConfigV2))...)
+}
+
+// CreateMetricsProcessor creates a metrics processor based on this config.
+func (f *processorFactory) CreateMetricsProcessor(
+	nextConsumer consumer.MetricsConsumer,
+	cfg configmodels.Processor,
+) (processor.MetricsProcessor, error) {
+	return NewMetricsProcessor(nextConsumer, optionsFromConfig(cfg.(*ConfigV2))...)
+}
+
+func optionsFromConfig(cfg *ConfigV2) []Option {
+	detectors := make([]Detector, 0, len(cfg.Detectors))
+	for _, name := range cfg.Detectors {
+		detectors = append(detectors, Detector(name))
+	}
+	return []Option{
+		WithDetectors(detectors...),
+		WithOverride(cfg.Override),
+		WithTimeout(cfg.Timeout),
+		WithMachineIDPath(cfg.MachineIDPath),
+		WithEndpoint(DetectorAWS, cfg.AWSEndpoint),
+		WithEndpoint(DetectorGCE, cfg.GCEEndpoint),
+		WithEndpoint(DetectorAzure, cfg.AzureEndpoint),
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package resourcedetectionprocessor contains a processor that detects, at
+// startup, the identity of the host the collector runs on, e.g. its name,
+// machine ID and cloud instance, and adds it to the Resource labels of the
+// traces and metrics going through the collector.
+package resourcedetectionprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/processor"
+)
+
+// detection holds the settings of the detectors and the detected Resource,
+// shared by the trace and metrics processors.
+type detection struct {
+	detectors     []Detector
+	override      bool
+	timeout       time.Duration
+	machineIDPath string
+	endpoints     map[Detector]string
+	resource      *resourcepb.Resource
+}
+
+// Option represents options that can be applied to the resource detection
+// processor.
+type Option func(*detection) error
+
+// WithDetectors returns an Option to run the given detectors, in order. A
+// label found by a detector isn't replaced by the next ones.
+func WithDetectors(detectors ...Detector) Option {
+	return func(d *detection) error {
+		for _, detector := range detectors {
+			if _, ok := detectFuncs[detector]; !ok {
+				return fmt.Errorf("unknown detector %q", detector)
+			}
+		}
+		d.detectors = detectors
+		return nil
+	}
+}
+
+// WithOverride returns an Option to replace the Resource labels the data
+// already has with the detected ones, instead of keeping them.
+func WithOverride(override bool) Option {
+	return func(d *detection) error {
+		d.override = override
+		return nil
+	}
+}
+
+// WithTimeout returns an Option to bound the time spent querying the cloud
+// metadata endpoints. Zero means no timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *detection) error {
+		if timeout < 0 {
+			return fmt.Errorf("negative timeout %v", timeout)
+		}
+		d.timeout = timeout
+		return nil
+	}
+}
+
+// WithMachineIDPath returns an Option to read the machine ID from the given
+// file.
+func WithMachineIDPath(path string) Option {
+	return func(d *detection) error {
+		d.machineIDPath = path
+		return nil
+	}
+}
+
+// WithEndpoint returns an Option to query the metadata of a cloud detector
+// at the given base URL, e.g. a local stand-in for tests.
+func WithEndpoint(detector Detector, endpoint string) Option {
+	return func(d *detection) error {
+		switch detector {
+		case DetectorAWS, DetectorGCE, DetectorAzure:
+		default:
+			return fmt.Errorf("detector %q has no endpoint", detector)
+		}
+		if _, err := url.Parse(endpoint); err != nil {
+			return fmt.Errorf("invalid endpoint for detector %q: %v", detector, err)
+		}
+		d.endpoints[detector] = endpoint
+		return nil
+	}
+}
+
+func newDetection(options []Option) (*detection, error) {
+	d := &detection{
+		detectors:     []Detector{DetectorHost},
+		machineIDPath: defaultMachineIDPath,
+		endpoints: map[Detector]string{
+			DetectorAWS:   defaultAWSEndpoint,
+			DetectorGCE:   defaultGCEEndpoint,
+			DetectorAzure: defaultAzureEndpoint,
+		},
+	}
+	for _, opt := range options {
+		if err := opt(d); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx := context.Background()
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+	d.resource = d.detect(ctx)
+	return d, nil
+}
+
+// detect runs the detectors and merges what they found. The detectors that
+// don't apply, e.g. the ones of other clouds, find nothing.
+func (d *detection) detect(ctx context.Context) *resourcepb.Resource {
+	var detected *resourcepb.Resource
+	for _, detector := range d.detectors {
+		res, err := detectFuncs[detector](ctx, d)
+		if err != nil || res == nil {
+			continue
+		}
+		detected = mergeResource(detected, res, false)
+	}
+	return detected
+}
+
+// apply returns the Resource of the data with the detected Resource merged
+// in.
+func (d *detection) apply(resource *resourcepb.Resource) *resourcepb.Resource {
+	if d.resource == nil {
+		return resource
+	}
+	return mergeResource(resource, d.resource, d.override)
+}
+
+// mergeResource returns a new Resource with the type and labels of from added
+// to the ones of to. The type and labels of to are replaced only if override
+// is set.
+func mergeResource(to, from *resourcepb.Resource, override bool) *resourcepb.Resource {
+	merged := &resourcepb.Resource{
+		Type:   to.GetType(),
+		Labels: make(map[string]string, len(to.GetLabels())+len(from.GetLabels())),
+	}
+	if merged.Type == "" || (override && from.GetType() != "") {
+		merged.Type = from.GetType()
+	}
+	for k, v := range to.GetLabels() {
+		merged.Labels[k] = v
+	}
+	for k, v := range from.GetLabels() {
+		if _, exists := merged.Labels[k]; !exists || override {
+			merged.Labels[k] = v
+		}
+	}
+	return merged
+}
+
+type traceProcessor struct {
+	*detection
+	nextConsumer consumer.TraceConsumer
+}
+
+var _ processor.TraceProcessor = (*traceProcessor)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that adds the Resource
+// detected when it is created to each batch of spans.
+func NewTraceProcessor(nextConsumer consumer.TraceConsumer, options ...Option) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+	d, err := newDetection(options)
+	if err != nil {
+		return nil, err
+	}
+	return &traceProcessor{detection: d, nextConsumer: nextConsumer}, nil
+}
+
+func (tp *traceProcessor) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	td.Resource = tp.apply(td.Resource)
+	return tp.nextConsumer.ConsumeTraceData(ctx, td)
+}
+
+type metricsProcessor struct {
+	*detection
+	nextConsumer consumer.MetricsConsumer
+}
+
+var _ processor.MetricsProcessor = (*metricsProcessor)(nil)
+
+// NewMetricsProcessor returns a processor.MetricsProcessor that adds the
+// Resource detected when it is created to each batch of metrics. Metrics
+// with their own Resource are left as is.
+func NewMetricsProcessor(nextConsumer consumer.MetricsConsumer, options ...Option) (processor.MetricsProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+	d, err := newDetection(options)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsProcessor{detection: d, nextConsumer: nextConsumer}, nil
+}
+
+func (mp *metricsProcessor) ConsumeMetricsData(ctx context.Context, md data.MetricsData) error {
+	md.Resource = mp.apply(md.Resource)
+	return mp.nextConsumer.ConsumeMetricsData(ctx, md)
+}
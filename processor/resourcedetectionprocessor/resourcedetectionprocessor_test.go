@@ -0,0 +1,126 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcedetectionprocessor
+
+import (
+	"context"
+	"testing"
+
+	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+)
+
+func TestNewTraceProcessor(t *testing.T) {
+	_, err := NewTraceProcessor(nil)
+	assert.Error(t, err)
+
+	sink := &exportertest.SinkTraceExporter{}
+	_, err = NewTraceProcessor(sink, WithDetectors("ec2"))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithEndpoint(DetectorHost, "http://localhost"))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithTimeout(-1))
+	assert.Error(t, err)
+}
+
+func TestMergeResource(t *testing.T) {
+	detected := &resourcepb.Resource{
+		Type:   ResourceTypeHost,
+		Labels: map[string]string{LabelHostName: "host-1", LabelOSType: "linux"},
+	}
+
+	tests := []struct {
+		name     string
+		override bool
+		resource *resourcepb.Resource
+		want     *resourcepb.Resource
+	}{
+		{
+			name: "no_resource",
+			want: detected,
+		},
+		{
+			name:     "no_type",
+			resource: &resourcepb.Resource{Labels: map[string]string{"service.version": "1.0"}},
+			want: &resourcepb.Resource{
+				Type:   ResourceTypeHost,
+				Labels: map[string]string{LabelHostName: "host-1", LabelOSType: "linux", "service.version": "1.0"},
+			},
+		},
+		{
+			name:     "keep",
+			resource: &resourcepb.Resource{Type: "container", Labels: map[string]string{LabelHostName: "host-2"}},
+			want: &resourcepb.Resource{
+				Type:   "container",
+				Labels: map[string]string{LabelHostName: "host-2", LabelOSType: "linux"},
+			},
+		},
+		{
+			name:     "override",
+			override: true,
+			resource: &resourcepb.Resource{Type: "container", Labels: map[string]string{LabelHostName: "host-2"}},
+			want:     detected,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &detection{override: tt.override, resource: detected}
+			sink := &exportertest.SinkTraceExporter{}
+			tp := &traceProcessor{detection: d, nextConsumer: sink}
+
+			var original *resourcepb.Resource
+			if tt.resource != nil {
+				original = &resourcepb.Resource{Type: tt.resource.Type, Labels: map[string]string{}}
+				for k, v := range tt.resource.Labels {
+					original.Labels[k] = v
+				}
+			}
+			require.NoError(t, tp.ConsumeTraceData(context.Background(), data.TraceData{Resource: tt.resource}))
+
+			got := sink.AllTraces()
+			require.Len(t, got, 1)
+			assert.Equal(t, tt.want, got[0].Resource)
+			// The Resource of the received batch must not be changed.
+			assert.Equal(t, original, tt.resource)
+		})
+	}
+}
+
+func TestMetricsProcessor(t *testing.T) {
+	sink := &exportertest.SinkMetricsExporter{}
+	mp, err := NewMetricsProcessor(sink, WithDetectors(DetectorHost))
+	require.NoError(t, err)
+
+	require.NoError(t, mp.ConsumeMetricsData(context.Background(), data.MetricsData{}))
+	got := sink.AllMetrics()
+	require.Len(t, got, 1)
+	assert.Equal(t, ResourceTypeHost, got[0].Resource.GetType())
+	assert.NotEmpty(t, got[0].Resource.GetLabels()[LabelOSType])
+}
+
+func TestNothingDetected(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(sink, WithDetectors())
+	require.NoError(t, err)
+
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), data.TraceData{}))
+	got := sink.AllTraces()
+	require.Len(t, got, 1)
+	assert.Nil(t, got[0].Resource)
+}
@@ -0,0 +1,44 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routingprocessor
+
+import (
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+)
+
+// ConfigV2 defines configuration for the routing processor.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// AttributeSource is where the value looked up in the routing table is
+	// read from: "node" for a Node attribute, "resource" for a Resource label,
+	// "span" for a span attribute or "source_format" for the format the data
+	// was received in. The last two are only supported for traces.
+	AttributeSource string `mapstructure:"attribute_source"`
+	// FromAttribute is the key of the attribute or label holding the value
+	// looked up in the routing table. It is not used for "source_format".
+	FromAttribute string `mapstructure:"from_attribute"`
+	// DefaultExporters are the exporters the data without a route in the
+	// table is sent to. If empty, such data is dropped.
+	DefaultExporters []string `mapstructure:"default_exporters"`
+	// Table lists the exporters the data is sent to for each value.
+	Table []RoutingTableItem `mapstructure:"table"`
+}
+
+// RoutingTableItem defines the exporters the data with a given value is sent
+// to.
+type RoutingTableItem struct {
+	Value     string   `mapstructure:"value"`
+	Exporters []string `mapstructure:"exporters"`
+}
@@ -0,0 +1,99 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routingprocessor
+
+import (
+	"errors"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+	"github.com/census-instrumentation/opencensus-service/internal/factories"
+	"github.com/census-instrumentation/opencensus-service/processor"
+)
+
+var _ = factories.RegisterProcessorFactory(&processorFactory{})
+
+const (
+	// The value of "type" key in configuration.
+	typeStr = "routing"
+)
+
+var errNotLastProcessor = errors.New("routing processor must be the last processor of its pipeline")
+
+// processorFactory is the factory for the routing processor.
+type processorFactory struct {
+}
+
+var _ factories.RoutingProcessorFactory = (*processorFactory)(nil)
+
+// Type gets the type of the config created by this factory.
+func (f *processorFactory) Type() string {
+	return typeStr
+}
+
+// CreateDefaultConfig creates the default configuration for the processor.
+func (f *processorFactory) CreateDefaultConfig() configmodels.Processor {
+	return &ConfigV2{
+		ProcessorSettings: configmodels.ProcessorSettings{
+			TypeVal: typeStr,
+		},
+		AttributeSource: string(SourceNode),
+	}
+}
+
+// CreateTraceProcessor fails, the routing processor has to be created by
+// CreateRoutingTraceProcessor.
+func (f *processorFactory) CreateTraceProcessor(
+	nextConsumer consumer.TraceConsumer,
+	cfg configmodels.Processor,
+) (processor.TraceProcessor, error) {
+	return nil, errNotLastProcessor
+}
+
+// CreateMetricsProcessor fails, the routing processor has to be created by
+// CreateRoutingMetricsProcessor.
+func (f *processorFactory) CreateMetricsProcessor(
+	nextConsumer consumer.MetricsConsumer,
+	cfg configmodels.Processor,
+) (processor.MetricsProcessor, error) {
+	return nil, errNotLastProcessor
+}
+
+// CreateRoutingTraceProcessor creates a trace processor based on this config.
+func (f *processorFactory) CreateRoutingTraceProcessor(
+	exporters map[string]consumer.TraceConsumer,
+	cfg configmodels.Processor,
+) (processor.TraceProcessor, error) {
+	return NewTraceProcessor(exporters, optionsFromConfig(cfg.(*ConfigV2))...)
+}
+
+// CreateRoutingMetricsProcessor creates a metrics processor based on this config.
+func (f *processorFactory) CreateRoutingMetricsProcessor(
+	exporters map[string]consumer.MetricsConsumer,
+	cfg configmodels.Processor,
+) (processor.MetricsProcessor, error) {
+	return NewMetricsProcessor(exporters, optionsFromConfig(cfg.(*ConfigV2))...)
+}
+
+func optionsFromConfig(oCfg *ConfigV2) []Option {
+	opts := []Option{
+		WithAttributeSource(AttributeSource(oCfg.AttributeSource), oCfg.FromAttribute),
+		WithDefaultRoute(oCfg.DefaultExporters),
+	}
+	for _, item := range oCfg.Table {
+		opts = append(opts, WithRoute(item.Value, item.Exporters))
+	}
+	return opts
+}
@@ -0,0 +1,68 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routingprocessor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+	"github.com/census-instrumentation/opencensus-service/internal/factories"
+)
+
+func TestCreateDefaultConfig(t *testing.T) {
+	factory := factories.GetProcessorFactory(typeStr)
+	require.NotNil(t, factory)
+
+	cfg := factory.CreateDefaultConfig()
+	assert.NotNil(t, cfg, "failed to create default config")
+}
+
+func TestCreateProcessor(t *testing.T) {
+	factory := factories.GetProcessorFactory(typeStr)
+	require.NotNil(t, factory)
+
+	cfg := factory.CreateDefaultConfig().(*ConfigV2)
+	cfg.FromAttribute = "tenant"
+	cfg.DefaultExporters = []string{"exporter"}
+
+	tp, err := factory.CreateTraceProcessor(exportertest.NewNopTraceExporter(), cfg)
+	assert.Nil(t, tp)
+	assert.Equal(t, errNotLastProcessor, err)
+
+	mp, err := factory.CreateMetricsProcessor(exportertest.NewNopMetricsExporter(), cfg)
+	assert.Nil(t, mp)
+	assert.Equal(t, errNotLastProcessor, err)
+
+	routingFactory, ok := factory.(factories.RoutingProcessorFactory)
+	require.True(t, ok)
+
+	tp, err = routingFactory.CreateRoutingTraceProcessor(
+		map[string]consumer.TraceConsumer{"exporter": exportertest.NewNopTraceExporter()}, cfg)
+	assert.NotNil(t, tp)
+	assert.NoError(t, err, "cannot create trace processor")
+
+	mp, err = routingFactory.CreateRoutingMetricsProcessor(
+		map[string]consumer.MetricsConsumer{"exporter": exportertest.NewNopMetricsExporter()}, cfg)
+	assert.NotNil(t, mp)
+	assert.NoError(t, err, "cannot create metrics processor")
+
+	tp, err = routingFactory.CreateRoutingTraceProcessor(map[string]consumer.TraceConsumer{}, cfg)
+	assert.Nil(t, tp)
+	assert.Error(t, err, "default exporter is not in the pipeline")
+}
@@ -0,0 +1,365 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package routingprocessor contains a processor that sends each batch, or
+// each span, to the exporters of its pipeline chosen by looking up the value
+// of an attribute in a routing table, instead of to all of them.
+package routingprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/internal"
+	"github.com/census-instrumentation/opencensus-service/processor"
+	"github.com/census-instrumentation/opencensus-service/processor/multiconsumer"
+)
+
+// AttributeSource is where the value looked up in the routing table is read
+// from.
+type AttributeSource string
+
+const (
+	// SourceNode routes by an attribute of the Node of each batch.
+	SourceNode AttributeSource = "node"
+	// SourceResource routes by a label of the Resource of each batch, or of
+	// each metric for the metrics that have their own Resource.
+	SourceResource AttributeSource = "resource"
+	// SourceSpan routes each span by one of its attributes. Spans of the same
+	// batch may be sent to different exporters.
+	SourceSpan AttributeSource = "span"
+	// SourceFormat routes by the format each batch of spans was received in.
+	SourceFormat AttributeSource = "source_format"
+)
+
+// router holds the routing table shared by the trace and metrics processors.
+type router struct {
+	source       AttributeSource
+	key          string
+	table        map[string][]string
+	defaultRoute []string
+}
+
+// Option represents options that can be applied to the routing processor.
+type Option func(*router) error
+
+// WithAttributeSource returns an Option to configure where the value looked up
+// in the routing table is read from. The key is ignored for SourceFormat.
+func WithAttributeSource(source AttributeSource, key string) Option {
+	return func(r *router) error {
+		switch source {
+		case SourceNode, SourceResource, SourceSpan:
+			if key == "" {
+				return fmt.Errorf("attribute source %q requires an attribute key", source)
+			}
+		case SourceFormat:
+		default:
+			return fmt.Errorf("unknown attribute source %q", source)
+		}
+		r.source = source
+		r.key = key
+		return nil
+	}
+}
+
+// WithRoute returns an Option to send the data with the given value to the
+// given exporters.
+func WithRoute(value string, exporters []string) Option {
+	return func(r *router) error {
+		if len(exporters) == 0 {
+			return fmt.Errorf("route for value %q has no exporters", value)
+		}
+		if _, ok := r.table[value]; ok {
+			return fmt.Errorf("duplicate route for value %q", value)
+		}
+		r.table[value] = exporters
+		return nil
+	}
+}
+
+// WithDefaultRoute returns an Option to send the data without a route to the
+// given exporters. By default such data is dropped.
+func WithDefaultRoute(exporters []string) Option {
+	return func(r *router) error {
+		r.defaultRoute = exporters
+		return nil
+	}
+}
+
+func newRouter(options []Option) (*router, error) {
+	r := &router{source: SourceNode, table: make(map[string][]string)}
+	for _, opt := range options {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+	if r.source != SourceFormat && r.key == "" {
+		return nil, errors.New("attribute key is required")
+	}
+	return r, nil
+}
+
+// checkExporters returns an error if a route references an exporter that is
+// not in the pipeline.
+func (r *router) checkExporters(hasExporter func(name string) bool) error {
+	check := func(names []string) error {
+		for _, name := range names {
+			if !hasExporter(name) {
+				return fmt.Errorf("exporter %q is not in the pipeline", name)
+			}
+		}
+		return nil
+	}
+	if err := check(r.defaultRoute); err != nil {
+		return err
+	}
+	for _, names := range r.table {
+		if err := check(names); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type traceRoute struct {
+	consumer consumer.TraceConsumer
+}
+
+type traceRouter struct {
+	*router
+	routes       map[string]*traceRoute
+	defaultRoute *traceRoute
+}
+
+var _ processor.TraceProcessor = (*traceRouter)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that sends each batch
+// of spans to the exporters of the route matching it, from the given exporters
+// keyed by name.
+func NewTraceProcessor(exporters map[string]consumer.TraceConsumer, options ...Option) (processor.TraceProcessor, error) {
+	r, err := newRouter(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkExporters(func(name string) bool { return exporters[name] != nil }); err != nil {
+		return nil, err
+	}
+
+	newRoute := func(names []string) *traceRoute {
+		if len(names) == 0 {
+			return nil
+		}
+		if len(names) == 1 {
+			return &traceRoute{exporters[names[0]]}
+		}
+		tcs := make([]consumer.TraceConsumer, 0, len(names))
+		for _, name := range names {
+			tcs = append(tcs, exporters[name])
+		}
+		return &traceRoute{multiconsumer.NewTraceProcessor(tcs)}
+	}
+
+	tr := &traceRouter{
+		router:       r,
+		routes:       make(map[string]*traceRoute, len(r.table)),
+		defaultRoute: newRoute(r.defaultRoute),
+	}
+	for value, names := range r.table {
+		tr.routes[value] = newRoute(names)
+	}
+	return tr, nil
+}
+
+func (tr *traceRouter) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	var value string
+	var ok bool
+	switch tr.source {
+	case SourceNode:
+		value, ok = td.Node.GetAttributes()[tr.key]
+	case SourceResource:
+		value, ok = td.Resource.GetLabels()[tr.key]
+	case SourceFormat:
+		value, ok = td.SourceFormat, true
+	case SourceSpan:
+		return tr.routeSpans(ctx, td)
+	}
+
+	route := tr.route(value, ok)
+	if route == nil {
+		return nil
+	}
+	return route.consumer.ConsumeTraceData(ctx, td)
+}
+
+// routeSpans splits the batch in one batch per route, keeping the order of the
+// spans.
+func (tr *traceRouter) routeSpans(ctx context.Context, td data.TraceData) error {
+	var order []*traceRoute
+	spansByRoute := make(map[*traceRoute][]*tracepb.Span)
+	for _, span := range td.Spans {
+		value, ok := attributeValueString(span.GetAttributes().GetAttributeMap()[tr.key])
+		route := tr.route(value, ok)
+		if route == nil {
+			continue
+		}
+		if _, seen := spansByRoute[route]; !seen {
+			order = append(order, route)
+		}
+		spansByRoute[route] = append(spansByRoute[route], span)
+	}
+
+	var errs []error
+	for _, route := range order {
+		routed := td
+		routed.Spans = spansByRoute[route]
+		if err := route.consumer.ConsumeTraceData(ctx, routed); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return internal.CombineErrors(errs)
+}
+
+func (tr *traceRouter) route(value string, ok bool) *traceRoute {
+	if ok {
+		if route, found := tr.routes[value]; found {
+			return route
+		}
+	}
+	return tr.defaultRoute
+}
+
+type metricsRoute struct {
+	consumer consumer.MetricsConsumer
+}
+
+type metricsRouter struct {
+	*router
+	routes       map[string]*metricsRoute
+	defaultRoute *metricsRoute
+}
+
+var _ processor.MetricsProcessor = (*metricsRouter)(nil)
+
+// NewMetricsProcessor returns a processor.MetricsProcessor that sends each
+// batch of metrics to the exporters of the route matching it, from the given
+// exporters keyed by name. Only SourceNode and SourceResource are supported.
+func NewMetricsProcessor(exporters map[string]consumer.MetricsConsumer, options ...Option) (processor.MetricsProcessor, error) {
+	r, err := newRouter(options)
+	if err != nil {
+		return nil, err
+	}
+	if r.source != SourceNode && r.source != SourceResource {
+		return nil, fmt.Errorf("attribute source %q is not supported for metrics", r.source)
+	}
+	if err := r.checkExporters(func(name string) bool { return exporters[name] != nil }); err != nil {
+		return nil, err
+	}
+
+	newRoute := func(names []string) *metricsRoute {
+		if len(names) == 0 {
+			return nil
+		}
+		if len(names) == 1 {
+			return &metricsRoute{exporters[names[0]]}
+		}
+		mcs := make([]consumer.MetricsConsumer, 0, len(names))
+		for _, name := range names {
+			mcs = append(mcs, exporters[name])
+		}
+		return &metricsRoute{multiconsumer.NewMetricsProcessor(mcs)}
+	}
+
+	mr := &metricsRouter{
+		router:       r,
+		routes:       make(map[string]*metricsRoute, len(r.table)),
+		defaultRoute: newRoute(r.defaultRoute),
+	}
+	for value, names := range r.table {
+		mr.routes[value] = newRoute(names)
+	}
+	return mr, nil
+}
+
+func (mr *metricsRouter) ConsumeMetricsData(ctx context.Context, md data.MetricsData) error {
+	if mr.source == SourceNode {
+		value, ok := md.Node.GetAttributes()[mr.key]
+		route := mr.route(value, ok)
+		if route == nil {
+			return nil
+		}
+		return route.consumer.ConsumeMetricsData(ctx, md)
+	}
+
+	// Metrics with their own Resource are routed by it, so the batch may have
+	// to be split.
+	batchValue, batchOK := md.Resource.GetLabels()[mr.key]
+	var order []*metricsRoute
+	metricsByRoute := make(map[*metricsRoute][]*metricspb.Metric)
+	for _, metric := range md.Metrics {
+		value, ok := batchValue, batchOK
+		if metric.GetResource() != nil {
+			value, ok = metric.Resource.Labels[mr.key]
+		}
+		route := mr.route(value, ok)
+		if route == nil {
+			continue
+		}
+		if _, seen := metricsByRoute[route]; !seen {
+			order = append(order, route)
+		}
+		metricsByRoute[route] = append(metricsByRoute[route], metric)
+	}
+
+	var errs []error
+	for _, route := range order {
+		routed := md
+		routed.Metrics = metricsByRoute[route]
+		if err := route.consumer.ConsumeMetricsData(ctx, routed); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return internal.CombineErrors(errs)
+}
+
+func (mr *metricsRouter) route(value string, ok bool) *metricsRoute {
+	if ok {
+		if route, found := mr.routes[value]; found {
+			return route
+		}
+	}
+	return mr.defaultRoute
+}
+
+// attributeValueString returns the string representation of a span attribute
+// value, or false if there is no value.
+func attributeValueString(attr *tracepb.AttributeValue) (string, bool) {
+	switch value := attr.GetValue().(type) {
+	case *tracepb.AttributeValue_StringValue:
+		return value.StringValue.GetValue(), true
+	case *tracepb.AttributeValue_IntValue:
+		return strconv.FormatInt(value.IntValue, 10), true
+	case *tracepb.AttributeValue_BoolValue:
+		return strconv.FormatBool(value.BoolValue), true
+	case *tracepb.AttributeValue_DoubleValue:
+		return strconv.FormatFloat(value.DoubleValue, 'g', -1, 64), true
+	}
+	return "", false
+}
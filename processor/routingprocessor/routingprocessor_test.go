@@ -0,0 +1,276 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routingprocessor
+
+import (
+	"context"
+	"testing"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+)
+
+func newTraceSinks(names ...string) (map[string]consumer.TraceConsumer, map[string]*exportertest.SinkTraceExporter) {
+	exporters := make(map[string]consumer.TraceConsumer)
+	sinks := make(map[string]*exportertest.SinkTraceExporter)
+	for _, name := range names {
+		sink := &exportertest.SinkTraceExporter{}
+		exporters[name] = sink
+		sinks[name] = sink
+	}
+	return exporters, sinks
+}
+
+func TestNewTraceProcessor(t *testing.T) {
+	exporters, _ := newTraceSinks("a", "b")
+	tests := []struct {
+		name    string
+		options []Option
+		wantErr bool
+	}{
+		{
+			name:    "no_key",
+			options: []Option{WithDefaultRoute([]string{"a"})},
+			wantErr: true,
+		},
+		{
+			name:    "source_format_without_key",
+			options: []Option{WithAttributeSource(SourceFormat, "")},
+		},
+		{
+			name:    "unknown_source",
+			options: []Option{WithAttributeSource("unknown", "key")},
+			wantErr: true,
+		},
+		{
+			name:    "unknown_exporter",
+			options: []Option{WithAttributeSource(SourceNode, "key"), WithRoute("x", []string{"c"})},
+			wantErr: true,
+		},
+		{
+			name:    "unknown_default_exporter",
+			options: []Option{WithAttributeSource(SourceNode, "key"), WithDefaultRoute([]string{"c"})},
+			wantErr: true,
+		},
+		{
+			name:    "empty_route",
+			options: []Option{WithAttributeSource(SourceNode, "key"), WithRoute("x", nil)},
+			wantErr: true,
+		},
+		{
+			name: "duplicate_route",
+			options: []Option{
+				WithAttributeSource(SourceNode, "key"),
+				WithRoute("x", []string{"a"}),
+				WithRoute("x", []string{"b"}),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tp, err := NewTraceProcessor(exporters, tt.options...)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, tp)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, tp)
+		})
+	}
+}
+
+func TestRouteTraces(t *testing.T) {
+	nodeWith := func(tenant string) *commonpb.Node {
+		return &commonpb.Node{Attributes: map[string]string{"tenant": tenant}}
+	}
+	resourceWith := func(tenant string) *resourcepb.Resource {
+		return &resourcepb.Resource{Labels: map[string]string{"tenant": tenant}}
+	}
+	tests := []struct {
+		name   string
+		source AttributeSource
+		td     data.TraceData
+		want   []string
+	}{
+		{
+			name:   "node",
+			source: SourceNode,
+			td:     data.TraceData{Node: nodeWith("acme")},
+			want:   []string{"a"},
+		},
+		{
+			name:   "node_multiple_exporters",
+			source: SourceNode,
+			td:     data.TraceData{Node: nodeWith("globex")},
+			want:   []string{"a", "b"},
+		},
+		{
+			name:   "node_default",
+			source: SourceNode,
+			td:     data.TraceData{Node: nodeWith("initech")},
+			want:   []string{"c"},
+		},
+		{
+			name:   "node_missing",
+			source: SourceNode,
+			td:     data.TraceData{},
+			want:   []string{"c"},
+		},
+		{
+			name:   "resource",
+			source: SourceResource,
+			td:     data.TraceData{Node: nodeWith("globex"), Resource: resourceWith("acme")},
+			want:   []string{"a"},
+		},
+		{
+			name:   "source_format",
+			source: SourceFormat,
+			td:     data.TraceData{SourceFormat: "acme"},
+			want:   []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporters, sinks := newTraceSinks("a", "b", "c")
+			tp, err := NewTraceProcessor(
+				exporters,
+				WithAttributeSource(tt.source, "tenant"),
+				WithRoute("acme", []string{"a"}),
+				WithRoute("globex", []string{"a", "b"}),
+				WithDefaultRoute([]string{"c"}),
+			)
+			require.NoError(t, err)
+			require.NoError(t, tp.ConsumeTraceData(context.Background(), tt.td))
+
+			for name, sink := range sinks {
+				if contains(tt.want, name) {
+					assert.Equal(t, []data.TraceData{tt.td}, sink.AllTraces(), name)
+				} else {
+					assert.Empty(t, sink.AllTraces(), name)
+				}
+			}
+		})
+	}
+}
+
+func TestRouteTraces_Spans(t *testing.T) {
+	spanWith := func(value *tracepb.AttributeValue) *tracepb.Span {
+		span := &tracepb.Span{}
+		if value != nil {
+			span.Attributes = &tracepb.Span_Attributes{
+				AttributeMap: map[string]*tracepb.AttributeValue{"tenant": value},
+			}
+		}
+		return span
+	}
+	acme := spanWith(&tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: "acme"}},
+	})
+	numeric := spanWith(&tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_IntValue{IntValue: 42},
+	})
+	other := spanWith(&tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_BoolValue{BoolValue: true},
+	})
+	missing := spanWith(nil)
+	acme2 := spanWith(&tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: "acme"}},
+	})
+
+	exporters, sinks := newTraceSinks("a", "b")
+	tp, err := NewTraceProcessor(
+		exporters,
+		WithAttributeSource(SourceSpan, "tenant"),
+		WithRoute("acme", []string{"a"}),
+		WithRoute("42", []string{"b"}),
+	)
+	require.NoError(t, err)
+
+	node := &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "svc"}}
+	td := data.TraceData{Node: node, Spans: []*tracepb.Span{acme, numeric, other, missing, acme2}}
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), td))
+
+	// Spans without a route are dropped since there is no default route.
+	assert.Equal(t, []data.TraceData{{Node: node, Spans: []*tracepb.Span{acme, acme2}}}, sinks["a"].AllTraces())
+	assert.Equal(t, []data.TraceData{{Node: node, Spans: []*tracepb.Span{numeric}}}, sinks["b"].AllTraces())
+}
+
+func TestNewMetricsProcessor(t *testing.T) {
+	exporters := map[string]consumer.MetricsConsumer{"a": &exportertest.SinkMetricsExporter{}}
+
+	_, err := NewMetricsProcessor(exporters, WithAttributeSource(SourceSpan, "tenant"))
+	assert.Error(t, err)
+	_, err = NewMetricsProcessor(exporters, WithAttributeSource(SourceFormat, ""))
+	assert.Error(t, err)
+	_, err = NewMetricsProcessor(exporters, WithAttributeSource(SourceResource, "tenant"), WithDefaultRoute([]string{"b"}))
+	assert.Error(t, err)
+}
+
+func TestRouteMetrics(t *testing.T) {
+	sinkA := &exportertest.SinkMetricsExporter{}
+	sinkB := &exportertest.SinkMetricsExporter{}
+	exporters := map[string]consumer.MetricsConsumer{"a": sinkA, "b": sinkB}
+
+	mp, err := NewMetricsProcessor(
+		exporters,
+		WithAttributeSource(SourceResource, "tenant"),
+		WithRoute("acme", []string{"a"}),
+		WithDefaultRoute([]string{"b"}),
+	)
+	require.NoError(t, err)
+
+	inherited := &metricspb.Metric{MetricDescriptor: &metricspb.MetricDescriptor{Name: "inherited"}}
+	own := &metricspb.Metric{
+		MetricDescriptor: &metricspb.MetricDescriptor{Name: "own"},
+		Resource:         &resourcepb.Resource{Labels: map[string]string{"tenant": "initech"}},
+	}
+	resource := &resourcepb.Resource{Labels: map[string]string{"tenant": "acme"}}
+	md := data.MetricsData{Resource: resource, Metrics: []*metricspb.Metric{inherited, own}}
+	require.NoError(t, mp.ConsumeMetricsData(context.Background(), md))
+
+	assert.Equal(t, []data.MetricsData{{Resource: resource, Metrics: []*metricspb.Metric{inherited}}}, sinkA.AllMetrics())
+	assert.Equal(t, []data.MetricsData{{Resource: resource, Metrics: []*metricspb.Metric{own}}}, sinkB.AllMetrics())
+
+	mp, err = NewMetricsProcessor(
+		exporters,
+		WithAttributeSource(SourceNode, "tenant"),
+		WithRoute("acme", []string{"a"}),
+	)
+	require.NoError(t, err)
+
+	md = data.MetricsData{Node: &commonpb.Node{Attributes: map[string]string{"tenant": "acme"}}}
+	require.NoError(t, mp.ConsumeMetricsData(context.Background(), md))
+	assert.Equal(t, md, sinkA.AllMetrics()[1])
+	assert.Len(t, sinkB.AllMetrics(), 1)
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanlimitsprocessor
+
+import (
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+)
+
+// ConfigV2 defines configuration for the span limits processor. A limit of
+// zero means no limit.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// MaxAttributes is the maximum number of attributes of a span, annotation
+	// or link.
+	MaxAttributes int `mapstructure:"max_attributes"`
+	// MaxAttributeValueLength is the maximum length, in bytes, of string
+	// attribute values.
+	MaxAttributeValueLength int `mapstructure:"max_attribute_value_length"`
+	// MaxAnnotations is the maximum number of annotations of a span.
+	MaxAnnotations int `mapstructure:"max_annotations"`
+	// MaxMessageEvents is the maximum number of message events of a span.
+	MaxMessageEvents int `mapstructure:"max_message_events"`
+	// MaxLinks is the maximum number of links of a span.
+	MaxLinks int `mapstructure:"max_links"`
+	// MaxStackFrames is the maximum number of frames of the stack trace of a
+	// span.
+	MaxStackFrames int `mapstructure:"max_stack_frames"`
+}
@@ -0,0 +1,101 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanlimitsprocessor
+
+import (
+	"context"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/census-instrumentation/opencensus-service/internal/collector/processor"
+	"github.com/census-instrumentation/opencensus-service/internal/collector/telemetry"
+)
+
+// Variables related to metrics specific to the span limits processor.
+var (
+	tagLimitKey, _ = tag.NewKey("limit")
+
+	statDroppedCount = stats.Int64("spanlimits_dropped", "Count of span parts dropped or truncated for exceeding a limit", stats.UnitDimensionless)
+)
+
+// Values of tagLimitKey, one per limit.
+const (
+	limitAttributes           = "attributes"
+	limitAttributeValueLength = "attribute_value_length"
+	limitAnnotations          = "annotations"
+	limitMessageEvents        = "message_events"
+	limitLinks                = "links"
+	limitStackFrames          = "stack_frames"
+)
+
+// droppedCounts accumulates what was dropped from the spans of a batch.
+type droppedCounts struct {
+	attributes      int64
+	truncatedValues int64
+	annotations     int64
+	messageEvents   int64
+	links           int64
+	stackFrames     int64
+}
+
+func (dc *droppedCounts) record(ctx context.Context, node *commonpb.Node) {
+	serviceName := processor.ServiceNameForNode(node)
+	for _, count := range []struct {
+		limit string
+		value int64
+	}{
+		{limitAttributes, dc.attributes},
+		{limitAttributeValueLength, dc.truncatedValues},
+		{limitAnnotations, dc.annotations},
+		{limitMessageEvents, dc.messageEvents},
+		{limitLinks, dc.links},
+		{limitStackFrames, dc.stackFrames},
+	} {
+		if count.value == 0 {
+			continue
+		}
+		_ = stats.RecordWithTags(
+			ctx,
+			[]tag.Mutator{
+				tag.Upsert(tagLimitKey, count.limit),
+				tag.Upsert(processor.TagServiceNameKey, serviceName),
+			},
+			statDroppedCount.M(count.value))
+	}
+}
+
+// MetricViews return the metrics views according to given telemetry level.
+func MetricViews(level telemetry.Level) []*view.View {
+	if level == telemetry.None {
+		return nil
+	}
+
+	tagKeys := []tag.Key{tagLimitKey}
+	if level == telemetry.Detailed {
+		tagKeys = append(tagKeys, processor.TagServiceNameKey)
+	}
+
+	droppedView := &view.View{
+		Name:        statDroppedCount.Name(),
+		Measure:     statDroppedCount,
+		Description: statDroppedCount.Description(),
+		TagKeys:     tagKeys,
+		Aggregation: view.Sum(),
+	}
+	return []*view.View{droppedView}
+}
@@ -0,0 +1,210 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package spanlimitsprocessor contains a trace processor that enforces limits
+// on the size of spans, dropping or truncating the parts of a span that exceed
+// them and recording how much was dropped in the span itself.
+package spanlimitsprocessor
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"unicode/utf8"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/processor"
+)
+
+// Limits are the limits enforced on each span. A limit of zero means no limit.
+type Limits struct {
+	// MaxAttributes is the maximum number of attributes of a span, annotation
+	// or link. The attributes with the lexicographically smallest keys are
+	// kept.
+	MaxAttributes int
+	// MaxAttributeValueLength is the maximum length, in bytes, of string
+	// attribute values. Values are truncated at a rune boundary.
+	MaxAttributeValueLength int
+	// MaxAnnotations is the maximum number of annotations of a span, the
+	// earliest ones are kept.
+	MaxAnnotations int
+	// MaxMessageEvents is the maximum number of message events of a span, the
+	// earliest ones are kept.
+	MaxMessageEvents int
+	// MaxLinks is the maximum number of links of a span, the first ones are
+	// kept.
+	MaxLinks int
+	// MaxStackFrames is the maximum number of frames of the stack trace of a
+	// span, the innermost ones are kept.
+	MaxStackFrames int
+}
+
+type spanlimitsprocessor struct {
+	nextConsumer consumer.TraceConsumer
+	limits       Limits
+}
+
+// Option represents options that can be applied to the span limits processor.
+type Option func(*spanlimitsprocessor) error
+
+// WithLimits returns an Option to configure the limits enforced on each span.
+func WithLimits(limits Limits) Option {
+	return func(slp *spanlimitsprocessor) error {
+		if limits.MaxAttributes < 0 ||
+			limits.MaxAttributeValueLength < 0 ||
+			limits.MaxAnnotations < 0 ||
+			limits.MaxMessageEvents < 0 ||
+			limits.MaxLinks < 0 ||
+			limits.MaxStackFrames < 0 {
+			return errors.New("limits must not be negative")
+		}
+		slp.limits = limits
+		return nil
+	}
+}
+
+var _ processor.TraceProcessor = (*spanlimitsprocessor)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that enforces the
+// configured limits on all the spans passed to it.
+func NewTraceProcessor(nextConsumer consumer.TraceConsumer, options ...Option) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+
+	slp := &spanlimitsprocessor{nextConsumer: nextConsumer}
+	for _, opt := range options {
+		if err := opt(slp); err != nil {
+			return nil, err
+		}
+	}
+	return slp, nil
+}
+
+func (slp *spanlimitsprocessor) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	var dropped droppedCounts
+	for _, span := range td.Spans {
+		if span == nil {
+			continue
+		}
+		slp.limitSpan(span, &dropped)
+	}
+	dropped.record(ctx, td.Node)
+	return slp.nextConsumer.ConsumeTraceData(ctx, td)
+}
+
+func (slp *spanlimitsprocessor) limitSpan(span *tracepb.Span, dropped *droppedCounts) {
+	slp.limitAttributes(span.Attributes, dropped)
+
+	if timeEvents := span.TimeEvents; timeEvents != nil {
+		var numAnnotations, numMessageEvents int
+		kept := timeEvents.TimeEvent[:0]
+		for _, timeEvent := range timeEvents.TimeEvent {
+			switch value := timeEvent.GetValue().(type) {
+			case *tracepb.Span_TimeEvent_Annotation_:
+				numAnnotations++
+				if exceeds(numAnnotations, slp.limits.MaxAnnotations) {
+					timeEvents.DroppedAnnotationsCount++
+					dropped.annotations++
+					continue
+				}
+				slp.limitAttributes(value.Annotation.GetAttributes(), dropped)
+			case *tracepb.Span_TimeEvent_MessageEvent_:
+				numMessageEvents++
+				if exceeds(numMessageEvents, slp.limits.MaxMessageEvents) {
+					timeEvents.DroppedMessageEventsCount++
+					dropped.messageEvents++
+					continue
+				}
+			}
+			kept = append(kept, timeEvent)
+		}
+		timeEvents.TimeEvent = kept
+	}
+
+	if links := span.Links; links != nil {
+		if exceeds(len(links.Link), slp.limits.MaxLinks) {
+			numDropped := len(links.Link) - slp.limits.MaxLinks
+			links.Link = links.Link[:slp.limits.MaxLinks]
+			links.DroppedLinksCount += int32(numDropped)
+			dropped.links += int64(numDropped)
+		}
+		for _, link := range links.Link {
+			slp.limitAttributes(link.GetAttributes(), dropped)
+		}
+	}
+
+	if frames := span.GetStackTrace().GetStackFrames(); frames != nil {
+		if exceeds(len(frames.Frame), slp.limits.MaxStackFrames) {
+			numDropped := len(frames.Frame) - slp.limits.MaxStackFrames
+			frames.Frame = frames.Frame[:slp.limits.MaxStackFrames]
+			frames.DroppedFramesCount += int32(numDropped)
+			dropped.stackFrames += int64(numDropped)
+		}
+	}
+}
+
+func (slp *spanlimitsprocessor) limitAttributes(attributes *tracepb.Span_Attributes, dropped *droppedCounts) {
+	if attributes == nil {
+		return
+	}
+
+	if exceeds(len(attributes.AttributeMap), slp.limits.MaxAttributes) {
+		// Sort the keys so the same attributes are kept for all spans.
+		keys := make([]string, 0, len(attributes.AttributeMap))
+		for key := range attributes.AttributeMap {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys[slp.limits.MaxAttributes:] {
+			delete(attributes.AttributeMap, key)
+		}
+		numDropped := len(keys) - slp.limits.MaxAttributes
+		attributes.DroppedAttributesCount += int32(numDropped)
+		dropped.attributes += int64(numDropped)
+	}
+
+	if slp.limits.MaxAttributeValueLength > 0 {
+		for _, value := range attributes.AttributeMap {
+			if truncate(value.GetStringValue(), slp.limits.MaxAttributeValueLength) {
+				dropped.truncatedValues++
+			}
+		}
+	}
+}
+
+// truncate truncates s to at most maxBytes bytes without splitting a rune and
+// adds the number of bytes removed to its TruncatedByteCount. It returns true
+// if s was truncated.
+func truncate(s *tracepb.TruncatableString, maxBytes int) bool {
+	if s == nil || len(s.Value) <= maxBytes {
+		return false
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s.Value[cut]) {
+		cut--
+	}
+	s.TruncatedByteCount += int32(len(s.Value) - cut)
+	s.Value = s.Value[:cut]
+	return true
+}
+
+// exceeds returns true if count is over the limit, a zero limit means no
+// limit.
+func exceeds(count, limit int) bool {
+	return limit > 0 && count > limit
+}
@@ -0,0 +1,185 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanlimitsprocessor
+
+import (
+	"context"
+	"testing"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+)
+
+func TestNewTraceProcessor(t *testing.T) {
+	_, err := NewTraceProcessor(nil)
+	assert.Error(t, err)
+
+	_, err = NewTraceProcessor(exportertest.NewNopTraceExporter(), WithLimits(Limits{MaxLinks: -1}))
+	assert.Error(t, err)
+
+	tp, err := NewTraceProcessor(exportertest.NewNopTraceExporter(), WithLimits(Limits{MaxLinks: 1}))
+	assert.NoError(t, err)
+	assert.NotNil(t, tp)
+}
+
+func TestLimitAttributes(t *testing.T) {
+	span := &tracepb.Span{Attributes: attributes("c", "b", "a", "d")}
+	span.Attributes.DroppedAttributesCount = 1
+
+	process(t, Limits{MaxAttributes: 2}, span)
+
+	assert.Equal(t, []string{"a", "b"}, keys(span.Attributes))
+	assert.EqualValues(t, 3, span.Attributes.DroppedAttributesCount)
+}
+
+func TestLimitAttributeValueLength(t *testing.T) {
+	span := &tracepb.Span{Attributes: &tracepb.Span_Attributes{
+		AttributeMap: map[string]*tracepb.AttributeValue{
+			"short": stringValue("abc"),
+			"long":  stringValue("abcdefgh"),
+			// "é" is 2 bytes long, it must not be split.
+			"utf8": stringValue("abcdé"),
+			"int":  {Value: &tracepb.AttributeValue_IntValue{IntValue: 123456789}},
+		},
+	}}
+
+	process(t, Limits{MaxAttributeValueLength: 5}, span)
+
+	attrs := span.Attributes.AttributeMap
+	assert.Equal(t, &tracepb.TruncatableString{Value: "abc"}, attrs["short"].GetStringValue())
+	assert.Equal(t, &tracepb.TruncatableString{Value: "abcde", TruncatedByteCount: 3}, attrs["long"].GetStringValue())
+	assert.Equal(t, &tracepb.TruncatableString{Value: "abcd", TruncatedByteCount: 2}, attrs["utf8"].GetStringValue())
+	assert.EqualValues(t, 123456789, attrs["int"].GetIntValue())
+}
+
+func TestLimitTimeEvents(t *testing.T) {
+	span := &tracepb.Span{TimeEvents: &tracepb.Span_TimeEvents{
+		TimeEvent: []*tracepb.Span_TimeEvent{
+			annotation("a1", attributes("x", "y")),
+			messageEvent(1),
+			annotation("a2", nil),
+			messageEvent(2),
+			annotation("a3", nil),
+			messageEvent(3),
+		},
+	}}
+
+	process(t, Limits{MaxAnnotations: 2, MaxMessageEvents: 1, MaxAttributes: 1}, span)
+
+	wantAttributes := attributes("x")
+	wantAttributes.DroppedAttributesCount = 1
+	assert.Equal(t, []*tracepb.Span_TimeEvent{
+		annotation("a1", wantAttributes),
+		messageEvent(1),
+		annotation("a2", nil),
+	}, span.TimeEvents.TimeEvent)
+	assert.EqualValues(t, 1, span.TimeEvents.DroppedAnnotationsCount)
+	assert.EqualValues(t, 2, span.TimeEvents.DroppedMessageEventsCount)
+}
+
+func TestLimitLinks(t *testing.T) {
+	span := &tracepb.Span{Links: &tracepb.Span_Links{
+		Link:              []*tracepb.Span_Link{{SpanId: []byte{1}}, {SpanId: []byte{2}}, {SpanId: []byte{3}}},
+		DroppedLinksCount: 2,
+	}}
+
+	process(t, Limits{MaxLinks: 2}, span)
+
+	assert.Equal(t, []*tracepb.Span_Link{{SpanId: []byte{1}}, {SpanId: []byte{2}}}, span.Links.Link)
+	assert.EqualValues(t, 3, span.Links.DroppedLinksCount)
+}
+
+func TestLimitStackFrames(t *testing.T) {
+	span := &tracepb.Span{StackTrace: &tracepb.StackTrace{StackFrames: &tracepb.StackTrace_StackFrames{
+		Frame: []*tracepb.StackTrace_StackFrame{{LineNumber: 1}, {LineNumber: 2}, {LineNumber: 3}},
+	}}}
+
+	process(t, Limits{MaxStackFrames: 1}, span)
+
+	assert.Equal(t, []*tracepb.StackTrace_StackFrame{{LineNumber: 1}}, span.StackTrace.StackFrames.Frame)
+	assert.EqualValues(t, 2, span.StackTrace.StackFrames.DroppedFramesCount)
+}
+
+func TestNoLimits(t *testing.T) {
+	span := &tracepb.Span{
+		Attributes: attributes("a", "b", "c"),
+		Links:      &tracepb.Span_Links{Link: []*tracepb.Span_Link{{}, {}}},
+	}
+	want := &tracepb.Span{
+		Attributes: attributes("a", "b", "c"),
+		Links:      &tracepb.Span_Links{Link: []*tracepb.Span_Link{{}, {}}},
+	}
+
+	process(t, Limits{}, span, nil)
+
+	assert.Equal(t, want, span)
+}
+
+func process(t *testing.T, limits Limits, spans ...*tracepb.Span) {
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(sink, WithLimits(limits))
+	require.NoError(t, err)
+
+	td := data.TraceData{Spans: spans}
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), td))
+	assert.Equal(t, []data.TraceData{td}, sink.AllTraces())
+}
+
+func attributes(keys ...string) *tracepb.Span_Attributes {
+	attrs := &tracepb.Span_Attributes{AttributeMap: make(map[string]*tracepb.AttributeValue)}
+	for _, key := range keys {
+		attrs.AttributeMap[key] = stringValue(key)
+	}
+	return attrs
+}
+
+func keys(attrs *tracepb.Span_Attributes) []string {
+	var keys []string
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if _, ok := attrs.AttributeMap[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func stringValue(s string) *tracepb.AttributeValue {
+	return &tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: s}},
+	}
+}
+
+func annotation(description string, attrs *tracepb.Span_Attributes) *tracepb.Span_TimeEvent {
+	return &tracepb.Span_TimeEvent{
+		Value: &tracepb.Span_TimeEvent_Annotation_{
+			Annotation: &tracepb.Span_TimeEvent_Annotation{
+				Description: &tracepb.TruncatableString{Value: description},
+				Attributes:  attrs,
+			},
+		},
+	}
+}
+
+func messageEvent(id uint64) *tracepb.Span_TimeEvent {
+	return &tracepb.Span_TimeEvent{
+		Value: &tracepb.Span_TimeEvent_MessageEvent_{
+			MessageEvent: &tracepb.Span_TimeEvent_MessageEvent{Id: id},
+		},
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstatusprocessor
+
+import "github.com/census-instrumentation/opencensus-service/internal/configmodels"
+
+// ConfigV2 defines configuration for the span status processor.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// Client are the rules for client spans.
+	Client RulesConfig `mapstructure:"client"`
+	// Server are the rules for server spans.
+	Server RulesConfig `mapstructure:"server"`
+	// Unspecified are the rules for the spans without a kind.
+	Unspecified RulesConfig `mapstructure:"unspecified"`
+}
+
+// RulesConfig defines how the status of the spans of a kind is inferred.
+type RulesConfig struct {
+	// Sources are the attributes the status is inferred from, in order of
+	// precedence, among grpc, http and error. All of them, in this order, if
+	// empty.
+	Sources []string `mapstructure:"sources"`
+	// HTTPClientErrors marks the spans with a 4xx HTTP status code as errors.
+	HTTPClientErrors bool `mapstructure:"http_client_errors"`
+}
+
+// rules returns the processor Rules for the configuration.
+func (rc RulesConfig) rules() Rules {
+	var sources []Source
+	for _, source := range rc.Sources {
+		sources = append(sources, Source(source))
+	}
+	return Rules{Sources: sources, HTTPClientErrors: rc.HTTPClientErrors}
+}
@@ -0,0 +1,319 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package spanstatusprocessor contains a trace processor that sets the status
+// of the spans that have none, or one that is not a canonical code, from
+// their protocol attributes, e.g. the HTTP or gRPC status code, so error rates
+// don't depend on which protocol and receiver the spans came through.
+package spanstatusprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/processor"
+)
+
+// Span attributes the status is inferred from.
+const (
+	AttributeGRPCStatusCode = "grpc.status_code"
+	AttributeHTTPStatusCode = "http.status_code"
+	AttributeError          = "error"
+)
+
+// Source is a kind of span attribute the status can be inferred from.
+type Source string
+
+const (
+	// SourceGRPC infers the status from the gRPC status code, given as a
+	// number or as a canonical code name.
+	SourceGRPC Source = "grpc"
+	// SourceHTTP infers the status from the HTTP status code, mapped to the
+	// canonical code like the OpenCensus HTTP plugins do.
+	SourceHTTP Source = "http"
+	// SourceError infers the status from the Zipkin and Jaeger error tags.
+	// Any value except false is an error, whose message is the value unless
+	// it is true or a canonical code name, which gives the code.
+	SourceError Source = "error"
+)
+
+// DefaultSources are the sources used when none are configured, in order of
+// precedence.
+var DefaultSources = []Source{SourceGRPC, SourceHTTP, SourceError}
+
+// Rules are how the status of the spans of a kind is inferred.
+type Rules struct {
+	// Sources are tried in order until one gives a status.
+	Sources []Source
+	// HTTPClientErrors marks the spans with a 4xx HTTP status code as
+	// errors. Otherwise their status is OK, since the failure is the
+	// caller's.
+	HTTPClientErrors bool
+}
+
+// Canonical status codes, see
+// https://github.com/googleapis/googleapis/blob/master/google/rpc/code.proto.
+const (
+	codeOK                 = 0
+	codeCancelled          = 1
+	codeUnknown            = 2
+	codeInvalidArgument    = 3
+	codeDeadlineExceeded   = 4
+	codeNotFound           = 5
+	codeAlreadyExists      = 6
+	codePermissionDenied   = 7
+	codeResourceExhausted  = 8
+	codeFailedPrecondition = 9
+	codeAborted            = 10
+	codeOutOfRange         = 11
+	codeUnimplemented      = 12
+	codeInternal           = 13
+	codeUnavailable        = 14
+	codeDataLoss           = 15
+	codeUnauthenticated    = 16
+)
+
+var codeNames = []string{
+	codeOK:                 "OK",
+	codeCancelled:          "CANCELLED",
+	codeUnknown:            "UNKNOWN",
+	codeInvalidArgument:    "INVALID_ARGUMENT",
+	codeDeadlineExceeded:   "DEADLINE_EXCEEDED",
+	codeNotFound:           "NOT_FOUND",
+	codeAlreadyExists:      "ALREADY_EXISTS",
+	codePermissionDenied:   "PERMISSION_DENIED",
+	codeResourceExhausted:  "RESOURCE_EXHAUSTED",
+	codeFailedPrecondition: "FAILED_PRECONDITION",
+	codeAborted:            "ABORTED",
+	codeOutOfRange:         "OUT_OF_RANGE",
+	codeUnimplemented:      "UNIMPLEMENTED",
+	codeInternal:           "INTERNAL",
+	codeUnavailable:        "UNAVAILABLE",
+	codeDataLoss:           "DATA_LOSS",
+	codeUnauthenticated:    "UNAUTHENTICATED",
+}
+
+// codesByName maps the canonical code names to their code.
+var codesByName = func() map[string]int32 {
+	m := make(map[string]int32, len(codeNames))
+	for code, name := range codeNames {
+		m[name] = int32(code)
+	}
+	return m
+}()
+
+// httpCodes maps the HTTP status codes that have a specific canonical code,
+// other 4xx and 5xx ones are UNKNOWN.
+var httpCodes = map[int64]int32{
+	400: codeInvalidArgument,
+	401: codeUnauthenticated,
+	403: codePermissionDenied,
+	404: codeNotFound,
+	409: codeAlreadyExists,
+	422: codeInvalidArgument,
+	429: codeResourceExhausted,
+	499: codeCancelled,
+	501: codeUnimplemented,
+	503: codeUnavailable,
+	504: codeDeadlineExceeded,
+}
+
+type spanstatusprocessor struct {
+	nextConsumer consumer.TraceConsumer
+	rulesByKind  map[tracepb.Span_SpanKind]Rules
+}
+
+// Option represents options that can be applied to the span status
+// processor.
+type Option func(*spanstatusprocessor) error
+
+// WithRules returns an Option to infer the status of the spans of the given
+// kind with the given rules.
+func WithRules(kind tracepb.Span_SpanKind, rules Rules) Option {
+	return func(sp *spanstatusprocessor) error {
+		if _, ok := tracepb.Span_SpanKind_name[int32(kind)]; !ok {
+			return fmt.Errorf("unknown span kind %d", kind)
+		}
+		for _, source := range rules.Sources {
+			switch source {
+			case SourceGRPC, SourceHTTP, SourceError:
+			default:
+				return fmt.Errorf("unknown source %q", source)
+			}
+		}
+		if rules.Sources == nil {
+			rules.Sources = DefaultSources
+		}
+		sp.rulesByKind[kind] = rules
+		return nil
+	}
+}
+
+var _ processor.TraceProcessor = (*spanstatusprocessor)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that sets the status
+// of the spans without one from their attributes. By default all the sources
+// are used, and 4xx HTTP status codes are errors only on client spans.
+func NewTraceProcessor(nextConsumer consumer.TraceConsumer, options ...Option) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+
+	sp := &spanstatusprocessor{
+		nextConsumer: nextConsumer,
+		rulesByKind: map[tracepb.Span_SpanKind]Rules{
+			tracepb.Span_SPAN_KIND_UNSPECIFIED: {Sources: DefaultSources},
+			tracepb.Span_SERVER:                {Sources: DefaultSources},
+			tracepb.Span_CLIENT:                {Sources: DefaultSources, HTTPClientErrors: true},
+		},
+	}
+	for _, opt := range options {
+		if err := opt(sp); err != nil {
+			return nil, err
+		}
+	}
+	return sp, nil
+}
+
+func (sp *spanstatusprocessor) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	for _, span := range td.Spans {
+		if span == nil || isCanonical(span.Status) {
+			continue
+		}
+		// The Jaeger translators copy the HTTP status code as is, such a
+		// status is replaced by the inferred one when there is one.
+		if status := sp.inferStatus(span); status != nil || span.Status == nil {
+			span.Status = status
+		}
+	}
+	return sp.nextConsumer.ConsumeTraceData(ctx, td)
+}
+
+// isCanonical returns whether status is set with a canonical code.
+func isCanonical(status *tracepb.Status) bool {
+	return status != nil && status.Code >= codeOK && status.Code <= codeUnauthenticated
+}
+
+// inferStatus returns the status given by the first source of the rules of
+// the span kind that has one, or nil.
+func (sp *spanstatusprocessor) inferStatus(span *tracepb.Span) *tracepb.Status {
+	rules, ok := sp.rulesByKind[span.Kind]
+	if !ok {
+		rules = sp.rulesByKind[tracepb.Span_SPAN_KIND_UNSPECIFIED]
+	}
+	attrs := span.GetAttributes().GetAttributeMap()
+	if len(attrs) == 0 {
+		return nil
+	}
+	for _, source := range rules.Sources {
+		var status *tracepb.Status
+		switch source {
+		case SourceGRPC:
+			status = grpcStatus(attrs[AttributeGRPCStatusCode])
+		case SourceHTTP:
+			status = httpStatus(attrs[AttributeHTTPStatusCode], rules.HTTPClientErrors)
+		case SourceError:
+			status = errorStatus(attrs[AttributeError])
+		}
+		if status != nil {
+			return status
+		}
+	}
+	return nil
+}
+
+func newStatus(code int32) *tracepb.Status {
+	return &tracepb.Status{Code: code, Message: codeNames[code]}
+}
+
+// grpcStatus returns the status for a gRPC status code attribute, or nil if
+// it isn't a valid one.
+func grpcStatus(value *tracepb.AttributeValue) *tracepb.Status {
+	switch v := value.GetValue().(type) {
+	case *tracepb.AttributeValue_IntValue:
+		if v.IntValue >= 0 && v.IntValue < int64(len(codeNames)) {
+			return newStatus(int32(v.IntValue))
+		}
+	case *tracepb.AttributeValue_StringValue:
+		s := strings.TrimSpace(v.StringValue.GetValue())
+		if code, ok := codesByName[strings.ToUpper(s)]; ok {
+			return newStatus(code)
+		}
+		if code, err := strconv.ParseInt(s, 10, 32); err == nil && code >= 0 && code < int64(len(codeNames)) {
+			return newStatus(int32(code))
+		}
+	}
+	return nil
+}
+
+// httpStatus returns the status for an HTTP status code attribute, or nil if
+// it isn't a valid one.
+func httpStatus(value *tracepb.AttributeValue, clientErrors bool) *tracepb.Status {
+	var code int64
+	switch v := value.GetValue().(type) {
+	case *tracepb.AttributeValue_IntValue:
+		code = v.IntValue
+	case *tracepb.AttributeValue_StringValue:
+		var err error
+		if code, err = strconv.ParseInt(strings.TrimSpace(v.StringValue.GetValue()), 10, 64); err != nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	switch {
+	case code < 100 || code > 599:
+		return nil
+	case code >= 200 && code < 400:
+		return newStatus(codeOK)
+	case code >= 400 && code < 500 && code != 499 && !clientErrors:
+		return newStatus(codeOK)
+	}
+	if c, ok := httpCodes[code]; ok {
+		return newStatus(c)
+	}
+	return newStatus(codeUnknown)
+}
+
+// errorStatus returns the status for an error attribute, or nil if it
+// doesn't mark an error.
+func errorStatus(value *tracepb.AttributeValue) *tracepb.Status {
+	switch v := value.GetValue().(type) {
+	case *tracepb.AttributeValue_BoolValue:
+		if v.BoolValue {
+			return newStatus(codeUnknown)
+		}
+	case *tracepb.AttributeValue_StringValue:
+		s := strings.TrimSpace(v.StringValue.GetValue())
+		switch {
+		case s == "", strings.EqualFold(s, "false"):
+			return nil
+		case strings.EqualFold(s, "true"):
+			return newStatus(codeUnknown)
+		}
+		if code, ok := codesByName[s]; ok {
+			return newStatus(code)
+		}
+		return &tracepb.Status{Code: codeUnknown, Message: s}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spanstatusprocessor
+
+import (
+	"context"
+	"testing"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+)
+
+func stringValue(s string) *tracepb.AttributeValue {
+	return &tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: s}},
+	}
+}
+
+func intValue(i int64) *tracepb.AttributeValue {
+	return &tracepb.AttributeValue{Value: &tracepb.AttributeValue_IntValue{IntValue: i}}
+}
+
+func boolValue(b bool) *tracepb.AttributeValue {
+	return &tracepb.AttributeValue{Value: &tracepb.AttributeValue_BoolValue{BoolValue: b}}
+}
+
+func TestNewTraceProcessor(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	_, err := NewTraceProcessor(nil)
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithRules(tracepb.Span_CLIENT, Rules{Sources: []Source{"thrift"}}))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithRules(tracepb.Span_SpanKind(42), Rules{}))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithRules(tracepb.Span_SERVER, Rules{Sources: []Source{SourceHTTP}}))
+	assert.NoError(t, err)
+}
+
+func TestConsumeTraceData(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		kind    tracepb.Span_SpanKind
+		status  *tracepb.Status
+		attrs   map[string]*tracepb.AttributeValue
+		want    *tracepb.Status
+	}{
+		{
+			name:  "http_ok",
+			attrs: map[string]*tracepb.AttributeValue{AttributeHTTPStatusCode: intValue(204)},
+			want:  &tracepb.Status{Code: codeOK, Message: "OK"},
+		},
+		{
+			name:  "http_not_found_client",
+			kind:  tracepb.Span_CLIENT,
+			attrs: map[string]*tracepb.AttributeValue{AttributeHTTPStatusCode: intValue(404)},
+			want:  &tracepb.Status{Code: codeNotFound, Message: "NOT_FOUND"},
+		},
+		{
+			name:  "http_not_found_server",
+			kind:  tracepb.Span_SERVER,
+			attrs: map[string]*tracepb.AttributeValue{AttributeHTTPStatusCode: intValue(404)},
+			want:  &tracepb.Status{Code: codeOK, Message: "OK"},
+		},
+		{
+			name:    "http_not_found_server_configured",
+			options: []Option{WithRules(tracepb.Span_SERVER, Rules{HTTPClientErrors: true})},
+			kind:    tracepb.Span_SERVER,
+			attrs:   map[string]*tracepb.AttributeValue{AttributeHTTPStatusCode: intValue(404)},
+			want:    &tracepb.Status{Code: codeNotFound, Message: "NOT_FOUND"},
+		},
+		{
+			name:  "http_server_error_string",
+			kind:  tracepb.Span_SERVER,
+			attrs: map[string]*tracepb.AttributeValue{AttributeHTTPStatusCode: stringValue("503")},
+			want:  &tracepb.Status{Code: codeUnavailable, Message: "UNAVAILABLE"},
+		},
+		{
+			name:  "http_unmapped_error",
+			kind:  tracepb.Span_SERVER,
+			attrs: map[string]*tracepb.AttributeValue{AttributeHTTPStatusCode: intValue(502)},
+			want:  &tracepb.Status{Code: codeUnknown, Message: "UNKNOWN"},
+		},
+		{
+			name:  "http_invalid",
+			attrs: map[string]*tracepb.AttributeValue{AttributeHTTPStatusCode: stringValue("abc")},
+		},
+		{
+			name: "grpc_before_http",
+			attrs: map[string]*tracepb.AttributeValue{
+				AttributeGRPCStatusCode: intValue(codeDeadlineExceeded),
+				AttributeHTTPStatusCode: intValue(200),
+			},
+			want: &tracepb.Status{Code: codeDeadlineExceeded, Message: "DEADLINE_EXCEEDED"},
+		},
+		{
+			name:  "grpc_name",
+			attrs: map[string]*tracepb.AttributeValue{AttributeGRPCStatusCode: stringValue("permission_denied")},
+			want:  &tracepb.Status{Code: codePermissionDenied, Message: "PERMISSION_DENIED"},
+		},
+		{
+			name:  "grpc_invalid",
+			attrs: map[string]*tracepb.AttributeValue{AttributeGRPCStatusCode: intValue(99)},
+		},
+		{
+			name:  "jaeger_error",
+			attrs: map[string]*tracepb.AttributeValue{AttributeError: boolValue(true)},
+			want:  &tracepb.Status{Code: codeUnknown, Message: "UNKNOWN"},
+		},
+		{
+			name:  "jaeger_no_error",
+			attrs: map[string]*tracepb.AttributeValue{AttributeError: boolValue(false)},
+		},
+		{
+			name:  "zipkin_error_message",
+			attrs: map[string]*tracepb.AttributeValue{AttributeError: stringValue("connection refused")},
+			want:  &tracepb.Status{Code: codeUnknown, Message: "connection refused"},
+		},
+		{
+			name:  "zipkin_error_code",
+			attrs: map[string]*tracepb.AttributeValue{AttributeError: stringValue("ABORTED")},
+			want:  &tracepb.Status{Code: codeAborted, Message: "ABORTED"},
+		},
+		{
+			name: "error_before_http_configured",
+			options: []Option{
+				WithRules(tracepb.Span_SPAN_KIND_UNSPECIFIED, Rules{Sources: []Source{SourceError, SourceHTTP}}),
+			},
+			attrs: map[string]*tracepb.AttributeValue{
+				AttributeError:          stringValue("true"),
+				AttributeHTTPStatusCode: intValue(200),
+			},
+			want: &tracepb.Status{Code: codeUnknown, Message: "UNKNOWN"},
+		},
+		{
+			name: "source_disabled",
+			options: []Option{
+				WithRules(tracepb.Span_SPAN_KIND_UNSPECIFIED, Rules{Sources: []Source{SourceGRPC}}),
+			},
+			attrs: map[string]*tracepb.AttributeValue{AttributeHTTPStatusCode: intValue(500)},
+		},
+		{
+			name:   "status_kept",
+			status: &tracepb.Status{Code: codeInternal, Message: "boom"},
+			attrs:  map[string]*tracepb.AttributeValue{AttributeHTTPStatusCode: intValue(200)},
+			want:   &tracepb.Status{Code: codeInternal, Message: "boom"},
+		},
+		{
+			name:   "http_code_status_replaced",
+			kind:   tracepb.Span_SERVER,
+			status: &tracepb.Status{Code: 503, Message: "Service Unavailable"},
+			attrs:  map[string]*tracepb.AttributeValue{AttributeHTTPStatusCode: intValue(503)},
+			want:   &tracepb.Status{Code: codeUnavailable, Message: "UNAVAILABLE"},
+		},
+		{
+			name:   "http_code_status_kept_without_inference",
+			status: &tracepb.Status{Code: 503, Message: "Service Unavailable"},
+			attrs:  map[string]*tracepb.AttributeValue{AttributeError: boolValue(false)},
+			want:   &tracepb.Status{Code: 503, Message: "Service Unavailable"},
+		},
+		{
+			name: "no_attributes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &exportertest.SinkTraceExporter{}
+			sp, err := NewTraceProcessor(sink, tt.options...)
+			require.NoError(t, err)
+
+			span := &tracepb.Span{Kind: tt.kind, Status: tt.status}
+			if tt.attrs != nil {
+				span.Attributes = &tracepb.Span_Attributes{AttributeMap: tt.attrs}
+			}
+			td := data.TraceData{Spans: []*tracepb.Span{span, nil}}
+			require.NoError(t, sp.ConsumeTraceData(context.Background(), td))
+
+			got := sink.AllTraces()
+			require.Len(t, got, 1)
+			assert.Equal(t, tt.want, got[0].Spans[0].Status)
+		})
+	}
+}
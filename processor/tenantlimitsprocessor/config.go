@@ -0,0 +1,48 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenantlimitsprocessor
+
+import (
+	"time"
+
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+)
+
+// ConfigV2 defines configuration for the tenant limits processor. A rate of
+// zero means no limit.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// SpansPerSecond is the rate of spans accepted from each tenant.
+	SpansPerSecond float64 `mapstructure:"spans_per_second"`
+	// MetricPointsPerSecond is the rate of metric points accepted from each
+	// tenant.
+	MetricPointsPerSecond float64 `mapstructure:"metric_points_per_second"`
+	// BurstDuration is for how long a tenant that was idle can send data above
+	// its rates.
+	BurstDuration time.Duration `mapstructure:"burst_duration"`
+	// MaxTenants is the maximum number of tenants whose usage is tracked, the
+	// least recently seen ones are forgotten first.
+	MaxTenants int `mapstructure:"max_tenants"`
+	// Overrides are the rates of the tenants that do not use the ones above.
+	// Only these tenants are told apart in the metrics of the processor.
+	Overrides []TenantOverride `mapstructure:"overrides"`
+}
+
+// TenantOverride defines the rates of a single tenant.
+type TenantOverride struct {
+	Tenant                string  `mapstructure:"tenant"`
+	SpansPerSecond        float64 `mapstructure:"spans_per_second"`
+	MetricPointsPerSecond float64 `mapstructure:"metric_points_per_second"`
+}
@@ -0,0 +1,83 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenantlimitsprocessor
+
+import (
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+	"github.com/census-instrumentation/opencensus-service/internal/factories"
+	"github.com/census-instrumentation/opencensus-service/processor"
+)
+
+var _ = factories.RegisterProcessorFactory(&processorFactory{})
+
+const (
+	// The value of "type" key in configuration.
+	typeStr = "tenantlimits"
+)
+
+// processorFactory is the factory for the tenant limits processor.
+type processorFactory struct {
+}
+
+// Type gets the type of the config created by this factory.
+func (f *processorFactory) Type() string {
+	return typeStr
+}
+
+// CreateDefaultConfig creates the default configuration for the processor.
+func (f *processorFactory) CreateDefaultConfig() configmodels.Processor {
+	return &ConfigV2{
+		ProcessorSettings: configmodels.ProcessorSettings{
+			TypeVal: typeStr,
+		},
+		BurstDuration: defaultBurstDuration,
+		MaxTenants:    defaultMaxTenants,
+	}
+}
+
+// CreateTraceProcessor creates a trace processor based on this config.
+func (f *processorFactory) CreateTraceProcessor(
+	nextConsumer consumer.TraceConsumer,
+	cfg configmodels.Processor,
+) (processor.TraceProcessor, error) {
+	return NewTraceProcessor(nextConsumer, optionsFromConfig(cfg.(*ConfigV2))...)
+}
+
+// CreateMetricsProcessor creates a metrics processor based on this config.
+func (f *processorFactory) CreateMetricsProcessor(
+	nextConsumer consumer.MetricsConsumer,
+	cfg configmodels.Processor,
+) (processor.MetricsProcessor, error) {
+	return NewMetricsProcessor(nextConsumer, optionsFromConfig(cfg.(*ConfigV2))...)
+}
+
+func optionsFromConfig(oCfg *ConfigV2) []Option {
+	opts := []Option{
+		WithDefaultLimits(Limits{
+			SpansPerSecond:        oCfg.SpansPerSecond,
+			MetricPointsPerSecond: oCfg.MetricPointsPerSecond,
+		}),
+		WithBurstDuration(oCfg.BurstDuration),
+		WithMaxTenants(oCfg.MaxTenants),
+	}
+	for _, override := range oCfg.Overrides {
+		opts = append(opts, WithTenantLimits(override.Tenant, Limits{
+			SpansPerSecond:        override.SpansPerSecond,
+			MetricPointsPerSecond: override.MetricPointsPerSecond,
+		}))
+	}
+	return opts
+}
@@ -0,0 +1,66 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenantlimitsprocessor
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/census-instrumentation/opencensus-service/internal/collector/telemetry"
+	"github.com/census-instrumentation/opencensus-service/observability"
+)
+
+// Variables related to metrics specific to the tenant limits processor.
+var (
+	statRejectedSpans        = stats.Int64("tenantlimits_rejected_spans", "Count of spans rejected for exceeding the rate limit of their tenant", stats.UnitDimensionless)
+	statRejectedMetricPoints = stats.Int64("tenantlimits_rejected_metric_points", "Count of metric points rejected for exceeding the rate limit of their tenant", stats.UnitDimensionless)
+)
+
+// otherTenants is the tag value the tenants without limits of their own are
+// recorded under, since their number is unbounded.
+const otherTenants = "other"
+
+// recordRejected records the rejected items, tagged by the given tenant.
+func recordRejected(ctx context.Context, tenant string, measure *stats.Int64Measure, count int) {
+	_ = stats.RecordWithTags(ctx, []tag.Mutator{tag.Upsert(observability.TagKeyTenant, tenant)}, measure.M(int64(count)))
+}
+
+// MetricViews return the metrics views according to given telemetry level.
+func MetricViews(level telemetry.Level) []*view.View {
+	if level == telemetry.None {
+		return nil
+	}
+
+	tagKeys := []tag.Key{observability.TagKeyTenant}
+
+	rejectedSpansView := &view.View{
+		Name:        statRejectedSpans.Name(),
+		Measure:     statRejectedSpans,
+		Description: statRejectedSpans.Description(),
+		TagKeys:     tagKeys,
+		Aggregation: view.Sum(),
+	}
+	rejectedMetricPointsView := &view.View{
+		Name:        statRejectedMetricPoints.Name(),
+		Measure:     statRejectedMetricPoints,
+		Description: statRejectedMetricPoints.Description(),
+		TagKeys:     tagKeys,
+		Aggregation: view.Sum(),
+	}
+	return []*view.View{rejectedSpansView, rejectedMetricPointsView}
+}
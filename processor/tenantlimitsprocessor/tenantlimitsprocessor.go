@@ -0,0 +1,275 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tenantlimitsprocessor contains a processor that limits the rate of
+// spans and metric points accepted from each tenant, so one tenant can not
+// starve the others on a shared collector. Data over the limits is rejected
+// with a *tenancy.LimitExceededError, which receivers can report to their
+// clients as retryable.
+package tenantlimitsprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/processor"
+	"github.com/census-instrumentation/opencensus-service/processor/internal/lru"
+	"github.com/census-instrumentation/opencensus-service/processor/internal/tokenbucket"
+	"github.com/census-instrumentation/opencensus-service/tenancy"
+)
+
+const (
+	defaultBurstDuration = time.Second
+	defaultMaxTenants    = 10000
+)
+
+// Limits are the rates accepted from a tenant. A rate of zero means no limit.
+type Limits struct {
+	SpansPerSecond        float64
+	MetricPointsPerSecond float64
+}
+
+// dataKind is the kind of items limited by a rate.
+type dataKind int
+
+const (
+	spans dataKind = iota
+	metricPoints
+	numDataKinds
+)
+
+func (limits Limits) rate(kind dataKind) float64 {
+	if kind == spans {
+		return limits.SpansPerSecond
+	}
+	return limits.MetricPointsPerSecond
+}
+
+// tenantBuckets holds the usage of a tenant, per data kind.
+type tenantBuckets [numDataKinds]*tokenbucket.Bucket
+
+// limiter holds the state shared by the trace and metrics processors. Data
+// without a tenant is limited as if its tenant ID was empty.
+type limiter struct {
+	defaults      Limits
+	overrides     map[string]Limits
+	burstDuration time.Duration
+
+	// mu protects buckets, which maps tenants to *tenantBuckets.
+	mu      sync.Mutex
+	buckets *lru.Cache
+
+	now func() time.Time
+}
+
+// Option represents options that can be applied to the tenant limits
+// processor.
+type Option func(*limiter) error
+
+// WithDefaultLimits returns an Option to configure the rates accepted from the
+// tenants without their own limits.
+func WithDefaultLimits(limits Limits) Option {
+	return func(l *limiter) error {
+		if err := checkLimits(limits); err != nil {
+			return err
+		}
+		l.defaults = limits
+		return nil
+	}
+}
+
+// WithTenantLimits returns an Option to configure the rates accepted from a
+// given tenant.
+func WithTenantLimits(tenant string, limits Limits) Option {
+	return func(l *limiter) error {
+		if err := checkLimits(limits); err != nil {
+			return err
+		}
+		if tenant == otherTenants {
+			return fmt.Errorf("tenant %q is reserved for the metrics of the tenants without limits", otherTenants)
+		}
+		if _, ok := l.overrides[tenant]; ok {
+			return fmt.Errorf("duplicate limits for tenant %q", tenant)
+		}
+		l.overrides[tenant] = limits
+		return nil
+	}
+}
+
+// WithBurstDuration returns an Option to configure for how long a tenant that
+// was idle can send data above its rates.
+func WithBurstDuration(burstDuration time.Duration) Option {
+	return func(l *limiter) error {
+		if burstDuration <= 0 {
+			return errors.New("burst duration must be positive")
+		}
+		l.burstDuration = burstDuration
+		return nil
+	}
+}
+
+// WithMaxTenants returns an Option to configure the maximum number of tenants
+// whose usage is tracked. The least recently seen tenants are forgotten first.
+func WithMaxTenants(maxTenants int) Option {
+	return func(l *limiter) error {
+		if maxTenants <= 0 {
+			return errors.New("max tenants must be positive")
+		}
+		l.buckets = lru.New(maxTenants)
+		return nil
+	}
+}
+
+func checkLimits(limits Limits) error {
+	if limits.SpansPerSecond < 0 || limits.MetricPointsPerSecond < 0 {
+		return errors.New("limits must not be negative")
+	}
+	return nil
+}
+
+func newLimiter(options []Option) (*limiter, error) {
+	l := &limiter{
+		overrides:     make(map[string]Limits),
+		burstDuration: defaultBurstDuration,
+		buckets:       lru.New(defaultMaxTenants),
+		now:           time.Now,
+	}
+	for _, opt := range options {
+		if err := opt(l); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// take returns an error if the tenant in ctx can not send n more items of the
+// given kind.
+func (l *limiter) take(ctx context.Context, kind dataKind, n int) error {
+	tenant, _ := tenancy.FromContext(ctx)
+	limits, ok := l.overrides[tenant]
+	if !ok {
+		limits = l.defaults
+	}
+	rate := limits.rate(kind)
+	if rate == 0 || n == 0 {
+		return nil
+	}
+
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var buckets *tenantBuckets
+	if value, ok := l.buckets.Get(tenant); ok {
+		buckets = value.(*tenantBuckets)
+	} else {
+		buckets = &tenantBuckets{}
+		l.buckets.Add(tenant, buckets)
+	}
+	bucket := buckets[kind]
+	if bucket == nil {
+		bucket = tokenbucket.New(rate, rate*l.burstDuration.Seconds(), now)
+		buckets[kind] = bucket
+	}
+
+	if !bucket.Take(float64(n), now) {
+		return &tenancy.LimitExceededError{Tenant: tenant, RetryAfter: bucket.RetryAfter(now)}
+	}
+	return nil
+}
+
+// metricsTenant returns the value the tenant in ctx is recorded under in the
+// metrics: the configured tenants are recorded by name, the other ones share a
+// single value.
+func (l *limiter) metricsTenant(ctx context.Context) string {
+	tenant, _ := tenancy.FromContext(ctx)
+	if _, ok := l.overrides[tenant]; ok {
+		return tenant
+	}
+	return otherTenants
+}
+
+type traceProcessor struct {
+	*limiter
+	nextConsumer consumer.TraceConsumer
+}
+
+var _ processor.TraceProcessor = (*traceProcessor)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that rejects the
+// batches of spans of the tenants that exceeded their rates.
+func NewTraceProcessor(nextConsumer consumer.TraceConsumer, options ...Option) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+	l, err := newLimiter(options)
+	if err != nil {
+		return nil, err
+	}
+	return &traceProcessor{limiter: l, nextConsumer: nextConsumer}, nil
+}
+
+func (tp *traceProcessor) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	if err := tp.take(ctx, spans, len(td.Spans)); err != nil {
+		recordRejected(ctx, tp.metricsTenant(ctx), statRejectedSpans, len(td.Spans))
+		return err
+	}
+	return tp.nextConsumer.ConsumeTraceData(ctx, td)
+}
+
+type metricsProcessor struct {
+	*limiter
+	nextConsumer consumer.MetricsConsumer
+}
+
+var _ processor.MetricsProcessor = (*metricsProcessor)(nil)
+
+// NewMetricsProcessor returns a processor.MetricsProcessor that rejects the
+// batches of metrics of the tenants that exceeded their rates.
+func NewMetricsProcessor(nextConsumer consumer.MetricsConsumer, options ...Option) (processor.MetricsProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+	l, err := newLimiter(options)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsProcessor{limiter: l, nextConsumer: nextConsumer}, nil
+}
+
+func (mp *metricsProcessor) ConsumeMetricsData(ctx context.Context, md data.MetricsData) error {
+	numPoints := countPoints(md.Metrics)
+	if err := mp.take(ctx, metricPoints, numPoints); err != nil {
+		recordRejected(ctx, mp.metricsTenant(ctx), statRejectedMetricPoints, numPoints)
+		return err
+	}
+	return mp.nextConsumer.ConsumeMetricsData(ctx, md)
+}
+
+func countPoints(metrics []*metricspb.Metric) int {
+	numPoints := 0
+	for _, metric := range metrics {
+		for _, ts := range metric.GetTimeseries() {
+			numPoints += len(ts.GetPoints())
+		}
+	}
+	return numPoints
+}
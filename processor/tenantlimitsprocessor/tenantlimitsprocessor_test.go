@@ -0,0 +1,151 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenantlimitsprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+	"github.com/census-instrumentation/opencensus-service/internal/collector/telemetry"
+	"github.com/census-instrumentation/opencensus-service/tenancy"
+)
+
+func newSpans(n int) []*tracepb.Span {
+	spans := make([]*tracepb.Span, n)
+	for i := range spans {
+		spans[i] = &tracepb.Span{}
+	}
+	return spans
+}
+
+func TestNewTraceProcessor(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	_, err := NewTraceProcessor(nil)
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithDefaultLimits(Limits{SpansPerSecond: -1}))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithTenantLimits("a", Limits{}), WithTenantLimits("a", Limits{}))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithTenantLimits(otherTenants, Limits{}))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithBurstDuration(0))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithMaxTenants(0))
+	assert.Error(t, err)
+}
+
+func TestLimitSpans(t *testing.T) {
+	views := MetricViews(telemetry.Normal)
+	require.NoError(t, view.Register(views...))
+	defer view.Unregister(views...)
+
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(
+		sink,
+		WithDefaultLimits(Limits{SpansPerSecond: 10}),
+		WithTenantLimits("acme", Limits{SpansPerSecond: 10}),
+		WithTenantLimits("unlimited", Limits{}),
+		WithBurstDuration(2*time.Second),
+	)
+	require.NoError(t, err)
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	tp.(*traceProcessor).now = func() time.Time { return now }
+
+	acme := tenancy.NewContext(context.Background(), "acme")
+	globex := tenancy.NewContext(context.Background(), "globex")
+	unlimited := tenancy.NewContext(context.Background(), "unlimited")
+
+	// The bucket of each tenant holds 20 spans.
+	require.NoError(t, tp.ConsumeTraceData(acme, data.TraceData{Spans: newSpans(15)}))
+	require.NoError(t, tp.ConsumeTraceData(acme, data.TraceData{Spans: newSpans(15)}))
+	err = tp.ConsumeTraceData(acme, data.TraceData{Spans: newSpans(1)})
+	require.Error(t, err)
+	assert.True(t, tenancy.IsRetryable(err))
+	assert.Equal(t, &tenancy.LimitExceededError{Tenant: "acme", RetryAfter: time.Second + time.Nanosecond}, err)
+
+	// Other tenants are not affected.
+	require.NoError(t, tp.ConsumeTraceData(globex, data.TraceData{Spans: newSpans(15)}))
+	require.NoError(t, tp.ConsumeTraceData(unlimited, data.TraceData{Spans: newSpans(1000)}))
+
+	// Once the debt is paid acme can send again.
+	now = now.Add(1100 * time.Millisecond)
+	require.NoError(t, tp.ConsumeTraceData(acme, data.TraceData{Spans: newSpans(1)}))
+
+	// globex has 5 spans left plus the 11 of the elapsed time, going into
+	// debt rejects its next batch.
+	require.NoError(t, tp.ConsumeTraceData(globex, data.TraceData{Spans: newSpans(20)}))
+	require.Error(t, tp.ConsumeTraceData(globex, data.TraceData{Spans: newSpans(2)}))
+
+	assert.Len(t, sink.AllTraces(), 6)
+
+	// The tenants without limits of their own are recorded together.
+	rows, err := view.RetrieveData(statRejectedSpans.Name())
+	require.NoError(t, err)
+	rejectedPerTenant := make(map[string]float64)
+	for _, row := range rows {
+		rejectedPerTenant[row.Tags[0].Value] = row.Data.(*view.SumData).Value
+	}
+	assert.Equal(t, map[string]float64{"acme": 1, otherTenants: 2}, rejectedPerTenant)
+}
+
+func TestLimitMetricPoints(t *testing.T) {
+	sink := &exportertest.SinkMetricsExporter{}
+	mp, err := NewMetricsProcessor(sink, WithDefaultLimits(Limits{SpansPerSecond: 1, MetricPointsPerSecond: 2}))
+	require.NoError(t, err)
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	mp.(*metricsProcessor).now = func() time.Time { return now }
+
+	md := data.MetricsData{
+		Metrics: []*metricspb.Metric{
+			{
+				Timeseries: []*metricspb.TimeSeries{
+					{Points: []*metricspb.Point{{}, {}}},
+					{Points: []*metricspb.Point{{}}},
+				},
+			},
+		},
+	}
+	// Data without tenant is limited as well.
+	require.NoError(t, mp.ConsumeMetricsData(context.Background(), md))
+	err = mp.ConsumeMetricsData(context.Background(), md)
+	assert.Equal(t, &tenancy.LimitExceededError{RetryAfter: 500*time.Millisecond + time.Nanosecond}, err)
+	assert.Len(t, sink.AllMetrics(), 1)
+}
+
+func TestMaxTenants(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(sink, WithDefaultLimits(Limits{SpansPerSecond: 1}), WithMaxTenants(1))
+	require.NoError(t, err)
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	tp.(*traceProcessor).now = func() time.Time { return now }
+
+	acme := tenancy.NewContext(context.Background(), "acme")
+	globex := tenancy.NewContext(context.Background(), "globex")
+	require.NoError(t, tp.ConsumeTraceData(acme, data.TraceData{Spans: newSpans(1)}))
+	require.Error(t, tp.ConsumeTraceData(acme, data.TraceData{Spans: newSpans(1)}))
+
+	// Tracking globex makes the processor forget about acme.
+	require.NoError(t, tp.ConsumeTraceData(globex, data.TraceData{Spans: newSpans(1)}))
+	require.NoError(t, tp.ConsumeTraceData(acme, data.TraceData{Spans: newSpans(1)}))
+}
@@ -0,0 +1,121 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracecompletenessprocessor
+
+import (
+	"context"
+	"strconv"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/census-instrumentation/opencensus-service/internal/collector/processor"
+	"github.com/census-instrumentation/opencensus-service/internal/collector/telemetry"
+)
+
+// Variables related to metrics specific to the trace completeness processor.
+var (
+	tagCompleteKey, _ = tag.NewKey("complete")
+	tagIssueKey, _    = tag.NewKey("issue")
+
+	statTraces            = stats.Int64("tracecompleteness_traces", "Count of traces checked, by service of their root span", stats.UnitDimensionless)
+	statTracesWithoutRoot = stats.Int64("tracecompleteness_traces_without_root", "Count of traces without a root span", stats.UnitDimensionless)
+	statBrokenSpans       = stats.Int64("tracecompleteness_broken_spans", "Count of spans breaking their trace, per issue", stats.UnitDimensionless)
+)
+
+type traceKey struct {
+	service  string
+	complete bool
+}
+
+type issueKey struct {
+	service string
+	issue   Issue
+}
+
+// counts accumulates the checks of the traces of a batch, per service.
+type counts struct {
+	traces            map[traceKey]int64
+	tracesWithoutRoot map[string]int64
+	spans             map[issueKey]int64
+}
+
+func newCounts() *counts {
+	return &counts{
+		traces:            make(map[traceKey]int64),
+		tracesWithoutRoot: make(map[string]int64),
+		spans:             make(map[issueKey]int64),
+	}
+}
+
+func (c *counts) record(ctx context.Context) {
+	for key, count := range c.traces {
+		_ = stats.RecordWithTags(
+			ctx,
+			[]tag.Mutator{
+				tag.Upsert(processor.TagServiceNameKey, key.service),
+				tag.Upsert(tagCompleteKey, strconv.FormatBool(key.complete)),
+			},
+			statTraces.M(count))
+	}
+	for service, count := range c.tracesWithoutRoot {
+		_ = stats.RecordWithTags(
+			ctx,
+			[]tag.Mutator{tag.Upsert(processor.TagServiceNameKey, service)},
+			statTracesWithoutRoot.M(count))
+	}
+	for key, count := range c.spans {
+		_ = stats.RecordWithTags(
+			ctx,
+			[]tag.Mutator{
+				tag.Upsert(processor.TagServiceNameKey, key.service),
+				tag.Upsert(tagIssueKey, string(key.issue)),
+			},
+			statBrokenSpans.M(count))
+	}
+}
+
+// MetricViews return the metrics views according to given telemetry level.
+// The views are always per service, since telling which services break their
+// traces is their purpose.
+func MetricViews(level telemetry.Level) []*view.View {
+	if level == telemetry.None {
+		return nil
+	}
+
+	tracesView := &view.View{
+		Name:        statTraces.Name(),
+		Measure:     statTraces,
+		Description: statTraces.Description(),
+		TagKeys:     []tag.Key{processor.TagServiceNameKey, tagCompleteKey},
+		Aggregation: view.Sum(),
+	}
+	tracesWithoutRootView := &view.View{
+		Name:        statTracesWithoutRoot.Name(),
+		Measure:     statTracesWithoutRoot,
+		Description: statTracesWithoutRoot.Description(),
+		TagKeys:     []tag.Key{processor.TagServiceNameKey},
+		Aggregation: view.Sum(),
+	}
+	brokenSpansView := &view.View{
+		Name:        statBrokenSpans.Name(),
+		Measure:     statBrokenSpans,
+		Description: statBrokenSpans.Description(),
+		TagKeys:     []tag.Key{processor.TagServiceNameKey, tagIssueKey},
+		Aggregation: view.Sum(),
+	}
+	return []*view.View{tracesView, tracesWithoutRootView, brokenSpansView}
+}
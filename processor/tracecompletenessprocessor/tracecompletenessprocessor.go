@@ -0,0 +1,227 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracecompletenessprocessor contains a trace processor that checks
+// whether the traces it receives are complete, recording per-service metrics
+// about the broken ones and optionally tagging the spans at fault.
+//
+// The traces are checked per batch, see processor/internal/traces for how
+// the pipeline must be set up for batches to hold entire traces.
+//
+// Orphan spans, whose parent is missing from the trace, and traces without a
+// root span point at spans lost on their way, e.g. dropped by the collector
+// or arriving after the trace was emitted. Client spans without a server
+// child, or server spans whose parent isn't a client span, point at
+// instrumentation that failed to propagate the context across the call, or
+// at calls to services that aren't instrumented. The client and server
+// halves of a Zipkin shared span, which have the same ID, match each other,
+// so the deduplication processor doesn't need to merge them first.
+package tracecompletenessprocessor
+
+import (
+	"context"
+	"errors"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	processormetrics "github.com/census-instrumentation/opencensus-service/internal/collector/processor"
+	"github.com/census-instrumentation/opencensus-service/processor"
+	"github.com/census-instrumentation/opencensus-service/processor/internal/traces"
+)
+
+// DefaultServiceAttribute is the span attribute the service of a span is read
+// from by default.
+const DefaultServiceAttribute = traces.DefaultServiceAttribute
+
+// Issue is a reason for a span to break its trace. It is the value of the
+// attribute set on the span when tagging is enabled.
+type Issue string
+
+const (
+	// IssueOrphan is set on the spans whose parent isn't in the trace.
+	IssueOrphan Issue = "orphan"
+	// IssueUnmatchedClient is set on the client spans without a server
+	// child.
+	IssueUnmatchedClient Issue = "unmatched_client"
+	// IssueUnmatchedServer is set on the server spans whose parent isn't a
+	// client span.
+	IssueUnmatchedServer Issue = "unmatched_server"
+)
+
+type tracecompletenessprocessor struct {
+	nextConsumer     consumer.TraceConsumer
+	attribute        string
+	serviceAttribute string
+}
+
+// Option represents options that can be applied to the trace completeness
+// processor.
+type Option func(*tracecompletenessprocessor) error
+
+// WithAttribute returns an Option to set the span attribute with the given
+// key to the Issue of the spans breaking their trace. Spans aren't tagged if
+// it is empty.
+func WithAttribute(key string) Option {
+	return func(tcp *tracecompletenessprocessor) error {
+		tcp.attribute = key
+		return nil
+	}
+}
+
+// WithServiceAttribute returns an Option to read the service of the spans
+// from the span attribute with the given key, falling back to the service of
+// the Node when absent.
+func WithServiceAttribute(key string) Option {
+	return func(tcp *tracecompletenessprocessor) error {
+		tcp.serviceAttribute = key
+		return nil
+	}
+}
+
+var _ processor.TraceProcessor = (*tracecompletenessprocessor)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that checks the
+// completeness of the traces of each batch, which must hold entire traces.
+func NewTraceProcessor(nextConsumer consumer.TraceConsumer, options ...Option) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+
+	tcp := &tracecompletenessprocessor{
+		nextConsumer:     nextConsumer,
+		serviceAttribute: DefaultServiceAttribute,
+	}
+	for _, opt := range options {
+		if err := opt(tcp); err != nil {
+			return nil, err
+		}
+	}
+	return tcp, nil
+}
+
+func (tcp *tracecompletenessprocessor) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	nodeService := processormetrics.ServiceNameForNode(td.Node)
+	c := newCounts()
+	for _, spans := range traces.GroupByTraceID(td.Spans) {
+		tcp.checkTrace(spans, nodeService, c)
+	}
+	c.record(ctx)
+
+	return tcp.nextConsumer.ConsumeTraceData(ctx, td)
+}
+
+// spanKey identifies a span of a trace. Both halves of a Zipkin shared span
+// have the same ID, so the kind is needed to tell them apart.
+type spanKey struct {
+	id   string
+	kind tracepb.Span_SpanKind
+}
+
+// parentKinds is the order the kinds of the spans with the parent ID of a
+// span are looked up in. The children of a shared span are created under its
+// server half.
+var parentKinds = []tracepb.Span_SpanKind{
+	tracepb.Span_SERVER,
+	tracepb.Span_CLIENT,
+	tracepb.Span_SPAN_KIND_UNSPECIFIED,
+}
+
+// checkTrace counts the issues of the spans of a trace and tags the spans
+// at fault.
+func (tcp *tracecompletenessprocessor) checkTrace(spans []*tracepb.Span, nodeService string, c *counts) {
+	byKey := make(map[spanKey]*tracepb.Span, len(spans))
+	var root *tracepb.Span
+	for _, span := range spans {
+		byKey[spanKey{id: string(span.SpanId), kind: span.Kind}] = span
+		if root == nil && len(span.ParentSpanId) == 0 {
+			root = span
+		}
+	}
+
+	// A client span is matched by a server child, or by the server half of
+	// its shared span.
+	matchedClients := make(map[*tracepb.Span]bool)
+	issues := make(map[*tracepb.Span]Issue)
+	for _, span := range spans {
+		if span.Kind == tracepb.Span_SERVER {
+			if client, ok := byKey[spanKey{id: string(span.SpanId), kind: tracepb.Span_CLIENT}]; ok {
+				matchedClients[client] = true
+				continue
+			}
+		}
+		if len(span.ParentSpanId) == 0 {
+			continue
+		}
+		var parent *tracepb.Span
+		for _, kind := range parentKinds {
+			if parent = byKey[spanKey{id: string(span.ParentSpanId), kind: kind}]; parent != nil {
+				break
+			}
+		}
+		switch {
+		case parent == nil:
+			issues[span] = IssueOrphan
+		case span.Kind != tracepb.Span_SERVER:
+		case parent.Kind == tracepb.Span_CLIENT:
+			matchedClients[parent] = true
+		default:
+			issues[span] = IssueUnmatchedServer
+		}
+	}
+	for _, span := range spans {
+		if span.Kind == tracepb.Span_CLIENT && !matchedClients[span] {
+			if _, ok := issues[span]; !ok {
+				issues[span] = IssueUnmatchedClient
+			}
+		}
+	}
+
+	traceService := nodeService
+	switch {
+	case root != nil:
+		traceService = traces.SpanService(root, tcp.serviceAttribute, nodeService)
+	case len(spans) > 0:
+		traceService = traces.SpanService(spans[0], tcp.serviceAttribute, nodeService)
+	}
+	orphans := false
+	for _, span := range spans {
+		issue, ok := issues[span]
+		if !ok {
+			continue
+		}
+		orphans = orphans || issue == IssueOrphan
+		c.spans[issueKey{service: traces.SpanService(span, tcp.serviceAttribute, nodeService), issue: issue}]++
+		if tcp.attribute != "" {
+			setAttribute(span, tcp.attribute, string(issue))
+		}
+	}
+	if root == nil {
+		c.tracesWithoutRoot[traceService]++
+	}
+	c.traces[traceKey{service: traceService, complete: root != nil && !orphans}]++
+}
+
+func setAttribute(span *tracepb.Span, key, value string) {
+	if span.Attributes == nil {
+		span.Attributes = &tracepb.Span_Attributes{}
+	}
+	if span.Attributes.AttributeMap == nil {
+		span.Attributes.AttributeMap = make(map[string]*tracepb.AttributeValue)
+	}
+	span.Attributes.AttributeMap[key] = &tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: value}},
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracecompletenessprocessor
+
+import (
+	"context"
+	"testing"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+	"github.com/census-instrumentation/opencensus-service/internal/collector/telemetry"
+)
+
+func newSpan(traceID, spanID, parentID byte, kind tracepb.Span_SpanKind, service string) *tracepb.Span {
+	span := &tracepb.Span{
+		TraceId: []byte{traceID},
+		SpanId:  []byte{spanID},
+		Kind:    kind,
+	}
+	if parentID != 0 {
+		span.ParentSpanId = []byte{parentID}
+	}
+	if service != "" {
+		span.Attributes = &tracepb.Span_Attributes{AttributeMap: map[string]*tracepb.AttributeValue{
+			DefaultServiceAttribute: {
+				Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: service}},
+			},
+		}}
+	}
+	return span
+}
+
+func spanIssue(span *tracepb.Span) string {
+	return span.GetAttributes().GetAttributeMap()["trace.issue"].GetStringValue().GetValue()
+}
+
+func TestNewTraceProcessor(t *testing.T) {
+	_, err := NewTraceProcessor(nil)
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(&exportertest.SinkTraceExporter{}, WithAttribute("trace.issue"), WithServiceAttribute(""))
+	assert.NoError(t, err)
+}
+
+func TestCheckTraces(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(sink, WithAttribute("trace.issue"))
+	require.NoError(t, err)
+
+	var (
+		// Trace 1 is complete: frontend calls backend.
+		root         = newSpan(1, 1, 0, tracepb.Span_SERVER, "frontend")
+		client       = newSpan(1, 2, 1, tracepb.Span_CLIENT, "frontend")
+		server       = newSpan(1, 3, 2, tracepb.Span_SERVER, "backend")
+		internalSpan = newSpan(1, 4, 3, tracepb.Span_SPAN_KIND_UNSPECIFIED, "backend")
+
+		// Trace 2 lost its root span, and its client span called a service
+		// that started a new trace.
+		orphan    = newSpan(2, 2, 1, tracepb.Span_SERVER, "")
+		unmatched = newSpan(2, 3, 2, tracepb.Span_CLIENT, "")
+
+		// Trace 3 has a server span whose parent is an internal span.
+		root3           = newSpan(3, 1, 0, tracepb.Span_SPAN_KIND_UNSPECIFIED, "frontend")
+		unmatchedServer = newSpan(3, 2, 1, tracepb.Span_SERVER, "backend")
+	)
+	td := data.TraceData{
+		Node: &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "gateway"}},
+		Spans: []*tracepb.Span{
+			root, client, server, orphan, unmatched, root3, unmatchedServer, internalSpan, nil,
+		},
+	}
+
+	views := MetricViews(telemetry.Normal)
+	require.NoError(t, view.Register(views...))
+	defer view.Unregister(views...)
+
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), td))
+	require.Len(t, sink.AllTraces(), 1)
+
+	for _, span := range []*tracepb.Span{root, client, server, internalSpan, root3} {
+		assert.Equal(t, "", spanIssue(span))
+	}
+	assert.Equal(t, string(IssueOrphan), spanIssue(orphan))
+	assert.Equal(t, string(IssueUnmatchedClient), spanIssue(unmatched))
+	assert.Equal(t, string(IssueUnmatchedServer), spanIssue(unmatchedServer))
+
+	sums := func(name string) map[string]float64 {
+		rows, err := view.RetrieveData(name)
+		require.NoError(t, err)
+		sums := make(map[string]float64)
+		for _, row := range rows {
+			key := ""
+			for _, tag := range row.Tags {
+				key += tag.Key.Name() + "=" + tag.Value + ";"
+			}
+			sums[key] = row.Data.(*view.SumData).Value
+		}
+		return sums
+	}
+	assert.Equal(t, map[string]float64{
+		"complete=true;service=frontend;": 2,
+		"complete=false;service=gateway;": 1,
+	}, sums(statTraces.Name()))
+	assert.Equal(t, map[string]float64{
+		"service=gateway;": 1,
+	}, sums(statTracesWithoutRoot.Name()))
+	assert.Equal(t, map[string]float64{
+		"issue=orphan;service=gateway;":           1,
+		"issue=unmatched_client;service=gateway;": 1,
+		"issue=unmatched_server;service=backend;": 1,
+	}, sums(statBrokenSpans.Name()))
+}
+
+func TestCheckTraces_SharedSpan(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(sink, WithAttribute("trace.issue"))
+	require.NoError(t, err)
+
+	var (
+		// The halves of the shared span 2 are reported by frontend and
+		// backend, and backend calls storage with a span of its own.
+		root         = newSpan(1, 1, 0, tracepb.Span_SERVER, "frontend")
+		sharedClient = newSpan(1, 2, 1, tracepb.Span_CLIENT, "frontend")
+		sharedServer = newSpan(1, 2, 1, tracepb.Span_SERVER, "backend")
+		client       = newSpan(1, 3, 2, tracepb.Span_CLIENT, "backend")
+		server       = newSpan(1, 4, 3, tracepb.Span_SERVER, "storage")
+	)
+	td := data.TraceData{
+		Spans: []*tracepb.Span{root, sharedServer, client, server, sharedClient},
+	}
+
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), td))
+	for _, span := range td.Spans {
+		assert.Equal(t, "", spanIssue(span))
+	}
+}
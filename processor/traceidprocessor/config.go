@@ -0,0 +1,34 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceidprocessor
+
+import (
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+)
+
+// ConfigV2 defines configuration for the trace ID normalization processor.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// HighBits is the hex encoded high half given to the 64-bit trace IDs
+	// not known to be the low half of a 128-bit one. When empty they are
+	// zero-padded, as done by the translators.
+	HighBits string `mapstructure:"high_bits"`
+	// MergeMixedTraces enables replacing the 64-bit trace IDs by the 128-bit
+	// trace ID previously seen with the same low half.
+	MergeMixedTraces bool `mapstructure:"merge_mixed_traces"`
+	// MaxEntries is the maximum number of 128-bit trace IDs remembered to
+	// merge mixed traces, the least recently seen ones are forgotten first.
+	MaxEntries int `mapstructure:"max_entries"`
+}
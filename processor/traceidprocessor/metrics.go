@@ -0,0 +1,70 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceidprocessor
+
+import (
+	"context"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/census-instrumentation/opencensus-service/internal/collector/processor"
+	"github.com/census-instrumentation/opencensus-service/internal/collector/telemetry"
+)
+
+// Variables related to metrics specific to the trace ID normalization processor.
+var (
+	statIDsPadded = stats.Int64("traceid_ids_padded", "Count of trace IDs shorter than 128 bits padded with zeros", stats.UnitDimensionless)
+	statIDsMerged = stats.Int64("traceid_mixed_ids_merged", "Count of 64-bit trace IDs replaced by the 128-bit trace ID with the same low bits", stats.UnitDimensionless)
+	statIDsMapped = stats.Int64("traceid_ids_mapped", "Count of 64-bit trace IDs given the configured high bits", stats.UnitDimensionless)
+)
+
+func recordCounts(ctx context.Context, node *commonpb.Node, c counts) {
+	if c == (counts{}) {
+		return
+	}
+	_ = stats.RecordWithTags(
+		ctx,
+		[]tag.Mutator{tag.Upsert(processor.TagServiceNameKey, processor.ServiceNameForNode(node))},
+		statIDsPadded.M(c.padded),
+		statIDsMerged.M(c.merged),
+		statIDsMapped.M(c.mapped))
+}
+
+// MetricViews return the metrics views according to given telemetry level.
+func MetricViews(level telemetry.Level) []*view.View {
+	if level == telemetry.None {
+		return nil
+	}
+
+	var tagKeys []tag.Key
+	if level == telemetry.Detailed {
+		tagKeys = append(tagKeys, processor.TagServiceNameKey)
+	}
+
+	var views []*view.View
+	for _, measure := range []*stats.Int64Measure{statIDsPadded, statIDsMerged, statIDsMapped} {
+		views = append(views, &view.View{
+			Name:        measure.Name(),
+			Measure:     measure,
+			Description: measure.Description(),
+			TagKeys:     tagKeys,
+			Aggregation: view.Sum(),
+		})
+	}
+	return views
+}
@@ -0,0 +1,199 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package traceidprocessor contains a trace processor that normalizes the
+// trace IDs of 64-bit Jaeger and Zipkin clients to 128 bits, so that the
+// spans of a trace crossing both old and new clients end up in a single
+// trace in the backend.
+package traceidprocessor
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/processor"
+	"github.com/census-instrumentation/opencensus-service/processor/internal/lru"
+	tracetranslator "github.com/census-instrumentation/opencensus-service/translator/trace"
+)
+
+const (
+	defaultMaxEntries = 100000
+
+	traceIDLen = 16
+)
+
+type traceidprocessor struct {
+	nextConsumer     consumer.TraceConsumer
+	highBits         uint64
+	mergeMixedTraces bool
+
+	// mu protects highByLow, which maps the low half of the 128-bit trace
+	// IDs seen to their high half.
+	mu        sync.Mutex
+	highByLow *lru.Cache
+}
+
+// counts holds the number of trace IDs changed in a batch, per reason.
+type counts struct {
+	padded int64
+	merged int64
+	mapped int64
+}
+
+// Option represents options that can be applied to the trace ID
+// normalization processor.
+type Option func(*traceidprocessor) error
+
+// WithHighBits returns an Option to give the 64-bit trace IDs not known to be
+// part of a 128-bit trace the given high half, instead of zeros.
+func WithHighBits(highBits uint64) Option {
+	return func(tp *traceidprocessor) error {
+		tp.highBits = highBits
+		return nil
+	}
+}
+
+// WithMergeMixedTraces returns an Option to replace the 64-bit trace IDs by
+// the 128-bit trace ID with the same low half, when it was seen in the same
+// batch or before. This merges the traces started by 128-bit clients and
+// propagated by 64-bit ones, which only keep the low half.
+func WithMergeMixedTraces(mergeMixedTraces bool) Option {
+	return func(tp *traceidprocessor) error {
+		tp.mergeMixedTraces = mergeMixedTraces
+		return nil
+	}
+}
+
+// WithMaxEntries returns an Option to configure the maximum number of 128-bit
+// trace IDs remembered to merge mixed traces. When full the least recently
+// seen trace IDs are forgotten first.
+func WithMaxEntries(maxEntries int) Option {
+	return func(tp *traceidprocessor) error {
+		if maxEntries <= 0 {
+			return errors.New("max entries must be positive")
+		}
+		tp.highByLow = lru.New(maxEntries)
+		return nil
+	}
+}
+
+var _ processor.TraceProcessor = (*traceidprocessor)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that makes all the
+// trace IDs of the spans and of their links 128 bits long. Shorter IDs are
+// zero-padded on the left, like the translators do with 64-bit IDs.
+func NewTraceProcessor(nextConsumer consumer.TraceConsumer, options ...Option) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+
+	tp := &traceidprocessor{
+		nextConsumer:     nextConsumer,
+		mergeMixedTraces: true,
+		highByLow:        lru.New(defaultMaxEntries),
+	}
+	for _, opt := range options {
+		if err := opt(tp); err != nil {
+			return nil, err
+		}
+	}
+	return tp, nil
+}
+
+func (tp *traceidprocessor) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	var c counts
+
+	// Pad the IDs first, so the 128-bit ones are all remembered before the
+	// 64-bit ones are looked up, regardless of their order in the batch.
+	for _, span := range td.Spans {
+		if span == nil {
+			continue
+		}
+		span.TraceId = padTraceID(span.TraceId, &c)
+		for _, link := range span.GetLinks().GetLink() {
+			link.TraceId = padTraceID(link.TraceId, &c)
+		}
+	}
+
+	tp.mu.Lock()
+	if tp.mergeMixedTraces {
+		for _, span := range td.Spans {
+			if span == nil {
+				continue
+			}
+			tp.remember(span.TraceId)
+		}
+	}
+	for _, span := range td.Spans {
+		if span == nil {
+			continue
+		}
+		span.TraceId = tp.setHighBits(span.TraceId, &c)
+		for _, link := range span.GetLinks().GetLink() {
+			link.TraceId = tp.setHighBits(link.TraceId, &c)
+		}
+	}
+	tp.mu.Unlock()
+
+	recordCounts(ctx, td.Node, c)
+	return tp.nextConsumer.ConsumeTraceData(ctx, td)
+}
+
+// remember records the high half of a 128-bit trace ID. The caller must hold
+// tp.mu.
+func (tp *traceidprocessor) remember(traceID []byte) {
+	high, low, err := tracetranslator.BytesToUInt64TraceID(traceID)
+	if err != nil || high == 0 || high == tp.highBits {
+		return
+	}
+	tp.highByLow.Add(low, high)
+}
+
+// setHighBits returns a 64-bit trace ID, i.e. whose high half is zero, with
+// the high half of the 128-bit trace ID with the same low half, if any, or
+// the configured one. Other trace IDs are returned as is. The caller must
+// hold tp.mu.
+func (tp *traceidprocessor) setHighBits(traceID []byte, c *counts) []byte {
+	high, low, err := tracetranslator.BytesToUInt64TraceID(traceID)
+	if err != nil || high != 0 || low == 0 {
+		return traceID
+	}
+
+	if tp.mergeMixedTraces {
+		if known, ok := tp.highByLow.Get(low); ok {
+			c.merged++
+			return tracetranslator.UInt64ToByteTraceID(known.(uint64), low)
+		}
+	}
+	if tp.highBits != 0 {
+		c.mapped++
+		return tracetranslator.UInt64ToByteTraceID(tp.highBits, low)
+	}
+	return traceID
+}
+
+// padTraceID returns the given trace ID left-padded with zeros to 128 bits.
+// Empty IDs and IDs already at least 128 bits long are returned as is.
+func padTraceID(traceID []byte, c *counts) []byte {
+	if len(traceID) == 0 || len(traceID) >= traceIDLen {
+		return traceID
+	}
+	padded := make([]byte, traceIDLen)
+	copy(padded[traceIDLen-len(traceID):], traceID)
+	c.padded++
+	return padded
+}
@@ -0,0 +1,124 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traceidprocessor
+
+import (
+	"context"
+	"testing"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+	tracetranslator "github.com/census-instrumentation/opencensus-service/translator/trace"
+)
+
+const (
+	high = uint64(0x0102030405060708)
+	low  = uint64(0x1112131415161718)
+)
+
+var (
+	id128 = tracetranslator.UInt64ToByteTraceID(high, low)
+	id64  = tracetranslator.UInt64ToByteTraceID(0, low)
+)
+
+func spanWithTraceID(traceID []byte) *tracepb.Span {
+	return &tracepb.Span{TraceId: append([]byte(nil), traceID...)}
+}
+
+func consume(t *testing.T, tp *traceidprocessor, spans ...*tracepb.Span) []*tracepb.Span {
+	sink := tp.nextConsumer.(*exportertest.SinkTraceExporter)
+	require.NoError(t, tp.ConsumeTraceData(context.Background(), data.TraceData{Spans: spans}))
+	tds := sink.AllTraces()
+	return tds[len(tds)-1].Spans
+}
+
+func newTestProcessor(t *testing.T, options ...Option) *traceidprocessor {
+	tp, err := NewTraceProcessor(&exportertest.SinkTraceExporter{}, options...)
+	require.NoError(t, err)
+	return tp.(*traceidprocessor)
+}
+
+func TestNewTraceProcessor(t *testing.T) {
+	_, err := NewTraceProcessor(nil)
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(&exportertest.SinkTraceExporter{}, WithMaxEntries(0))
+	assert.Error(t, err)
+}
+
+func TestPadTraceIDs(t *testing.T) {
+	tp := newTestProcessor(t, WithMergeMixedTraces(false))
+
+	short := &tracepb.Span{
+		TraceId: []byte{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18},
+		Links: &tracepb.Span_Links{Link: []*tracepb.Span_Link{
+			{TraceId: []byte{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18}},
+		}},
+	}
+	spans := consume(t, tp, short, spanWithTraceID(id128), &tracepb.Span{}, nil)
+
+	// Padded like the translators convert 64-bit IDs.
+	assert.Equal(t, id64, spans[0].TraceId)
+	assert.Equal(t, id64, spans[0].Links.Link[0].TraceId)
+	assert.Equal(t, id128, spans[1].TraceId)
+	assert.Nil(t, spans[2].TraceId)
+}
+
+func TestMergeMixedTraces(t *testing.T) {
+	tp := newTestProcessor(t)
+
+	// The 64-bit span comes first in the batch, the 128-bit ID of its trace
+	// is still used.
+	spans := consume(t, tp, spanWithTraceID(id64), spanWithTraceID(id128))
+	assert.Equal(t, id128, spans[0].TraceId)
+	assert.Equal(t, id128, spans[1].TraceId)
+
+	// Later batches of the trace are merged too, even with short IDs.
+	spans = consume(t, tp, spanWithTraceID(id64[8:]))
+	assert.Equal(t, id128, spans[0].TraceId)
+
+	// Other 64-bit traces are left alone.
+	other := tracetranslator.UInt64ToByteTraceID(0, low+1)
+	spans = consume(t, tp, spanWithTraceID(other))
+	assert.Equal(t, other, spans[0].TraceId)
+}
+
+func TestHighBits(t *testing.T) {
+	configured := uint64(0xff)
+	tp := newTestProcessor(t, WithHighBits(configured))
+
+	other := tracetranslator.UInt64ToByteTraceID(0, low+1)
+	spans := consume(t, tp, spanWithTraceID(id128), spanWithTraceID(id64), spanWithTraceID(other))
+
+	// Known 128-bit traces take precedence over the configured high bits.
+	assert.Equal(t, id128, spans[1].TraceId)
+	assert.Equal(t, tracetranslator.UInt64ToByteTraceID(configured, low+1), spans[2].TraceId)
+
+	// Mapped IDs are stable and not mistaken for 128-bit ones.
+	spans = consume(t, tp, spanWithTraceID(other))
+	assert.Equal(t, tracetranslator.UInt64ToByteTraceID(configured, low+1), spans[0].TraceId)
+}
+
+func TestSharedTraceIDNotModified(t *testing.T) {
+	tp := newTestProcessor(t, WithHighBits(0xff))
+
+	// Receivers may share the same trace ID slice among spans.
+	shared := append([]byte(nil), id64...)
+	consume(t, tp, &tracepb.Span{TraceId: shared}, &tracepb.Span{TraceId: shared})
+	assert.Equal(t, id64, shared)
+}
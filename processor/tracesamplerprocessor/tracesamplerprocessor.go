@@ -24,6 +24,7 @@ import (
 
 	"github.com/census-instrumentation/opencensus-service/consumer"
 	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/internal/spanmatch"
 	"github.com/census-instrumentation/opencensus-service/processor"
 )
 
@@ -31,6 +32,7 @@ const (
 	// The constants below are tags used to read the configuration via viper.
 	samplingPercentageCfgTag = "sampling-percentage"
 	hashSeedCfgTag           = "hash-seed"
+	matchCfgTag              = "match"
 
 	// The constants help translate user friendly percentages to numbers direct used in sampling.
 	numHashBuckets        = 0x4000 // Using a power of 2 to avoid division.
@@ -47,6 +49,9 @@ type TraceSamplerCfg struct {
 	// have different sampling rates: if they use the same seed all passing one layer may pass the other even if they have
 	// different sampling rates, configuring different seeds avoids that.
 	HashSeed uint32
+	// Match is a spanmatch expression selecting the spans that are sampled, the other spans are all kept. All
+	// spans are sampled if empty.
+	Match string
 }
 
 // InitFromViper updates TraceSamplerCfg according to the viper configuration.
@@ -60,6 +65,7 @@ func (tsc *TraceSamplerCfg) InitFromViper(v *viper.Viper) (*TraceSamplerCfg, err
 	if err := v.UnmarshalKey(hashSeedCfgTag, &tsc.HashSeed); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal %q: %v", hashSeedCfgTag, err)
 	}
+	tsc.Match = v.GetString(matchCfgTag)
 	return tsc, nil
 }
 
@@ -67,6 +73,7 @@ type tracesamplerprocessor struct {
 	nextConsumer       consumer.TraceConsumer
 	scaledSamplingRate uint32
 	hashSeed           uint32
+	match              *spanmatch.Expr
 }
 
 var _ processor.TraceProcessor = (*tracesamplerprocessor)(nil)
@@ -77,12 +84,17 @@ func NewTraceProcessor(nextConsumer consumer.TraceConsumer, cfg TraceSamplerCfg)
 	if nextConsumer == nil {
 		return nil, errors.New("nextConsumer is nil")
 	}
+	match, err := spanmatch.Parse(cfg.Match)
+	if err != nil {
+		return nil, err
+	}
 
 	return &tracesamplerprocessor{
 		nextConsumer: nextConsumer,
 		// Adjust sampling percentage on private so recalculations are avoided.
 		scaledSamplingRate: uint32(cfg.SamplingPercentage * percentageScaleFactor),
 		hashSeed:           cfg.HashSeed,
+		match:              match,
 	}, nil
 }
 
@@ -103,7 +115,8 @@ func (tsp *tracesamplerprocessor) ConsumeTraceData(ctx context.Context, td data.
 		// If one assumes random trace ids hashing may seems avoidable, however, traces can be coming from sources
 		// with various different criterias to generate trace id and perhaps were already sampled without hashing.
 		// Hashing here prevents bias due to such systems.
-		if hash(span.TraceId, tsp.hashSeed)&bitMaskHashBuckets < scaledSamplingRate {
+		if !tsp.match.Match(td.Node, td.Resource, span) ||
+			hash(span.TraceId, tsp.hashSeed)&bitMaskHashBuckets < scaledSamplingRate {
 			sampledSpans = append(sampledSpans, span)
 		}
 	}
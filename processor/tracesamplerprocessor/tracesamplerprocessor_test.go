@@ -93,6 +93,19 @@ func TestTraceSamplerCfg_InitFromViper(t *testing.T) {
 				HashSeed:           1234,
 			},
 		},
+		{
+			name: "happy_path_match",
+			genViperFn: func() *viper.Viper {
+				v := viper.New()
+				v.Set(samplingPercentageCfgTag, 10)
+				v.Set(matchCfgTag, `service == "frontend"`)
+				return v
+			},
+			want: &TraceSamplerCfg{
+				SamplingPercentage: 10,
+				Match:              `service == "frontend"`,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -123,6 +136,14 @@ func TestNewTraceProcessor(t *testing.T) {
 			name:    "nil_nextConsumer",
 			wantErr: true,
 		},
+		{
+			name:         "invalid_match",
+			nextConsumer: &exportertest.SinkTraceExporter{},
+			cfg: TraceSamplerCfg{
+				Match: `duration > 2`,
+			},
+			wantErr: true,
+		},
 		{
 			name:         "happy_path",
 			nextConsumer: &exportertest.SinkTraceExporter{},
@@ -251,6 +272,30 @@ func Test_tracesamplerprocessor_SamplingPercentageRange(t *testing.T) {
 	}
 }
 
+// Test_tracesamplerprocessor_Match checks that only the spans matching the expression are sampled.
+func Test_tracesamplerprocessor_Match(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	tsp, err := NewTraceProcessor(sink, TraceSamplerCfg{Match: `kind == SERVER`})
+	if err != nil {
+		t.Fatalf("error when creating tracesamplerprocessor: %v", err)
+	}
+
+	td := data.TraceData{
+		Spans: []*tracepb.Span{
+			{TraceId: []byte{1, 2, 3, 4}, Kind: tracepb.Span_SERVER},
+			{TraceId: []byte{1, 2, 3, 4}, Kind: tracepb.Span_CLIENT},
+		},
+	}
+	if err := tsp.ConsumeTraceData(context.Background(), td); err != nil {
+		t.Fatalf("tracesamplerprocessor.ConsumeTraceData() error = %v", err)
+	}
+
+	got := sink.AllTraces()
+	if len(got) != 1 || len(got[0].Spans) != 1 || got[0].Spans[0].Kind != tracepb.Span_CLIENT {
+		t.Errorf("got %v, want only the client span", got)
+	}
+}
+
 // Test_hash ensures that the hash function supports different key lengths even if in
 // practice it is only expected to receive keys with length 16 (trace id length in OC proto).
 func Test_hash(t *testing.T) {
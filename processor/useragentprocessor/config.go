@@ -0,0 +1,27 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package useragentprocessor
+
+import "github.com/census-instrumentation/opencensus-service/internal/configmodels"
+
+// ConfigV2 defines configuration for the user agent processor.
+type ConfigV2 struct {
+	configmodels.ProcessorSettings `mapstructure:",squash"`
+	// Key is the span attribute holding the raw user agent.
+	Key string `mapstructure:"key"`
+	// CacheSize is the maximum number of parsed user agents kept, so that
+	// repeated ones aren't parsed again.
+	CacheSize int `mapstructure:"cache_size"`
+}
@@ -0,0 +1,158 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package useragentprocessor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DeviceType is the kind of device a user agent runs on.
+type DeviceType string
+
+const (
+	// DeviceDesktop is a desktop or laptop computer.
+	DeviceDesktop DeviceType = "desktop"
+	// DeviceMobile is a phone.
+	DeviceMobile DeviceType = "mobile"
+	// DeviceTablet is a tablet.
+	DeviceTablet DeviceType = "tablet"
+	// DeviceBot is a crawler or another automated client.
+	DeviceBot DeviceType = "bot"
+	// DeviceOther is any other device, e.g. the one of an HTTP library.
+	DeviceOther DeviceType = "other"
+)
+
+// userAgent is what is known of a parsed user agent.
+type userAgent struct {
+	browserFamily  string
+	browserVersion string
+	osFamily       string
+	osVersion      string
+	deviceType     DeviceType
+	bot            bool
+}
+
+// agentRule matches the product of a user agent, e.g. a browser, and
+// captures its version. Without family, the first group is the family and
+// the second one the version.
+type agentRule struct {
+	re     *regexp.Regexp
+	family string
+}
+
+// browserRules are tried in order: user agents commonly mention the products
+// they are compatible with, e.g. Edge ones mention Chrome and Safari, so the
+// most specific rules come first.
+var browserRules = []agentRule{
+	{regexp.MustCompile(`(Googlebot|bingbot|YandexBot|DuckDuckBot|Baiduspider|Applebot|AhrefsBot|facebookexternalhit)/(\d[\d.]*)`), ""},
+	{regexp.MustCompile(`Edg(?:e|A|iOS)?/(\d[\d.]*)`), "Edge"},
+	{regexp.MustCompile(`OPR/(\d[\d.]*)`), "Opera"},
+	{regexp.MustCompile(`Opera/.*Version/(\d[\d.]*)`), "Opera"},
+	{regexp.MustCompile(`SamsungBrowser/(\d[\d.]*)`), "Samsung Internet"},
+	{regexp.MustCompile(`YaBrowser/(\d[\d.]*)`), "Yandex Browser"},
+	{regexp.MustCompile(`FxiOS/(\d[\d.]*)`), "Firefox"},
+	{regexp.MustCompile(`CriOS/(\d[\d.]*)`), "Chrome"},
+	{regexp.MustCompile(`HeadlessChrome/(\d[\d.]*)`), "HeadlessChrome"},
+	{regexp.MustCompile(`Chromium/(\d[\d.]*)`), "Chromium"},
+	{regexp.MustCompile(`Chrome/(\d[\d.]*)`), "Chrome"},
+	{regexp.MustCompile(`Firefox/(\d[\d.]*)`), "Firefox"},
+	{regexp.MustCompile(`MSIE (\d[\d.]*)`), "IE"},
+	{regexp.MustCompile(`Trident/.*rv:(\d[\d.]*)`), "IE"},
+	{regexp.MustCompile(`Version/(\d[\d.]*).*Safari/`), "Safari"},
+	{regexp.MustCompile(`(curl|Wget|python-requests|Go-http-client|okhttp|Apache-HttpClient|Java)/(\d[\d.]*)`), ""},
+}
+
+// osRule matches an operating system and captures its version, unless the
+// rule has one.
+type osRule struct {
+	re      *regexp.Regexp
+	family  string
+	version string
+}
+
+var osRules = []osRule{
+	{re: regexp.MustCompile(`Windows Phone(?: OS)? (\d[\d.]*)`), family: "Windows Phone"},
+	{re: regexp.MustCompile(`Windows NT 10\.0`), family: "Windows", version: "10"},
+	{re: regexp.MustCompile(`Windows NT 6\.3`), family: "Windows", version: "8.1"},
+	{re: regexp.MustCompile(`Windows NT 6\.2`), family: "Windows", version: "8"},
+	{re: regexp.MustCompile(`Windows NT 6\.1`), family: "Windows", version: "7"},
+	{re: regexp.MustCompile(`Windows NT 6\.0`), family: "Windows", version: "Vista"},
+	{re: regexp.MustCompile(`Windows NT 5\.[12]`), family: "Windows", version: "XP"},
+	{re: regexp.MustCompile(`Windows`), family: "Windows"},
+	{re: regexp.MustCompile(`(?:iPhone|iPad|iPod).*? OS (\d[_\d]*)`), family: "iOS"},
+	{re: regexp.MustCompile(`Android (\d[\d.]*)`), family: "Android"},
+	{re: regexp.MustCompile(`Android`), family: "Android"},
+	{re: regexp.MustCompile(`CrOS \S+ (\d[\d.]*)`), family: "Chrome OS"},
+	{re: regexp.MustCompile(`Mac OS X (\d[_.\d]*)`), family: "Mac OS X"},
+	{re: regexp.MustCompile(`Linux`), family: "Linux"},
+}
+
+var (
+	botRe    = regexp.MustCompile(`(?i)bot\b|crawl|spider|slurp|facebookexternalhit|headlesschrome|lighthouse|pingdom`)
+	tabletRe = regexp.MustCompile(`iPad|Tablet|Kindle|Silk/`)
+	mobileRe = regexp.MustCompile(`Mobi|iPhone|iPod|Windows Phone`)
+)
+
+var desktopOSFamilies = map[string]bool{
+	"Windows":   true,
+	"Mac OS X":  true,
+	"Linux":     true,
+	"Chrome OS": true,
+}
+
+// parseUserAgent parses the value of a User-Agent header. The parts it
+// doesn't recognize are left empty.
+func parseUserAgent(s string) *userAgent {
+	ua := &userAgent{}
+	for _, rule := range browserRules {
+		m := rule.re.FindStringSubmatch(s)
+		if m == nil {
+			continue
+		}
+		if rule.family == "" {
+			ua.browserFamily, ua.browserVersion = m[1], m[2]
+		} else {
+			ua.browserFamily, ua.browserVersion = rule.family, m[1]
+		}
+		break
+	}
+	for _, rule := range osRules {
+		m := rule.re.FindStringSubmatch(s)
+		if m == nil {
+			continue
+		}
+		ua.osFamily, ua.osVersion = rule.family, rule.version
+		if len(m) > 1 {
+			ua.osVersion = strings.Replace(m[1], "_", ".", -1)
+		}
+		break
+	}
+
+	ua.bot = botRe.MatchString(s)
+	switch {
+	case ua.bot:
+		ua.deviceType = DeviceBot
+	case tabletRe.MatchString(s) || (ua.osFamily == "Android" && !strings.Contains(s, "Mobile")):
+		ua.deviceType = DeviceTablet
+	case mobileRe.MatchString(s):
+		ua.deviceType = DeviceMobile
+	case desktopOSFamilies[ua.osFamily] && ua.browserFamily != "":
+		ua.deviceType = DeviceDesktop
+	default:
+		ua.deviceType = DeviceOther
+	}
+	return ua
+}
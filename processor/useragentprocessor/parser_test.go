@@ -0,0 +1,110 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package useragentprocessor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want userAgent
+	}{
+		{
+			name: "chrome_windows",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/75.0.3770.100 Safari/537.36",
+			want: userAgent{"Chrome", "75.0.3770.100", "Windows", "10", DeviceDesktop, false},
+		},
+		{
+			name: "edge_windows",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/64.0.3282.140 Safari/537.36 Edge/18.17763",
+			want: userAgent{"Edge", "18.17763", "Windows", "10", DeviceDesktop, false},
+		},
+		{
+			name: "firefox_linux",
+			ua:   "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:67.0) Gecko/20100101 Firefox/67.0",
+			want: userAgent{"Firefox", "67.0", "Linux", "", DeviceDesktop, false},
+		},
+		{
+			name: "safari_mac",
+			ua:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1.1 Safari/605.1.15",
+			want: userAgent{"Safari", "12.1.1", "Mac OS X", "10.14.5", DeviceDesktop, false},
+		},
+		{
+			name: "ie11",
+			ua:   "Mozilla/5.0 (Windows NT 6.1; WOW64; Trident/7.0; rv:11.0) like Gecko",
+			want: userAgent{"IE", "11.0", "Windows", "7", DeviceDesktop, false},
+		},
+		{
+			name: "opera",
+			ua:   "Mozilla/5.0 (Windows NT 6.3; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/74.0.3729.169 Safari/537.36 OPR/61.0.3298.6",
+			want: userAgent{"Opera", "61.0.3298.6", "Windows", "8.1", DeviceDesktop, false},
+		},
+		{
+			name: "safari_iphone",
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 12_3_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1.1 Mobile/15E148 Safari/604.1",
+			want: userAgent{"Safari", "12.1.1", "iOS", "12.3.1", DeviceMobile, false},
+		},
+		{
+			name: "chrome_ipad",
+			ua:   "Mozilla/5.0 (iPad; CPU OS 12_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/75.0.3770.103 Mobile/15E148 Safari/605.1",
+			want: userAgent{"Chrome", "75.0.3770.103", "iOS", "12.2", DeviceTablet, false},
+		},
+		{
+			name: "samsung_android_phone",
+			ua:   "Mozilla/5.0 (Linux; Android 9; SM-G960F) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/9.2 Chrome/67.0.3396.87 Mobile Safari/537.36",
+			want: userAgent{"Samsung Internet", "9.2", "Android", "9", DeviceMobile, false},
+		},
+		{
+			name: "chrome_android_tablet",
+			ua:   "Mozilla/5.0 (Linux; Android 8.1.0; Nexus 9) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/75.0.3770.101 Safari/537.36",
+			want: userAgent{"Chrome", "75.0.3770.101", "Android", "8.1.0", DeviceTablet, false},
+		},
+		{
+			name: "chrome_os",
+			ua:   "Mozilla/5.0 (X11; CrOS x86_64 11895.118.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/74.0.3729.159 Safari/537.36",
+			want: userAgent{"Chrome", "74.0.3729.159", "Chrome OS", "11895.118.0", DeviceDesktop, false},
+		},
+		{
+			name: "googlebot",
+			ua:   "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want: userAgent{"Googlebot", "2.1", "", "", DeviceBot, true},
+		},
+		{
+			name: "headless_chrome",
+			ua:   "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/75.0.3770.100 Safari/537.36",
+			want: userAgent{"HeadlessChrome", "75.0.3770.100", "Linux", "", DeviceBot, true},
+		},
+		{
+			name: "curl",
+			ua:   "curl/7.64.1",
+			want: userAgent{"curl", "7.64.1", "", "", DeviceOther, false},
+		},
+		{
+			name: "unknown",
+			ua:   "something",
+			want: userAgent{"", "", "", "", DeviceOther, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, &tt.want, parseUserAgent(tt.ua))
+		})
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package useragentprocessor contains a trace processor that parses the user
+// agent found in the span attributes, e.g. of browser-instrumented spans,
+// into its browser, operating system and device, so spans can be grouped by
+// them.
+package useragentprocessor
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/processor"
+	"github.com/census-instrumentation/opencensus-service/processor/internal/lru"
+)
+
+const (
+	// DefaultKey is the attribute the user agent is read from by default.
+	DefaultKey = "http.user_agent"
+
+	defaultCacheSize = 1000
+)
+
+// Span attributes added by the processor.
+const (
+	AttributeBrowserFamily  = "user_agent.browser.family"
+	AttributeBrowserVersion = "user_agent.browser.version"
+	AttributeOSFamily       = "user_agent.os.family"
+	AttributeOSVersion      = "user_agent.os.version"
+	AttributeDeviceType     = "user_agent.device.type"
+	AttributeBot            = "user_agent.bot"
+)
+
+type useragentprocessor struct {
+	nextConsumer consumer.TraceConsumer
+	key          string
+
+	// mu protects cache, which maps the user agents recently seen to their
+	// parsed *userAgent.
+	mu    sync.Mutex
+	cache *lru.Cache
+}
+
+// Option represents options that can be applied to the user agent processor.
+type Option func(*useragentprocessor) error
+
+// WithKey returns an Option to read the user agent from the span attribute
+// with the given key.
+func WithKey(key string) Option {
+	return func(up *useragentprocessor) error {
+		if key == "" {
+			return errors.New("key must not be empty")
+		}
+		up.key = key
+		return nil
+	}
+}
+
+// WithCacheSize returns an Option to configure the maximum number of parsed
+// user agents kept. When full the least recently seen user agents are
+// forgotten first.
+func WithCacheSize(cacheSize int) Option {
+	return func(up *useragentprocessor) error {
+		if cacheSize <= 0 {
+			return errors.New("cache size must be positive")
+		}
+		up.cache = lru.New(cacheSize)
+		return nil
+	}
+}
+
+var _ processor.TraceProcessor = (*useragentprocessor)(nil)
+
+// NewTraceProcessor returns a processor.TraceProcessor that adds the browser,
+// operating system and device parsed from the user agent of each span to its
+// attributes. Attributes already set on the span are kept.
+func NewTraceProcessor(nextConsumer consumer.TraceConsumer, options ...Option) (processor.TraceProcessor, error) {
+	if nextConsumer == nil {
+		return nil, errors.New("nextConsumer is nil")
+	}
+
+	up := &useragentprocessor{
+		nextConsumer: nextConsumer,
+		key:          DefaultKey,
+		cache:        lru.New(defaultCacheSize),
+	}
+	for _, opt := range options {
+		if err := opt(up); err != nil {
+			return nil, err
+		}
+	}
+	return up, nil
+}
+
+func (up *useragentprocessor) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	for _, span := range td.Spans {
+		if span == nil {
+			continue
+		}
+		value := span.GetAttributes().GetAttributeMap()[up.key].GetStringValue().GetValue()
+		if value == "" {
+			continue
+		}
+		up.parse(value).addAttributes(span)
+	}
+	return up.nextConsumer.ConsumeTraceData(ctx, td)
+}
+
+// parse returns the parsed user agent, from the cache if it was seen
+// recently.
+func (up *useragentprocessor) parse(s string) *userAgent {
+	up.mu.Lock()
+	cached, ok := up.cache.Get(s)
+	up.mu.Unlock()
+	if ok {
+		return cached.(*userAgent)
+	}
+
+	// Concurrent batches may parse the same user agent, which is harmless.
+	ua := parseUserAgent(s)
+	up.mu.Lock()
+	up.cache.Add(s, ua)
+	up.mu.Unlock()
+	return ua
+}
+
+// addAttributes adds the user agent to the attributes of the span, keeping
+// the ones the span already has. The values are created for each span, since
+// spans must not share them.
+func (ua *userAgent) addAttributes(span *tracepb.Span) {
+	if span.Attributes == nil {
+		span.Attributes = &tracepb.Span_Attributes{}
+	}
+	if span.Attributes.AttributeMap == nil {
+		span.Attributes.AttributeMap = make(map[string]*tracepb.AttributeValue)
+	}
+	attrs := span.Attributes.AttributeMap
+	add := func(key string, value *tracepb.AttributeValue) {
+		if _, exists := attrs[key]; !exists {
+			attrs[key] = value
+		}
+	}
+	for key, value := range map[string]string{
+		AttributeBrowserFamily:  ua.browserFamily,
+		AttributeBrowserVersion: ua.browserVersion,
+		AttributeOSFamily:       ua.osFamily,
+		AttributeOSVersion:      ua.osVersion,
+		AttributeDeviceType:     string(ua.deviceType),
+	} {
+		if value != "" {
+			add(key, &tracepb.AttributeValue{
+				Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: value}},
+			})
+		}
+	}
+	add(AttributeBot, &tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_BoolValue{BoolValue: ua.bot},
+	})
+}
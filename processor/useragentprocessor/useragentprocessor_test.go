@@ -0,0 +1,147 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package useragentprocessor
+
+import (
+	"context"
+	"testing"
+
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+)
+
+const chromeWindows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/75.0.3770.100 Safari/537.36"
+
+func stringValue(s string) *tracepb.AttributeValue {
+	return &tracepb.AttributeValue{
+		Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: s}},
+	}
+}
+
+func boolValue(b bool) *tracepb.AttributeValue {
+	return &tracepb.AttributeValue{Value: &tracepb.AttributeValue_BoolValue{BoolValue: b}}
+}
+
+func TestNewTraceProcessor(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	_, err := NewTraceProcessor(nil)
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithKey(""))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithCacheSize(0))
+	assert.Error(t, err)
+	_, err = NewTraceProcessor(sink, WithKey("user_agent.original"), WithCacheSize(10))
+	assert.NoError(t, err)
+}
+
+func TestConsumeTraceData(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		attrs   map[string]*tracepb.AttributeValue
+		want    map[string]*tracepb.AttributeValue
+	}{
+		{
+			name:  "default_key",
+			attrs: map[string]*tracepb.AttributeValue{"http.user_agent": stringValue(chromeWindows)},
+			want: map[string]*tracepb.AttributeValue{
+				"http.user_agent":       stringValue(chromeWindows),
+				AttributeBrowserFamily:  stringValue("Chrome"),
+				AttributeBrowserVersion: stringValue("75.0.3770.100"),
+				AttributeOSFamily:       stringValue("Windows"),
+				AttributeOSVersion:      stringValue("10"),
+				AttributeDeviceType:     stringValue("desktop"),
+				AttributeBot:            boolValue(false),
+			},
+		},
+		{
+			name:    "custom_key",
+			options: []Option{WithKey("user_agent.original")},
+			attrs:   map[string]*tracepb.AttributeValue{"user_agent.original": stringValue("curl/7.64.1")},
+			want: map[string]*tracepb.AttributeValue{
+				"user_agent.original":   stringValue("curl/7.64.1"),
+				AttributeBrowserFamily:  stringValue("curl"),
+				AttributeBrowserVersion: stringValue("7.64.1"),
+				AttributeDeviceType:     stringValue("other"),
+				AttributeBot:            boolValue(false),
+			},
+		},
+		{
+			name: "existing_attributes_kept",
+			attrs: map[string]*tracepb.AttributeValue{
+				"http.user_agent":   stringValue(chromeWindows),
+				AttributeDeviceType: stringValue("kiosk"),
+			},
+			want: map[string]*tracepb.AttributeValue{
+				"http.user_agent":       stringValue(chromeWindows),
+				AttributeBrowserFamily:  stringValue("Chrome"),
+				AttributeBrowserVersion: stringValue("75.0.3770.100"),
+				AttributeOSFamily:       stringValue("Windows"),
+				AttributeOSVersion:      stringValue("10"),
+				AttributeDeviceType:     stringValue("kiosk"),
+				AttributeBot:            boolValue(false),
+			},
+		},
+		{
+			name:  "no_user_agent",
+			attrs: map[string]*tracepb.AttributeValue{"http.method": stringValue("GET")},
+			want:  map[string]*tracepb.AttributeValue{"http.method": stringValue("GET")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &exportertest.SinkTraceExporter{}
+			up, err := NewTraceProcessor(sink, tt.options...)
+			require.NoError(t, err)
+
+			span := &tracepb.Span{Attributes: &tracepb.Span_Attributes{AttributeMap: tt.attrs}}
+			td := data.TraceData{Spans: []*tracepb.Span{span, nil}}
+			require.NoError(t, up.ConsumeTraceData(context.Background(), td))
+
+			got := sink.AllTraces()
+			require.Len(t, got, 1)
+			assert.Equal(t, tt.want, got[0].Spans[0].GetAttributes().GetAttributeMap())
+		})
+	}
+}
+
+func TestConsumeTraceDataCached(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	tp, err := NewTraceProcessor(sink, WithCacheSize(1))
+	require.NoError(t, err)
+	up := tp.(*useragentprocessor)
+
+	newSpan := func(ua string) *tracepb.Span {
+		return &tracepb.Span{Attributes: &tracepb.Span_Attributes{
+			AttributeMap: map[string]*tracepb.AttributeValue{"http.user_agent": stringValue(ua)},
+		}}
+	}
+	spans := []*tracepb.Span{newSpan(chromeWindows), newSpan(chromeWindows), newSpan("curl/7.64.1")}
+	require.NoError(t, up.ConsumeTraceData(context.Background(), data.TraceData{Spans: spans}))
+
+	assert.Equal(t, 1, up.cache.Len())
+	_, ok := up.cache.Get("curl/7.64.1")
+	assert.True(t, ok)
+
+	// Spans with the same user agent must not share attribute values.
+	attrs0 := spans[0].Attributes.AttributeMap
+	attrs1 := spans[1].Attributes.AttributeMap
+	assert.Equal(t, attrs0[AttributeBrowserFamily], attrs1[AttributeBrowserFamily])
+	assert.False(t, attrs0[AttributeBrowserFamily] == attrs1[AttributeBrowserFamily])
+}
@@ -0,0 +1,155 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package propagation carries selected metadata of the received requests,
+// e.g. auth tokens or tenant headers, along with the data in the
+// context.Context passed to the consumers, so exporters can forward it on
+// their outgoing requests and the collector becomes a transparent hop.
+//
+// Components that merge data of several requests into one outgoing request,
+// e.g. batching processors, drop the metadata.
+package propagation
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// Metadata maps the lower-cased keys of request headers or gRPC metadata to
+// their first value.
+type Metadata map[string]string
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the given metadata.
+func NewContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, contextKey{}, md)
+}
+
+// FromContext returns the metadata carried by the context, if any.
+func FromContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(contextKey{}).(Metadata)
+	return md, ok
+}
+
+// Extractor selects the metadata of the received requests that is passed
+// along with the data.
+type Extractor struct {
+	// Keys are the names of the gRPC metadata or HTTP headers to keep.
+	Keys []string `mapstructure:"keys"`
+}
+
+// Extract returns the configured keys found in the incoming gRPC metadata of
+// ctx or in the HTTP header, which can be nil. It is safe to call on a nil
+// Extractor, in which case no metadata is found.
+func (e *Extractor) Extract(ctx context.Context, header http.Header) Metadata {
+	if e == nil {
+		return nil
+	}
+
+	var incoming metadata.MD
+	if ctx != nil {
+		incoming, _ = metadata.FromIncomingContext(ctx)
+	}
+
+	var md Metadata
+	for _, key := range e.Keys {
+		value := ""
+		if values := incoming.Get(key); len(values) > 0 {
+			value = values[0]
+		} else if header != nil {
+			value = header.Get(key)
+		}
+		if value == "" {
+			continue
+		}
+		if md == nil {
+			md = make(Metadata, len(e.Keys))
+		}
+		md[strings.ToLower(key)] = value
+	}
+	return md
+}
+
+// NewContext returns a context carrying the metadata extracted from the given
+// sources, or ctx itself if none is found.
+func (e *Extractor) NewContext(ctx context.Context, header http.Header) context.Context {
+	if md := e.Extract(ctx, header); len(md) > 0 {
+		return NewContext(ctx, md)
+	}
+	return ctx
+}
+
+// Injector selects the metadata carried by the context that an exporter sets
+// on its outgoing requests.
+type Injector struct {
+	// Keys are the metadata forwarded under their own name.
+	Keys []string `mapstructure:"keys"`
+	// Rename maps metadata keys to the header name they are forwarded as.
+	Rename map[string]string `mapstructure:"rename"`
+}
+
+// Headers returns the outgoing headers for the metadata carried by ctx. It is
+// safe to call on a nil Injector, in which case no header is returned.
+func (i *Injector) Headers(ctx context.Context) map[string]string {
+	if i == nil {
+		return nil
+	}
+	md, ok := FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	var headers map[string]string
+	set := func(name, value string) {
+		if headers == nil {
+			headers = make(map[string]string, len(i.Keys)+len(i.Rename))
+		}
+		headers[name] = value
+	}
+	for _, key := range i.Keys {
+		if value, ok := md[strings.ToLower(key)]; ok {
+			set(key, value)
+		}
+	}
+	for key, name := range i.Rename {
+		if value, ok := md[strings.ToLower(key)]; ok {
+			set(name, value)
+		}
+	}
+	return headers
+}
+
+// InjectHTTP sets the outgoing headers for the metadata carried by ctx on
+// the given HTTP header.
+func (i *Injector) InjectHTTP(ctx context.Context, header http.Header) {
+	for name, value := range i.Headers(ctx) {
+		header.Set(name, value)
+	}
+}
+
+// HeadersKey returns a string that uniquely identifies the given headers, to
+// be used by exporters that keep one client per distinct set of headers.
+func HeadersKey(headers map[string]string) string {
+	pairs := make([]string, 0, len(headers))
+	for name, value := range headers {
+		pairs = append(pairs, strings.ToLower(name)+"\x00"+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\x00")
+}
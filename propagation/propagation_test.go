@@ -0,0 +1,88 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestExtractor(t *testing.T) {
+	grpcCtx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("authorization", "Bearer grpc", "x-other", "ignored"))
+	header := http.Header{}
+	header.Set("Authorization", "Bearer http")
+	header.Set("X-Tenant", "acme")
+
+	e := &Extractor{Keys: []string{"Authorization", "X-Tenant"}}
+	tests := []struct {
+		name      string
+		extractor *Extractor
+		ctx       context.Context
+		header    http.Header
+		want      Metadata
+	}{
+		{name: "nil_extractor", ctx: grpcCtx, header: header},
+		{name: "grpc", extractor: e, ctx: grpcCtx, want: Metadata{"authorization": "Bearer grpc"}},
+		{name: "http", extractor: e, ctx: context.Background(), header: header,
+			want: Metadata{"authorization": "Bearer http", "x-tenant": "acme"}},
+		{name: "grpc_first", extractor: e, ctx: grpcCtx, header: header,
+			want: Metadata{"authorization": "Bearer grpc", "x-tenant": "acme"}},
+		{name: "none", extractor: e, ctx: context.Background()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.extractor.Extract(tt.ctx, tt.header))
+
+			md, ok := FromContext(tt.extractor.NewContext(tt.ctx, tt.header))
+			assert.Equal(t, tt.want != nil, ok)
+			assert.Equal(t, tt.want, md)
+		})
+	}
+}
+
+func TestInjector(t *testing.T) {
+	ctx := NewContext(context.Background(), Metadata{"authorization": "Bearer abc", "x-tenant": "acme"})
+
+	var nilInjector *Injector
+	assert.Nil(t, nilInjector.Headers(ctx))
+
+	i := &Injector{
+		Keys:   []string{"Authorization", "X-Missing"},
+		Rename: map[string]string{"X-Tenant": "X-Scope-OrgID"},
+	}
+	assert.Nil(t, i.Headers(context.Background()))
+	assert.Equal(t, map[string]string{"Authorization": "Bearer abc", "X-Scope-OrgID": "acme"}, i.Headers(ctx))
+
+	header := http.Header{}
+	i.InjectHTTP(ctx, header)
+	assert.Equal(t, "Bearer abc", header.Get("Authorization"))
+	assert.Equal(t, "acme", header.Get("X-Scope-OrgID"))
+	assert.Len(t, header, 2)
+}
+
+func TestHeadersKey(t *testing.T) {
+	assert.Equal(t, "", HeadersKey(nil))
+	assert.Equal(t,
+		HeadersKey(map[string]string{"A": "1", "b": "2"}),
+		HeadersKey(map[string]string{"B": "2", "a": "1"}))
+	assert.NotEqual(t,
+		HeadersKey(map[string]string{"a": "1", "b": "2"}),
+		HeadersKey(map[string]string{"a": "12"}))
+}
@@ -15,10 +15,15 @@
 package jaegerreceiver
 
 import (
+	"github.com/census-instrumentation/opencensus-service/internal/config"
 	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
 )
 
 // ConfigV2 defines configuration for Jaeger receiver.
 type ConfigV2 struct {
 	Protocols map[string]*configmodels.ReceiverSettings `mapstructure:"protocols"`
+
+	// TLSCredentials is a (cert_file, key_file) configuration securing the
+	// grpc protocol.
+	TLSCredentials *config.TLSCredentials `mapstructure:"tls_credentials"`
 }
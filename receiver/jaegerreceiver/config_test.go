@@ -21,6 +21,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/census-instrumentation/opencensus-service/internal/config"
 	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
 	"github.com/census-instrumentation/opencensus-service/internal/configv2"
 	"github.com/census-instrumentation/opencensus-service/internal/factories"
@@ -31,17 +32,17 @@ var _ = configv2.RegisterTestFactories()
 func TestLoadConfig(t *testing.T) {
 	factory := factories.GetReceiverFactory(typeStr)
 
-	config, err := configv2.LoadConfigFile(t, path.Join(".", "testdata", "config.yaml"))
+	cfg, err := configv2.LoadConfigFile(t, path.Join(".", "testdata", "config.yaml"))
 
 	require.NoError(t, err)
-	require.NotNil(t, config)
+	require.NotNil(t, cfg)
 
-	assert.Equal(t, len(config.Receivers), 2)
+	assert.Equal(t, len(cfg.Receivers), 2)
 
-	r0 := config.Receivers["jaeger"]
+	r0 := cfg.Receivers["jaeger"]
 	assert.Equal(t, r0, factory.CreateDefaultConfig())
 
-	r1 := config.Receivers["jaeger/customname"].(*ConfigV2)
+	r1 := cfg.Receivers["jaeger/customname"].(*ConfigV2)
 	assert.Equal(t, r1,
 		&ConfigV2{
 			Protocols: map[string]*configmodels.ReceiverSettings{
@@ -53,6 +54,14 @@ func TestLoadConfig(t *testing.T) {
 					Enabled:  true,
 					Endpoint: "0.0.0.0:123",
 				},
+				"grpc": {
+					Enabled:  true,
+					Endpoint: "0.0.0.0:14250",
+				},
+			},
+			TLSCredentials: &config.TLSCredentials{
+				CertFile: "server.crt",
+				KeyFile:  "server.key",
 			},
 		})
 }
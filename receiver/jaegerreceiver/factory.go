@@ -38,10 +38,12 @@ const (
 	// Protocol values.
 	protoThriftHTTP     = "thrift-http"
 	protoThriftTChannel = "thrift-tchannel"
+	protoGRPC           = "grpc"
 
 	// Default endpoints to bind to.
 	defaultHTTPBindEndpoint     = "127.0.0.1:14268"
 	defaultTChannelBindEndpoint = "127.0.0.1:14267"
+	defaultGRPCBindEndpoint     = "127.0.0.1:14250"
 )
 
 // receiverFactory is the factory for Jaeger receiver.
@@ -70,6 +72,10 @@ func (f *receiverFactory) CreateDefaultConfig() configmodels.Receiver {
 				Enabled:  false,
 				Endpoint: defaultHTTPBindEndpoint,
 			},
+			protoGRPC: {
+				Enabled:  false,
+				Endpoint: defaultGRPCBindEndpoint,
+			},
 		},
 	}
 }
@@ -88,6 +94,7 @@ func (f *receiverFactory) CreateTraceReceiver(
 
 	protoHTTP := rCfg.Protocols[protoThriftHTTP]
 	protoTChannel := rCfg.Protocols[protoThriftTChannel]
+	protoGRPCSettings := rCfg.Protocols[protoGRPC]
 
 	config := Configuration{}
 
@@ -108,9 +115,28 @@ func (f *receiverFactory) CreateTraceReceiver(
 		}
 	}
 
-	if (protoHTTP == nil && protoTChannel == nil) ||
-		(config.CollectorHTTPPort == 0 && config.CollectorThriftPort == 0) {
-		return nil, errors.New("either " + protoThriftHTTP + " or " + protoThriftTChannel +
+	// The gRPC collector is only started if it is enabled.
+	if protoGRPCSettings != nil && protoGRPCSettings.Enabled {
+		var err error
+		config.CollectorGRPCPort, err = extractPortFromEndpoint(protoGRPCSettings.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rCfg.TLSCredentials != nil {
+		opt, ok, err := rCfg.TLSCredentials.ToGRPCServerOption()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS credentials of %s receiver: %v", typeStr, err)
+		}
+		if ok {
+			config.CollectorGRPCOptions = append(config.CollectorGRPCOptions, opt)
+		}
+	}
+
+	if (protoHTTP == nil && protoTChannel == nil && protoGRPCSettings == nil) ||
+		(config.CollectorHTTPPort == 0 && config.CollectorThriftPort == 0 && config.CollectorGRPCPort == 0) {
+		return nil, errors.New("one of " + protoThriftHTTP + ", " + protoThriftTChannel + " or " + protoGRPC +
 			" protocol endpoint with non-zero port must be defined for " + typeStr + " receiver")
 	}
 
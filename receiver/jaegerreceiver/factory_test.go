@@ -16,10 +16,12 @@ package jaegerreceiver
 
 import (
 	"context"
+	"path"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/census-instrumentation/opencensus-service/internal/config"
 	"github.com/census-instrumentation/opencensus-service/internal/factories"
 )
 
@@ -63,6 +65,32 @@ func TestCreateInvalidTChannelEndpoint(t *testing.T) {
 	assert.Error(t, err, "receiver creation with invalid tchannel endpoint must fail")
 }
 
+func TestCreateInvalidGRPCEndpoint(t *testing.T) {
+	factory := factories.GetReceiverFactory(typeStr)
+	cfg := factory.CreateDefaultConfig()
+	rCfg := cfg.(*ConfigV2)
+
+	rCfg.Protocols[protoGRPC].Enabled = true
+	rCfg.Protocols[protoGRPC].Endpoint = "127.0.0.1"
+	_, err := factory.CreateTraceReceiver(context.Background(), cfg, nil)
+	assert.Error(t, err, "receiver creation with invalid grpc endpoint must fail")
+}
+
+func TestCreateGRPCOnlyIfEnabled(t *testing.T) {
+	factory := factories.GetReceiverFactory(typeStr)
+	cfg := factory.CreateDefaultConfig()
+	rCfg := cfg.(*ConfigV2)
+
+	tReceiver, err := factory.CreateTraceReceiver(context.Background(), cfg, nil)
+	assert.NoError(t, err, "receiver creation failed")
+	assert.Equal(t, 0, tReceiver.(*jReceiver).config.CollectorGRPCPort, "grpc must be disabled by default")
+
+	rCfg.Protocols[protoGRPC].Enabled = true
+	tReceiver, err = factory.CreateTraceReceiver(context.Background(), cfg, nil)
+	assert.NoError(t, err, "receiver creation failed")
+	assert.Equal(t, 14250, tReceiver.(*jReceiver).config.CollectorGRPCPort)
+}
+
 func TestCreateNoPort(t *testing.T) {
 	factory := factories.GetReceiverFactory(typeStr)
 	cfg := factory.CreateDefaultConfig()
@@ -90,6 +118,36 @@ func TestCreateNoProtocols(t *testing.T) {
 
 	delete(rCfg.Protocols, protoThriftHTTP)
 	delete(rCfg.Protocols, protoThriftTChannel)
+	delete(rCfg.Protocols, protoGRPC)
 	_, err := factory.CreateTraceReceiver(context.Background(), cfg, nil)
 	assert.Error(t, err, "receiver creation with no protocols must fail")
 }
+
+func TestCreateTLSCredentials(t *testing.T) {
+	factory := factories.GetReceiverFactory(typeStr)
+	cfg := factory.CreateDefaultConfig()
+	rCfg := cfg.(*ConfigV2)
+
+	rCfg.Protocols[protoGRPC].Enabled = true
+	rCfg.TLSCredentials = &config.TLSCredentials{
+		CertFile: path.Join(".", "testdata", "server.crt"),
+		KeyFile:  path.Join(".", "testdata", "server.key"),
+	}
+	tReceiver, err := factory.CreateTraceReceiver(context.Background(), cfg, nil)
+	assert.NoError(t, err, "receiver creation with TLS credentials failed")
+	assert.Len(t, tReceiver.(*jReceiver).config.CollectorGRPCOptions, 1)
+}
+
+func TestCreateInvalidTLSCredentials(t *testing.T) {
+	factory := factories.GetReceiverFactory(typeStr)
+	cfg := factory.CreateDefaultConfig()
+	rCfg := cfg.(*ConfigV2)
+
+	rCfg.Protocols[protoGRPC].Enabled = true
+	rCfg.TLSCredentials = &config.TLSCredentials{
+		CertFile: path.Join(".", "testdata", "missing.crt"),
+		KeyFile:  path.Join(".", "testdata", "missing.key"),
+	}
+	_, err := factory.CreateTraceReceiver(context.Background(), cfg, nil)
+	assert.Error(t, err, "receiver creation with missing TLS credentials must fail")
+}
@@ -19,15 +19,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"mime"
 	"net"
 	"net/http"
 	"sync"
 
+	apachethrift "github.com/apache/thrift/lib/go/thrift"
 	"github.com/gorilla/mux"
 	agentapp "github.com/jaegertracing/jaeger/cmd/agent/app"
 	"github.com/jaegertracing/jaeger/cmd/agent/app/configmanager"
 	"github.com/jaegertracing/jaeger/cmd/agent/app/reporter"
-	"github.com/jaegertracing/jaeger/cmd/collector/app"
+	"github.com/jaegertracing/jaeger/proto-gen/api_v2"
 	"github.com/jaegertracing/jaeger/thrift-gen/baggage"
 	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
 	"github.com/jaegertracing/jaeger/thrift-gen/sampling"
@@ -36,9 +39,12 @@ import (
 	tchannel "github.com/uber/tchannel-go"
 	"github.com/uber/tchannel-go/thrift"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 
+	"github.com/census-instrumentation/opencensus-service/client"
 	"github.com/census-instrumentation/opencensus-service/consumer"
 	"github.com/census-instrumentation/opencensus-service/observability"
+	"github.com/census-instrumentation/opencensus-service/propagation"
 	"github.com/census-instrumentation/opencensus-service/receiver"
 	jaegertranslator "github.com/census-instrumentation/opencensus-service/translator/trace/jaeger"
 )
@@ -48,10 +54,21 @@ import (
 type Configuration struct {
 	CollectorThriftPort int `mapstructure:"tchannel_port"`
 	CollectorHTTPPort   int `mapstructure:"collector_http_port"`
+	// CollectorGRPCPort is the port of the gRPC collector, which is only
+	// started if it is set.
+	CollectorGRPCPort int `mapstructure:"collector_grpc_port"`
+
+	// CollectorGRPCOptions are the options of the gRPC server of the
+	// collector, e.g. grpc.Creds to enable TLS.
+	CollectorGRPCOptions []grpc.ServerOption `mapstructure:"-"`
 
 	AgentPort              int `mapstructure:"agent_port"`
 	AgentCompactThriftPort int `mapstructure:"agent_compact_thrift_port"`
 	AgentBinaryThriftPort  int `mapstructure:"agent_binary_thrift_port"`
+
+	// Metadata selects the headers of the collector HTTP requests that are
+	// passed to the next consumer in the context.
+	Metadata *propagation.Extractor `mapstructure:"metadata"`
 }
 
 // Receiver type is used to receive spans that were originally intended to be sent to Jaeger.
@@ -72,6 +89,7 @@ type jReceiver struct {
 
 	tchannel        *tchannel.Channel
 	collectorServer *http.Server
+	grpc            *grpc.Server
 
 	defaultAgentCtx context.Context
 }
@@ -121,6 +139,15 @@ func (jr *jReceiver) collectorAddr() string {
 	return fmt.Sprintf(":%d", port)
 }
 
+// grpcAddr returns the address of the gRPC collector, or an empty string if
+// it is disabled.
+func (jr *jReceiver) grpcAddr() string {
+	if jr.config == nil || jr.config.CollectorGRPCPort <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(":%d", jr.config.CollectorGRPCPort)
+}
+
 const defaultAgentPort = 5778
 
 func (jr *jReceiver) agentAddress() string {
@@ -219,6 +246,10 @@ func (jr *jReceiver) stopTraceReceptionLocked(ctx context.Context) error {
 			jr.tchannel.Close()
 			jr.tchannel = nil
 		}
+		if jr.grpc != nil {
+			jr.grpc.Stop()
+			jr.grpc = nil
+		}
 		if len(errs) == 0 {
 			err = nil
 			return
@@ -260,6 +291,36 @@ func (jr *jReceiver) SubmitBatches(ctx thrift.Context, batches []*jaeger.Batch)
 	return jbsr, nil
 }
 
+var _ api_v2.CollectorServiceServer = (*jReceiver)(nil)
+
+// PostSpans implements the gRPC collector service, which the jaeger-agent and
+// clients use to send spans in model.proto format.
+func (jr *jReceiver) PostSpans(ctx context.Context, r *api_v2.PostSpansRequest) (*api_v2.PostSpansResponse, error) {
+	var extractor *propagation.Extractor
+	if jr.config != nil {
+		extractor = jr.config.Metadata
+	}
+	ctx = extractor.NewContext(ctx, nil)
+	if c, ok := client.FromGRPC(ctx); ok {
+		ctx = client.NewContext(ctx, c)
+	}
+	ctxWithReceiverName := observability.ContextWithReceiverName(ctx, collectorReceiverTagValue)
+
+	td, err := jaegertranslator.ProtoBatchToOCProto(r.Batch)
+	if err != nil {
+		observability.RecordTraceReceiverMetrics(ctxWithReceiverName, len(r.Batch.Spans), len(r.Batch.Spans))
+		return nil, err
+	}
+	td.SourceFormat = "jaeger"
+
+	err = jr.nextConsumer.ConsumeTraceData(ctx, td)
+	observability.RecordTraceReceiverMetrics(ctxWithReceiverName, len(r.Batch.Spans), len(r.Batch.Spans)-len(td.Spans))
+	if err != nil {
+		return nil, err
+	}
+	return &api_v2.PostSpansResponse{}, nil
+}
+
 var _ reporter.Reporter = (*jReceiver)(nil)
 var _ agentapp.CollectorProxy = (*jReceiver)(nil)
 
@@ -369,12 +430,83 @@ func (jr *jReceiver) startCollector() error {
 	}
 
 	nr := mux.NewRouter()
-	apiHandler := app.NewAPIHandler(jr)
-	apiHandler.RegisterRoutes(nr)
+	// The Jaeger API handler doesn't pass the request context to
+	// SubmitBatches, so serve the route here to keep its metadata and client.
+	nr.HandleFunc("/api/traces", jr.saveSpan).Methods(http.MethodPost)
 	jr.collectorServer = &http.Server{Handler: nr}
 	go func() {
 		_ = jr.collectorServer.Serve(cln)
 	}()
 
+	// And the collector that runs over gRPC, if enabled
+	gaddr := jr.grpcAddr()
+	if gaddr == "" {
+		return nil
+	}
+	gln, gerr := net.Listen("tcp", gaddr)
+	if gerr != nil {
+		// Abort and close the other collectors
+		jr.collectorServer.Close()
+		tch.Close()
+		return fmt.Errorf("Failed to bind to gRPC address %q: %v", gaddr, gerr)
+	}
+
+	var grpcOptions []grpc.ServerOption
+	if jr.config != nil {
+		grpcOptions = jr.config.CollectorGRPCOptions
+	}
+	jr.grpc = observability.GRPCServerWithObservabilityEnabled(grpcOptions...)
+	api_v2.RegisterCollectorServiceServer(jr.grpc, jr)
+	go func() {
+		_ = jr.grpc.Serve(gln)
+	}()
+
 	return nil
 }
+
+var acceptedThriftFormats = map[string]struct{}{
+	"application/x-thrift":                 {},
+	"application/vnd.apache.thrift.binary": {},
+}
+
+// saveSpan mirrors the Jaeger collector API handler, except that the batch is
+// submitted with the context of the request, the selected metadata and the
+// client.
+func (jr *jReceiver) saveSpan(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unable to process request body: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Cannot parse content type: %v", err), http.StatusBadRequest)
+		return
+	}
+	if _, ok := acceptedThriftFormats[contentType]; !ok {
+		http.Error(w, fmt.Sprintf("Unsupported content type: %v", contentType), http.StatusBadRequest)
+		return
+	}
+
+	batch := &jaeger.Batch{}
+	if err := apachethrift.NewTDeserializer().Read(batch, body); err != nil {
+		http.Error(w, fmt.Sprintf("Unable to process request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var extractor *propagation.Extractor
+	if jr.config != nil {
+		extractor = jr.config.Metadata
+	}
+	ctx := extractor.NewContext(r.Context(), r.Header)
+	if c, ok := client.FromHTTP(r); ok {
+		ctx = client.NewContext(ctx, c)
+	}
+	if _, err := jr.SubmitBatches(thrift.Wrap(ctx), []*jaeger.Batch{batch}); err != nil {
+		http.Error(w, fmt.Sprintf("Cannot submit Jaeger batch: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -21,6 +21,8 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/jaegertracing/jaeger/proto-gen/api_v2"
+	"google.golang.org/grpc"
 
 	"contrib.go.opencensus.io/exporter/jaeger"
 	"go.opencensus.io/trace"
@@ -30,6 +32,7 @@ import (
 	"github.com/census-instrumentation/opencensus-service/data"
 	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
 	"github.com/census-instrumentation/opencensus-service/internal"
+	jaegertranslator "github.com/census-instrumentation/opencensus-service/translator/trace/jaeger"
 )
 
 func TestReception(t *testing.T) {
@@ -222,3 +225,108 @@ func TestReception(t *testing.T) {
 		t.Errorf("Mismatched responses\n-Got +Want:\n\t%s", diff)
 	}
 }
+
+func TestGRPCReception(t *testing.T) {
+	config := &Configuration{
+		CollectorThriftPort: 14267,
+		CollectorHTTPPort:   14268,
+		CollectorGRPCPort:   14250,
+	}
+	sink := new(exportertest.SinkTraceExporter)
+	jr, err := New(context.Background(), config, sink)
+	if err != nil {
+		t.Fatalf("Failed to create new Jaeger Receiver: %v", err)
+	}
+	defer jr.StopTraceReception(context.Background())
+
+	if err := jr.StartTraceReception(context.Background(), nil); err != nil {
+		t.Fatalf("StartTraceReception failed: %v", err)
+	}
+
+	conn, err := grpc.Dial(fmt.Sprintf("localhost:%d", config.CollectorGRPCPort), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to dial the gRPC collector: %v", err)
+	}
+	defer conn.Close()
+
+	now := time.Unix(1542158650, 536343000).UTC()
+	td := data.TraceData{
+		Node: &commonpb.Node{
+			ServiceInfo: &commonpb.ServiceInfo{Name: "issaTest"},
+			Attributes:  map[string]string{"string": "yes"},
+		},
+		Spans: []*tracepb.Span{
+			{
+				TraceId:      []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x80},
+				SpanId:       []byte{0xAF, 0xAE, 0xAD, 0xAC, 0xAB, 0xAA, 0xA9, 0xA8},
+				ParentSpanId: []byte{0x1F, 0x1E, 0x1D, 0x1C, 0x1B, 0x1A, 0x19, 0x18},
+				Name:         &tracepb.TruncatableString{Value: "DBSearch"},
+				Kind:         tracepb.Span_SERVER,
+				StartTime:    internal.TimeToTimestamp(now),
+				EndTime:      internal.TimeToTimestamp(now.Add(10 * time.Minute)),
+			},
+		},
+	}
+	batch, err := jaegertranslator.OCProtoToJaegerProto(td)
+	if err != nil {
+		t.Fatalf("Failed to translate the batch to Jaeger Proto: %v", err)
+	}
+
+	collector := api_v2.NewCollectorServiceClient(conn)
+	if _, err := collector.PostSpans(context.Background(), &api_v2.PostSpansRequest{Batch: *batch}); err != nil {
+		t.Fatalf("PostSpans failed: %v", err)
+	}
+
+	want := []data.TraceData{
+		{
+			Node: &commonpb.Node{
+				ServiceInfo: &commonpb.ServiceInfo{Name: "issaTest"},
+				LibraryInfo: &commonpb.LibraryInfo{},
+				Identifier:  &commonpb.ProcessIdentifier{},
+				Attributes:  map[string]string{"string": "yes"},
+			},
+			Spans: []*tracepb.Span{
+				{
+					TraceId:      td.Spans[0].TraceId,
+					SpanId:       td.Spans[0].SpanId,
+					ParentSpanId: td.Spans[0].ParentSpanId,
+					Name:         td.Spans[0].Name,
+					Kind:         tracepb.Span_SERVER,
+					StartTime:    td.Spans[0].StartTime,
+					EndTime:      td.Spans[0].EndTime,
+					Attributes: &tracepb.Span_Attributes{
+						AttributeMap: map[string]*tracepb.AttributeValue{
+							"span.kind": {
+								Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: "server"}},
+							},
+						},
+					},
+				},
+			},
+			SourceFormat: "jaeger",
+		},
+	}
+
+	if diff := cmp.Diff(sink.AllTraces(), want); diff != "" {
+		t.Errorf("Mismatched responses\n-Got +Want:\n\t%s", diff)
+	}
+}
+
+func TestGRPCDisabledByDefault(t *testing.T) {
+	config := &Configuration{
+		CollectorThriftPort: 14267,
+		CollectorHTTPPort:   14268,
+	}
+	jr, err := New(context.Background(), config, new(exportertest.SinkTraceExporter))
+	if err != nil {
+		t.Fatalf("Failed to create new Jaeger Receiver: %v", err)
+	}
+	defer jr.StopTraceReception(context.Background())
+
+	if err := jr.StartTraceReception(context.Background(), nil); err != nil {
+		t.Fatalf("StartTraceReception failed: %v", err)
+	}
+	if jr.(*jReceiver).grpc != nil {
+		t.Error("The gRPC collector was started without a port")
+	}
+}
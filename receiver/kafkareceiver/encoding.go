@@ -0,0 +1,125 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkareceiver
+
+import (
+	"bytes"
+	"encoding/json"
+
+	agenttracepb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/trace/v1"
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	jaegermodel "github.com/jaegertracing/jaeger/model"
+	zipkinmodel "github.com/openzipkin/zipkin-go/model"
+	zipkinproto "github.com/openzipkin/zipkin-go/proto/v2"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/receiver/zipkinreceiver"
+	jaegertranslator "github.com/census-instrumentation/opencensus-service/translator/trace/jaeger"
+)
+
+// Encodings of the Kafka messages.
+const (
+	// EncodingJaegerProto is a Jaeger model.Span in protobuf, as written by
+	// the Kafka exporter and the Jaeger collector.
+	EncodingJaegerProto = "jaeger_proto"
+	// EncodingJaegerJSON is a Jaeger model.Span in the JSON mapping of protobuf.
+	EncodingJaegerJSON = "jaeger_json"
+	// EncodingZipkinJSON is a list of Zipkin v2 spans in JSON.
+	EncodingZipkinJSON = "zipkin_json"
+	// EncodingZipkinProto is a list of Zipkin v2 spans in protobuf.
+	EncodingZipkinProto = "zipkin_proto"
+	// EncodingOCProto is an OpenCensus ExportTraceServiceRequest in protobuf.
+	EncodingOCProto = "oc_proto"
+)
+
+// decodeFunc decodes the value of a Kafka message.
+type decodeFunc func(value []byte) ([]data.TraceData, error)
+
+var decoders = map[string]decodeFunc{
+	EncodingJaegerProto: decodeJaegerProto,
+	EncodingJaegerJSON:  decodeJaegerJSON,
+	EncodingZipkinJSON:  decodeZipkinJSON,
+	EncodingZipkinProto: decodeZipkinProto,
+	EncodingOCProto:     decodeOCProto,
+}
+
+func decodeJaegerProto(value []byte) ([]data.TraceData, error) {
+	span := &jaegermodel.Span{}
+	if err := proto.Unmarshal(value, span); err != nil {
+		return nil, err
+	}
+	return jaegerSpanToTraceData(span)
+}
+
+func decodeJaegerJSON(value []byte) ([]data.TraceData, error) {
+	span := &jaegermodel.Span{}
+	if err := jsonpb.Unmarshal(bytes.NewReader(value), span); err != nil {
+		return nil, err
+	}
+	return jaegerSpanToTraceData(span)
+}
+
+// jaegerSpanToTraceData converts a Jaeger span, which carries its own process
+// when written to Kafka, to OC proto.
+func jaegerSpanToTraceData(span *jaegermodel.Span) ([]data.TraceData, error) {
+	td, err := jaegertranslator.ProtoBatchToOCProto(jaegermodel.Batch{
+		Spans:   []*jaegermodel.Span{span},
+		Process: span.Process,
+	})
+	if err != nil {
+		return nil, err
+	}
+	td.SourceFormat = "jaeger"
+	return []data.TraceData{td}, nil
+}
+
+func decodeZipkinJSON(value []byte) ([]data.TraceData, error) {
+	var zspans []*zipkinmodel.SpanModel
+	if err := json.Unmarshal(value, &zspans); err != nil {
+		return nil, err
+	}
+	return zipkinSpansToTraceData(zspans), nil
+}
+
+func decodeZipkinProto(value []byte) ([]data.TraceData, error) {
+	zspans, err := zipkinproto.ParseSpans(value, false)
+	if err != nil {
+		return nil, err
+	}
+	return zipkinSpansToTraceData(zspans), nil
+}
+
+func zipkinSpansToTraceData(zspans []*zipkinmodel.SpanModel) []data.TraceData {
+	tds := zipkinreceiver.V2SpansToTraceData(zspans)
+	for i := range tds {
+		tds[i].SourceFormat = "zipkin"
+	}
+	return tds
+}
+
+func decodeOCProto(value []byte) ([]data.TraceData, error) {
+	req := &agenttracepb.ExportTraceServiceRequest{}
+	if err := proto.Unmarshal(value, req); err != nil {
+		return nil, err
+	}
+	td := data.TraceData{
+		Node:         req.Node,
+		Resource:     req.Resource,
+		Spans:        req.Spans,
+		SourceFormat: "oc_trace",
+	}
+	return []data.TraceData{td}, nil
+}
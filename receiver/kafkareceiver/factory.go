@@ -0,0 +1,86 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkareceiver
+
+// This file implements factory for Kafka receiver.
+
+import (
+	"context"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+	"github.com/census-instrumentation/opencensus-service/internal/factories"
+	"github.com/census-instrumentation/opencensus-service/receiver"
+)
+
+var _ = factories.RegisterReceiverFactory(&receiverFactory{})
+
+const (
+	// The value of "type" key in configuration.
+	typeStr = "kafka"
+
+	// Defaults of the configuration.
+	defaultBroker = "localhost:9092"
+	defaultTopic  = "jaeger-spans"
+)
+
+// receiverFactory is the factory for Kafka receiver.
+type receiverFactory struct {
+}
+
+// Type gets the type of the Receiver config created by this factory.
+func (f *receiverFactory) Type() string {
+	return typeStr
+}
+
+// CustomUnmarshaler returns nil because we don't need custom unmarshaling for this config.
+func (f *receiverFactory) CustomUnmarshaler() factories.CustomUnmarshaler {
+	return nil
+}
+
+// CreateDefaultConfig creates the default configuration for Kafka receiver.
+func (f *receiverFactory) CreateDefaultConfig() configmodels.Receiver {
+	return &ConfigV2{
+		ReceiverSettings: configmodels.ReceiverSettings{
+			Enabled: false,
+		},
+		Configuration: Configuration{
+			Brokers:       []string{defaultBroker},
+			Topics:        []string{defaultTopic},
+			GroupID:       defaultGroupID,
+			ClientID:      defaultClientID,
+			Encoding:      EncodingJaegerProto,
+			InitialOffset: offsetLatest,
+		},
+	}
+}
+
+// CreateTraceReceiver creates a trace receiver based on provided config.
+func (f *receiverFactory) CreateTraceReceiver(
+	ctx context.Context,
+	cfg configmodels.Receiver,
+	nextConsumer consumer.TraceConsumer,
+) (receiver.TraceReceiver, error) {
+	rCfg := cfg.(*ConfigV2)
+	return New(&rCfg.Configuration, nextConsumer)
+}
+
+// CreateMetricsReceiver creates a metrics receiver based on provided config.
+func (f *receiverFactory) CreateMetricsReceiver(
+	cfg configmodels.Receiver,
+	consumer consumer.MetricsConsumer,
+) (receiver.MetricsReceiver, error) {
+	return nil, factories.ErrDataTypeIsNotSupported
+}
@@ -0,0 +1,54 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkareceiver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+	"github.com/census-instrumentation/opencensus-service/internal/factories"
+)
+
+func TestCreateDefaultConfig(t *testing.T) {
+	factory := factories.GetReceiverFactory(typeStr)
+	cfg := factory.CreateDefaultConfig()
+	assert.NotNil(t, cfg, "failed to create default config")
+}
+
+func TestCreateReceiver(t *testing.T) {
+	factory := factories.GetReceiverFactory(typeStr)
+	cfg := factory.CreateDefaultConfig()
+
+	tReceiver, err := factory.CreateTraceReceiver(context.Background(), cfg, &exportertest.SinkTraceExporter{})
+	assert.NoError(t, err, "receiver creation failed")
+	assert.NotNil(t, tReceiver, "receiver creation failed")
+
+	mReceiver, err := factory.CreateMetricsReceiver(cfg, nil)
+	assert.Equal(t, err, factories.ErrDataTypeIsNotSupported)
+	assert.Nil(t, mReceiver)
+}
+
+func TestCreateInvalidEncoding(t *testing.T) {
+	factory := factories.GetReceiverFactory(typeStr)
+	cfg := factory.CreateDefaultConfig()
+	rCfg := cfg.(*ConfigV2)
+
+	rCfg.Encoding = "thrift"
+	_, err := factory.CreateTraceReceiver(context.Background(), cfg, &exportertest.SinkTraceExporter{})
+	assert.Error(t, err, "receiver creation with unknown encoding must fail")
+}
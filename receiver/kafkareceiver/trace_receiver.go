@@ -0,0 +1,334 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kafkareceiver consumes spans that were buffered in Kafka, e.g. by
+// the Kafka exporter of another tier of collectors, and passes them to the
+// next consumer.
+package kafkareceiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"go.uber.org/zap"
+
+	"github.com/census-instrumentation/opencensus-service/consumer"
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/observability"
+	"github.com/census-instrumentation/opencensus-service/receiver"
+)
+
+// Configuration defines the brokers and topics the Kafka receiver consumes
+// spans from.
+type Configuration struct {
+	// Brokers is the addresses of the Kafka brokers used to bootstrap.
+	Brokers []string `mapstructure:"brokers"`
+	// Topics is the topics to consume spans from.
+	Topics []string `mapstructure:"topics"`
+	// GroupID is the consumer group the receiver joins, the partitions of
+	// the topics are balanced between the members of the group.
+	GroupID string `mapstructure:"group_id"`
+	// ClientID is the client ID sent to the brokers.
+	ClientID string `mapstructure:"client_id"`
+	// Encoding is the encoding of the messages, one of jaeger_proto (the
+	// encoding of the Kafka exporter, and the default), jaeger_json,
+	// zipkin_json, zipkin_proto and oc_proto.
+	Encoding string `mapstructure:"encoding"`
+	// InitialOffset is where the group starts to consume partitions that
+	// have no committed offset, either latest (the default) or earliest.
+	InitialOffset string `mapstructure:"initial_offset"`
+}
+
+const (
+	defaultGroupID  = "opencensus-service"
+	defaultClientID = "opencensus-service"
+
+	// Initial offsets.
+	offsetLatest   = "latest"
+	offsetEarliest = "earliest"
+
+	// initialRetryDelay is how long a claim waits before passing again a
+	// message the next consumer failed to consume. The delay doubles after
+	// each failure, up to maxRetryDelay.
+	initialRetryDelay = 100 * time.Millisecond
+	maxRetryDelay     = 10 * time.Second
+
+	traceSource      string = "Kafka"
+	receiverTagValue        = "kafka"
+)
+
+var (
+	errNilNextConsumer = errors.New("nil nextConsumer")
+	errNoBrokers       = errors.New("no Kafka brokers configured")
+	errNoTopics        = errors.New("no Kafka topics configured")
+	errAlreadyStarted  = errors.New("already started")
+	errAlreadyStopped  = errors.New("already stopped")
+)
+
+// KafkaReceiver joins a Kafka consumer group and passes the spans of the
+// messages of its claims to the next consumer. The offset of a message is
+// only committed once the next consumer accepted its spans, which gives
+// at-least-once delivery.
+type KafkaReceiver struct {
+	// mu protects the fields of this struct
+	mu sync.Mutex
+
+	config        Configuration
+	decode        decodeFunc
+	initialOffset int64
+	nextConsumer  consumer.TraceConsumer
+	logger        *zap.Logger
+
+	// newConsumerGroup defaults to sarama.NewConsumerGroup; it can be replaced for tests.
+	newConsumerGroup func(brokers []string, groupID string, config *sarama.Config) (sarama.ConsumerGroup, error)
+
+	group  sarama.ConsumerGroup
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+var _ receiver.TraceReceiver = (*KafkaReceiver)(nil)
+
+// Option configures optional settings of the KafkaReceiver.
+type Option func(*KafkaReceiver)
+
+// WithLogger sets the logger used to report the messages that are dropped
+// because they cannot be decoded.
+func WithLogger(logger *zap.Logger) Option {
+	return func(kr *KafkaReceiver) {
+		kr.logger = logger
+	}
+}
+
+// New creates a new kafkareceiver.KafkaReceiver reference. It does not
+// connect to the brokers until StartTraceReception is called.
+func New(config *Configuration, nextConsumer consumer.TraceConsumer, opts ...Option) (*KafkaReceiver, error) {
+	if nextConsumer == nil {
+		return nil, errNilNextConsumer
+	}
+	if config == nil || len(config.Brokers) == 0 {
+		return nil, errNoBrokers
+	}
+	if len(config.Topics) == 0 {
+		return nil, errNoTopics
+	}
+
+	kr := &KafkaReceiver{
+		config:           *config,
+		nextConsumer:     nextConsumer,
+		logger:           zap.NewNop(),
+		newConsumerGroup: sarama.NewConsumerGroup,
+	}
+	if kr.config.GroupID == "" {
+		kr.config.GroupID = defaultGroupID
+	}
+	if kr.config.ClientID == "" {
+		kr.config.ClientID = defaultClientID
+	}
+	if kr.config.Encoding == "" {
+		kr.config.Encoding = EncodingJaegerProto
+	}
+
+	decode, ok := decoders[kr.config.Encoding]
+	if !ok {
+		return nil, fmt.Errorf("unknown Kafka message encoding %q", kr.config.Encoding)
+	}
+	kr.decode = decode
+
+	switch kr.config.InitialOffset {
+	case "", offsetLatest:
+		kr.initialOffset = sarama.OffsetNewest
+	case offsetEarliest:
+		kr.initialOffset = sarama.OffsetOldest
+	default:
+		return nil, fmt.Errorf("initial offset must be %q or %q, got %q", offsetLatest, offsetEarliest, kr.config.InitialOffset)
+	}
+
+	for _, opt := range opts {
+		opt(kr)
+	}
+	return kr, nil
+}
+
+// TraceSource returns the name of the trace data source.
+func (kr *KafkaReceiver) TraceSource() string {
+	return traceSource
+}
+
+// StartTraceReception joins the consumer group and starts consuming its
+// claims in the background.
+func (kr *KafkaReceiver) StartTraceReception(ctx context.Context, asyncErrorChan chan<- error) error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	var err = errAlreadyStarted
+	kr.startOnce.Do(func() {
+		config := sarama.NewConfig()
+		config.ClientID = kr.config.ClientID
+		// Consumer groups need at least version 0.10.2 of the protocol.
+		config.Version = sarama.V0_10_2_0
+		config.Consumer.Offsets.Initial = kr.initialOffset
+
+		group, gerr := kr.newConsumerGroup(kr.config.Brokers, kr.config.GroupID, config)
+		if gerr != nil {
+			err = fmt.Errorf("failed to create the Kafka consumer group: %v", gerr)
+			return
+		}
+		kr.group = group
+
+		cctx, cancel := context.WithCancel(context.Background())
+		kr.cancel = cancel
+		kr.wg.Add(1)
+		go kr.consumeLoop(cctx, asyncErrorChan)
+		err = nil
+	})
+	return err
+}
+
+func (kr *KafkaReceiver) consumeLoop(ctx context.Context, asyncErrorChan chan<- error) {
+	defer kr.wg.Done()
+
+	handler := &groupHandler{kr: kr}
+	for {
+		// Consume returns at the end of each session, e.g. on a rebalance,
+		// and must be called again to rejoin the group.
+		if err := kr.group.Consume(ctx, kr.config.Topics, handler); err != nil {
+			if err != sarama.ErrClosedConsumerGroup {
+				select {
+				case asyncErrorChan <- err:
+				case <-ctx.Done():
+				}
+			}
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// StopTraceReception leaves the consumer group, committing the offsets of
+// the messages consumed so far, and waits for the claims to stop.
+func (kr *KafkaReceiver) StopTraceReception(ctx context.Context) error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	var err = errAlreadyStopped
+	kr.stopOnce.Do(func() {
+		err = nil
+		if kr.group == nil {
+			return
+		}
+		kr.cancel()
+		err = kr.group.Close()
+		kr.wg.Wait()
+	})
+	return err
+}
+
+// consumeMessage passes the spans of a message to the next consumer until it
+// accepts them, backing off between attempts. A message can decode to several
+// batches, e.g. one per Zipkin local endpoint: only the batch that failed and
+// the following ones are passed again. It only gives up, with the last error
+// of the next consumer, once ctx is done. A message that cannot be decoded
+// never will be, so it is dropped rather than retried.
+func (kr *KafkaReceiver) consumeMessage(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	tds, err := kr.decode(msg.Value)
+	if err != nil {
+		kr.logger.Warn("Dropping Kafka message that cannot be decoded",
+			zap.String("topic", msg.Topic),
+			zap.Int32("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.String("encoding", kr.config.Encoding),
+			zap.Error(err))
+		return nil
+	}
+
+	ctxWithReceiverName := observability.ContextWithReceiverName(ctx, receiverTagValue)
+	delay := initialRetryDelay
+	for i := 0; i < len(tds); {
+		err := kr.nextConsumer.ConsumeTraceData(ctx, tds[i])
+		if err == nil {
+			observability.RecordTraceReceiverMetrics(ctxWithReceiverName, len(tds[i].Spans), 0)
+			i++
+			continue
+		}
+		kr.logger.Warn("Next consumer failed to consume Kafka message, retrying",
+			zap.String("topic", msg.Topic),
+			zap.Int32("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.Duration("retry-delay", delay),
+			zap.Error(err))
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			// The message is consumed again by the group, including the
+			// batches the next consumer already accepted.
+			numSpans := countSpans(tds[i:])
+			observability.RecordTraceReceiverMetrics(ctxWithReceiverName, numSpans, numSpans)
+			return err
+		}
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+	return nil
+}
+
+func countSpans(tds []data.TraceData) int {
+	n := 0
+	for _, td := range tds {
+		n += len(td.Spans)
+	}
+	return n
+}
+
+// groupHandler consumes the claims of a consumer group session.
+type groupHandler struct {
+	kr *KafkaReceiver
+}
+
+var _ sarama.ConsumerGroupHandler = (*groupHandler)(nil)
+
+func (h *groupHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *groupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim marks each message once its spans were consumed. When the next
+// consumer fails the message is retried in place rather than by ending the
+// session, which would rebalance the whole group. The claim only stops on a
+// failed message once the session ends, without marking it, so that the
+// group resumes the partition from that message.
+func (h *groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		if err := h.kr.consumeMessage(session.Context(), msg); err != nil {
+			return err
+		}
+		session.MarkMessage(msg, "")
+	}
+	return nil
+}
@@ -0,0 +1,469 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafkareceiver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	agenttracepb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/trace/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	zipkinmodel "github.com/openzipkin/zipkin-go/model"
+	zipkinproto "github.com/openzipkin/zipkin-go/proto/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
+	jaegertranslator "github.com/census-instrumentation/opencensus-service/translator/trace/jaeger"
+)
+
+const testTopic = "spans"
+
+var (
+	testTraceID = []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x10}
+	testSpanID  = []byte{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18}
+)
+
+func testTraceData() data.TraceData {
+	return data.TraceData{
+		Node: &commonpb.Node{ServiceInfo: &commonpb.ServiceInfo{Name: "frontend"}},
+		Spans: []*tracepb.Span{
+			{
+				TraceId:   testTraceID,
+				SpanId:    testSpanID,
+				Name:      &tracepb.TruncatableString{Value: "get"},
+				StartTime: &timestamp.Timestamp{Seconds: 1500000000},
+				EndTime:   &timestamp.Timestamp{Seconds: 1500000001},
+			},
+		},
+	}
+}
+
+// testMessages returns the value of a message with the test span in each
+// encoding.
+func testMessages(t *testing.T) map[string][]byte {
+	batch, err := jaegertranslator.OCProtoToJaegerProto(testTraceData())
+	require.NoError(t, err)
+	jspan := batch.Spans[0]
+	// As the Kafka exporter does.
+	jspan.Process = batch.Process
+	jaegerProto, err := proto.Marshal(jspan)
+	require.NoError(t, err)
+	jaegerJSON, err := (&jsonpb.Marshaler{}).MarshalToString(jspan)
+	require.NoError(t, err)
+
+	zipkinJSON, err := json.Marshal([]*zipkinmodel.SpanModel{
+		{
+			SpanContext: zipkinmodel.SpanContext{
+				TraceID: zipkinmodel.TraceID{High: 0x0102030405060708, Low: 0x090A0B0C0D0E0F10},
+				ID:      zipkinmodel.ID(0x1112131415161718),
+			},
+			Name:          "get",
+			Timestamp:     time.Unix(1500000000, 0),
+			Duration:      time.Second,
+			LocalEndpoint: &zipkinmodel.Endpoint{ServiceName: "frontend"},
+		},
+	})
+	require.NoError(t, err)
+	zipkinProto, err := proto.Marshal(&zipkinproto.ListOfSpans{
+		Spans: []*zipkinproto.Span{
+			{
+				TraceId:       testTraceID,
+				Id:            testSpanID,
+				Name:          "get",
+				Timestamp:     1500000000 * 1e6,
+				Duration:      1e6,
+				LocalEndpoint: &zipkinproto.Endpoint{ServiceName: "frontend"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	td := testTraceData()
+	ocProto, err := proto.Marshal(&agenttracepb.ExportTraceServiceRequest{Node: td.Node, Spans: td.Spans})
+	require.NoError(t, err)
+
+	return map[string][]byte{
+		EncodingJaegerProto: jaegerProto,
+		EncodingJaegerJSON:  []byte(jaegerJSON),
+		EncodingZipkinJSON:  zipkinJSON,
+		EncodingZipkinProto: zipkinProto,
+		EncodingOCProto:     ocProto,
+	}
+}
+
+// testSession is a sarama.ConsumerGroupSession recording the marked messages.
+type testSession struct {
+	// ctx is the context of the session, context.Background() if nil.
+	ctx    context.Context
+	mu     sync.Mutex
+	marked []*sarama.ConsumerMessage
+}
+
+var _ sarama.ConsumerGroupSession = (*testSession)(nil)
+
+func (s *testSession) Claims() map[string][]int32 {
+	return map[string][]int32{testTopic: {0}}
+}
+
+func (s *testSession) MemberID() string {
+	return "member"
+}
+
+func (s *testSession) GenerationID() int32 {
+	return 1
+}
+
+func (s *testSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+}
+
+func (s *testSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {
+}
+
+func (s *testSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.marked = append(s.marked, msg)
+}
+
+func (s *testSession) Context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+func (s *testSession) markedMessages() []*sarama.ConsumerMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.marked
+}
+
+// testClaim is a sarama.ConsumerGroupClaim backed by a mock partition consumer.
+type testClaim struct {
+	sarama.PartitionConsumer
+}
+
+var _ sarama.ConsumerGroupClaim = (*testClaim)(nil)
+
+func (c *testClaim) Topic() string {
+	return testTopic
+}
+
+func (c *testClaim) Partition() int32 {
+	return 0
+}
+
+func (c *testClaim) InitialOffset() int64 {
+	return sarama.OffsetOldest
+}
+
+// newTestClaim returns a claim yielding a message for each value, that ends
+// after the last one.
+func newTestClaim(t *testing.T, values ...[]byte) (*testClaim, []*sarama.ConsumerMessage) {
+	consumer := mocks.NewConsumer(t, nil)
+	expectation := consumer.ExpectConsumePartition(testTopic, 0, sarama.OffsetOldest)
+	var msgs []*sarama.ConsumerMessage
+	for _, value := range values {
+		msg := &sarama.ConsumerMessage{Value: value}
+		expectation.YieldMessage(msg)
+		msgs = append(msgs, msg)
+	}
+
+	pc, err := consumer.ConsumePartition(testTopic, 0, sarama.OffsetOldest)
+	require.NoError(t, err)
+	pc.AsyncClose()
+	return &testClaim{PartitionConsumer: pc}, msgs
+}
+
+func TestNew(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	valid := Configuration{Brokers: []string{"localhost:9092"}, Topics: []string{testTopic}}
+
+	tests := []struct {
+		name   string
+		modify func(*Configuration)
+	}{
+		{name: "no_brokers", modify: func(c *Configuration) { c.Brokers = nil }},
+		{name: "no_topics", modify: func(c *Configuration) { c.Topics = nil }},
+		{name: "unknown_encoding", modify: func(c *Configuration) { c.Encoding = "thrift" }},
+		{name: "unknown_initial_offset", modify: func(c *Configuration) { c.InitialOffset = "oldest" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid
+			tt.modify(&config)
+			_, err := New(&config, sink)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := New(&valid, nil)
+	assert.Equal(t, errNilNextConsumer, err)
+
+	kr, err := New(&valid, sink)
+	require.NoError(t, err)
+	assert.Equal(t, defaultGroupID, kr.config.GroupID)
+	assert.Equal(t, EncodingJaegerProto, kr.config.Encoding)
+	assert.Equal(t, sarama.OffsetNewest, kr.initialOffset)
+}
+
+func TestConsumeClaim(t *testing.T) {
+	for encoding, value := range testMessages(t) {
+		t.Run(encoding, func(t *testing.T) {
+			sink := &exportertest.SinkTraceExporter{}
+			kr, err := New(&Configuration{
+				Brokers:  []string{"localhost:9092"},
+				Topics:   []string{testTopic},
+				Encoding: encoding,
+			}, sink)
+			require.NoError(t, err)
+
+			claim, msgs := newTestClaim(t, value, value)
+			session := &testSession{}
+			handler := &groupHandler{kr: kr}
+			require.NoError(t, handler.ConsumeClaim(session, claim))
+
+			assert.Equal(t, msgs, session.markedMessages())
+			got := sink.AllTraces()
+			require.Len(t, got, 2)
+			for _, td := range got {
+				assert.Equal(t, "frontend", td.Node.GetServiceInfo().GetName())
+				require.Len(t, td.Spans, 1)
+				assert.Equal(t, testTraceID, td.Spans[0].TraceId)
+				assert.Equal(t, testSpanID, td.Spans[0].SpanId)
+				assert.Equal(t, "get", td.Spans[0].Name.GetValue())
+			}
+		})
+	}
+}
+
+func TestConsumeClaim_UndecodableMessage(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	kr, err := New(&Configuration{
+		Brokers:  []string{"localhost:9092"},
+		Topics:   []string{testTopic},
+		Encoding: EncodingZipkinJSON,
+	}, sink)
+	require.NoError(t, err)
+
+	claim, msgs := newTestClaim(t, []byte("{not json"), testMessages(t)[EncodingZipkinJSON])
+	session := &testSession{}
+	handler := &groupHandler{kr: kr}
+	require.NoError(t, handler.ConsumeClaim(session, claim))
+
+	// The undecodable message is dropped, and must not block the partition.
+	assert.Equal(t, msgs, session.markedMessages())
+	assert.Len(t, sink.AllTraces(), 1)
+}
+
+// failingTraceConsumer fails failures calls after its first skip ones, and
+// passes the data of the other calls to the sink.
+type failingTraceConsumer struct {
+	skip     int
+	failures int
+	calls    int
+	sink     exportertest.SinkTraceExporter
+}
+
+func (c *failingTraceConsumer) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	c.calls++
+	if c.calls > c.skip && c.calls <= c.skip+c.failures {
+		return errors.New("queue is full")
+	}
+	return c.sink.ConsumeTraceData(ctx, td)
+}
+
+func TestConsumeClaim_ConsumerError(t *testing.T) {
+	next := &failingTraceConsumer{failures: 2}
+	kr, err := New(&Configuration{
+		Brokers: []string{"localhost:9092"},
+		Topics:  []string{testTopic},
+	}, next)
+	require.NoError(t, err)
+
+	value := testMessages(t)[EncodingJaegerProto]
+	claim, msgs := newTestClaim(t, value, value)
+	session := &testSession{}
+	handler := &groupHandler{kr: kr}
+	require.NoError(t, handler.ConsumeClaim(session, claim))
+
+	// The first message is retried in place until the next consumer accepts it.
+	assert.Equal(t, msgs, session.markedMessages())
+	assert.Equal(t, 4, next.calls)
+	assert.Len(t, next.sink.AllTraces(), 2)
+}
+
+func TestConsumeClaim_ConsumerErrorOnLaterBatch(t *testing.T) {
+	next := &failingTraceConsumer{skip: 1, failures: 1}
+	kr, err := New(&Configuration{
+		Brokers:  []string{"localhost:9092"},
+		Topics:   []string{testTopic},
+		Encoding: EncodingZipkinJSON,
+	}, next)
+	require.NoError(t, err)
+
+	// A batch per local endpoint.
+	value, err := json.Marshal([]*zipkinmodel.SpanModel{
+		{
+			SpanContext:   zipkinmodel.SpanContext{TraceID: zipkinmodel.TraceID{Low: 1}, ID: 1},
+			Name:          "get",
+			LocalEndpoint: &zipkinmodel.Endpoint{ServiceName: "frontend"},
+		},
+		{
+			SpanContext:   zipkinmodel.SpanContext{TraceID: zipkinmodel.TraceID{Low: 1}, ID: 2},
+			Name:          "query",
+			LocalEndpoint: &zipkinmodel.Endpoint{ServiceName: "backend"},
+		},
+	})
+	require.NoError(t, err)
+	claim, msgs := newTestClaim(t, value)
+	session := &testSession{}
+	handler := &groupHandler{kr: kr}
+	require.NoError(t, handler.ConsumeClaim(session, claim))
+
+	// Only the failed batch is passed again.
+	assert.Equal(t, msgs, session.markedMessages())
+	assert.Equal(t, 3, next.calls)
+	var services []string
+	for _, td := range next.sink.AllTraces() {
+		services = append(services, td.Node.GetServiceInfo().GetName())
+	}
+	assert.ElementsMatch(t, []string{"frontend", "backend"}, services)
+}
+
+func TestConsumeClaim_ConsumerErrorOnSessionEnd(t *testing.T) {
+	consumeErr := errors.New("queue is full")
+	kr, err := New(&Configuration{
+		Brokers: []string{"localhost:9092"},
+		Topics:  []string{testTopic},
+	}, exportertest.NewNopTraceExporter(exportertest.WithReturnError(consumeErr)))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(3*initialRetryDelay, cancel)
+
+	value := testMessages(t)[EncodingJaegerProto]
+	claim, _ := newTestClaim(t, value, value)
+	session := &testSession{ctx: ctx}
+	handler := &groupHandler{kr: kr}
+	assert.Equal(t, consumeErr, handler.ConsumeClaim(session, claim))
+
+	// Nothing is marked so that the group consumes the message again.
+	assert.Empty(t, session.markedMessages())
+}
+
+// testConsumerGroup is a sarama.ConsumerGroup whose first session consumes a
+// single claim.
+type testConsumerGroup struct {
+	claim    sarama.ConsumerGroupClaim
+	session  *testSession
+	consumed chan struct{}
+	closed   chan struct{}
+	once     sync.Once
+}
+
+var _ sarama.ConsumerGroup = (*testConsumerGroup)(nil)
+
+func (g *testConsumerGroup) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) error {
+	select {
+	case <-g.closed:
+		return sarama.ErrClosedConsumerGroup
+	default:
+	}
+
+	g.once.Do(func() {
+		handler.Setup(g.session)
+		handler.ConsumeClaim(g.session, g.claim)
+		handler.Cleanup(g.session)
+		close(g.consumed)
+	})
+
+	select {
+	case <-ctx.Done():
+	case <-g.closed:
+	}
+	return nil
+}
+
+func (g *testConsumerGroup) Errors() <-chan error {
+	return nil
+}
+
+func (g *testConsumerGroup) Close() error {
+	close(g.closed)
+	return nil
+}
+
+func TestStartStopTraceReception(t *testing.T) {
+	sink := &exportertest.SinkTraceExporter{}
+	kr, err := New(&Configuration{
+		Brokers:       []string{"kafka-0:9092", "kafka-1:9092"},
+		Topics:        []string{testTopic},
+		GroupID:       "tier-2",
+		InitialOffset: offsetEarliest,
+	}, sink)
+	require.NoError(t, err)
+
+	claim, msgs := newTestClaim(t, testMessages(t)[EncodingJaegerProto])
+	group := &testConsumerGroup{
+		claim:    claim,
+		session:  &testSession{},
+		consumed: make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+	kr.newConsumerGroup = func(brokers []string, groupID string, config *sarama.Config) (sarama.ConsumerGroup, error) {
+		assert.Equal(t, []string{"kafka-0:9092", "kafka-1:9092"}, brokers)
+		assert.Equal(t, "tier-2", groupID)
+		assert.Equal(t, sarama.OffsetOldest, config.Consumer.Offsets.Initial)
+		assert.Equal(t, defaultClientID, config.ClientID)
+		return group, nil
+	}
+
+	require.NoError(t, kr.StartTraceReception(context.Background(), make(chan error)))
+	assert.Equal(t, errAlreadyStarted, kr.StartTraceReception(context.Background(), make(chan error)))
+
+	<-group.consumed
+	assert.Equal(t, msgs, group.session.markedMessages())
+	assert.Len(t, sink.AllTraces(), 1)
+
+	require.NoError(t, kr.StopTraceReception(context.Background()))
+	assert.Equal(t, errAlreadyStopped, kr.StopTraceReception(context.Background()))
+}
+
+func TestStartTraceReception_GroupError(t *testing.T) {
+	kr, err := New(&Configuration{
+		Brokers: []string{"localhost:9092"},
+		Topics:  []string{testTopic},
+	}, &exportertest.SinkTraceExporter{})
+	require.NoError(t, err)
+
+	kr.newConsumerGroup = func(brokers []string, groupID string, config *sarama.Config) (sarama.ConsumerGroup, error) {
+		return nil, sarama.ErrOutOfBrokers
+	}
+	assert.Error(t, kr.StartTraceReception(context.Background(), make(chan error)))
+	assert.NoError(t, kr.StopTraceReception(context.Background()))
+}
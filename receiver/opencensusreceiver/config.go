@@ -14,9 +14,15 @@
 
 package opencensusreceiver
 
-import "github.com/census-instrumentation/opencensus-service/internal/configmodels"
+import (
+	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
+	"github.com/census-instrumentation/opencensus-service/tenancy"
+)
 
 // ConfigV2 defines configuration for OpenCensus receiver.
 type ConfigV2 struct {
 	configmodels.ReceiverSettings `mapstructure:",squash"` // squash ensures fields are correctly decoded in embedded struct
+
+	// Tenant configures how the tenant of each stream is identified.
+	Tenant *tenancy.Extractor `mapstructure:"tenant"`
 }
@@ -24,6 +24,7 @@ import (
 	"github.com/census-instrumentation/opencensus-service/internal/configmodels"
 	"github.com/census-instrumentation/opencensus-service/internal/configv2"
 	"github.com/census-instrumentation/opencensus-service/internal/factories"
+	"github.com/census-instrumentation/opencensus-service/tenancy"
 )
 
 var _ = configv2.RegisterTestFactories()
@@ -48,4 +49,5 @@ func TestLoadConfig(t *testing.T) {
 			Endpoint: "0.0.0.0:9090",
 			Enabled:  true,
 		})
+	assert.Equal(t, r1.Tenant, &tenancy.Extractor{GRPCMetadataKey: "x-tenant"})
 }
@@ -22,6 +22,8 @@ import (
 
 	"github.com/census-instrumentation/opencensus-service/consumer"
 	"github.com/census-instrumentation/opencensus-service/receiver"
+	"github.com/census-instrumentation/opencensus-service/receiver/opencensusreceiver/ocmetrics"
+	"github.com/census-instrumentation/opencensus-service/receiver/opencensusreceiver/octrace"
 )
 
 var _ = factories.RegisterReceiverFactory(&receiverFactory{})
@@ -98,8 +100,14 @@ func (f *receiverFactory) createReceiver(cfg configmodels.Receiver) (*Receiver,
 	receiver, ok := receivers[rCfg]
 	if !ok {
 		// We don't have a receiver, so create one.
+		var opts []Option
+		if rCfg.Tenant != nil {
+			opts = append(opts,
+				WithTraceReceiverOptions(octrace.WithTenantExtractor(rCfg.Tenant)),
+				WithMetricsReceiverOptions(ocmetrics.WithTenantExtractor(rCfg.Tenant)))
+		}
 		var err error
-		receiver, err = New(rCfg.Endpoint, nil, nil)
+		receiver, err = New(rCfg.Endpoint, nil, nil, opts...)
 		if err != nil {
 			return nil, err
 		}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sync"
 	"time"
 
 	"google.golang.org/api/support/bundler"
@@ -28,9 +29,12 @@ import (
 	agentmetricspb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/metrics/v1"
 	metricspb "github.com/census-instrumentation/opencensus-proto/gen-go/metrics/v1"
 	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+	"github.com/census-instrumentation/opencensus-service/client"
 	"github.com/census-instrumentation/opencensus-service/consumer"
 	"github.com/census-instrumentation/opencensus-service/data"
 	"github.com/census-instrumentation/opencensus-service/observability"
+	"github.com/census-instrumentation/opencensus-service/propagation"
+	"github.com/census-instrumentation/opencensus-service/tenancy"
 )
 
 // Receiver is the type used to handle metrics from OpenCensus exporters.
@@ -38,6 +42,8 @@ type Receiver struct {
 	nextConsumer       consumer.MetricsConsumer
 	metricBufferPeriod time.Duration
 	metricBufferCount  int
+	tenantExtractor    *tenancy.Extractor
+	metadataExtractor  *propagation.Extractor
 }
 
 // New creates a new ocmetrics.Receiver reference.
@@ -61,11 +67,43 @@ const receiverTagValue = "oc_metrics"
 // Export is the gRPC method that receives streamed metrics from
 // OpenCensus-metricproto compatible libraries/applications.
 func (ocr *Receiver) Export(mes agentmetricspb.MetricsService_ExportServer) error {
+	ctxWithReceiverName := observability.ContextWithReceiverName(mes.Context(), receiverTagValue)
+
+	// Retrieve the first message. It MUST have a non-nil Node.
+	recv, err := mes.Recv()
+	if err != nil {
+		return err
+	}
+
+	// Check the condition that the first message has a non-nil Node.
+	if recv.Node == nil {
+		return errMetricsExportProtocolViolation
+	}
+
+	// The tenant is identified once per stream, by its metadata or first Node.
+	ctxWithReceiverName = ocr.tenantExtractor.NewContext(ctxWithReceiverName, nil, recv.Node)
+	ctxWithReceiverName = ocr.metadataExtractor.NewContext(ctxWithReceiverName, nil)
+
+	// The first retryable error of the next consumer ends the stream, so that
+	// the client sends the metrics again later.
+	var exportErrMu sync.Mutex
+	var exportErr error
+	streamErr := func() error {
+		exportErrMu.Lock()
+		defer exportErrMu.Unlock()
+		return tenancy.GRPCError(exportErr)
+	}
+
 	// The bundler will receive batches of metrics i.e. []*metricspb.Metric
 	// We need to ensure that it propagates the receiver name as a tag
-	ctxWithReceiverName := observability.ContextWithReceiverName(mes.Context(), receiverTagValue)
 	metricsBundler := bundler.NewBundler((*data.MetricsData)(nil), func(payload interface{}) {
-		ocr.batchMetricExporting(ctxWithReceiverName, payload)
+		if err := ocr.batchMetricExporting(ctxWithReceiverName, payload); tenancy.IsRetryable(err) {
+			exportErrMu.Lock()
+			if exportErr == nil {
+				exportErr = err
+			}
+			exportErrMu.Unlock()
+		}
 	})
 
 	metricBufferPeriod := ocr.metricBufferPeriod
@@ -81,17 +119,6 @@ func (ocr *Receiver) Export(mes agentmetricspb.MetricsService_ExportServer) erro
 	metricsBundler.DelayThreshold = metricBufferPeriod
 	metricsBundler.BundleCountThreshold = metricBufferCount
 
-	// Retrieve the first message. It MUST have a non-nil Node.
-	recv, err := mes.Recv()
-	if err != nil {
-		return err
-	}
-
-	// Check the condition that the first message has a non-nil Node.
-	if recv.Node == nil {
-		return errMetricsExportProtocolViolation
-	}
-
 	var lastNonNilNode *commonpb.Node
 	var resource *resourcepb.Resource
 	// Now that we've got the first message with a Node, we can start to receive streamed up metrics.
@@ -108,13 +135,18 @@ func (ocr *Receiver) Export(mes agentmetricspb.MetricsService_ExportServer) erro
 		}
 
 		processReceivedMetrics(lastNonNilNode, resource, recv.Metrics, metricsBundler)
+		if err := streamErr(); err != nil {
+			return err
+		}
 
 		recv, err = mes.Recv()
 		if err != nil {
 			if err == io.EOF {
+				// Report the error of the metrics still buffered, if any.
+				metricsBundler.Flush()
 				// Do not return EOF as an error so that grpc-gateway calls get an empty
 				// response with HTTP status code 200 rather than a 500 error with EOF.
-				return nil
+				return streamErr()
 			}
 			return err
 		}
@@ -129,16 +161,28 @@ func processReceivedMetrics(ni *commonpb.Node, resource *resourcepb.Resource, me
 	}
 }
 
-func (ocr *Receiver) batchMetricExporting(longLivedRPCCtx context.Context, payload interface{}) {
+func (ocr *Receiver) batchMetricExporting(longLivedRPCCtx context.Context, payload interface{}) error {
 	mds := payload.([]*data.MetricsData)
 	if len(mds) == 0 {
-		return
+		return nil
 	}
 
 	// Trace this method
 	ctx, span := trace.StartSpan(context.Background(), "OpenCensusMetricsReceiver.Export")
 	defer span.End()
 
+	// Pass the tenant, metadata and client of the stream, if any, along with
+	// the data.
+	if tenant, ok := tenancy.FromContext(longLivedRPCCtx); ok {
+		ctx = tenancy.NewContext(ctx, tenant)
+	}
+	if md, ok := propagation.FromContext(longLivedRPCCtx); ok {
+		ctx = propagation.NewContext(ctx, md)
+	}
+	if c, ok := client.FromGRPC(longLivedRPCCtx); ok {
+		ctx = client.NewContext(ctx, c)
+	}
+
 	// TODO: (@odeke-em) investigate if it is necessary
 	// to group nodes with their respective metrics during
 	// bundledMetrics list unfurling then send metrics grouped per node
@@ -146,13 +190,17 @@ func (ocr *Receiver) batchMetricExporting(longLivedRPCCtx context.Context, paylo
 	// If the starting RPC has a parent span, then add it as a parent link.
 	observability.SetParentLink(longLivedRPCCtx, span)
 
+	var exportErr error
 	nMetrics := int64(0)
 	for _, md := range mds {
-		ocr.nextConsumer.ConsumeMetricsData(ctx, *md)
+		if err := ocr.nextConsumer.ConsumeMetricsData(ctx, *md); err != nil && exportErr == nil {
+			exportErr = err
+		}
 		nMetrics += int64(len(md.Metrics))
 	}
 
 	span.Annotate([]trace.Attribute{
 		trace.Int64Attribute("num_metrics", nMetrics),
 	}, "")
+	return exportErr
 }
@@ -14,7 +14,12 @@
 
 package ocmetrics
 
-import "time"
+import (
+	"time"
+
+	"github.com/census-instrumentation/opencensus-service/propagation"
+	"github.com/census-instrumentation/opencensus-service/tenancy"
+)
 
 // Option interface defines for configuration settings to be applied to receivers.
 //
@@ -54,3 +59,35 @@ func (mpc metricBufferCount) WithReceiver(oci *Receiver) {
 func WithMetricBufferCount(count int) Option {
 	return metricBufferCount(count)
 }
+
+type tenantExtractor struct {
+	extractor *tenancy.Extractor
+}
+
+var _ Option = (*tenantExtractor)(nil)
+
+func (te *tenantExtractor) WithReceiver(ocr *Receiver) {
+	ocr.tenantExtractor = te.extractor
+}
+
+// WithTenantExtractor is an option that sets how the tenant of each stream
+// is identified. The tenant is passed to the next consumer in the context.
+func WithTenantExtractor(extractor *tenancy.Extractor) Option {
+	return &tenantExtractor{extractor: extractor}
+}
+
+type metadataExtractor struct {
+	extractor *propagation.Extractor
+}
+
+var _ Option = (*metadataExtractor)(nil)
+
+func (me *metadataExtractor) WithReceiver(ocr *Receiver) {
+	ocr.metadataExtractor = me.extractor
+}
+
+// WithMetadataExtractor is an option that sets which gRPC metadata of each
+// stream is passed to the next consumer in the context.
+func WithMetadataExtractor(extractor *propagation.Extractor) Option {
+	return &metadataExtractor{extractor: extractor}
+}
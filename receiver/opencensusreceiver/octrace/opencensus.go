@@ -18,15 +18,19 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sync"
 
 	"go.opencensus.io/trace"
 
 	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
 	agenttracepb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/trace/v1"
 	resourcepb "github.com/census-instrumentation/opencensus-proto/gen-go/resource/v1"
+	"github.com/census-instrumentation/opencensus-service/client"
 	"github.com/census-instrumentation/opencensus-service/consumer"
 	"github.com/census-instrumentation/opencensus-service/data"
 	"github.com/census-instrumentation/opencensus-service/observability"
+	"github.com/census-instrumentation/opencensus-service/propagation"
+	"github.com/census-instrumentation/opencensus-service/tenancy"
 )
 
 const (
@@ -37,15 +41,19 @@ const (
 
 // Receiver is the type used to handle spans from OpenCensus exporters.
 type Receiver struct {
-	nextConsumer consumer.TraceConsumer
-	numWorkers   int
-	workers      []*receiverWorker
-	messageChan  chan *traceDataWithCtx
+	nextConsumer      consumer.TraceConsumer
+	numWorkers        int
+	workers           []*receiverWorker
+	messageChan       chan *traceDataWithCtx
+	tenantExtractor   *tenancy.Extractor
+	metadataExtractor *propagation.Extractor
 }
 
 type traceDataWithCtx struct {
 	data *data.TraceData
 	ctx  context.Context
+	// onError is called with the error of the next consumer, if any.
+	onError func(error)
 }
 
 // New creates a new opencensus.Receiver reference.
@@ -107,6 +115,31 @@ func (ocr *Receiver) Export(tes agenttracepb.TraceService_ExportServer) error {
 		return errTraceExportProtocolViolation
 	}
 
+	// The tenant is identified once per stream, by its metadata or first Node.
+	ctxWithReceiverName = ocr.tenantExtractor.NewContext(ctxWithReceiverName, nil, recv.Node)
+	ctxWithReceiverName = ocr.metadataExtractor.NewContext(ctxWithReceiverName, nil)
+
+	// The first retryable error of the next consumer ends the stream, so that
+	// the client sends the spans again later. Other errors are not reported
+	// back, the stream is not held up waiting for the next consumer.
+	var exportErrMu sync.Mutex
+	var exportErr error
+	onError := func(err error) {
+		if !tenancy.IsRetryable(err) {
+			return
+		}
+		exportErrMu.Lock()
+		if exportErr == nil {
+			exportErr = err
+		}
+		exportErrMu.Unlock()
+	}
+	streamErr := func() error {
+		exportErrMu.Lock()
+		defer exportErrMu.Unlock()
+		return tenancy.GRPCError(exportErr)
+	}
+
 	var lastNonNilNode *commonpb.Node
 	var resource *resourcepb.Resource
 	// Now that we've got the first message with a Node, we can start to receive streamed up spans.
@@ -129,16 +162,19 @@ func (ocr *Receiver) Export(tes agenttracepb.TraceService_ExportServer) error {
 			SourceFormat: "oc_trace",
 		}
 
-		ocr.messageChan <- &traceDataWithCtx{data: td, ctx: ctxWithReceiverName}
+		ocr.messageChan <- &traceDataWithCtx{data: td, ctx: ctxWithReceiverName, onError: onError}
 
 		observability.RecordTraceReceiverMetrics(ctxWithReceiverName, len(td.Spans), 0)
+		if err := streamErr(); err != nil {
+			return err
+		}
 
 		recv, err = tes.Recv()
 		if err != nil {
 			if err == io.EOF {
 				// Do not return EOF as an error so that grpc-gateway calls get an empty
 				// response with HTTP status code 200 rather than a 500 error with EOF.
-				return nil
+				return streamErr()
 			}
 			return err
 		}
@@ -169,7 +205,9 @@ func (rw *receiverWorker) listenOn(cn <-chan *traceDataWithCtx) {
 	for {
 		select {
 		case tdWithCtx := <-cn:
-			rw.export(tdWithCtx.ctx, tdWithCtx.data)
+			if err := rw.export(tdWithCtx.ctx, tdWithCtx.data); err != nil && tdWithCtx.onError != nil {
+				tdWithCtx.onError(err)
+			}
 		case <-rw.cancel:
 			return
 		}
@@ -180,19 +218,31 @@ func (rw *receiverWorker) stopListening() {
 	close(rw.cancel)
 }
 
-func (rw *receiverWorker) export(longLivedCtx context.Context, tracedata *data.TraceData) {
+func (rw *receiverWorker) export(longLivedCtx context.Context, tracedata *data.TraceData) error {
 	if tracedata == nil {
-		return
+		return nil
 	}
 
 	if len(tracedata.Spans) == 0 {
-		return
+		return nil
 	}
 
 	// Trace this method
 	ctx, span := trace.StartSpan(context.Background(), "OpenCensusTraceReceiver.Export")
 	defer span.End()
 
+	// Pass the tenant, metadata and client of the stream, if any, along with
+	// the data.
+	if tenant, ok := tenancy.FromContext(longLivedCtx); ok {
+		ctx = tenancy.NewContext(ctx, tenant)
+	}
+	if md, ok := propagation.FromContext(longLivedCtx); ok {
+		ctx = propagation.NewContext(ctx, md)
+	}
+	if c, ok := client.FromGRPC(longLivedCtx); ok {
+		ctx = client.NewContext(ctx, c)
+	}
+
 	// TODO: (@odeke-em) investigate if it is necessary
 	// to group nodes with their respective spans during
 	// spansAndNode list unfurling then send spans grouped per node
@@ -200,9 +250,10 @@ func (rw *receiverWorker) export(longLivedCtx context.Context, tracedata *data.T
 	// If the starting RPC has a parent span, then add it as a parent link.
 	observability.SetParentLink(longLivedCtx, span)
 
-	rw.receiver.nextConsumer.ConsumeTraceData(ctx, *tracedata)
+	err := rw.receiver.nextConsumer.ConsumeTraceData(ctx, *tracedata)
 
 	span.Annotate([]trace.Attribute{
 		trace.Int64Attribute("num_spans", int64(len(tracedata.Spans))),
 	}, "")
+	return err
 }
@@ -31,6 +31,8 @@ import (
 
 	"github.com/golang/protobuf/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"contrib.go.opencensus.io/exporter/ocagent"
 	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
@@ -40,6 +42,7 @@ import (
 	"github.com/census-instrumentation/opencensus-service/data"
 	"github.com/census-instrumentation/opencensus-service/internal"
 	"github.com/census-instrumentation/opencensus-service/observability"
+	"github.com/census-instrumentation/opencensus-service/tenancy"
 	"go.opencensus.io/trace"
 	"go.opencensus.io/trace/tracestate"
 )
@@ -430,6 +433,65 @@ func TestExportProtocolConformation_spansInFirstMessage(t *testing.T) {
 	}
 }
 
+func TestExport_retryableErrorEndsStream(t *testing.T) {
+	limited := &erroringTraceConsumer{err: &tenancy.LimitExceededError{Tenant: "acme", RetryAfter: time.Second}}
+
+	_, port, doneFn := ocReceiverOnGRPCServer(t, limited)
+	defer doneFn()
+
+	traceClient, traceClientDoneFn, err := makeTraceServiceClient(port)
+	if err != nil {
+		t.Fatalf("Failed to create the gRPC TraceService_ExportClient: %v", err)
+	}
+	defer traceClientDoneFn()
+
+	// The error of the next consumer is only known after the message was
+	// received, so it ends the stream at one of the following messages.
+	ni := &commonpb.Node{Identifier: &commonpb.ProcessIdentifier{Pid: 1}}
+	sLi := []*tracepb.Span{{TraceId: []byte("1234567890abcde")}}
+	for i := 0; i < 100; i++ {
+		if err := traceClient.Send(&agenttracepb.ExportTraceServiceRequest{Node: ni, Spans: sLi}); err != nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The retryable error tells the client to send the spans again later.
+	_, err = traceClient.Recv()
+	if g, w := status.Code(err), codes.ResourceExhausted; g != w {
+		t.Fatalf("Got status code %v (%v) Want %v", g, err, w)
+	}
+}
+
+func TestExport_otherErrorsDoNotEndStream(t *testing.T) {
+	failing := &erroringTraceConsumer{err: errors.New("export failed")}
+
+	_, port, doneFn := ocReceiverOnGRPCServer(t, failing)
+	defer doneFn()
+
+	traceClient, traceClientDoneFn, err := makeTraceServiceClient(port)
+	if err != nil {
+		t.Fatalf("Failed to create the gRPC TraceService_ExportClient: %v", err)
+	}
+	defer traceClientDoneFn()
+
+	ni := &commonpb.Node{Identifier: &commonpb.ProcessIdentifier{Pid: 1}}
+	sLi := []*tracepb.Span{{TraceId: []byte("1234567890abcde")}}
+	for i := 0; i < 10; i++ {
+		if err := traceClient.Send(&agenttracepb.ExportTraceServiceRequest{Node: ni, Spans: sLi}); err != nil {
+			t.Fatalf("Failed to send message %d: %v", i, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := traceClient.CloseSend(); err != nil {
+		t.Fatalf("Failed to close the stream: %v", err)
+	}
+
+	if _, err := traceClient.Recv(); err != io.EOF {
+		t.Fatalf("Got %v Want the stream to end without an error", err)
+	}
+}
+
 // Helper functions from here on below
 func makeTraceServiceClient(port int) (agenttracepb.TraceService_ExportClient, func(), error) {
 	addr := fmt.Sprintf(":%d", port)
@@ -474,6 +536,16 @@ func (sa *spanAppender) ConsumeTraceData(ctx context.Context, td data.TraceData)
 	return nil
 }
 
+type erroringTraceConsumer struct {
+	err error
+}
+
+var _ consumer.TraceConsumer = (*erroringTraceConsumer)(nil)
+
+func (etc *erroringTraceConsumer) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	return etc.err
+}
+
 func ocReceiverOnGRPCServer(t *testing.T, sr consumer.TraceConsumer, opts ...Option) (oci *Receiver, port int, done func()) {
 	ln, err := net.Listen("tcp", ":0")
 	if err != nil {
@@ -14,6 +14,11 @@
 
 package octrace
 
+import (
+	"github.com/census-instrumentation/opencensus-service/propagation"
+	"github.com/census-instrumentation/opencensus-service/tenancy"
+)
+
 // Option interface defines for configuration settings to be applied to receivers.
 //
 // WithReceiver applies the configuration to the given receiver.
@@ -26,3 +31,19 @@ func WithWorkerCount(workerCount int) Option {
 		r.numWorkers = workerCount
 	}
 }
+
+// WithTenantExtractor sets how the tenant of each stream is identified. The
+// tenant is passed to the next consumer in the context.
+func WithTenantExtractor(extractor *tenancy.Extractor) Option {
+	return func(r *Receiver) {
+		r.tenantExtractor = extractor
+	}
+}
+
+// WithMetadataExtractor sets which gRPC metadata of each stream is passed to
+// the next consumer in the context.
+func WithMetadataExtractor(extractor *propagation.Extractor) Option {
+	return func(r *Receiver) {
+		r.metadataExtractor = extractor
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"net"
 	"net/http"
 	"strconv"
@@ -37,11 +38,14 @@ import (
 	zipkinproto "github.com/openzipkin/zipkin-go/proto/v2"
 	"go.opencensus.io/trace"
 
+	"github.com/census-instrumentation/opencensus-service/client"
 	"github.com/census-instrumentation/opencensus-service/consumer"
 	"github.com/census-instrumentation/opencensus-service/data"
 	"github.com/census-instrumentation/opencensus-service/internal"
 	"github.com/census-instrumentation/opencensus-service/observability"
+	"github.com/census-instrumentation/opencensus-service/propagation"
 	"github.com/census-instrumentation/opencensus-service/receiver"
+	"github.com/census-instrumentation/opencensus-service/tenancy"
 	tracetranslator "github.com/census-instrumentation/opencensus-service/translator/trace"
 	zipkintranslator "github.com/census-instrumentation/opencensus-service/translator/trace/zipkin"
 )
@@ -62,6 +66,9 @@ type ZipkinReceiver struct {
 
 	nextConsumer consumer.TraceConsumer
 
+	tenantExtractor   *tenancy.Extractor
+	metadataExtractor *propagation.Extractor
+
 	startOnce sync.Once
 	stopOnce  sync.Once
 	server    *http.Server
@@ -70,8 +77,27 @@ type ZipkinReceiver struct {
 var _ receiver.TraceReceiver = (*ZipkinReceiver)(nil)
 var _ http.Handler = (*ZipkinReceiver)(nil)
 
+// Option configures optional settings of the ZipkinReceiver.
+type Option func(*ZipkinReceiver)
+
+// WithTenantExtractor sets how the tenant of each request is identified. The
+// tenant is passed to the next consumer in the context.
+func WithTenantExtractor(extractor *tenancy.Extractor) Option {
+	return func(zr *ZipkinReceiver) {
+		zr.tenantExtractor = extractor
+	}
+}
+
+// WithMetadataExtractor sets which headers of each request are passed to the
+// next consumer in the context.
+func WithMetadataExtractor(extractor *propagation.Extractor) Option {
+	return func(zr *ZipkinReceiver) {
+		zr.metadataExtractor = extractor
+	}
+}
+
 // New creates a new zipkinreceiver.ZipkinReceiver reference.
-func New(address string, nextConsumer consumer.TraceConsumer) (*ZipkinReceiver, error) {
+func New(address string, nextConsumer consumer.TraceConsumer, opts ...Option) (*ZipkinReceiver, error) {
 	if nextConsumer == nil {
 		return nil, errNilNextConsumer
 	}
@@ -80,6 +106,9 @@ func New(address string, nextConsumer consumer.TraceConsumer) (*ZipkinReceiver,
 		addr:         address,
 		nextConsumer: nextConsumer,
 	}
+	for _, opt := range opts {
+		opt(zr)
+	}
 	return zr, nil
 }
 
@@ -190,6 +219,12 @@ func (zr *ZipkinReceiver) v2ToTraceSpans(blob []byte, hdr http.Header) (reqs []d
 		return nil, err
 	}
 
+	return V2SpansToTraceData(zipkinSpans), nil
+}
+
+// V2SpansToTraceData converts Zipkin v2 spans to OpenCensus Proto spans, grouped
+// by the node derived from their local endpoint.
+func V2SpansToTraceData(zipkinSpans []*zipkinmodel.SpanModel) (reqs []data.TraceData) {
 	// *commonpb.Node instances have unique addresses hence
 	// for grouping within a map, we'll use the .String() value
 	byNodeGrouping := make(map[string][]*tracepb.Span)
@@ -222,7 +257,7 @@ func (zr *ZipkinReceiver) v2ToTraceSpans(blob []byte, hdr http.Header) (reqs []d
 		delete(byNodeGrouping, key)
 	}
 
-	return reqs, nil
+	return reqs
 }
 
 func (zr *ZipkinReceiver) deserializeFromJSON(jsonBlob []byte, debugWasSet bool) (zs []*zipkinmodel.SpanModel, err error) {
@@ -327,10 +362,29 @@ func (zr *ZipkinReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctxWithReceiverName := observability.ContextWithReceiverName(ctx, receiverTagValue)
+	ctxWithReceiverName = zr.metadataExtractor.NewContext(ctxWithReceiverName, r.Header)
+	if c, ok := client.FromHTTP(r); ok {
+		ctxWithReceiverName = client.NewContext(ctxWithReceiverName, c)
+	}
 	tdsSize := 0
-	for _, td := range tds {
+	for i, td := range tds {
 		td.SourceFormat = "zipkin"
-		zr.nextConsumer.ConsumeTraceData(ctxWithReceiverName, td)
+		ctxWithTenant := zr.tenantExtractor.NewContext(ctxWithReceiverName, r.Header, td.Node)
+		if err := zr.nextConsumer.ConsumeTraceData(ctxWithTenant, td); tenancy.IsRetryable(err) {
+			if i == 0 {
+				// The tenant is over its quota and nothing was consumed
+				// yet, ask the client to send the whole request again later.
+				observability.RecordTraceReceiverMetrics(ctxWithTenant, 0, countSpans(tds))
+				retryAfter := err.(*tenancy.LimitExceededError).RetryAfter
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				http.Error(w, err.Error(), http.StatusTooManyRequests)
+				return
+			}
+			// Sending the request again would duplicate the spans already
+			// consumed, so accept it partially and drop these spans.
+			observability.RecordTraceReceiverMetrics(ctxWithTenant, 0, len(td.Spans))
+			continue
+		}
 		tdsSize += len(td.Spans)
 	}
 
@@ -342,6 +396,14 @@ func (zr *ZipkinReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusAccepted)
 }
 
+func countSpans(tds []data.TraceData) int {
+	n := 0
+	for _, td := range tds {
+		n += len(td.Spans)
+	}
+	return n
+}
+
 var (
 	errNilZipkinSpan = errors.New("non-nil Zipkin span expected")
 	errZeroTraceID   = errors.New("trace id is zero")
@@ -39,6 +39,7 @@ import (
 	"github.com/census-instrumentation/opencensus-service/exporter/exportertest"
 	"github.com/census-instrumentation/opencensus-service/internal"
 	"github.com/census-instrumentation/opencensus-service/internal/testutils"
+	"github.com/census-instrumentation/opencensus-service/tenancy"
 	spandatatranslator "github.com/census-instrumentation/opencensus-service/translator/trace/spandata"
 )
 
@@ -520,3 +521,123 @@ func TestConversionRoundtrip(t *testing.T) {
 		t.Errorf("The roundtrip JSON doesn't match the JSON that we want\nGot:\n%s\nWant:\n%s", gj, wj)
 	}
 }
+
+type tenantLimitedSink struct {
+	tenants []string
+}
+
+var _ consumer.TraceConsumer = (*tenantLimitedSink)(nil)
+
+func (s *tenantLimitedSink) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	tenant, _ := tenancy.FromContext(ctx)
+	s.tenants = append(s.tenants, tenant)
+	if tenant == "limited" {
+		return &tenancy.LimitExceededError{Tenant: tenant, RetryAfter: 1500 * time.Millisecond}
+	}
+	return nil
+}
+
+func TestZipkinReceiverTenant(t *testing.T) {
+	body, err := ioutil.ReadFile("./testdata/sample1.json")
+	if err != nil {
+		t.Fatalf("failed to read sample file: %v", err)
+	}
+
+	tests := []struct {
+		tenant         string
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{tenant: "tenant-a", wantStatus: http.StatusAccepted},
+		{tenant: "limited", wantStatus: http.StatusTooManyRequests, wantRetryAfter: "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tenant, func(t *testing.T) {
+			sink := &tenantLimitedSink{}
+			zr, err := New("", sink, WithTenantExtractor(&tenancy.Extractor{HTTPHeader: "X-Tenant"}))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			req := httptest.NewRequest("POST", "/api/v2/spans", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Tenant", tt.tenant)
+			rec := httptest.NewRecorder()
+			zr.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+			if len(sink.tenants) == 0 || sink.tenants[0] != tt.tenant {
+				t.Errorf("consumed tenants = %v, want %q", sink.tenants, tt.tenant)
+			}
+		})
+	}
+}
+
+// limitAfterSink rejects the data once it consumed the given number of
+// batches, like a tenant reaching its limit in the midst of a request.
+type limitAfterSink struct {
+	limit    int
+	consumed []data.TraceData
+	rejected int
+}
+
+var _ consumer.TraceConsumer = (*limitAfterSink)(nil)
+
+func (s *limitAfterSink) ConsumeTraceData(ctx context.Context, td data.TraceData) error {
+	if len(s.consumed) >= s.limit {
+		s.rejected++
+		return &tenancy.LimitExceededError{Tenant: "limited", RetryAfter: time.Second}
+	}
+	s.consumed = append(s.consumed, td)
+	return nil
+}
+
+func TestZipkinReceiverPartiallyLimited(t *testing.T) {
+	// The spans of each local endpoint are consumed in their own batch.
+	body := []byte(`[{
+  "traceId": "4d1e00c0db9010db86154a4ba6e91385",
+  "id": "4d1e00c0db9010db",
+  "kind": "CLIENT",
+  "name": "get",
+  "timestamp": 1472470996199000,
+  "duration": 207000,
+  "localEndpoint": {"serviceName": "frontend"}
+}, {
+  "traceId": "4d1e00c0db9010db86154a4ba6e91385",
+  "parentId": "4d1e00c0db9010db",
+  "id": "86154a4ba6e91385",
+  "kind": "SERVER",
+  "name": "get",
+  "timestamp": 1472470996250000,
+  "duration": 100000,
+  "localEndpoint": {"serviceName": "backend"}
+}]`)
+
+	sink := &limitAfterSink{limit: 1}
+	zr, err := New("", sink)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v2/spans", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	zr.ServeHTTP(rec, req)
+
+	// Part of the request was consumed, asking the client to send it again
+	// would duplicate those spans.
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "" {
+		t.Errorf("Retry-After = %q, want none", got)
+	}
+	if len(sink.consumed) != 1 || sink.rejected == 0 {
+		t.Errorf("consumed %d batches and rejected %d, want 1 and some", len(sink.consumed), sink.rejected)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tenancy identifies the tenant the received data belongs to, when a
+// collector is shared by many teams, and carries it along with the data in the
+// context.Context passed to the consumers.
+package tenancy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/census-instrumentation/opencensus-service/observability"
+)
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the given tenant ID. The context is
+// also tagged with it, see observability.ContextWithTenant.
+func NewContext(ctx context.Context, tenant string) context.Context {
+	ctx = context.WithValue(ctx, contextKey{}, tenant)
+	return observability.ContextWithTenant(ctx, tenant)
+}
+
+// FromContext returns the tenant ID carried by the context, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(contextKey{}).(string)
+	return tenant, ok
+}
+
+// Extractor extracts the tenant ID from the received requests. The sources
+// are tried in the order of the fields, empty ones are skipped.
+type Extractor struct {
+	// GRPCMetadataKey is the key of the gRPC metadata holding the tenant ID.
+	GRPCMetadataKey string `mapstructure:"grpc-metadata-key"`
+	// HTTPHeader is the HTTP header holding the tenant ID.
+	HTTPHeader string `mapstructure:"http-header"`
+	// NodeAttribute is the attribute of the Node holding the tenant ID.
+	NodeAttribute string `mapstructure:"node-attribute"`
+}
+
+// Extract returns the tenant ID found in the incoming gRPC metadata of ctx,
+// the HTTP header or the Node, any of which can be nil. It is safe to call on
+// a nil Extractor, in which case no tenant is found.
+func (e *Extractor) Extract(ctx context.Context, header http.Header, node *commonpb.Node) (string, bool) {
+	if e == nil {
+		return "", false
+	}
+
+	if e.GRPCMetadataKey != "" && ctx != nil {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(e.GRPCMetadataKey); len(values) > 0 && values[0] != "" {
+				return values[0], true
+			}
+		}
+	}
+
+	if e.HTTPHeader != "" && header != nil {
+		if value := header.Get(e.HTTPHeader); value != "" {
+			return value, true
+		}
+	}
+
+	if e.NodeAttribute != "" {
+		if value := node.GetAttributes()[e.NodeAttribute]; value != "" {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// NewContext returns a context carrying the tenant ID extracted from the
+// given sources, or ctx itself if no tenant is found.
+func (e *Extractor) NewContext(ctx context.Context, header http.Header, node *commonpb.Node) context.Context {
+	if tenant, ok := e.Extract(ctx, header, node); ok {
+		return NewContext(ctx, tenant)
+	}
+	return ctx
+}
+
+// LimitExceededError is returned by consumers that reject data because its
+// tenant exceeded its quota. The error is temporary, the same data can be sent
+// again after RetryAfter.
+type LimitExceededError struct {
+	Tenant     string
+	RetryAfter time.Duration
+}
+
+var _ error = (*LimitExceededError)(nil)
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("tenant %q exceeded its rate limit, retry after %v", e.Tenant, e.RetryAfter)
+}
+
+// IsRetryable returns true if err is a *LimitExceededError, i.e. if the
+// rejected data can be sent again later.
+func IsRetryable(err error) bool {
+	_, ok := err.(*LimitExceededError)
+	return ok
+}
+
+// GRPCError returns the error a gRPC receiver reports for the error of its
+// consumer. A *LimitExceededError is reported with the ResourceExhausted code,
+// so that the client sends the same data again later, other errors are
+// reported as they are.
+func GRPCError(err error) error {
+	if IsRetryable(err) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/tag"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/census-instrumentation/opencensus-service/observability"
+)
+
+func TestNewContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := NewContext(context.Background(), "acme")
+	tenant, ok := FromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "acme", tenant)
+
+	value, ok := tag.FromContext(ctx).Value(observability.TagKeyTenant)
+	assert.True(t, ok)
+	assert.Equal(t, "acme", value)
+}
+
+func TestExtractor(t *testing.T) {
+	grpcCtx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant", "from-grpc"))
+	header := http.Header{}
+	header.Set("X-Tenant", "from-http")
+	node := &commonpb.Node{Attributes: map[string]string{"tenant": "from-node"}}
+
+	all := &Extractor{GRPCMetadataKey: "X-Tenant", HTTPHeader: "X-Tenant", NodeAttribute: "tenant"}
+	tests := []struct {
+		name      string
+		extractor *Extractor
+		ctx       context.Context
+		header    http.Header
+		node      *commonpb.Node
+		want      string
+	}{
+		{name: "nil_extractor", ctx: grpcCtx, header: header, node: node},
+		{name: "grpc", extractor: all, ctx: grpcCtx, header: header, node: node, want: "from-grpc"},
+		{name: "http", extractor: all, ctx: context.Background(), header: header, node: node, want: "from-http"},
+		{name: "node", extractor: all, ctx: context.Background(), node: node, want: "from-node"},
+		{name: "none", extractor: all, ctx: context.Background()},
+		{name: "node_only", extractor: &Extractor{NodeAttribute: "tenant"}, ctx: grpcCtx, header: header, node: node, want: "from-node"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant, ok := tt.extractor.Extract(tt.ctx, tt.header, tt.node)
+			assert.Equal(t, tt.want != "", ok)
+			assert.Equal(t, tt.want, tenant)
+
+			tenant, ok = FromContext(tt.extractor.NewContext(tt.ctx, tt.header, tt.node))
+			assert.Equal(t, tt.want != "", ok)
+			assert.Equal(t, tt.want, tenant)
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	err := &LimitExceededError{Tenant: "acme", RetryAfter: time.Second}
+	assert.True(t, IsRetryable(err))
+	assert.Equal(t, `tenant "acme" exceeded its rate limit, retry after 1s`, err.Error())
+	assert.False(t, IsRetryable(errors.New("other")))
+	assert.False(t, IsRetryable(nil))
+}
+
+func TestGRPCError(t *testing.T) {
+	err := GRPCError(&LimitExceededError{Tenant: "acme", RetryAfter: time.Second})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	other := errors.New("other")
+	assert.Equal(t, other, GRPCError(other))
+	assert.Nil(t, GRPCError(nil))
+}
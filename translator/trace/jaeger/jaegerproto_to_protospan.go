@@ -0,0 +1,273 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jaeger
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	jaeger "github.com/jaegertracing/jaeger/model"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+	"github.com/census-instrumentation/opencensus-service/internal"
+	tracetranslator "github.com/census-instrumentation/opencensus-service/translator/trace"
+)
+
+// ProtoBatchToOCProto converts a single Jaeger Proto batch of spans, as received
+// by the GRPC collector service, to a OC proto batch.
+func ProtoBatchToOCProto(batch jaeger.Batch) (data.TraceData, error) {
+	ocbatch := data.TraceData{
+		Node:  jProtoProcessToOCProtoNode(batch.Process),
+		Spans: jProtoSpansToOCProtoSpans(batch.Spans),
+	}
+
+	return ocbatch, nil
+}
+
+// Replica of jaegerthrift_to_protospan.jProcessToOCProtoNode
+func jProtoProcessToOCProtoNode(p *jaeger.Process) *commonpb.Node {
+	if p == nil {
+		return nil
+	}
+
+	node := &commonpb.Node{
+		Identifier:  &commonpb.ProcessIdentifier{},
+		LibraryInfo: &commonpb.LibraryInfo{},
+		ServiceInfo: &commonpb.ServiceInfo{Name: p.ServiceName},
+	}
+
+	attribs := make(map[string]string)
+
+	for _, tag := range p.Tags {
+		// Special treatment for special keys in the tags.
+		switch tag.Key {
+		case "hostname":
+			node.Identifier.HostName = tag.VStr
+			continue
+		case "jaeger.version":
+			node.LibraryInfo.ExporterVersion = "Jaeger-" + tag.VStr
+			continue
+		}
+
+		switch tag.VType {
+		case jaeger.ValueType_STRING:
+			attribs[tag.Key] = tag.VStr
+		case jaeger.ValueType_FLOAT64:
+			attribs[tag.Key] = strconv.FormatFloat(tag.VFloat64, 'f', -1, 64)
+		case jaeger.ValueType_BOOL:
+			attribs[tag.Key] = strconv.FormatBool(tag.VBool)
+		case jaeger.ValueType_INT64:
+			attribs[tag.Key] = strconv.FormatInt(tag.VInt64, 10)
+		case jaeger.ValueType_BINARY:
+			attribs[tag.Key] = base64.StdEncoding.EncodeToString(tag.VBinary)
+		default:
+			attribs[tag.Key] = fmt.Sprintf("<Unknown Jaeger ValueType %q>", tag.VType)
+		}
+	}
+
+	if len(attribs) > 0 {
+		node.Attributes = attribs
+	}
+	return node
+}
+
+func jProtoSpansToOCProtoSpans(jspans []*jaeger.Span) []*tracepb.Span {
+	spans := make([]*tracepb.Span, 0, len(jspans))
+	for _, jspan := range jspans {
+		if jspan == nil || (jspan.TraceID == jaeger.TraceID{} && jspan.SpanID == 0) {
+			continue
+		}
+
+		parentSpanID, references := jProtoReferencesParentSpanID(jspan.TraceID, jspan.References)
+		_, sKind, sStatus, sAttributes := jProtoTagsToAttributes(jspan.Tags)
+		span := &tracepb.Span{
+			TraceId: tracetranslator.UInt64ToByteTraceID(jspan.TraceID.High, jspan.TraceID.Low),
+			SpanId:  tracetranslator.UInt64ToByteSpanID(uint64(jspan.SpanID)),
+			// TODO: Tracestate: Check RFC status and if is applicable,
+			ParentSpanId: parentSpanID,
+			Name:         strToTruncatableString(jspan.OperationName),
+			Kind:         sKind,
+			StartTime:    internal.TimeToTimestamp(jspan.StartTime),
+			EndTime:      internal.TimeToTimestamp(jspan.StartTime.Add(jspan.Duration)),
+			Attributes:   sAttributes,
+			TimeEvents:   jProtoLogsToOCProtoTimeEvents(jspan.Logs),
+			Links:        jProtoReferencesToOCProtoLinks(references),
+			Status:       sStatus,
+		}
+
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// jProtoReferencesParentSpanID returns the ID of the parent span, i.e. the
+// first CHILD_OF reference to a span of the same trace, as Jaeger does, and
+// the other references.
+func jProtoReferencesParentSpanID(traceID jaeger.TraceID, jrefs []jaeger.SpanRef) ([]byte, []jaeger.SpanRef) {
+	for i, jref := range jrefs {
+		if jref.RefType == jaeger.SpanRefType_CHILD_OF && jref.TraceID == traceID && jref.SpanID != 0 {
+			references := make([]jaeger.SpanRef, 0, len(jrefs)-1)
+			references = append(references, jrefs[:i]...)
+			references = append(references, jrefs[i+1:]...)
+			return tracetranslator.UInt64ToByteSpanID(uint64(jref.SpanID)), references
+		}
+	}
+	return nil, jrefs
+}
+
+func jProtoLogsToOCProtoTimeEvents(logs []jaeger.Log) *tracepb.Span_TimeEvents {
+	if logs == nil {
+		return nil
+	}
+
+	timeEvents := make([]*tracepb.Span_TimeEvent, 0, len(logs))
+
+	for _, log := range logs {
+		description, _, _, attribs := jProtoTagsToAttributes(log.Fields)
+		var annotation *tracepb.Span_TimeEvent_Annotation
+		if attribs != nil {
+			annotation = &tracepb.Span_TimeEvent_Annotation{
+				Description: strToTruncatableString(description),
+				Attributes:  attribs,
+			}
+		}
+		timeEvent := &tracepb.Span_TimeEvent{
+			Time:  internal.TimeToTimestamp(log.Timestamp),
+			Value: &tracepb.Span_TimeEvent_Annotation_{Annotation: annotation},
+		}
+
+		timeEvents = append(timeEvents, timeEvent)
+	}
+
+	return &tracepb.Span_TimeEvents{TimeEvent: timeEvents}
+}
+
+func jProtoReferencesToOCProtoLinks(jrefs []jaeger.SpanRef) *tracepb.Span_Links {
+	if len(jrefs) == 0 {
+		return nil
+	}
+
+	links := make([]*tracepb.Span_Link, 0, len(jrefs))
+
+	for _, jref := range jrefs {
+		// Skip the references without trace or span ID, as the Thrift translation does.
+		if (jref.TraceID == jaeger.TraceID{}) || jref.SpanID == 0 {
+			continue
+		}
+		var linkType tracepb.Span_Link_Type
+		if jref.RefType == jaeger.SpanRefType_CHILD_OF {
+			// Wording on OC for Span_Link_PARENT_LINKED_SPAN: The linked span is a parent of the current span.
+			linkType = tracepb.Span_Link_PARENT_LINKED_SPAN
+		} else {
+			// TODO: SpanRefType_FOLLOWS_FROM doesn't map well to OC, so treat all other cases as unknown
+			linkType = tracepb.Span_Link_TYPE_UNSPECIFIED
+		}
+
+		link := &tracepb.Span_Link{
+			TraceId: tracetranslator.UInt64ToByteTraceID(jref.TraceID.High, jref.TraceID.Low),
+			SpanId:  tracetranslator.UInt64ToByteSpanID(uint64(jref.SpanID)),
+			Type:    linkType,
+		}
+		links = append(links, link)
+	}
+
+	return &tracepb.Span_Links{Link: links}
+}
+
+// Replica of jaegerthrift_to_protospan.jtagsToAttributes
+func jProtoTagsToAttributes(tags []jaeger.KeyValue) (string, tracepb.Span_SpanKind, *tracepb.Status, *tracepb.Span_Attributes) {
+	if tags == nil {
+		return "", tracepb.Span_SPAN_KIND_UNSPECIFIED, nil, nil
+	}
+
+	var sKind tracepb.Span_SpanKind
+
+	var statusCodePtr *int32
+	var statusMessage string
+	var message string
+
+	sAttribs := make(map[string]*tracepb.AttributeValue)
+
+	for _, tag := range tags {
+		// Take the opportunity to get the "span.kind" per OpenTracing spec, however, keep it also on the attributes.
+		switch tag.Key {
+		case "span.kind":
+			switch tag.VStr {
+			case "client":
+				sKind = tracepb.Span_CLIENT
+			case "server":
+				sKind = tracepb.Span_SERVER
+			}
+
+		case "http.status_code", "status.code": // It is expected to be an int
+			statusCodePtr = new(int32)
+			*statusCodePtr = int32(tag.VInt64)
+
+		case "http.status_message", "status.message":
+			statusMessage = tag.VStr
+
+		case "message":
+			message = tag.VStr
+		}
+
+		attrib := &tracepb.AttributeValue{}
+		switch tag.VType {
+		case jaeger.ValueType_STRING:
+			attrib.Value = &tracepb.AttributeValue_StringValue{
+				StringValue: &tracepb.TruncatableString{Value: tag.VStr},
+			}
+		case jaeger.ValueType_FLOAT64:
+			attrib.Value = &tracepb.AttributeValue_DoubleValue{
+				DoubleValue: tag.VFloat64,
+			}
+		case jaeger.ValueType_BOOL:
+			attrib.Value = &tracepb.AttributeValue_BoolValue{
+				BoolValue: tag.VBool,
+			}
+		case jaeger.ValueType_INT64:
+			attrib.Value = &tracepb.AttributeValue_IntValue{
+				IntValue: tag.VInt64,
+			}
+		case jaeger.ValueType_BINARY:
+			attrib.Value = &tracepb.AttributeValue_StringValue{
+				StringValue: &tracepb.TruncatableString{Value: base64.StdEncoding.EncodeToString(tag.VBinary)},
+			}
+		default:
+			attrib.Value = &tracepb.AttributeValue_StringValue{
+				StringValue: &tracepb.TruncatableString{Value: fmt.Sprintf("<Unknown Jaeger ValueType %q>", tag.VType)},
+			}
+		}
+
+		sAttribs[tag.Key] = attrib
+	}
+
+	var sStatus *tracepb.Status
+	if statusCodePtr != nil || statusMessage != "" {
+		statusCode := int32(0)
+		if statusCodePtr != nil {
+			statusCode = *statusCodePtr
+		}
+		sStatus = &tracepb.Status{Message: statusMessage, Code: statusCode}
+	}
+
+	var sAttributes *tracepb.Span_Attributes
+	if len(sAttribs) > 0 {
+		sAttributes = &tracepb.Span_Attributes{AttributeMap: sAttribs}
+	}
+	return message, sKind, sStatus, sAttributes
+}
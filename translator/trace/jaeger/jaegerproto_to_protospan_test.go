@@ -0,0 +1,158 @@
+// Copyright 2019, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jaeger
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	commonpb "github.com/census-instrumentation/opencensus-proto/gen-go/agent/common/v1"
+	tracepb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	jaeger "github.com/jaegertracing/jaeger/model"
+
+	"github.com/census-instrumentation/opencensus-service/data"
+)
+
+func TestProtoBatchToOCProto(t *testing.T) {
+	startTime := time.Date(2017, 1, 26, 21, 46, 31, 639875000, time.UTC)
+	traceID := jaeger.TraceID{High: 0x1, Low: 0x52969a8955571a3f}
+	jb := jaeger.Batch{
+		Process: &jaeger.Process{
+			ServiceName: "api",
+			Tags: []jaeger.KeyValue{
+				{Key: "hostname", VStr: "api246-sjc1"},
+				{Key: "jaeger.version", VStr: "Go-2.9.0"},
+				{Key: "pid", VType: jaeger.ValueType_INT64, VInt64: 13},
+				{Key: "a.bool", VType: jaeger.ValueType_BOOL, VBool: true},
+			},
+		},
+		Spans: []*jaeger.Span{
+			{
+				TraceID:       traceID,
+				SpanID:        jaeger.SpanID(0x647d98),
+				OperationName: "get",
+				References: []jaeger.SpanRef{
+					{TraceID: jaeger.TraceID{Low: 0x10}, SpanID: jaeger.SpanID(0x20), RefType: jaeger.SpanRefType_FOLLOWS_FROM},
+					{TraceID: traceID, SpanID: jaeger.SpanID(0x68c4e3), RefType: jaeger.SpanRefType_CHILD_OF},
+				},
+				StartTime: startTime,
+				Duration:  22938 * time.Microsecond,
+				Tags: []jaeger.KeyValue{
+					{Key: "span.kind", VStr: "server"},
+					{Key: "http.status_code", VType: jaeger.ValueType_INT64, VInt64: 500},
+					{Key: "a.double", VType: jaeger.ValueType_FLOAT64, VFloat64: 1234.56789},
+					{Key: "a.binary", VType: jaeger.ValueType_BINARY, VBinary: []byte{0x01, 0x02}},
+				},
+				Logs: []jaeger.Log{
+					{
+						Timestamp: startTime.Add(time.Millisecond),
+						Fields:    []jaeger.KeyValue{{Key: "message", VStr: "retrying"}},
+					},
+				},
+			},
+			// Blank spans are skipped.
+			{},
+			nil,
+		},
+	}
+
+	got, err := ProtoBatchToOCProto(jb)
+	if err != nil {
+		t.Fatalf("Failed to translate Jaeger Proto batch: %v", err)
+	}
+
+	want := data.TraceData{
+		Node: &commonpb.Node{
+			Identifier:  &commonpb.ProcessIdentifier{HostName: "api246-sjc1"},
+			LibraryInfo: &commonpb.LibraryInfo{ExporterVersion: "Jaeger-Go-2.9.0"},
+			ServiceInfo: &commonpb.ServiceInfo{Name: "api"},
+			Attributes: map[string]string{
+				"pid":    "13",
+				"a.bool": "true",
+			},
+		},
+		Spans: []*tracepb.Span{
+			{
+				TraceId:      []byte{0, 0, 0, 0, 0, 0, 0, 0x1, 0x52, 0x96, 0x9a, 0x89, 0x55, 0x57, 0x1a, 0x3f},
+				SpanId:       []byte{0, 0, 0, 0, 0, 0x64, 0x7d, 0x98},
+				ParentSpanId: []byte{0, 0, 0, 0, 0, 0x68, 0xc4, 0xe3},
+				Name:         &tracepb.TruncatableString{Value: "get"},
+				Kind:         tracepb.Span_SERVER,
+				StartTime:    &timestamp.Timestamp{Seconds: 1485467191, Nanos: 639875000},
+				EndTime:      &timestamp.Timestamp{Seconds: 1485467191, Nanos: 662813000},
+				Attributes: &tracepb.Span_Attributes{
+					AttributeMap: map[string]*tracepb.AttributeValue{
+						"span.kind": {
+							Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: "server"}},
+						},
+						"http.status_code": {
+							Value: &tracepb.AttributeValue_IntValue{IntValue: 500},
+						},
+						"a.double": {
+							Value: &tracepb.AttributeValue_DoubleValue{DoubleValue: 1234.56789},
+						},
+						"a.binary": {
+							Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: "AQI="}},
+						},
+					},
+				},
+				TimeEvents: &tracepb.Span_TimeEvents{
+					TimeEvent: []*tracepb.Span_TimeEvent{
+						{
+							Time: &timestamp.Timestamp{Seconds: 1485467191, Nanos: 640875000},
+							Value: &tracepb.Span_TimeEvent_Annotation_{
+								Annotation: &tracepb.Span_TimeEvent_Annotation{
+									Description: &tracepb.TruncatableString{Value: "retrying"},
+									Attributes: &tracepb.Span_Attributes{
+										AttributeMap: map[string]*tracepb.AttributeValue{
+											"message": {
+												Value: &tracepb.AttributeValue_StringValue{StringValue: &tracepb.TruncatableString{Value: "retrying"}},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				Links: &tracepb.Span_Links{
+					Link: []*tracepb.Span_Link{
+						{
+							TraceId: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x10},
+							SpanId:  []byte{0, 0, 0, 0, 0, 0, 0, 0x20},
+							Type:    tracepb.Span_Link_TYPE_UNSPECIFIED,
+						},
+					},
+				},
+				Status: &tracepb.Status{Code: 500},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Jaeger Proto to OC Proto failed.\nGot:\n%+v\nWant:\n%+v\n", got, want)
+	}
+}
+
+func TestProtoBatchToOCProto_NilProcess(t *testing.T) {
+	got, err := ProtoBatchToOCProto(jaeger.Batch{})
+	if err != nil {
+		t.Fatalf("Failed to translate Jaeger Proto batch: %v", err)
+	}
+	if got.Node != nil || len(got.Spans) != 0 {
+		t.Errorf("Got %+v, want an empty batch", got)
+	}
+}